@@ -0,0 +1,488 @@
+package enzonix
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AuditOutcome reports whether an audited operation succeeded.
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	AuditFailure AuditOutcome = "failure"
+)
+
+// ErrAuditChainBroken is returned by VerifyAuditChain when an entry does not
+// link to its predecessor or its hash does not match its content.
+var ErrAuditChainBroken = errors.New("enzonix: audit chain broken")
+
+// AuditWriteError reports that the audit sink failed to record a call.
+// When the call itself failed too, the call's error is joined with it.
+type AuditWriteError struct {
+	Operation string
+	// Applied reports whether the audited mutation took effect, in which
+	// case the call's result is returned alongside the error.
+	Applied bool
+	Err     error
+}
+
+func (e *AuditWriteError) Error() string {
+	return fmt.Sprintf("enzonix: write audit entry for %s: %v", e.Operation, e.Err)
+}
+
+func (e *AuditWriteError) Unwrap() error { return e.Err }
+
+// IsAuditOnly reports whether err consists solely of AuditWriteErrors for
+// mutations that took effect, so the call's result is complete and only
+// its audit trail is not. Batch operations such as ReplaceRRSet join one
+// for each mutation they could not audit.
+func IsAuditOnly(err error) bool {
+	switch e := err.(type) {
+	case *AuditWriteError:
+		return e.Applied
+	case interface{ Unwrap() []error }:
+		errs := e.Unwrap()
+		for _, err := range errs {
+			if !IsAuditOnly(err) {
+				return false
+			}
+		}
+		return len(errs) > 0
+	case interface{ Unwrap() error }:
+		return IsAuditOnly(e.Unwrap())
+	}
+	return false
+}
+
+// batchAudit collects the audit failures of mutations that took effect,
+// so that batch operations can record their results and carry on.
+type batchAudit struct {
+	errs []error
+}
+
+// absorb reports whether err is nil or only an audit failure of an
+// applied mutation, which it keeps.
+func (b *batchAudit) absorb(err error) bool {
+	if err != nil && !IsAuditOnly(err) {
+		return false
+	}
+	if err != nil {
+		b.errs = append(b.errs, err)
+	}
+	return true
+}
+
+// join returns err together with the audit failures kept so far.
+func (b *batchAudit) join(err error) error {
+	if len(b.errs) == 0 {
+		return err
+	}
+	return errors.Join(append(b.errs, err)...)
+}
+
+// AuditEntry describes one mutation made through the client. Entries never
+// contain API keys.
+type AuditEntry struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor,omitempty"`
+	Operation string    `json:"operation"`
+	DomainID  string    `json:"domain_id,omitempty"`
+	RecordID  string    `json:"record_id,omitempty"`
+	// Domain is set for domain operations and imports.
+	Domain *Domain `json:"domain,omitempty"`
+	// Before and After hold the record state around record operations.
+	Before *Record `json:"before,omitempty"`
+	After  *Record `json:"after,omitempty"`
+	// Records lists the records removed by DeleteDomain or created by
+	// ImportBindZone.
+	Records   []Record     `json:"records,omitempty"`
+	Outcome   AuditOutcome `json:"outcome"`
+	Error     string       `json:"error,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	// PrevHash and Hash are filled in by sinks that chain entries, such as
+	// FileAuditSink.
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// AuditSink receives an entry for every audited mutation.
+type AuditSink interface {
+	WriteAuditEntry(ctx context.Context, entry AuditEntry) error
+}
+
+// AuditSinkFunc adapts a function to the AuditSink interface.
+type AuditSinkFunc func(ctx context.Context, entry AuditEntry) error
+
+// WriteAuditEntry calls f.
+func (f AuditSinkFunc) WriteAuditEntry(ctx context.Context, entry AuditEntry) error {
+	return f(ctx, entry)
+}
+
+// WithAuditSink records every CreateDomain, DeleteDomain, CreateRecord,
+// UpdateRecord, DeleteRecord, ImportBindZone and RotateAPIKey call that
+// reaches the API. Record state before updates and deletes is fetched
+// first, which costs extra requests.
+//
+// When the sink fails the mutation's result is still returned, together
+// with an *AuditWriteError wrapping the sink's error. Operations making
+// several changes carry on past such errors and return them joined.
+func WithAuditSink(sink AuditSink) Option {
+	return func(c *Client) error {
+		if sink == nil {
+			return errors.New("enzonix: audit sink must not be nil")
+		}
+		c.auditSink = sink
+		return nil
+	}
+}
+
+// WithAuditActor sets the actor label written to audit entries, e.g. the
+// name of the service or person using the client.
+func WithAuditActor(actor string) Option {
+	return func(c *Client) error {
+		c.auditActor = actor
+		return nil
+	}
+}
+
+type requestIDKey struct{}
+
+// requestIDHolder collects the X-Request-ID of the last response sent for
+// a context.
+type requestIDHolder struct {
+	mu sync.Mutex
+	id string
+}
+
+func (h *requestIDHolder) set(id string) {
+	h.mu.Lock()
+	h.id = id
+	h.mu.Unlock()
+}
+
+func (h *requestIDHolder) get() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.id
+}
+
+// auditRun tracks one audited call. A nil *auditRun is valid and does
+// nothing, which keeps call sites free of sink checks.
+type auditRun struct {
+	client *Client
+	holder *requestIDHolder
+	entry  AuditEntry
+}
+
+func (c *Client) beginAudit(ctx context.Context, op string) (context.Context, *auditRun) {
+	if c.auditSink == nil {
+		return ctx, nil
+	}
+	holder := &requestIDHolder{}
+	run := &auditRun{
+		client: c,
+		holder: holder,
+		entry:  AuditEntry{ID: newAuditID(), Actor: c.auditActor, Operation: op},
+	}
+	return context.WithValue(ctx, requestIDKey{}, holder), run
+}
+
+// finish writes the entry for a call that ended with err and returns the
+// error the caller should report.
+func (a *auditRun) finish(ctx context.Context, err error) error {
+	if a == nil {
+		return err
+	}
+	a.entry.Time = time.Now().UTC()
+	a.entry.RequestID = a.holder.get()
+	a.entry.Outcome = AuditSuccess
+	if err != nil {
+		a.entry.Outcome = AuditFailure
+		a.entry.Error = err.Error()
+	}
+
+	werr := a.client.auditSink.WriteAuditEntry(context.WithoutCancel(ctx), a.entry)
+	if werr == nil {
+		return err
+	}
+	werr = &AuditWriteError{Operation: a.entry.Operation, Applied: err == nil, Err: werr}
+	if err != nil {
+		return errors.Join(err, werr)
+	}
+	return werr
+}
+
+// captureRequestID stores the response's request ID for audit entries.
+func captureRequestID(ctx context.Context, id string) {
+	if holder, ok := ctx.Value(requestIDKey{}).(*requestIDHolder); ok && id != "" {
+		holder.set(id)
+	}
+}
+
+func newAuditID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// FileAuditOptions configures a FileAuditSink.
+type FileAuditOptions struct {
+	// MaxBytes rotates the journal once it grows beyond this size. Zero
+	// disables rotation.
+	MaxBytes int64
+	// MaxBackups is the number of rotated files kept as path.1, path.2 and
+	// so on. Zero keeps all of them.
+	MaxBackups int
+}
+
+// FileAuditSink appends entries as JSON lines to a file. Each entry carries
+// the hash of its predecessor, and the chain continues across rotations and
+// restarts, so removed or edited entries are detected by VerifyAuditChain.
+type FileAuditSink struct {
+	path string
+	opts FileAuditOptions
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	lastHash string
+}
+
+// NewFileAuditSink opens or creates the journal at path.
+func NewFileAuditSink(path string, opts FileAuditOptions) (*FileAuditSink, error) {
+	s := &FileAuditSink{path: path, opts: opts}
+	last, err := lastAuditHash(path)
+	if err != nil {
+		return nil, err
+	}
+	if last == "" {
+		// The current file may have just been rotated.
+		if last, err = lastAuditHash(path + ".1"); err != nil {
+			return nil, err
+		}
+	}
+	s.lastHash = last
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// WriteAuditEntry chains and appends entry.
+func (s *FileAuditSink) WriteAuditEntry(_ context.Context, entry AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return errors.New("enzonix: audit sink closed")
+	}
+
+	entry.PrevHash = s.lastHash
+	hash, err := auditHash(entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if s.opts.MaxBytes > 0 && s.size > 0 && s.size+int64(len(line)) > s.opts.MaxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	s.lastHash = hash
+	return nil
+}
+
+// Close closes the journal file.
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *FileAuditSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("enzonix: open audit journal: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("enzonix: open audit journal: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("enzonix: open audit journal: %w", err)
+	}
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+func (s *FileAuditSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+
+	// Find the highest existing backup, then shift every file up by one.
+	n := 1
+	for {
+		if _, err := os.Stat(fmt.Sprintf("%s.%d", s.path, n)); err != nil {
+			break
+		}
+		n++
+	}
+	for i := n; i > 1; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", s.path, i-1), fmt.Sprintf("%s.%d", s.path, i)); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil {
+		return err
+	}
+	if s.opts.MaxBackups > 0 {
+		for i := s.opts.MaxBackups + 1; i <= n; i++ {
+			if err := os.Remove(fmt.Sprintf("%s.%d", s.path, i)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return s.open()
+}
+
+// ReadAuditEntries decodes a JSON lines journal.
+func ReadAuditEntries(r io.Reader) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("enzonix: audit journal line %d: %w", n, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("enzonix: read audit journal: %w", err)
+	}
+	return entries, nil
+}
+
+// ReadAuditFile reads a journal written by FileAuditSink, including its
+// rotated backups, oldest entry first.
+func ReadAuditFile(path string) ([]AuditEntry, error) {
+	var files []string
+	for n := 1; ; n++ {
+		name := fmt.Sprintf("%s.%d", path, n)
+		if _, err := os.Stat(name); err != nil {
+			break
+		}
+		files = append([]string{name}, files...)
+	}
+	files = append(files, path)
+
+	var entries []AuditEntry
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("enzonix: read audit journal: %w", err)
+		}
+		chunk, err := ReadAuditEntries(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, chunk...)
+	}
+	return entries, nil
+}
+
+// VerifyAuditChain checks that every entry links to its predecessor and
+// that its hash matches its content. The first entry may link to a hash
+// outside the slice, for example in a backup that was pruned.
+func VerifyAuditChain(entries []AuditEntry) error {
+	for i, entry := range entries {
+		if i > 0 && entry.PrevHash != entries[i-1].Hash {
+			return fmt.Errorf("%w: entry %s does not follow %s", ErrAuditChainBroken, entry.ID, entries[i-1].ID)
+		}
+		want, err := auditHash(entry)
+		if err != nil {
+			return err
+		}
+		if entry.Hash != want {
+			return fmt.Errorf("%w: entry %s was modified", ErrAuditChainBroken, entry.ID)
+		}
+	}
+	return nil
+}
+
+// auditHash is the SHA-256 of the entry's JSON encoding without its own
+// hash, so it covers PrevHash and thereby the whole chain.
+func auditHash(entry AuditEntry) (string, error) {
+	entry.Hash = ""
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("enzonix: hash audit entry: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lastAuditHash returns the hash of the last entry in the file at path, or
+// "" when the file is missing or empty.
+func lastAuditHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("enzonix: open audit journal: %w", err)
+	}
+	defer f.Close()
+
+	entries, err := ReadAuditEntries(f)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "", nil
+	}
+	return entries[len(entries)-1].Hash, nil
+}
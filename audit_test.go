@@ -0,0 +1,160 @@
+package enzonix
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+func TestAuditJournal(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit", "journal.jsonl")
+	sink, err := NewFileAuditSink(path, FileAuditOptions{})
+	if err != nil {
+		t.Fatalf("open sink: %v", err)
+	}
+	defer sink.Close()
+
+	fake, client := newFakeClient(t, WithAuditSink(sink), WithAuditActor("deploy-bot"))
+	domain := fake.AddDomain("example.com")
+	www := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	ctx := context.Background()
+
+	created, err := client.CreateRecord(ctx, CreateRecordRequest{DomainID: domain.ID, Name: "api", Type: "A", Value: "192.0.2.2"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	value := "192.0.2.10"
+	if _, err := client.UpdateRecord(ctx, www.ID, UpdateRecordRequest{Value: &value}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := client.DeleteRecord(ctx, created.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := client.DeleteRecord(ctx, "missing"); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if _, err := client.RotateAPIKey(ctx); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read journal: %v", err)
+	}
+	if strings.Contains(string(data), fake.APIKey()) || strings.Contains(string(data), "Bearer") {
+		t.Fatalf("journal leaks credentials:\n%s", data)
+	}
+
+	entries, err := ReadAuditFile(path)
+	if err != nil {
+		t.Fatalf("read entries: %v", err)
+	}
+	ops := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ops = append(ops, e.Operation+":"+string(e.Outcome))
+		if e.Actor != "deploy-bot" || e.ID == "" || e.Time.IsZero() || e.RequestID == "" {
+			t.Fatalf("incomplete entry %#v", e)
+		}
+	}
+	want := "CreateRecord:success UpdateRecord:success DeleteRecord:success DeleteRecord:failure RotateAPIKey:success"
+	if got := strings.Join(ops, " "); got != want {
+		t.Fatalf("unexpected operations %q", got)
+	}
+
+	if e := entries[0]; e.After == nil || e.After.ID != created.ID || e.Before != nil {
+		t.Fatalf("unexpected create entry %#v", e)
+	}
+	if e := entries[1]; e.Before == nil || e.Before.Value != "192.0.2.1" || e.After == nil || e.After.Value != value {
+		t.Fatalf("unexpected update entry %#v", e)
+	}
+	if e := entries[2]; e.Before == nil || e.Before.Name != "api" || e.DomainID != domain.ID {
+		t.Fatalf("unexpected delete entry %#v", e)
+	}
+	if e := entries[3]; e.Error == "" || e.Before != nil {
+		t.Fatalf("unexpected failed entry %#v", e)
+	}
+
+	if err := VerifyAuditChain(entries); err != nil {
+		t.Fatalf("verify chain: %v", err)
+	}
+	entries[1].After.Value = "203.0.113.1"
+	if err := VerifyAuditChain(entries); !errors.Is(err, ErrAuditChainBroken) {
+		t.Fatalf("expected tampering to be detected, got %v", err)
+	}
+	if err := VerifyAuditChain(append(entries[:1:1], entries[2:]...)); !errors.Is(err, ErrAuditChainBroken) {
+		t.Fatalf("expected removal to be detected, got %v", err)
+	}
+}
+
+func TestAuditWriteError(t *testing.T) {
+	t.Parallel()
+
+	failing := AuditSinkFunc(func(context.Context, AuditEntry) error { return errors.New("disk full") })
+	fake, client := newFakeClient(t, WithAuditSink(failing))
+	domain := fake.AddDomain("example.com")
+	ctx := context.Background()
+
+	created, err := client.CreateRecord(ctx, CreateRecordRequest{DomainID: domain.ID, Name: "api", Type: "A", Value: "192.0.2.2"})
+	var auditErr *AuditWriteError
+	if created == nil || !errors.As(err, &auditErr) || !auditErr.Applied || auditErr.Operation != "CreateRecord" || !IsAuditOnly(err) {
+		t.Fatalf("expected the record and an audit error, got %+v, %v", created, err)
+	}
+	if err := client.DeleteRecord(ctx, "missing"); !IsNotFound(err) || IsAuditOnly(err) {
+		t.Fatalf("expected a failed delete, got %v", err)
+	}
+}
+
+func TestFileAuditSinkRotation(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	ctx := context.Background()
+	write := func(sink *FileAuditSink, n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			if err := sink.WriteAuditEntry(ctx, AuditEntry{ID: newAuditID(), Operation: "CreateRecord", Outcome: AuditSuccess}); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+		}
+	}
+
+	sink, err := NewFileAuditSink(path, FileAuditOptions{MaxBytes: 400, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("open sink: %v", err)
+	}
+	write(sink, 4)
+	sink.Close()
+
+	// Reopening continues the chain from the last entry on disk.
+	sink, err = NewFileAuditSink(path, FileAuditOptions{MaxBytes: 400, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("reopen sink: %v", err)
+	}
+	write(sink, 2)
+	sink.Close()
+
+	if _, err := os.Stat(path + ".2"); err != nil {
+		t.Fatalf("expected rotated backups: %v", err)
+	}
+	if _, err := os.Stat(path + ".3"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected old backups to be pruned, got %v", err)
+	}
+
+	entries, err := ReadAuditFile(path)
+	if err != nil {
+		t.Fatalf("read entries: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 retained entries, got %d", len(entries))
+	}
+	if err := VerifyAuditChain(entries); err != nil {
+		t.Fatalf("verify chain across rotations: %v", err)
+	}
+}
@@ -0,0 +1,389 @@
+// Package backup takes periodic snapshots of Enzonix zones and restores
+// them.
+//
+// Each snapshot consists of a manifest under snapshots/<domain>/ and a
+// content-addressed object under objects/ holding the zone's records and
+// BIND export. Zones that did not change since the previous snapshot are
+// not stored again.
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+)
+
+const (
+	snapshotPrefix = "snapshots/"
+	objectPrefix   = "objects/"
+	// timeLayout sorts lexically in chronological order.
+	timeLayout = "20060102T150405.000000000Z"
+)
+
+// Snapshot describes one stored state of a zone.
+type Snapshot struct {
+	// Key is the manifest's key in the store; it identifies the snapshot.
+	Key    string         `json:"-"`
+	Time   time.Time      `json:"time"`
+	Domain enzonix.Domain `json:"domain"`
+	// Hash addresses the zone content in the store.
+	Hash    string `json:"hash"`
+	Records int    `json:"records"`
+}
+
+// Zone is the content of a snapshot. Record IDs and timestamps are cleared
+// so that identical zones hash identically.
+type Zone struct {
+	Records []enzonix.Record `json:"records"`
+	Bind    string           `json:"bind"`
+}
+
+// Retention decides which snapshots Prune keeps. A snapshot is kept when
+// any rule keeps it; the zero value keeps everything.
+type Retention struct {
+	// KeepLast keeps the newest snapshots of each domain.
+	KeepLast int
+	// KeepDaily keeps the newest snapshot of each of the last days that
+	// have snapshots.
+	KeepDaily int
+	// KeepWithin keeps every snapshot younger than the duration.
+	KeepWithin time.Duration
+}
+
+func (r Retention) isZero() bool {
+	return r.KeepLast <= 0 && r.KeepDaily <= 0 && r.KeepWithin <= 0
+}
+
+// Options configures a Backup.
+type Options struct {
+	Retention Retention
+	// Logger receives errors from Run. Defaults to slog.Default().
+	Logger *slog.Logger
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Result reports the outcome of snapshotting one domain.
+type Result struct {
+	Snapshot Snapshot
+	// Unchanged is true when the zone matched the previous snapshot and
+	// no new snapshot was written.
+	Unchanged bool
+}
+
+// Backup snapshots zones into a BlobStore.
+type Backup struct {
+	client *enzonix.Client
+	store  BlobStore
+	opts   Options
+}
+
+// New returns a Backup reading zones through client.
+func New(client *enzonix.Client, store BlobStore, opts Options) *Backup {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Backup{client: client, store: store, opts: opts}
+}
+
+// Run snapshots every domain and prunes old snapshots each interval until
+// ctx is done. Failures are logged and retried on the next tick.
+func (b *Backup) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("backup: interval must be positive, got %v", interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := b.SnapshotAll(ctx); err != nil {
+			b.opts.Logger.ErrorContext(ctx, "enzonix backup failed", slog.Any("error", err))
+		}
+		if _, err := b.Prune(ctx); err != nil {
+			b.opts.Logger.ErrorContext(ctx, "enzonix backup prune failed", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// SnapshotAll snapshots every domain visible to the client. A failing
+// domain does not stop the others; their errors are joined.
+func (b *Backup) SnapshotAll(ctx context.Context) ([]Result, error) {
+	domains, err := b.client.ListDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		results []Result
+		errs    []error
+	)
+	for _, d := range domains {
+		res, err := b.SnapshotDomain(ctx, d)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("backup %s: %w", d.Name, err))
+			continue
+		}
+		results = append(results, res)
+	}
+	return results, errors.Join(errs...)
+}
+
+// SnapshotDomain stores the current state of a domain's zone.
+func (b *Backup) SnapshotDomain(ctx context.Context, domain enzonix.Domain) (Result, error) {
+	records, err := b.client.ListDomainRecords(ctx, domain.ID)
+	if err != nil {
+		return Result{}, err
+	}
+	bind, err := b.client.ExportBindZone(ctx, domain.ID)
+	if err != nil {
+		return Result{}, err
+	}
+
+	zone := Zone{Records: normalize(records), Bind: string(bind)}
+	data, err := json.Marshal(zone)
+	if err != nil {
+		return Result{}, err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	snaps, err := b.Snapshots(ctx, domain.Name)
+	if err != nil {
+		return Result{}, err
+	}
+	if n := len(snaps); n > 0 && snaps[n-1].Hash == hash {
+		return Result{Snapshot: snaps[n-1], Unchanged: true}, nil
+	}
+
+	if _, err := b.store.Get(ctx, objectKey(hash)); errors.Is(err, ErrNotExist) {
+		if err := b.store.Put(ctx, objectKey(hash), data); err != nil {
+			return Result{}, err
+		}
+	} else if err != nil {
+		return Result{}, err
+	}
+
+	snap := Snapshot{
+		Time:    b.opts.Now().UTC(),
+		Domain:  domain,
+		Hash:    hash,
+		Records: len(zone.Records),
+	}
+	snap.Key = snapshotPrefix + domainKey(domain.Name) + "/" + snap.Time.Format(timeLayout) + ".json"
+	manifest, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return Result{}, err
+	}
+	if err := b.store.Put(ctx, snap.Key, manifest); err != nil {
+		return Result{}, err
+	}
+	return Result{Snapshot: snap}, nil
+}
+
+// Snapshots lists the snapshots of a domain, oldest first.
+func (b *Backup) Snapshots(ctx context.Context, domainName string) ([]Snapshot, error) {
+	keys, err := b.store.List(ctx, snapshotPrefix+domainKey(domainName)+"/")
+	if err != nil {
+		return nil, err
+	}
+	snaps := make([]Snapshot, 0, len(keys))
+	for _, key := range keys {
+		snap, err := b.manifest(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, snap)
+	}
+	return snaps, nil
+}
+
+// Latest returns the newest snapshot of a domain.
+func (b *Backup) Latest(ctx context.Context, domainName string) (Snapshot, error) {
+	snaps, err := b.Snapshots(ctx, domainName)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if len(snaps) == 0 {
+		return Snapshot{}, fmt.Errorf("%w: no snapshots of %s", ErrNotExist, domainName)
+	}
+	return snaps[len(snaps)-1], nil
+}
+
+// Load reads the zone content of a snapshot.
+func (b *Backup) Load(ctx context.Context, snap Snapshot) (*Zone, error) {
+	data, err := b.store.Get(ctx, objectKey(snap.Hash))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != snap.Hash {
+		return nil, fmt.Errorf("backup: object %s is corrupt", snap.Hash)
+	}
+	var zone Zone
+	if err := json.Unmarshal(data, &zone); err != nil {
+		return nil, fmt.Errorf("backup: decode object %s: %w", snap.Hash, err)
+	}
+	return &zone, nil
+}
+
+// Prune deletes snapshots not kept by the retention policy, then objects
+// no snapshot refers to, and returns the deleted snapshots.
+func (b *Backup) Prune(ctx context.Context) ([]Snapshot, error) {
+	keys, err := b.store.List(ctx, snapshotPrefix)
+	if err != nil {
+		return nil, err
+	}
+	byDomain := map[string][]Snapshot{}
+	for _, key := range keys {
+		snap, err := b.manifest(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		dir := path.Dir(key)
+		byDomain[dir] = append(byDomain[dir], snap)
+	}
+
+	var removed []Snapshot
+	referenced := map[string]bool{}
+	now := b.opts.Now()
+	for _, snaps := range byDomain {
+		keep := b.opts.Retention.keep(snaps, now)
+		for i, snap := range snaps {
+			if keep[i] {
+				referenced[snap.Hash] = true
+				continue
+			}
+			if err := b.store.Delete(ctx, snap.Key); err != nil {
+				return removed, err
+			}
+			removed = append(removed, snap)
+		}
+	}
+
+	objects, err := b.store.List(ctx, objectPrefix)
+	if err != nil {
+		return removed, err
+	}
+	for _, key := range objects {
+		if !referenced[strings.TrimSuffix(path.Base(key), ".json")] {
+			if err := b.store.Delete(ctx, key); err != nil {
+				return removed, err
+			}
+		}
+	}
+	return removed, nil
+}
+
+// keep marks the snapshots to retain; snaps is sorted oldest first.
+func (r Retention) keep(snaps []Snapshot, now time.Time) []bool {
+	keep := make([]bool, len(snaps))
+	if r.isZero() {
+		for i := range keep {
+			keep[i] = true
+		}
+		return keep
+	}
+	days := map[string]bool{}
+	for i := len(snaps) - 1; i >= 0; i-- {
+		age := len(snaps) - 1 - i
+		if age < r.KeepLast {
+			keep[i] = true
+		}
+		if r.KeepWithin > 0 && now.Sub(snaps[i].Time) < r.KeepWithin {
+			keep[i] = true
+		}
+		day := snaps[i].Time.UTC().Format("2006-01-02")
+		if !days[day] && len(days) < r.KeepDaily {
+			days[day] = true
+			keep[i] = true
+		}
+	}
+	return keep
+}
+
+func (b *Backup) manifest(ctx context.Context, key string) (Snapshot, error) {
+	data, err := b.store.Get(ctx, key)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return Snapshot{}, fmt.Errorf("backup: decode manifest %s: %w", key, err)
+	}
+	snap.Key = key
+	return snap, nil
+}
+
+// PreDeleteSnapshot returns a hook for enzonix.WithPreDeleteDomainHook that
+// snapshots a domain before it is deleted. A failed snapshot aborts the
+// deletion.
+func PreDeleteSnapshot(store BlobStore, opts Options) enzonix.PreDeleteDomainHook {
+	return func(ctx context.Context, client *enzonix.Client, domain enzonix.Domain) error {
+		_, err := New(client, store, opts).SnapshotDomain(ctx, domain)
+		return err
+	}
+}
+
+// normalize strips server-assigned fields and sorts records so that the
+// snapshot content depends only on the zone's data.
+func normalize(records []enzonix.Record) []enzonix.Record {
+	out := make([]enzonix.Record, 0, len(records))
+	for _, r := range records {
+		countries := append([]string(nil), r.CountryCodes...)
+		sort.Strings(countries)
+		out = append(out, enzonix.Record{
+			Name:         r.Name,
+			Type:         strings.ToUpper(r.Type),
+			TTL:          r.TTL,
+			CountryCodes: countries,
+			Priority:     r.Priority,
+			Value:        r.Value,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Value != b.Value {
+			return a.Value < b.Value
+		}
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		if a.TTL != b.TTL {
+			return a.TTL < b.TTL
+		}
+		return strings.Join(a.CountryCodes, ",") < strings.Join(b.CountryCodes, ",")
+	})
+	return out
+}
+
+func objectKey(hash string) string {
+	return objectPrefix + hash + ".json"
+}
+
+// domainKey turns a domain name into a store path element.
+func domainKey(name string) string {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	return strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+}
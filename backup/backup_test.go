@@ -0,0 +1,291 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/enzonixtest"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+func newTestBackup(t *testing.T, opts Options, clientOpts ...enzonix.Option) (*fakeapi.Server, *enzonix.Client, *Backup, *DirStore) {
+	t.Helper()
+	fake, client := enzonixtest.NewClient(t, clientOpts...)
+	store := NewDirStore(t.TempDir())
+	return fake, client, New(client, store, opts), store
+}
+
+// clock returns a Now function advancing by step on every call.
+func clock(start time.Time, step time.Duration) func() time.Time {
+	now := start
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestSnapshotDeduplicatesAndRestores(t *testing.T) {
+	t.Parallel()
+
+	fake, client, b, store := newTestBackup(t, Options{Now: clock(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), time.Minute)})
+	domain := fake.AddDomain("example.com")
+	www := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "@", Type: "MX", TTL: 3600, Priority: 10, Value: "mx.example.com."})
+	ctx := context.Background()
+
+	results, err := b.SnapshotAll(ctx)
+	if err != nil || len(results) != 1 || results[0].Unchanged {
+		t.Fatalf("unexpected first snapshot %+v, %v", results, err)
+	}
+	first := results[0].Snapshot
+
+	results, err = b.SnapshotAll(ctx)
+	if err != nil || !results[0].Unchanged {
+		t.Fatalf("expected unchanged zone to be deduplicated, got %+v, %v", results, err)
+	}
+
+	// Break the zone.
+	value := "203.0.113.1"
+	if _, err := client.UpdateRecord(ctx, www.ID, enzonix.UpdateRecordRequest{Value: &value}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if _, err := client.CreateRecord(ctx, enzonix.CreateRecordRequest{DomainID: domain.ID, Name: "junk", Type: "TXT", Value: "x"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	mx := fake.Records(domain.ID)[1]
+	if err := client.DeleteRecord(ctx, mx.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := b.SnapshotAll(ctx); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+
+	snaps, err := b.Snapshots(ctx, "example.com")
+	if err != nil || len(snaps) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d, %v", len(snaps), err)
+	}
+	objects, _ := store.List(ctx, objectPrefix)
+	if len(objects) != 2 {
+		t.Fatalf("expected 2 objects, got %v", objects)
+	}
+
+	res, err := b.Restore(ctx, domain.ID, first)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if len(res.Created) != 1 || len(res.Updated) != 1 || len(res.Deleted) != 1 {
+		t.Fatalf("unexpected restore result %+v", res)
+	}
+	records := fake.Records(domain.ID)
+	if len(records) != 2 {
+		t.Fatalf("unexpected records after restore %+v", records)
+	}
+	for _, r := range records {
+		if (r.Type == "A" && (r.ID != www.ID || r.Value != "192.0.2.1")) || (r.Type == "MX" && r.Priority != 10) || r.Type == "TXT" {
+			t.Fatalf("record not restored: %+v", r)
+		}
+	}
+
+	// The restored zone matches the first snapshot again.
+	domains, err := client.ListDomains(ctx)
+	if err != nil {
+		t.Fatalf("list domains: %v", err)
+	}
+	result, err := b.SnapshotDomain(ctx, domains[0])
+	if err != nil || result.Snapshot.Hash != first.Hash {
+		t.Fatalf("expected restored zone to hash like the first snapshot, got %+v, %v", result, err)
+	}
+}
+
+func TestRestoreOverGeoVariant(t *testing.T) {
+	t.Parallel()
+
+	fake, client, b, _ := newTestBackup(t, Options{})
+	domain := fake.AddDomain("example.com")
+	www := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	ctx := context.Background()
+
+	results, err := b.SnapshotAll(ctx)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	first := results[0].Snapshot
+
+	// The default record was replaced by a country variant.
+	if err := client.DeleteRecord(ctx, www.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.9", CountryCodes: []string{"DE"}})
+
+	res, err := b.Restore(ctx, domain.ID, first)
+	if err != nil || len(res.Deleted) != 1 || len(res.Created) != 1 || len(res.Updated) != 0 {
+		t.Fatalf("expected a delete and a create, got %+v, %v", res, err)
+	}
+	if records := fake.Records(domain.ID); len(records) != 1 || records[0].Value != "192.0.2.1" || len(records[0].CountryCodes) != 0 {
+		t.Fatalf("record not restored: %+v", records)
+	}
+}
+
+func TestRestoreContinuesPastAuditErrors(t *testing.T) {
+	t.Parallel()
+
+	var full bool
+	journal := enzonix.AuditSinkFunc(func(context.Context, enzonix.AuditEntry) error {
+		if full {
+			return errors.New("disk full")
+		}
+		return nil
+	})
+	fake, client, b, _ := newTestBackup(t, Options{}, enzonix.WithAuditSink(journal))
+	domain := fake.AddDomain("example.com")
+	www := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	api := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "api", Type: "A", TTL: 300, Value: "192.0.2.2"})
+	ctx := context.Background()
+
+	results, err := b.SnapshotAll(ctx)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if err := client.DeleteRecord(ctx, www.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	value := "192.0.2.9"
+	if _, err := client.UpdateRecord(ctx, api.ID, enzonix.UpdateRecordRequest{Value: &value}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "tmp", Type: "TXT", TTL: 300, Value: "oops"})
+
+	full = true
+	res, err := b.Restore(ctx, domain.ID, results[0].Snapshot)
+	if !enzonix.IsAuditOnly(err) {
+		t.Fatalf("expected audit-only error, got %v", err)
+	}
+	if len(res.Deleted) != 1 || len(res.Updated) != 1 || len(res.Created) != 1 {
+		t.Fatalf("restore stopped early: %+v", res)
+	}
+	if records := fake.Records(domain.ID); len(records) != 2 {
+		t.Fatalf("zone not restored: %+v", records)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	retention := Retention{KeepLast: 2, KeepDaily: 3}
+	midnight := now.Truncate(24 * time.Hour)
+	var snaps []Snapshot
+	// Two snapshots a day over the five previous days.
+	for day := 5; day >= 1; day-- {
+		for _, hour := range []int{1, 13} {
+			snaps = append(snaps, Snapshot{Time: midnight.Add(-time.Duration(day)*24*time.Hour + time.Duration(hour)*time.Hour)})
+		}
+	}
+	keep := retention.keep(snaps, now)
+	var kept []string
+	for i, k := range keep {
+		if k {
+			kept = append(kept, snaps[i].Time.Format("01-02T15"))
+		}
+	}
+	// The newest two, plus the newest of each of the last three days.
+	want := []string{"05-07T13", "05-08T13", "05-09T01", "05-09T13"}
+	if len(kept) != len(want) {
+		t.Fatalf("kept %v, want %v", kept, want)
+	}
+	for i := range want {
+		if kept[i] != want[i] {
+			t.Fatalf("kept %v, want %v", kept, want)
+		}
+	}
+
+	fake, client, b, store := newTestBackup(t, Options{
+		Retention: Retention{KeepLast: 1},
+		Now:       clock(now, time.Hour),
+	})
+	domain := fake.AddDomain("example.com")
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, err := client.CreateRecord(ctx, enzonix.CreateRecordRequest{DomainID: domain.ID, Name: "r", Type: "TXT", Value: string(rune('a' + i))}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if _, err := b.SnapshotAll(ctx); err != nil {
+			t.Fatalf("snapshot: %v", err)
+		}
+	}
+	removed, err := b.Prune(ctx)
+	if err != nil || len(removed) != 2 {
+		t.Fatalf("expected 2 pruned snapshots, got %d, %v", len(removed), err)
+	}
+	objects, _ := store.List(ctx, objectPrefix)
+	if len(objects) != 1 {
+		t.Fatalf("expected unreferenced objects to be removed, got %v", objects)
+	}
+	latest, err := b.Latest(ctx, "example.com")
+	if err != nil || latest.Records != 3 {
+		t.Fatalf("unexpected latest snapshot %+v, %v", latest, err)
+	}
+}
+
+func TestPreDeleteSnapshot(t *testing.T) {
+	t.Parallel()
+
+	store := NewDirStore(t.TempDir())
+	fake, client, _, _ := newTestBackup(t, Options{}, enzonix.WithPreDeleteDomainHook(PreDeleteSnapshot(store, Options{})))
+	b := New(client, store, Options{})
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	ctx := context.Background()
+
+	if _, err := b.Latest(ctx, "example.com"); !errors.Is(err, ErrNotExist) {
+		t.Fatalf("expected no snapshots yet, got %v", err)
+	}
+	if err := client.DeleteDomain(ctx, domain.ID); err != nil {
+		t.Fatalf("delete domain: %v", err)
+	}
+	snap, err := b.Latest(ctx, "example.com")
+	if err != nil || snap.Records != 1 || snap.Domain.ID != domain.ID {
+		t.Fatalf("expected pre-delete snapshot, got %+v, %v", snap, err)
+	}
+
+	// The snapshot can be restored into a re-created domain.
+	recreated, err := client.CreateDomain(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("create domain: %v", err)
+	}
+	if _, err := b.Restore(ctx, recreated.ID, snap); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if records := fake.Records(recreated.ID); len(records) != 1 || records[0].Value != "192.0.2.1" {
+		t.Fatalf("unexpected restored records %+v", records)
+	}
+}
+
+func TestNormalizeIsStable(t *testing.T) {
+	t.Parallel()
+
+	records := []enzonix.Record{
+		{Name: "www", Type: "A", TTL: 600, Value: "192.0.2.1", CountryCodes: []string{"FR"}},
+		{Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1", CountryCodes: []string{"DE"}},
+		{Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"},
+	}
+	reversed := []enzonix.Record{records[2], records[1], records[0]}
+	a, _ := json.Marshal(normalize(records))
+	b, _ := json.Marshal(normalize(reversed))
+	if string(a) != string(b) {
+		t.Fatalf("order depends on the input:\n%s\n%s", a, b)
+	}
+}
+
+func TestRunRejectsInterval(t *testing.T) {
+	t.Parallel()
+
+	_, _, b, _ := newTestBackup(t, Options{})
+	if err := b.Run(context.Background(), 0); err == nil {
+		t.Fatal("expected an error for a zero interval")
+	}
+}
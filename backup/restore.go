@@ -0,0 +1,172 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+)
+
+// RestoreResult reports the changes Restore made to the live zone.
+type RestoreResult struct {
+	Created []enzonix.Record
+	Updated []enzonix.Record
+	Deleted []enzonix.Record
+}
+
+// Restore reconciles the live records of domainID with a snapshot. Records
+// that already match are left alone, records sharing a name and type are
+// updated in place, and the rest are created or deleted. The API cannot
+// clear country codes, so a default record and a country variant are
+// never updated into one another. The snapshot may come from another
+// domain, e.g. to restore a deleted domain under its new ID.
+//
+// Changes that were made but could not be audited do not stop the
+// restore; their errors are returned joined once it is done.
+func (b *Backup) Restore(ctx context.Context, domainID string, snap Snapshot) (*RestoreResult, error) {
+	zone, err := b.Load(ctx, snap)
+	if err != nil {
+		return nil, err
+	}
+	live, err := b.client.ListDomainRecords(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+
+	creates, updates, deletes := reconcile(live, zone.Records)
+	res := &RestoreResult{}
+	var audit []error
+	// fail reports whether err stops the restore, keeping it otherwise.
+	fail := func(err error) bool {
+		if enzonix.IsAuditOnly(err) {
+			audit = append(audit, err)
+			return false
+		}
+		return true
+	}
+	// Delete first so that replacing records never trips uniqueness rules,
+	// e.g. for CNAMEs.
+	for _, r := range deletes {
+		if err := b.client.DeleteRecord(ctx, r.ID); err != nil {
+			err = fmt.Errorf("backup: restore: delete %s %s: %w", r.Name, r.Type, err)
+			if fail(err) {
+				return res, errors.Join(append(audit, err)...)
+			}
+		}
+		res.Deleted = append(res.Deleted, r)
+	}
+	for _, u := range updates {
+		want := u.want
+		updated, err := b.client.UpdateRecord(ctx, u.id, enzonix.UpdateRecordRequest{
+			Value:        &want.Value,
+			TTL:          &want.TTL,
+			Priority:     &want.Priority,
+			CountryCodes: want.CountryCodes,
+		})
+		if err != nil {
+			err = fmt.Errorf("backup: restore: update %s %s: %w", want.Name, want.Type, err)
+			if fail(err) {
+				return res, errors.Join(append(audit, err)...)
+			}
+		}
+		res.Updated = append(res.Updated, *updated)
+	}
+	for _, want := range creates {
+		ttl, priority := want.TTL, want.Priority
+		created, err := b.client.CreateRecord(ctx, enzonix.CreateRecordRequest{
+			DomainID:     domainID,
+			Name:         want.Name,
+			Type:         want.Type,
+			Value:        want.Value,
+			TTL:          &ttl,
+			Priority:     &priority,
+			CountryCodes: want.CountryCodes,
+		})
+		if err != nil {
+			err = fmt.Errorf("backup: restore: create %s %s: %w", want.Name, want.Type, err)
+			if fail(err) {
+				return res, errors.Join(append(audit, err)...)
+			}
+		}
+		res.Created = append(res.Created, *created)
+	}
+	return res, errors.Join(audit...)
+}
+
+type recordUpdate struct {
+	id   string
+	want enzonix.Record
+}
+
+// reconcile computes the changes turning live into want.
+func reconcile(live, want []enzonix.Record) (creates []enzonix.Record, updates []recordUpdate, deletes []enzonix.Record) {
+	live = normalizeKeepID(live)
+	used := make([]bool, len(live))
+
+	// Exact matches need no change.
+	var pending []enzonix.Record
+	for _, w := range want {
+		matched := false
+		for i, l := range live {
+			if !used[i] && sameRecord(l, w) {
+				used[i], matched = true, true
+				break
+			}
+		}
+		if !matched {
+			pending = append(pending, w)
+		}
+	}
+
+	// Remaining records with the same name and type are updated in place,
+	// as long as both or neither have country codes.
+	for _, w := range pending {
+		matched := false
+		for i, l := range live {
+			if !used[i] && sameKey(l, w) && (len(l.CountryCodes) > 0) == (len(w.CountryCodes) > 0) {
+				used[i], matched = true, true
+				updates = append(updates, recordUpdate{id: l.ID, want: w})
+				break
+			}
+		}
+		if !matched {
+			creates = append(creates, w)
+		}
+	}
+
+	for i, l := range live {
+		if !used[i] {
+			deletes = append(deletes, l)
+		}
+	}
+	return creates, updates, deletes
+}
+
+func normalizeKeepID(records []enzonix.Record) []enzonix.Record {
+	out := make([]enzonix.Record, len(records))
+	for i, r := range records {
+		out[i] = normalize([]enzonix.Record{r})[0]
+		out[i].ID = r.ID
+	}
+	return out
+}
+
+func sameKey(a, b enzonix.Record) bool {
+	return strings.EqualFold(strings.TrimSuffix(a.Name, "."), strings.TrimSuffix(b.Name, ".")) &&
+		strings.EqualFold(a.Type, b.Type)
+}
+
+func sameRecord(a, b enzonix.Record) bool {
+	if !sameKey(a, b) || a.Value != b.Value || a.TTL != b.TTL || a.Priority != b.Priority ||
+		len(a.CountryCodes) != len(b.CountryCodes) {
+		return false
+	}
+	for i := range a.CountryCodes {
+		if a.CountryCodes[i] != b.CountryCodes[i] {
+			return false
+		}
+	}
+	return true
+}
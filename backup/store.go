@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrNotExist is returned by BlobStore.Get for missing keys.
+var ErrNotExist = errors.New("backup: blob does not exist")
+
+// BlobStore stores snapshot files under slash-separated keys. Implementations
+// must be safe for concurrent use.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns ErrNotExist, possibly wrapped, for missing keys.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// List returns all keys starting with prefix in lexical order.
+	List(ctx context.Context, prefix string) ([]string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// DirStore is a BlobStore backed by a local directory.
+type DirStore struct {
+	root string
+}
+
+// NewDirStore returns a store writing below dir, which is created on the
+// first write.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{root: dir}
+}
+
+func (d *DirStore) path(key string) (string, error) {
+	clean := filepath.FromSlash(key)
+	if key == "" || filepath.IsAbs(clean) || strings.HasPrefix(filepath.Clean(clean), "..") {
+		return "", fmt.Errorf("backup: invalid key %q", key)
+	}
+	return filepath.Join(d.root, clean), nil
+}
+
+// Put writes data atomically.
+func (d *DirStore) Put(_ context.Context, key string, data []byte) error {
+	name, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Get reads the blob stored under key.
+func (d *DirStore) Get(_ context.Context, key string) ([]byte, error) {
+	name, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotExist, key)
+	}
+	return data, err
+}
+
+// List returns the keys below the directory that start with prefix.
+func (d *DirStore) List(_ context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.WalkDir(d.root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".tmp-") {
+			return nil
+		}
+		rel, err := filepath.Rel(d.root, name)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+// Delete removes the blob stored under key. Missing keys are not an error.
+func (d *DirStore) Delete(_ context.Context, key string) error {
+	name, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
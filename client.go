@@ -24,10 +24,18 @@ type Option func(*Client) error
 
 // Client is an HTTP client for the Enzonix DNS API.
 type Client struct {
-	baseURL    *url.URL
-	apiKey     string
-	httpClient *http.Client
-	userAgent  string
+	baseURL        *url.URL
+	credentials    CredentialProvider
+	httpClient     *http.Client
+	userAgent      string
+	recorder       *recorder
+	limiter        *rateLimiter
+	rotationHooks  []KeyRotationHook
+	scopes         []*scope
+	policy         *Policy
+	auditSink      AuditSink
+	auditActor     string
+	preDeleteHooks []PreDeleteDomainHook
 }
 
 // NewClient creates a new Enzonix DNS API client.
@@ -35,6 +43,15 @@ func NewClient(apiKey string, opts ...Option) (*Client, error) {
 	if strings.TrimSpace(apiKey) == "" {
 		return nil, errors.New("enzonix: api key must not be empty")
 	}
+	return NewClientWithCredentials(NewKeyCredentials(apiKey), opts...)
+}
+
+// NewClientWithCredentials creates a client that obtains its API key from
+// provider on every request.
+func NewClientWithCredentials(provider CredentialProvider, opts ...Option) (*Client, error) {
+	if provider == nil {
+		return nil, errors.New("enzonix: credential provider must not be nil")
+	}
 
 	baseURL, err := url.Parse(defaultBaseURL)
 	if err != nil {
@@ -42,9 +59,9 @@ func NewClient(apiKey string, opts ...Option) (*Client, error) {
 	}
 
 	client := &Client{
-		baseURL:   baseURL,
-		apiKey:    apiKey,
-		userAgent: defaultUserAgent,
+		baseURL:     baseURL,
+		credentials: provider,
+		userAgent:   defaultUserAgent,
 		httpClient: &http.Client{
 			Timeout: defaultTimeout,
 		},
@@ -103,6 +120,48 @@ func WithUserAgent(ua string) Option {
 	}
 }
 
+// WithTimeout sets the timeout of the underlying http.Client. A client
+// supplied through WithHTTPClient is copied rather than modified.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) error {
+		if timeout < 0 {
+			return errors.New("enzonix: timeout must not be negative")
+		}
+		httpClient := *c.httpClient
+		httpClient.Timeout = timeout
+		c.httpClient = &httpClient
+		return nil
+	}
+}
+
+// WithRateLimit limits the client to the given number of requests per
+// second across all goroutines sharing it.
+func WithRateLimit(requestsPerSecond float64) Option {
+	return func(c *Client) error {
+		if requestsPerSecond <= 0 {
+			return errors.New("enzonix: rate limit must be positive")
+		}
+		c.limiter = newRateLimiter(requestsPerSecond)
+		return nil
+	}
+}
+
+// PreDeleteDomainHook runs before DeleteDomain sends its request, for
+// example to take a backup. Returning an error aborts the deletion.
+type PreDeleteDomainHook func(ctx context.Context, client *Client, domain Domain) error
+
+// WithPreDeleteDomainHook registers a hook run before every DeleteDomain.
+// Hooks run in registration order.
+func WithPreDeleteDomainHook(hook PreDeleteDomainHook) Option {
+	return func(c *Client) error {
+		if hook == nil {
+			return errors.New("enzonix: pre-delete hook must not be nil")
+		}
+		c.preDeleteHooks = append(c.preDeleteHooks, hook)
+		return nil
+	}
+}
+
 // APIError represents an error returned by the Enzonix API.
 type APIError struct {
 	StatusCode int             `json:"-"`
@@ -123,6 +182,43 @@ func (e *APIError) Error() string {
 	return fmt.Sprintf("enzonix: %s (status=%d)", msg, e.StatusCode)
 }
 
+// IsNotFound reports whether err is an APIError with status 404.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an APIError with status 401 or 403.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized) || hasStatus(err, http.StatusForbidden)
+}
+
+// IsConflict reports whether err is an APIError with status 409.
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsValidation reports whether err is an APIError rejecting the request
+// payload (status 400 or 422).
+func IsValidation(err error) bool {
+	return hasStatus(err, http.StatusBadRequest) || hasStatus(err, http.StatusUnprocessableEntity)
+}
+
+// IsRateLimited reports whether err is an APIError with status 429.
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+// IsServerError reports whether err is an APIError with a 5xx status.
+func IsServerError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode >= 500
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
 func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body any) (*http.Request, error) {
 	if ctx == nil {
 		return nil, errors.New("enzonix: context must not be nil")
@@ -152,7 +248,12 @@ func (c *Client) newRequest(ctx context.Context, method, path string, query url.
 		return nil, fmt.Errorf("enzonix: create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	apiKey, err := c.credentials.APIKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("enzonix: resolve api key: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Accept", "application/json")
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
@@ -164,8 +265,66 @@ func (c *Client) newRequest(ctx context.Context, method, path string, query url.
 	return req, nil
 }
 
+// send performs the request. A 401 response is retried once when the
+// credential provider yields a different key, either because it was
+// refreshed or because another goroutine swapped it in the meantime.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	res, err := c.roundTrip(req)
+	if err == nil && res.StatusCode == http.StatusUnauthorized {
+		if retry, ok := c.retryWithFreshKey(req); ok {
+			res.Body.Close()
+			res, err = c.roundTrip(retry)
+		}
+	}
+	if err == nil {
+		captureRequestID(req.Context(), res.Header.Get("X-Request-ID"))
+	}
+	return res, err
+}
+
+func (c *Client) retryWithFreshKey(req *http.Request) (*http.Request, bool) {
+	ctx := req.Context()
+	if refresher, ok := c.credentials.(CredentialRefresher); ok {
+		if err := refresher.Refresh(ctx); err != nil {
+			return nil, false
+		}
+	}
+
+	apiKey, err := c.credentials.APIKey(ctx)
+	if err != nil || "Bearer "+apiKey == req.Header.Get("Authorization") {
+		return nil, false
+	}
+
+	retry := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, false
+		}
+		retry.Body = body
+	} else if req.Body != nil && req.Body != http.NoBody {
+		return nil, false
+	}
+	retry.Header.Set("Authorization", "Bearer "+apiKey)
+	return retry, true
+}
+
+// roundTrip applies rate limiting and routes the request through the
+// cassette recorder when one is configured.
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	if c.limiter != nil {
+		if err := c.limiter.wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	if c.recorder != nil {
+		return c.recorder.roundTrip(c.httpClient, req)
+	}
+	return c.httpClient.Do(req)
+}
+
 func (c *Client) do(req *http.Request, out any) error {
-	res, err := c.httpClient.Do(req)
+	res, err := c.send(req)
 	if err != nil {
 		return fmt.Errorf("enzonix: request failed: %w", err)
 	}
@@ -3,6 +3,7 @@ package enzonix
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -18,7 +19,7 @@ func TestNewClientDefaults(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if client.apiKey != "apikey" {
+	if key, err := client.credentials.APIKey(context.Background()); err != nil || key != "apikey" {
 		t.Fatalf("expected api key to be stored")
 	}
 
@@ -136,3 +137,30 @@ func TestDoHandlesHTTPError(t *testing.T) {
 		t.Fatalf("expected message oops, got %s", apiErr.Message)
 	}
 }
+
+func TestErrorClassification(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		status int
+		check  func(error) bool
+	}{
+		{http.StatusNotFound, IsNotFound},
+		{http.StatusUnauthorized, IsUnauthorized},
+		{http.StatusForbidden, IsUnauthorized},
+		{http.StatusConflict, IsConflict},
+		{http.StatusUnprocessableEntity, IsValidation},
+		{http.StatusTooManyRequests, IsRateLimited},
+		{http.StatusBadGateway, IsServerError},
+	}
+	for _, tc := range cases {
+		err := fmt.Errorf("wrapped: %w", &APIError{StatusCode: tc.status})
+		if !tc.check(err) {
+			t.Fatalf("expected status %d to be classified", tc.status)
+		}
+	}
+
+	if IsNotFound(errors.New("plain")) {
+		t.Fatalf("expected plain errors not to be classified")
+	}
+}
@@ -0,0 +1,290 @@
+package enzonix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrParityMismatch is returned by MigrateDomain when the destination's
+// records differ from the source's after the copy. The source domain is
+// left untouched.
+var ErrParityMismatch = errors.New("enzonix: record parity mismatch")
+
+// CloneOptions controls how CloneDomain copies records.
+type CloneOptions struct {
+	// Rewrite maps host names to their replacements in record names and
+	// in CNAME, MX, NS, PTR and SRV targets: a name equal to or under a
+	// key is moved under its value. The source domain is mapped to the new
+	// domain unless Rewrite has an entry for it.
+	Rewrite map[string]string
+	// RewriteText applies Rewrite to TXT values as well, replacing every
+	// occurrence. SPF includes usually want this; verification tokens do
+	// not.
+	RewriteText bool
+	// SkipTypes are record types left out of the copy. SOA records are
+	// never copied.
+	SkipTypes []string
+}
+
+// CloneResult is the outcome of CloneDomain.
+type CloneResult struct {
+	Domain Domain
+	// Records are the records created in the new domain.
+	Records []Record
+}
+
+// CloneDomain creates the domain newName and copies the records of
+// srcDomainID into it, rewriting names and targets as opts describe. If a
+// record cannot be created the new domain is left in place with the
+// records copied so far, which the returned result lists.
+func (c *Client) CloneDomain(ctx context.Context, srcDomainID, newName string, opts CloneOptions) (*CloneResult, error) {
+	if err := requireID(srcDomainID, "domain id"); err != nil {
+		return nil, err
+	}
+	src, err := c.domainByID(ctx, srcDomainID)
+	if err != nil {
+		return nil, err
+	}
+	records, err := c.ListDomainRecords(ctx, srcDomainID)
+	if err != nil {
+		return nil, err
+	}
+
+	rw := newRewriter(src.Name, newName, opts)
+	var copies []Record
+	for _, r := range records {
+		if strings.EqualFold(r.Type, "SOA") || slices.ContainsFunc(opts.SkipTypes, func(t string) bool { return strings.EqualFold(t, r.Type) }) {
+			continue
+		}
+		copies = append(copies, rw.record(r))
+	}
+
+	var audit batchAudit
+	domain, err := c.CreateDomain(ctx, newName)
+	if !audit.absorb(err) {
+		return nil, err
+	}
+	res := &CloneResult{Domain: *domain}
+	res.Records, err = copyRecords(ctx, c, domain.ID, copies)
+	return res, audit.join(err)
+}
+
+// MigrateOptions controls MigrateDomain.
+type MigrateOptions struct {
+	// DeleteSource deletes the domain from the source account once the
+	// destination holds the same records.
+	DeleteSource bool
+}
+
+// MigrateResult is the outcome of MigrateDomain.
+type MigrateResult struct {
+	Source      Domain
+	Destination Domain
+	// Created are the records created on the destination.
+	Created []Record
+	// SourceDeleted reports whether the source domain was deleted.
+	SourceDeleted bool
+}
+
+// MigrateDomain moves a domain between client accounts. The records of
+// domainID are exported from src, the domain is created on dst and the
+// records are copied. The destination is then listed again and compared
+// with the export; only when every record is present, and nothing else,
+// is the source domain deleted, and only with opts.DeleteSource. A parity
+// failure returns ErrParityMismatch.
+//
+// A destination domain of the same name is reused and only the records it
+// lacks are created, so an interrupted migration can be run again.
+func MigrateDomain(ctx context.Context, src, dst *Client, domainID string, opts MigrateOptions) (*MigrateResult, error) {
+	if err := requireID(domainID, "domain id"); err != nil {
+		return nil, err
+	}
+	domain, err := src.domainByID(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+	records, err := src.ListDomainRecords(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+	res := &MigrateResult{Source: *domain}
+
+	target, err := dst.domainByName(ctx, domain.Name)
+	var live []Record
+	var audit batchAudit
+	switch {
+	case IsNotFound(err):
+		if target, err = dst.CreateDomain(ctx, strings.TrimSuffix(domain.Name, ".")); !audit.absorb(err) {
+			return res, err
+		}
+	case err != nil:
+		return res, err
+	default:
+		if live, err = dst.ListDomainRecords(ctx, target.ID); err != nil {
+			return res, err
+		}
+	}
+	res.Destination = *target
+
+	missing, _ := recordParity(records, live)
+	res.Created, err = copyRecords(ctx, dst, target.ID, missing)
+	if !audit.absorb(err) {
+		return res, audit.join(err)
+	}
+
+	after, err := dst.ListDomainRecords(ctx, target.ID)
+	if err != nil {
+		return res, audit.join(err)
+	}
+	if missing, extra := recordParity(records, after); len(missing) > 0 || len(extra) > 0 {
+		return res, audit.join(fmt.Errorf("%w: %s: %d records missing on the destination, %d unexpected", ErrParityMismatch, domain.Name, len(missing), len(extra)))
+	}
+
+	if opts.DeleteSource {
+		if err := src.DeleteDomain(ctx, domainID); !audit.absorb(err) {
+			return res, audit.join(err)
+		}
+		res.SourceDeleted = true
+	}
+	return res, audit.join(nil)
+}
+
+// copyRecords creates records in domainID, stopping at the first failure.
+// Records created but not audited are kept and copying goes on.
+func copyRecords(ctx context.Context, c *Client, domainID string, records []Record) ([]Record, error) {
+	var created []Record
+	var audit batchAudit
+	for _, r := range records {
+		r.DomainID = domainID
+		rec, err := c.CreateRecord(ctx, createRequestFor(r))
+		if !audit.absorb(err) {
+			return created, audit.join(fmt.Errorf("enzonix: copy %s %s %s: %w", r.Name, r.Type, describeValue(r), err))
+		}
+		created = append(created, *rec)
+	}
+	return created, audit.join(nil)
+}
+
+// recordParity compares two record lists as multisets, ignoring IDs and
+// SOA records.
+func recordParity(want, have []Record) (missing, extra []Record) {
+	matched := make([]bool, len(have))
+	for _, w := range want {
+		if strings.EqualFold(w.Type, "SOA") {
+			continue
+		}
+		found := false
+		for i, h := range have {
+			if !matched[i] && sameRecordState(w, h) {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, w)
+		}
+	}
+	for i, h := range have {
+		if !matched[i] && !strings.EqualFold(h.Type, "SOA") {
+			extra = append(extra, h)
+		}
+	}
+	return missing, extra
+}
+
+// domainByID returns a domain visible to the client.
+func (c *Client) domainByID(ctx context.Context, domainID string) (*Domain, error) {
+	return c.findDomain(ctx, func(d Domain) bool { return d.ID == domainID }, domainID)
+}
+
+// domainByName returns a domain visible to the client by name.
+func (c *Client) domainByName(ctx context.Context, name string) (*Domain, error) {
+	return c.findDomain(ctx, func(d Domain) bool { return sameRecordName(d.Name, name) }, name)
+}
+
+func (c *Client) findDomain(ctx context.Context, match func(Domain) bool, label string) (*Domain, error) {
+	domains, err := c.ListDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range domains {
+		if match(d) {
+			return &d, nil
+		}
+	}
+	return nil, &APIError{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("domain %s not found", label)}
+}
+
+// rewriter moves host names from one domain to another, longest match
+// first.
+type rewriter struct {
+	pairs [][2]string
+	text  bool
+}
+
+func newRewriter(from, to string, opts CloneOptions) *rewriter {
+	rw := &rewriter{text: opts.RewriteText}
+	seen := map[string]bool{}
+	for old, repl := range opts.Rewrite {
+		old = normalizeHost(old)
+		seen[old] = true
+		rw.pairs = append(rw.pairs, [2]string{old, normalizeHost(repl)})
+	}
+	if from = normalizeHost(from); !seen[from] {
+		rw.pairs = append(rw.pairs, [2]string{from, normalizeHost(to)})
+	}
+	sort.Slice(rw.pairs, func(i, j int) bool { return len(rw.pairs[i][0]) > len(rw.pairs[j][0]) })
+	return rw
+}
+
+func normalizeHost(name string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+}
+
+// host rewrites a host name, keeping a trailing dot.
+func (rw *rewriter) host(name string) string {
+	base, dot := strings.CutSuffix(name, ".")
+	lower := strings.ToLower(base)
+	for _, p := range rw.pairs {
+		switch {
+		case lower == p[0]:
+			base = p[1]
+		case strings.HasSuffix(lower, "."+p[0]):
+			base = base[:len(base)-len(p[0])] + p[1]
+		default:
+			continue
+		}
+		if dot {
+			base += "."
+		}
+		return base
+	}
+	return name
+}
+
+func (rw *rewriter) record(r Record) Record {
+	r.ID, r.DomainID, r.CreatedAt, r.UpdatedAt = "", "", nil, nil
+	r.Name = rw.host(r.Name)
+	switch strings.ToUpper(r.Type) {
+	case "CNAME", "MX", "NS", "PTR":
+		r.Value = rw.host(r.Value)
+	case "SRV":
+		if f := strings.Fields(r.Value); len(f) == 3 {
+			r.Value = f[0] + " " + f[1] + " " + rw.host(f[2])
+		}
+	case "TXT":
+		if rw.text {
+			oldnew := make([]string, 0, 2*len(rw.pairs))
+			for _, p := range rw.pairs {
+				oldnew = append(oldnew, p[0], p[1])
+			}
+			r.Value = strings.NewReplacer(oldnew...).Replace(r.Value)
+		}
+	}
+	return r
+}
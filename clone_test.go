@@ -0,0 +1,168 @@
+package enzonix
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+func recordStates(records []Record) []string {
+	out := make([]string, 0, len(records))
+	for _, r := range records {
+		s := r.Name + " " + r.Type + " " + describeValue(r)
+		for _, cc := range r.CountryCodes {
+			s += " " + cc
+		}
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestCloneDomain(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeClient(t)
+	ctx := context.Background()
+	src := fake.AddDomain("example.com")
+	for _, r := range []fakeapi.Record{
+		{Name: "www", Type: "CNAME", TTL: 300, Value: "web.example.com."},
+		{Name: "@", Type: "MX", TTL: 3600, Priority: 10, Value: "mx.example.com"},
+		{Name: "_sip._tcp", Type: "SRV", TTL: 600, Priority: 5, Value: "10 5060 sip.example.com"},
+		{Name: "@", Type: "TXT", TTL: 3600, Value: "v=spf1 include:_spf.example.com -all"},
+		{Name: "cdn", Type: "CNAME", TTL: 300, Value: "cdn.myexample.com", CountryCodes: []string{"DE"}},
+		{Name: "assets", Type: "CNAME", TTL: 300, Value: "static.example.org"},
+		{Name: "@", Type: "SOA", TTL: 3600, Value: "ns1.enzonix.com. hostmaster.example.com. 1 3600 600 604800 300"},
+		{Name: "old", Type: "A", TTL: 300, Value: "192.0.2.1"},
+	} {
+		r.DomainID = src.ID
+		fake.AddRecord(r)
+	}
+
+	res, err := client.CloneDomain(ctx, src.ID, "example.net", CloneOptions{
+		Rewrite:   map[string]string{"example.org": "example.io"},
+		SkipTypes: []string{"a"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Domain.Name != "example.net." || len(res.Records) != 6 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	records, err := client.ListDomainRecords(ctx, res.Domain.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"@ MX 10 mx.example.net",
+		"@ TXT v=spf1 include:_spf.example.com -all",
+		"_sip._tcp SRV 5 10 5060 sip.example.net",
+		"assets CNAME static.example.io",
+		"cdn CNAME cdn.myexample.com DE",
+		"www CNAME web.example.net.",
+	}
+	if got := recordStates(records); !slices.Equal(got, want) {
+		t.Fatalf("unexpected records:\n got %q\nwant %q", got, want)
+	}
+
+	res, err = client.CloneDomain(ctx, src.ID, "example.dev", CloneOptions{RewriteText: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, _ = client.ListDomainRecords(ctx, res.Domain.ID)
+	if got := recordStates(records); got[1] != "@ TXT v=spf1 include:_spf.example.dev -all" {
+		t.Fatalf("TXT not rewritten: %q", got)
+	}
+
+	if _, err := client.CloneDomain(ctx, "missing", "example.xyz", CloneOptions{}); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestMigrateDomain(t *testing.T) {
+	t.Parallel()
+
+	srcFake, src := newFakeClient(t)
+	dstFake, dst := newFakeClient(t)
+	ctx := context.Background()
+
+	domain := srcFake.AddDomain("example.com")
+	srcFake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	srcFake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.9", CountryCodes: []string{"DE"}})
+	srcFake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "@", Type: "MX", TTL: 3600, Priority: 10, Value: "mx.example.com"})
+
+	// A previous run that stopped after the first record.
+	partial := dstFake.AddDomain("example.com")
+	dstFake.AddRecord(fakeapi.Record{DomainID: partial.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+
+	res, err := MigrateDomain(ctx, src, dst, domain.ID, MigrateOptions{DeleteSource: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Destination.ID != partial.ID || len(res.Created) != 2 || !res.SourceDeleted {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if domains, _ := src.ListDomains(ctx); len(domains) != 0 {
+		t.Fatalf("source not deleted: %v", domains)
+	}
+	records, _ := dst.ListDomainRecords(ctx, partial.ID)
+	if got := recordStates(records); len(got) != 3 || got[2] != "www A 192.0.2.9 DE" {
+		t.Fatalf("unexpected destination records %q", got)
+	}
+}
+
+func TestMigrateDomainParityMismatch(t *testing.T) {
+	t.Parallel()
+
+	srcFake, src := newFakeClient(t)
+	dstFake, dst := newFakeClient(t)
+	ctx := context.Background()
+
+	domain := srcFake.AddDomain("example.com")
+	srcFake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	existing := dstFake.AddDomain("example.com")
+	dstFake.AddRecord(fakeapi.Record{DomainID: existing.ID, Name: "stale", Type: "A", TTL: 300, Value: "192.0.2.5"})
+
+	res, err := MigrateDomain(ctx, src, dst, domain.ID, MigrateOptions{DeleteSource: true})
+	if !errors.Is(err, ErrParityMismatch) {
+		t.Fatalf("expected parity mismatch, got %v", err)
+	}
+	if res.SourceDeleted {
+		t.Fatal("source deleted despite the mismatch")
+	}
+	if domains, _ := src.ListDomains(ctx); len(domains) != 1 {
+		t.Fatalf("source changed: %v", domains)
+	}
+}
+
+func TestCloneAuditErrors(t *testing.T) {
+	t.Parallel()
+
+	failing := AuditSinkFunc(func(context.Context, AuditEntry) error { return errors.New("disk full") })
+	fake, client := newFakeClient(t, WithAuditSink(failing))
+	dstFake, dst := newFakeClient(t, WithAuditSink(failing))
+	ctx := context.Background()
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "api", Type: "A", TTL: 300, Value: "192.0.2.2"})
+
+	clone, err := client.CloneDomain(ctx, domain.ID, "example.net", CloneOptions{})
+	if !IsAuditOnly(err) || clone == nil || len(clone.Records) != 2 {
+		t.Fatalf("expected a complete clone, got %+v, %v", clone, err)
+	}
+	if records := fake.Records(clone.Domain.ID); len(records) != 2 {
+		t.Fatalf("expected 2 cloned records, got %+v", records)
+	}
+
+	res, err := MigrateDomain(ctx, client, dst, domain.ID, MigrateOptions{})
+	if !IsAuditOnly(err) || len(res.Created) != 2 {
+		t.Fatalf("expected a complete migration, got %+v, %v", res, err)
+	}
+	if records := dstFake.Records(res.Destination.ID); len(records) != 2 {
+		t.Fatalf("expected 2 migrated records, got %+v", records)
+	}
+}
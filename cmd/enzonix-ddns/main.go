@@ -0,0 +1,163 @@
+// Command enzonix-ddns keeps A and AAAA records pointed at the host's
+// public address.
+//
+// Usage:
+//
+//	enzonix-ddns -host home.example.com [flags]
+//
+// The addresses come from the sources given by -ipv4 and -ipv6:
+//
+//	https://...     an echo endpoint returning the address as plain text
+//	iface:NAME      the public address of a local interface
+//	cmd:COMMAND     the first line printed by a command, run with
+//	                $DDNS_FAMILY set to 4 or 6
+//	off             do not manage records of the family
+//
+// Records are updated only when the address differs from the one last
+// applied, which is cached in -cache. With -once the command updates once
+// and exits, for use from cron; otherwise it updates every -interval and
+// backs off after failures. API credentials are resolved like the enzonix
+// command's: ENZONIX_API_KEY, ENZONIX_API_KEY_FILE or the selected profile
+// of the config file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/ddns"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stderr))
+}
+
+// list is a repeatable string flag.
+type list []string
+
+func (l *list) String() string     { return strings.Join(*l, ",") }
+func (l *list) Set(v string) error { *l = append(*l, v); return nil }
+
+func run(ctx context.Context, args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("enzonix-ddns", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		hosts      list
+		ipv4, ipv6 string
+		opts       ddns.Options
+		once       bool
+		verbose    bool
+	)
+	fs.Var(&hosts, "host", "fully qualified host name to update (repeatable)")
+	fs.StringVar(&ipv4, "ipv4", "https://api.ipify.org", "IPv4 address source")
+	fs.StringVar(&ipv6, "ipv6", "off", "IPv6 address source")
+	fs.IntVar(&opts.TTL, "ttl", 0, "TTL of updated records (default: keep)")
+	fs.DurationVar(&opts.Interval, "interval", 5*time.Minute, "time between updates")
+	fs.DurationVar(&opts.MaxBackoff, "max-backoff", 30*time.Minute, "maximum delay after failed updates")
+	fs.StringVar(&opts.CacheFile, "cache", defaultCacheFile(), "file caching the applied addresses (empty: memory only)")
+	fs.BoolVar(&once, "once", false, "update once and exit")
+	fs.BoolVar(&verbose, "v", false, "log debug messages")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "enzonix-ddns: unexpected arguments %q\n", fs.Args())
+		return 2
+	}
+	if len(hosts) == 0 {
+		fmt.Fprintln(stderr, "enzonix-ddns: at least one -host is required")
+		return 2
+	}
+	var err error
+	if opts.IPv4, err = parseSource(ipv4); err == nil {
+		opts.IPv6, err = parseSource(ipv6)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "enzonix-ddns: %v\n", err)
+		return 2
+	}
+
+	level := slog.LevelInfo
+	if verbose {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+	opts.Hosts = hosts
+	opts.Logger = logger
+
+	client, err := enzonix.NewClientFromEnvironment()
+	if err != nil {
+		logger.Error("create client", slog.Any("error", err))
+		return 2
+	}
+	if opts.CacheFile != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.CacheFile), 0o700); err != nil {
+			logger.Error("create cache directory", slog.Any("error", err))
+			return 1
+		}
+	}
+	updater, err := ddns.New(client, opts)
+	if err != nil {
+		logger.Error("start updater", slog.Any("error", err))
+		return 2
+	}
+
+	if once {
+		changes, err := updater.Update(ctx)
+		for _, c := range changes {
+			if c.Err == nil {
+				logger.Debug("checked", slog.String("host", c.Host), slog.String("type", c.Type),
+					slog.String("address", c.Address.String()), slog.Bool("changed", c.Changed))
+			}
+		}
+		if err != nil {
+			logger.Error("update", slog.Any("error", err))
+			return 1
+		}
+		return 0
+	}
+	if err := updater.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		logger.Error("run", slog.Any("error", err))
+		return 1
+	}
+	return 0
+}
+
+// parseSource turns a source flag into a Source; "off" is nil.
+func parseSource(spec string) (ddns.Source, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch {
+	case spec == "" || spec == "off":
+		return nil, nil
+	case kind == "http" || kind == "https":
+		return ddns.HTTPSource{URL: spec}, nil
+	case kind == "iface" && arg != "":
+		return ddns.InterfaceSource{Name: arg}, nil
+	case kind == "cmd" && strings.TrimSpace(arg) != "":
+		return ddns.CommandSource{Command: strings.Fields(arg)}, nil
+	}
+	return nil, fmt.Errorf("invalid address source %q", spec)
+}
+
+func defaultCacheFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "enzonix", "ddns.json")
+}
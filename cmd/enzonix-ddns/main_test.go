@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/ddns"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+func TestParseSource(t *testing.T) {
+	t.Parallel()
+
+	for spec, want := range map[string]ddns.Source{
+		"off":                ddns.Source(nil),
+		"https://ip.example": ddns.HTTPSource{URL: "https://ip.example"},
+		"iface:eth0":         ddns.InterfaceSource{Name: "eth0"},
+	} {
+		if got, err := parseSource(spec); err != nil || got != want {
+			t.Errorf("parseSource(%q) = %+v, %v, want %+v", spec, got, err, want)
+		}
+	}
+	got, err := parseSource("cmd:dig +short myip.opendns.com @resolver1.opendns.com")
+	if c, ok := got.(ddns.CommandSource); err != nil || !ok || len(c.Command) != 4 || c.Command[0] != "dig" {
+		t.Errorf("unexpected command source %+v, %v", got, err)
+	}
+	if _, err := parseSource("iface:"); err == nil {
+		t.Error("expected an error for an interface source without name")
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	fake := fakeapi.New("key")
+	api := httptest.NewServer(fake)
+	defer api.Close()
+	domain := fake.AddDomain("example.com")
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "203.0.113.5")
+	}))
+	defer echo.Close()
+
+	dir := t.TempDir()
+	t.Setenv("ENZONIX_API_KEY", "key")
+	t.Setenv("ENZONIX_BASE_URL", api.URL)
+	t.Setenv("ENZONIX_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	var stderr strings.Builder
+	args := []string{"-once", "-host", "home.example.com", "-ipv4", echo.URL, "-cache", filepath.Join(dir, "cache", "ddns.json")}
+	if code := run(context.Background(), args, &stderr); code != 0 {
+		t.Fatalf("unexpected exit %d: %s", code, stderr.String())
+	}
+	if r := fake.Records(domain.ID); len(r) != 1 || r[0].Name != "home" || r[0].Value != "203.0.113.5" {
+		t.Fatalf("unexpected records %+v", r)
+	}
+
+	stderr.Reset()
+	if code := run(context.Background(), []string{"-once"}, &stderr); code != 2 || !strings.Contains(stderr.String(), "-host") {
+		t.Fatalf("unexpected exit %d: %s", code, stderr.String())
+	}
+}
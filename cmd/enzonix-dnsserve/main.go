@@ -0,0 +1,254 @@
+// Command enzonix-dnsserve serves Enzonix zones on a local port, for
+// integration tests of services that resolve them.
+//
+// Usage:
+//
+//	enzonix-dnsserve [flags]
+//
+// Zones come from the API (every domain, or those named by -domain), from
+// zone files given as -zone [origin=]path, or from the newest backup
+// snapshots of the domains named by -snapshot in -snapshot-dir. API
+// credentials are resolved like the enzonix command's: ENZONIX_API_KEY,
+// ENZONIX_API_KEY_FILE or the selected profile of the config file.
+//
+// With -geo, records with country codes are answered according to a file
+// mapping client subnets to countries, one "subnet country" pair per line.
+//
+// Zones loaded from the API accept dynamic updates (nsupdate) from the
+// networks and TSIG keys named by -allow-update. Keys are given with
+// -tsig-key as [algorithm:]name:secret, the secret in base64.
+//
+// Secondaries may transfer the zones (AXFR and IXFR) from the networks and
+// TSIG keys named by -allow-transfer, and the secondaries named by -notify
+// are sent a NOTIFY whenever a zone's serial changes. With -watch, zones
+// loaded from the API are polled for changes and reloaded when they
+// change.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/netip"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/backup"
+	"github.com/Enzonix-LLC/dns-sdk-go/dnsserve"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stderr))
+}
+
+// list is a repeatable string flag.
+type list []string
+
+func (l *list) String() string     { return strings.Join(*l, ",") }
+func (l *list) Set(v string) error { *l = append(*l, v); return nil }
+
+type config struct {
+	listen      string
+	domains     list
+	zones       list
+	snapshots   list
+	snapshotDir string
+	geo         string
+	keys        list
+	allowUpdate list
+	allowXfr    list
+	notify      list
+	serialFile  string
+	refresh     time.Duration
+	watch       time.Duration
+	verbose     bool
+}
+
+func run(ctx context.Context, args []string, stderr io.Writer) int {
+	fs := flag.NewFlagSet("enzonix-dnsserve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var cfg config
+	fs.StringVar(&cfg.listen, "listen", "127.0.0.1:5353", "UDP and TCP address to serve on")
+	fs.Var(&cfg.domains, "domain", "serve this domain ID from the API (repeatable; default all)")
+	fs.Var(&cfg.zones, "zone", "serve a zone file, as [origin=]path (repeatable)")
+	fs.Var(&cfg.snapshots, "snapshot", "serve the newest snapshot of this domain name (repeatable)")
+	fs.StringVar(&cfg.snapshotDir, "snapshot-dir", "", "backup directory for -snapshot")
+	fs.StringVar(&cfg.geo, "geo", "", "client subnet to country mapping file")
+	fs.Var(&cfg.keys, "tsig-key", "accept TSIG key [algorithm:]name:secret (repeatable)")
+	fs.Var(&cfg.allowUpdate, "allow-update", "allow dynamic updates from this network or TSIG key name (repeatable)")
+	fs.Var(&cfg.allowXfr, "allow-transfer", "allow zone transfers from this network or TSIG key name (repeatable)")
+	fs.Var(&cfg.notify, "notify", "send NOTIFY to this secondary, as host[:port] (repeatable)")
+	fs.StringVar(&cfg.serialFile, "serial-file", "", "keep synthesized SOA serials in this file across restarts")
+	fs.DurationVar(&cfg.refresh, "refresh", 0, "reload zones from the API at this interval")
+	fs.DurationVar(&cfg.watch, "watch", 0, "poll zones from the API for changes at this interval")
+	fs.BoolVar(&cfg.verbose, "v", false, "log debug messages")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "enzonix-dnsserve: unexpected arguments %q\n", fs.Args())
+		return 2
+	}
+	if len(cfg.snapshots) > 0 && cfg.snapshotDir == "" {
+		fmt.Fprintln(stderr, "enzonix-dnsserve: -snapshot requires -snapshot-dir")
+		return 2
+	}
+
+	level := slog.LevelInfo
+	if cfg.verbose {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+
+	opts := dnsserve.Options{Logger: logger}
+	if cfg.geo != "" {
+		geo, err := dnsserve.LoadGeoMap(cfg.geo)
+		if err != nil {
+			logger.Error("load geo map", slog.Any("error", err))
+			return 1
+		}
+		opts.Geo = geo
+	}
+	for _, spec := range cfg.keys {
+		key, err := dnsserve.ParseTSIGKey(spec)
+		if err != nil {
+			fmt.Fprintf(stderr, "enzonix-dnsserve: %v\n", err)
+			return 2
+		}
+		opts.Keys = append(opts.Keys, key)
+	}
+	opts.Update = parseACL(cfg.allowUpdate)
+	opts.Transfer = parseACL(cfg.allowXfr)
+	opts.Notify = cfg.notify
+	opts.SerialFile = cfg.serialFile
+
+	// The API is used unless only files or snapshots were given.
+	var client *enzonix.Client
+	if len(cfg.domains) > 0 || (len(cfg.zones) == 0 && len(cfg.snapshots) == 0) {
+		var err error
+		if client, err = enzonix.NewClientFromEnvironment(); err != nil {
+			logger.Error("create client", slog.Any("error", err))
+			return 2
+		}
+		opts.Client = client
+	}
+
+	zones, err := loadZones(ctx, client, cfg)
+	if err != nil {
+		logger.Error("load zones", slog.Any("error", err))
+		return 1
+	}
+	server, err := dnsserve.New(zones, opts)
+	if err != nil {
+		logger.Error("start server", slog.Any("error", err))
+		return 1
+	}
+	if client != nil && cfg.refresh > 0 {
+		go refresh(ctx, server, client, cfg, logger)
+	}
+	if client != nil && cfg.watch > 0 {
+		go func() {
+			if err := server.Watch(ctx, cfg.watch); err != nil {
+				logger.Error("watch zones", slog.Any("error", err))
+			}
+		}()
+	}
+
+	names := make([]string, 0, len(zones))
+	for _, z := range zones {
+		names = append(names, z.Name)
+	}
+	logger.Info("serving", slog.String("listen", cfg.listen), slog.Any("zones", names))
+	if err := server.ListenAndServe(ctx, cfg.listen); err != nil {
+		logger.Error("serve", slog.Any("error", err))
+		return 1
+	}
+	return 0
+}
+
+// parseACL reads networks and addresses; other entries name TSIG keys.
+func parseACL(entries []string) dnsserve.ACL {
+	var acl dnsserve.ACL
+	for _, e := range entries {
+		if prefix, err := netip.ParsePrefix(e); err == nil {
+			acl.Networks = append(acl.Networks, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(e); err == nil {
+			acl.Networks = append(acl.Networks, netip.PrefixFrom(addr, addr.BitLen()))
+		} else {
+			acl.Keys = append(acl.Keys, e)
+		}
+	}
+	return acl
+}
+
+func loadZones(ctx context.Context, client *enzonix.Client, cfg config) ([]dnsserve.Zone, error) {
+	var zones []dnsserve.Zone
+	if client != nil {
+		loaded, err := dnsserve.LoadZones(ctx, client, cfg.domains...)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, loaded...)
+	}
+	for _, spec := range cfg.zones {
+		origin, path, ok := strings.Cut(spec, "=")
+		if !ok {
+			origin, path = "", spec
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		zone, err := dnsserve.ParseZoneFile(f, origin)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		zones = append(zones, zone)
+	}
+	if len(cfg.snapshots) > 0 {
+		b := backup.New(client, backup.NewDirStore(cfg.snapshotDir), backup.Options{})
+		for _, name := range cfg.snapshots {
+			snap, err := b.Latest(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			content, err := b.Load(ctx, snap)
+			if err != nil {
+				return nil, err
+			}
+			zones = append(zones, dnsserve.SnapshotZone(snap, content))
+		}
+	}
+	return zones, nil
+}
+
+func refresh(ctx context.Context, server *dnsserve.Server, client *enzonix.Client, cfg config, logger *slog.Logger) {
+	ticker := time.NewTicker(cfg.refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		zones, err := loadZones(ctx, client, cfg)
+		if err == nil {
+			err = server.SetZones(zones)
+		}
+		if err != nil && ctx.Err() == nil {
+			logger.Error("reload zones", slog.Any("error", err))
+		}
+	}
+}
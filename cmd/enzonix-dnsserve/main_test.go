@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadZoneFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	withOrigin := filepath.Join(dir, "example.zone")
+	if err := os.WriteFile(withOrigin, []byte("$ORIGIN example.com.\nwww 300 IN A 192.0.2.1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	bare := filepath.Join(dir, "bare.zone")
+	if err := os.WriteFile(bare, []byte("@ 300 IN MX 10 mx\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	zones, err := loadZones(context.Background(), nil, config{zones: list{withOrigin, "example.org=" + bare}})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(zones) != 2 || zones[0].Name != "example.com" || zones[1].Name != "example.org" {
+		t.Fatalf("unexpected zones %+v", zones)
+	}
+	if r := zones[1].Records; len(r) != 1 || r[0].Priority != 10 || r[0].Value != "mx" {
+		t.Fatalf("unexpected records %+v", r)
+	}
+
+	if _, err := loadZones(context.Background(), nil, config{zones: list{bare}}); err == nil || !strings.Contains(err.Error(), "origin") {
+		t.Fatalf("expected an error for a zone file without origin, got %v", err)
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	t.Parallel()
+
+	var stderr strings.Builder
+	if code := run(context.Background(), []string{"-snapshot", "example.com"}, &stderr); code != 2 || !strings.Contains(stderr.String(), "-snapshot-dir") {
+		t.Fatalf("unexpected exit %d: %s", code, stderr.String())
+	}
+}
+
+func TestParseACL(t *testing.T) {
+	t.Parallel()
+
+	acl := parseACL([]string{"192.0.2.0/24", "2001:db8::1", "update-key"})
+	if len(acl.Networks) != 2 || acl.Networks[1].Bits() != 128 || len(acl.Keys) != 1 || acl.Keys[0] != "update-key" {
+		t.Fatalf("unexpected ACL %+v", acl)
+	}
+}
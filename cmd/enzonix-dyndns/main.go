@@ -0,0 +1,127 @@
+// Command enzonix-dyndns is a dyndns2 gateway: routers and NAS devices
+// configured with a custom dyndns2 server update Enzonix records through
+// it, using per-hostname credentials instead of an API key.
+//
+// Usage:
+//
+//	enzonix-dyndns -credentials FILE [flags]
+//
+// The credentials file has one "host username password" line per
+// hostname a device may update; # starts a comment. Updates are served
+// at /nic/update. API credentials are resolved like the enzonix command's:
+// ENZONIX_API_KEY, ENZONIX_API_KEY_FILE or the selected profile of the
+// config file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/dyndns"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stderr, nil))
+}
+
+// run serves until ctx is done. ready, when not nil, receives the address
+// the gateway listens on.
+func run(ctx context.Context, args []string, stderr io.Writer, ready chan<- string) int {
+	fs := flag.NewFlagSet("enzonix-dyndns", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var (
+		listen          string
+		credentials     string
+		tlsCert, tlsKey string
+		opts            dyndns.Options
+		verbose         bool
+	)
+	fs.StringVar(&listen, "listen", ":8245", "HTTP address to serve on")
+	fs.StringVar(&credentials, "credentials", "", "file of \"host username password\" lines")
+	fs.StringVar(&tlsCert, "tls-cert", "", "serve HTTPS with this certificate file")
+	fs.StringVar(&tlsKey, "tls-key", "", "key file for -tls-cert")
+	fs.IntVar(&opts.TTL, "ttl", 0, "TTL of updated records (default: keep)")
+	fs.BoolVar(&opts.TrustProxy, "trust-proxy", false, "take the client address from X-Forwarded-For")
+	fs.BoolVar(&verbose, "v", false, "log debug messages")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "enzonix-dyndns: unexpected arguments %q\n", fs.Args())
+		return 2
+	}
+	if credentials == "" {
+		fmt.Fprintln(stderr, "enzonix-dyndns: -credentials is required")
+		return 2
+	}
+	if (tlsCert == "") != (tlsKey == "") {
+		fmt.Fprintln(stderr, "enzonix-dyndns: -tls-cert and -tls-key must be given together")
+		return 2
+	}
+
+	level := slog.LevelInfo
+	if verbose {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level}))
+
+	creds, err := dyndns.LoadCredentials(credentials)
+	if err != nil {
+		logger.Error("load credentials", slog.Any("error", err))
+		return 1
+	}
+	opts.Credentials = creds
+	opts.Logger = logger
+
+	client, err := enzonix.NewClientFromEnvironment()
+	if err != nil {
+		logger.Error("create client", slog.Any("error", err))
+		return 2
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/nic/update", dyndns.New(client, opts))
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second, ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelWarn)}
+
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		logger.Error("listen", slog.Any("error", err))
+		return 1
+	}
+	logger.Info("serving", slog.String("listen", ln.Addr().String()), slog.Int("credentials", len(creds)))
+	if ready != nil {
+		ready <- ln.Addr().String()
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	})
+	defer stop()
+	if tlsCert != "" {
+		err = srv.ServeTLS(ln, tlsCert, tlsKey)
+	} else {
+		err = srv.Serve(ln)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("serve", slog.Any("error", err))
+		return 1
+	}
+	return 0
+}
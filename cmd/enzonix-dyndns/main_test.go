@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+func TestRunUsage(t *testing.T) {
+	t.Parallel()
+
+	var stderr strings.Builder
+	if code := run(context.Background(), nil, &stderr, nil); code != 2 || !strings.Contains(stderr.String(), "-credentials") {
+		t.Fatalf("unexpected exit %d: %s", code, stderr.String())
+	}
+}
+
+func TestRunServes(t *testing.T) {
+	fake := fakeapi.New("key")
+	api := httptest.NewServer(fake)
+	defer api.Close()
+	domain := fake.AddDomain("example.com")
+
+	dir := t.TempDir()
+	creds := filepath.Join(dir, "credentials")
+	if err := os.WriteFile(creds, []byte("home.example.com router s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ENZONIX_API_KEY", "key")
+	t.Setenv("ENZONIX_BASE_URL", api.URL)
+	t.Setenv("ENZONIX_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := make(chan string, 1)
+	done := make(chan int, 1)
+	go func() {
+		done <- run(ctx, []string{"-listen", "127.0.0.1:0", "-credentials", creds}, io.Discard, ready)
+	}()
+	addr := <-ready
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+addr+"/nic/update?hostname=home.example.com&myip=203.0.113.5", nil)
+	req.SetBasicAuth("router", "s3cret")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if strings.TrimSpace(string(body)) != "good 203.0.113.5" {
+		t.Fatalf("unexpected response %q", body)
+	}
+	if r := fake.Records(domain.ID); len(r) != 1 || r[0].Value != "203.0.113.5" {
+		t.Fatalf("unexpected records %+v", r)
+	}
+
+	cancel()
+	if code := <-done; code != 0 {
+		t.Fatalf("unexpected exit %d", code)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+)
+
+// errAborted is returned when the user declines to apply a plan.
+var errAborted = errors.New("aborted")
+
+func (a *app) requireAudit(cmd string) error {
+	if a.audit == nil {
+		return usageErrorf("%s requires --audit-log or %s", cmd, envAuditLog)
+	}
+	return nil
+}
+
+func auditList(ctx context.Context, a *app, args []string) error {
+	if _, err := parseFlags(newFlagSet("audit list"), args); err != nil {
+		return err
+	}
+	if err := a.requireAudit("audit list"); err != nil {
+		return err
+	}
+	entries, err := a.audit.AuditEntries(ctx)
+	if err != nil {
+		return err
+	}
+	return a.render(entries, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tTIME\tACTOR\tOPERATION\tOUTCOME\tTARGET")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.ID, formatTime(&e.Time), orDash(e.Actor), e.Operation, e.Outcome, auditTarget(e))
+		}
+	}, nil)
+}
+
+func auditVerify(ctx context.Context, a *app, args []string) error {
+	if _, err := parseFlags(newFlagSet("audit verify"), args); err != nil {
+		return err
+	}
+	if err := a.requireAudit("audit verify"); err != nil {
+		return err
+	}
+	entries, err := a.audit.AuditEntries(ctx)
+	if err != nil {
+		return err
+	}
+	if err := enzonix.VerifyAuditChain(entries); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stderr, "verified %d entries\n", len(entries))
+	return nil
+}
+
+func undo(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("undo")
+	last := fs.Int("last", 0, "revert the last N revertable changes")
+	dryRun := fs.Bool("dry-run", false, "show the plan without applying it")
+	yes := fs.Bool("yes", false, "apply without asking")
+	skip := fs.Bool("skip-conflicts", false, "apply the steps without conflicts")
+	force := fs.Bool("force", false, "apply conflicting steps too")
+	ids, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := a.requireAudit("undo"); err != nil {
+		return err
+	}
+	if (len(ids) == 0) == (*last == 0) {
+		return usageErrorf("undo requires entry IDs or --last N")
+	}
+	if *last > 0 {
+		if ids, err = a.lastRevertable(ctx, *last); err != nil {
+			return err
+		}
+	}
+
+	plan, err := a.client.PlanRevert(ctx, ids)
+	if err != nil {
+		return err
+	}
+	if err := a.render(plan, func(w io.Writer) {
+		fmt.Fprintln(w, "ACTION\tNAME\tTYPE\tTTL\tVALUE\tRECORD\tCONFLICT")
+		for _, s := range plan.Steps {
+			t := s.Target
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", s.Action, t.Name, t.Type, t.TTL, t.Value, orDash(s.RecordID), orDash(s.Conflict))
+		}
+	}, nil); err != nil {
+		return err
+	}
+
+	if len(plan.Steps) == 0 {
+		fmt.Fprintln(a.stderr, "nothing to revert")
+		return nil
+	}
+	if *dryRun {
+		return nil
+	}
+	opts := enzonix.RevertOptions{SkipConflicts: *skip, Force: *force}
+	if n := len(plan.Conflicts()); n > 0 && !*skip && !*force {
+		return fmt.Errorf("%w: %d steps conflict; rerun with --skip-conflicts or --force", enzonix.ErrRevertConflict, n)
+	}
+	if !*yes && !a.confirm(fmt.Sprintf("Apply %d changes? [y/N] ", len(plan.Steps))) {
+		return errAborted
+	}
+	if err := a.client.ApplyRevert(ctx, plan, opts); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stderr, "reverted %d entries\n", len(ids))
+	return nil
+}
+
+// lastRevertable returns the IDs of the newest n successful record changes.
+func (a *app) lastRevertable(ctx context.Context, n int) ([]string, error) {
+	entries, err := a.audit.AuditEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for i := len(entries) - 1; i >= 0 && len(ids) < n; i-- {
+		e := entries[i]
+		if e.Outcome != enzonix.AuditSuccess {
+			continue
+		}
+		switch e.Operation {
+		case "CreateRecord", "UpdateRecord", "DeleteRecord", "ImportBindZone":
+			ids = append(ids, e.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no revertable changes in the journal: %w", errNotFound)
+	}
+	return ids, nil
+}
+
+// confirm asks a yes/no question on stderr and reads the answer from stdin.
+func (a *app) confirm(question string) bool {
+	fmt.Fprint(a.stderr, question)
+	answer, _ := bufio.NewReader(a.stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func auditTarget(e enzonix.AuditEntry) string {
+	r := e.After
+	if r == nil {
+		r = e.Before
+	}
+	switch {
+	case r != nil:
+		return fmt.Sprintf("%s %s %s", r.Name, r.Type, r.Value)
+	case e.Domain != nil:
+		return e.Domain.Name
+	}
+	return "-"
+}
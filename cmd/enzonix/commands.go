@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/export"
+	"github.com/Enzonix-LLC/dns-sdk-go/importer"
+)
+
+func domainsList(ctx context.Context, a *app, args []string) error {
+	if _, err := parseFlags(newFlagSet("domains list"), args); err != nil {
+		return err
+	}
+	domains, err := a.client.ListDomains(ctx)
+	if err != nil {
+		return err
+	}
+	return a.printDomains(domains)
+}
+
+func domainsCreate(ctx context.Context, a *app, args []string) error {
+	pos, err := parseFlags(newFlagSet("domains create"), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageErrorf("domains create requires exactly one domain name")
+	}
+	domain, err := a.client.CreateDomain(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+	return a.printDomain(domain)
+}
+
+func domainsDelete(ctx context.Context, a *app, args []string) error {
+	pos, err := parseFlags(newFlagSet("domains delete"), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageErrorf("domains delete requires exactly one domain")
+	}
+	domainID, err := a.resolveDomainID(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+	if err := a.client.DeleteDomain(ctx, domainID); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stderr, "deleted domain %s\n", domainID)
+	return nil
+}
+
+func domainsCheckNS(ctx context.Context, a *app, args []string) error {
+	pos, err := parseFlags(newFlagSet("domains check-ns"), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageErrorf("domains check-ns requires exactly one domain")
+	}
+	domainID, err := a.resolveDomainID(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.CheckNameserver(ctx, domainID)
+	if err != nil {
+		return err
+	}
+	return a.render(resp, func(w io.Writer) {
+		fmt.Fprintf(w, "Domain\t%s\n", resp.Domain.Name)
+		fmt.Fprintf(w, "Valid\t%t\n", resp.Check.Valid)
+		fmt.Fprintf(w, "Status\t%s\n", orDash(string(resp.Check.Status)))
+	}, nil)
+}
+
+func recordsList(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("records list")
+	name := fs.String("name", "", "only show records with this name")
+	typ := fs.String("type", "", "only show records of this type")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageErrorf("records list requires exactly one domain")
+	}
+	domainID, err := a.resolveDomainID(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+	records, err := a.client.ListDomainRecords(ctx, domainID)
+	if err != nil {
+		return err
+	}
+
+	filtered := records[:0]
+	for _, r := range records {
+		if *name != "" && !strings.EqualFold(strings.TrimSuffix(r.Name, "."), strings.TrimSuffix(*name, ".")) {
+			continue
+		}
+		if *typ != "" && !strings.EqualFold(r.Type, *typ) {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return a.printRecords(filtered)
+}
+
+// recordFlags holds the flags shared by records create, update and upsert.
+type recordFlags struct {
+	name, typ, value, countries string
+	ttl, priority               int
+	set                         map[string]bool
+}
+
+func registerRecordFlags(fs *flag.FlagSet, f *recordFlags) {
+	fs.StringVar(&f.name, "name", "", "record name")
+	fs.StringVar(&f.typ, "type", "", "record type")
+	fs.StringVar(&f.value, "value", "", "record value")
+	fs.IntVar(&f.ttl, "ttl", 0, "TTL in seconds")
+	fs.IntVar(&f.priority, "priority", 0, "priority for MX and SRV records")
+	fs.StringVar(&f.countries, "country", "", "comma-separated ISO country codes")
+}
+
+func (f *recordFlags) countryCodes() []string {
+	if f.countries == "" {
+		return nil
+	}
+	var out []string
+	for _, cc := range strings.Split(f.countries, ",") {
+		if cc = strings.TrimSpace(cc); cc != "" {
+			out = append(out, strings.ToUpper(cc))
+		}
+	}
+	return out
+}
+
+func (f *recordFlags) createRequest(domainID string) (enzonix.CreateRecordRequest, error) {
+	if f.name == "" || f.typ == "" || f.value == "" {
+		return enzonix.CreateRecordRequest{}, usageErrorf("--name, --type and --value are required")
+	}
+	req := enzonix.CreateRecordRequest{
+		DomainID:     domainID,
+		Name:         f.name,
+		Type:         strings.ToUpper(f.typ),
+		Value:        f.value,
+		CountryCodes: f.countryCodes(),
+	}
+	if f.set["ttl"] {
+		req.TTL = &f.ttl
+	}
+	if f.set["priority"] {
+		req.Priority = &f.priority
+	}
+	return req, nil
+}
+
+func parseRecordFlags(name string, args []string) ([]string, *recordFlags, error) {
+	fs := newFlagSet(name)
+	f := &recordFlags{set: map[string]bool{}}
+	registerRecordFlags(fs, f)
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	fs.Visit(func(fl *flag.Flag) { f.set[fl.Name] = true })
+	return pos, f, nil
+}
+
+func recordsCreate(ctx context.Context, a *app, args []string) error {
+	pos, f, err := parseRecordFlags("records create", args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageErrorf("records create requires exactly one domain")
+	}
+	domainID, err := a.resolveDomainID(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+	req, err := f.createRequest(domainID)
+	if err != nil {
+		return err
+	}
+	record, err := a.client.CreateRecord(ctx, req)
+	if err != nil {
+		return err
+	}
+	return a.printRecord(record)
+}
+
+func recordsUpsert(ctx context.Context, a *app, args []string) error {
+	pos, f, err := parseRecordFlags("records upsert", args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageErrorf("records upsert requires exactly one domain")
+	}
+	domainID, err := a.resolveDomainID(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+	req, err := f.createRequest(domainID)
+	if err != nil {
+		return err
+	}
+	record, err := a.client.UpsertRecord(ctx, req)
+	if err != nil {
+		return err
+	}
+	return a.printRecord(record)
+}
+
+func recordsUpdate(ctx context.Context, a *app, args []string) error {
+	pos, f, err := parseRecordFlags("records update", args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageErrorf("records update requires exactly one record id")
+	}
+	if len(f.set) == 0 {
+		return usageErrorf("records update requires at least one field flag")
+	}
+
+	var req enzonix.UpdateRecordRequest
+	if f.set["name"] {
+		req.Name = &f.name
+	}
+	if f.set["type"] {
+		typ := strings.ToUpper(f.typ)
+		req.Type = &typ
+	}
+	if f.set["value"] {
+		req.Value = &f.value
+	}
+	if f.set["ttl"] {
+		req.TTL = &f.ttl
+	}
+	if f.set["priority"] {
+		req.Priority = &f.priority
+	}
+	if f.set["country"] {
+		req.CountryCodes = f.countryCodes()
+	}
+
+	record, err := a.client.UpdateRecord(ctx, pos[0], req)
+	if err != nil {
+		return err
+	}
+	return a.printRecord(record)
+}
+
+func recordsDelete(ctx context.Context, a *app, args []string) error {
+	pos, err := parseFlags(newFlagSet("records delete"), args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageErrorf("records delete requires exactly one record id")
+	}
+	if err := a.client.DeleteRecord(ctx, pos[0]); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stderr, "deleted record %s\n", pos[0])
+	return nil
+}
+
+func zoneExport(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("zone export")
+	file := fs.String("file", "", "write the zone to this file instead of stdout")
+	format := fs.String("format", "", "json, yaml, csv, terraform, octodns or dnscontrol instead of BIND")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageErrorf("zone export requires exactly one domain")
+	}
+	var exportFormat export.Format
+	if *format != "" {
+		if exportFormat, err = export.ParseFormat(*format); err != nil {
+			return usageErrorf("%v", err)
+		}
+	}
+	domainID, err := a.resolveDomainID(ctx, pos[0])
+	if err != nil {
+		return err
+	}
+
+	if exportFormat != "" {
+		var b bytes.Buffer
+		warnings, err := export.Domain(ctx, a.client, domainID, exportFormat, &b)
+		if err != nil {
+			return err
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(a.stderr, "warning: %s\n", w)
+		}
+		if *file != "" {
+			return os.WriteFile(*file, b.Bytes(), 0o644)
+		}
+		_, err = a.stdout.Write(b.Bytes())
+		return err
+	}
+
+	// Structured formats export the record list; table and bind both emit
+	// the zone file as served by the API.
+	if a.format == formatJSON || a.format == formatYAML {
+		records, err := a.client.ListDomainRecords(ctx, domainID)
+		if err != nil {
+			return err
+		}
+		return a.printRecords(records)
+	}
+
+	zone, err := a.client.ExportBindZone(ctx, domainID)
+	if err != nil {
+		return err
+	}
+	if *file != "" {
+		return os.WriteFile(*file, zone, 0o644)
+	}
+	_, err = a.stdout.Write(zone)
+	return err
+}
+
+func zoneImport(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("zone import")
+	contentType := fs.String("content-type", "text/plain", "content type of the zone data")
+	format := fs.String("format", "", "cloudflare, route53, octodns, tinydns or csv instead of BIND")
+	zone := fs.String("zone", "", "zone the file describes; required with --format")
+	pos, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return usageErrorf("zone import requires a file path or -")
+	}
+	var importFormat importer.Format
+	if *format != "" {
+		if importFormat, err = importer.ParseFormat(*format); err != nil {
+			return usageErrorf("%v", err)
+		}
+		if *zone == "" {
+			return usageErrorf("zone import --format requires --zone")
+		}
+	}
+
+	var data []byte
+	if pos[0] == "-" {
+		data, err = io.ReadAll(a.stdin)
+	} else {
+		data, err = os.ReadFile(pos[0])
+	}
+	if err != nil {
+		return fmt.Errorf("read zone: %w", err)
+	}
+
+	var resp *enzonix.BindImportResponse
+	if importFormat != "" {
+		var res *importer.Result
+		if res, err = importer.Parse(bytes.NewReader(data), importFormat, *zone); err != nil {
+			return err
+		}
+		for _, w := range res.Warnings {
+			fmt.Fprintf(a.stderr, "warning: %s\n", w)
+		}
+		resp, err = res.Import(ctx, a.client)
+	} else {
+		resp, err = a.client.ImportBindZone(ctx, data, *contentType)
+	}
+	if err != nil {
+		return err
+	}
+	for _, msg := range resp.Errors {
+		fmt.Fprintf(a.stderr, "warning: %s\n", msg)
+	}
+	return a.render(resp, func(w io.Writer) {
+		fmt.Fprintf(w, "Domain\t%s\n", resp.Domain.Name)
+		fmt.Fprintf(w, "Records created\t%d\n", resp.RecordsCreated)
+		fmt.Fprintf(w, "Partial success\t%t\n", resp.PartialSuccess)
+	}, func(w io.Writer) {
+		for _, r := range resp.Records {
+			fmt.Fprintln(w, bindLine(r))
+		}
+	})
+}
+
+func keyRotate(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("key rotate")
+	save := fs.String("save", "", "write the new key to this file")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	profile, err := a.client.RotateAPIKey(ctx)
+	if profile == nil {
+		return err
+	}
+	if err != nil {
+		// Rotated, but journaling failed; the new key must not be lost.
+		fmt.Fprintf(a.stderr, "new API token: %s\n", profile.APIToken)
+		return err
+	}
+	if *save != "" {
+		if err := enzonix.PersistAPIKeyToFile(*save)(ctx, profile); err != nil {
+			// The old key is already revoked; never lose the new one.
+			fmt.Fprintf(a.stderr, "new API token: %s\n", profile.APIToken)
+			return err
+		}
+	}
+	return a.render(profile, func(w io.Writer) {
+		fmt.Fprintf(w, "Client\t%s\n", profile.ID)
+		fmt.Fprintf(w, "Name\t%s\n", profile.Name)
+		fmt.Fprintf(w, "API token\t%s\n", profile.APIToken)
+	}, nil)
+}
+
+// resolveDomainID accepts a domain ID or a domain name and returns the ID.
+func (a *app) resolveDomainID(ctx context.Context, ref string) (string, error) {
+	if !strings.Contains(ref, ".") {
+		return ref, nil
+	}
+	domains, err := a.client.ListDomains(ctx)
+	if err != nil {
+		return "", err
+	}
+	want := strings.TrimSuffix(strings.ToLower(ref), ".")
+	for _, d := range domains {
+		if strings.TrimSuffix(strings.ToLower(d.Name), ".") == want {
+			return d.ID, nil
+		}
+	}
+	for _, d := range domains {
+		if d.ID == ref {
+			return d.ID, nil
+		}
+	}
+	return "", fmt.Errorf("domain %q: %w", ref, errNotFound)
+}
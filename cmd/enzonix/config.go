@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+)
+
+// envAuditLog names the journal file when --audit-log is not given. It is
+// a CLI setting rather than part of the SDK's profiles.
+const envAuditLog = "ENZONIX_AUDIT_LOG"
+
+// settings holds connection parameters given on the command line.
+type settings struct {
+	APIKey     string
+	BaseURL    string
+	ConfigPath string
+	Profile    string
+	Timeout    time.Duration
+}
+
+// resolveProfile merges flags on top of the SDK's profile resolution, which
+// itself layers environment variables over the config file.
+func resolveProfile(env environment, flags settings) (enzonix.Profile, error) {
+	profile, err := enzonix.ResolveProfile(enzonix.LoadOptions{
+		Path:      flags.ConfigPath,
+		Profile:   flags.Profile,
+		LookupEnv: env.lookup,
+	})
+	if err != nil {
+		return enzonix.Profile{}, err
+	}
+
+	if flags.APIKey != "" {
+		profile.APIKey = flags.APIKey
+	}
+	if flags.BaseURL != "" {
+		profile.BaseURL = flags.BaseURL
+	}
+	if flags.Timeout > 0 {
+		profile.Timeout = flags.Timeout
+	}
+
+	if profile.APIKey == "" {
+		return enzonix.Profile{}, errors.New("no API key: set --api-key, ENZONIX_API_KEY or api_key in the config file")
+	}
+	return profile, nil
+}
+
+// auditActor labels journal entries with the local user.
+func auditActor(env environment) string {
+	if user := env.get("USER"); user != "" {
+		return "enzonix-cli:" + user
+	}
+	return "enzonix-cli"
+}
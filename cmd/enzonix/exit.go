@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+)
+
+// Exit codes are part of the CLI's public contract; scripts branch on them.
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitAuth        = 3
+	exitNotFound    = 4
+	exitInvalid     = 5
+	exitRateLimited = 6
+	exitServer      = 7
+)
+
+// errNotFound is returned when a CLI-side lookup finds nothing.
+var errNotFound = errors.New("not found")
+
+// usageError marks errors caused by invalid command-line input.
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string { return e.err.Error() }
+func (e usageError) Unwrap() error { return e.err }
+
+func usageErrorf(format string, args ...any) error {
+	return usageError{fmt.Errorf(format, args...)}
+}
+
+// exitCode maps an error to the CLI exit code using the SDK's APIError
+// classification.
+func exitCode(err error) int {
+	var ue usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &ue):
+		return exitUsage
+	case enzonix.IsUnauthorized(err):
+		return exitAuth
+	case enzonix.IsNotFound(err), errors.Is(err, errNotFound):
+		return exitNotFound
+	case enzonix.IsValidation(err), enzonix.IsConflict(err), errors.Is(err, enzonix.ErrRevertConflict):
+		return exitInvalid
+	case enzonix.IsRateLimited(err):
+		return exitRateLimited
+	case enzonix.IsServerError(err):
+		return exitServer
+	default:
+		return exitError
+	}
+}
@@ -0,0 +1,274 @@
+// Command enzonix is a command-line interface for the Enzonix DNS API.
+//
+// Usage:
+//
+//	enzonix [global flags] <group> <command> [flags] [args]
+//
+// Groups and commands:
+//
+//	domains  list | create <name> | delete <domain> | check-ns <domain>
+//	records  list <domain> | create <domain> | update <record-id> |
+//	         delete <record-id> | upsert <domain>
+//	zone     export <domain> | import <file|->
+//	key      rotate
+//	audit    list | verify
+//	undo     <entry-id>... | --last N
+//
+// With --audit-log (or ENZONIX_AUDIT_LOG) every change is journaled, and
+// undo reverts journaled changes after showing a plan.
+//
+// Credentials are read from --api-key, the ENZONIX_API_KEY (or
+// ENZONIX_API_KEY_FILE) environment variable, or the selected profile of the
+// config file, in that order.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	env := environment{lookup: os.LookupEnv}
+	os.Exit(run(ctx, os.Args[1:], env, os.Stdin, os.Stdout, os.Stderr))
+}
+
+// environment abstracts environment variable lookup so tests can inject
+// values without touching the process environment.
+type environment struct {
+	lookup func(string) (string, bool)
+}
+
+func (e environment) get(key string) string {
+	if e.lookup == nil {
+		return ""
+	}
+	v, _ := e.lookup(key)
+	return v
+}
+
+// app carries the state shared by all subcommands.
+type app struct {
+	client *enzonix.Client
+	// audit is the journal opened by --audit-log, nil without one.
+	audit  *enzonix.FileAuditSink
+	format string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command func(ctx context.Context, a *app, args []string) error
+
+var commands = map[string]map[string]command{
+	"domains": {
+		"list":     domainsList,
+		"create":   domainsCreate,
+		"delete":   domainsDelete,
+		"check-ns": domainsCheckNS,
+	},
+	"records": {
+		"list":   recordsList,
+		"create": recordsCreate,
+		"update": recordsUpdate,
+		"delete": recordsDelete,
+		"upsert": recordsUpsert,
+	},
+	"zone": {
+		"export": zoneExport,
+		"import": zoneImport,
+	},
+	"key": {
+		"rotate": keyRotate,
+	},
+	"audit": {
+		"list":   auditList,
+		"verify": auditVerify,
+	},
+}
+
+// topLevel holds commands that take no subcommand.
+var topLevel = map[string]command{
+	"undo": undo,
+}
+
+func run(ctx context.Context, args []string, env environment, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("enzonix", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { usage(stderr) }
+
+	var (
+		apiKey     = fs.String("api-key", "", "API key (overrides ENZONIX_API_KEY)")
+		baseURL    = fs.String("base-url", "", "API base URL")
+		configPath = fs.String("config", "", "path to the config file")
+		profile    = fs.String("profile", "", "config profile (overrides ENZONIX_PROFILE)")
+		output     = fs.String("output", "table", "output format: table, json, yaml or bind")
+		timeout    = fs.Duration("timeout", 0, "HTTP timeout")
+		auditLog   = fs.String("audit-log", "", "journal changes to this file (overrides ENZONIX_AUDIT_LOG)")
+	)
+	fs.StringVar(output, "o", "table", "shorthand for --output")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	rest := fs.Args()
+	if len(rest) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	cmd, ok := topLevel[rest[0]]
+	if ok {
+		rest = rest[1:]
+	} else {
+		if len(rest) < 2 {
+			usage(stderr)
+			return exitUsage
+		}
+		group, ok := commands[rest[0]]
+		if !ok {
+			fmt.Fprintf(stderr, "enzonix: unknown command group %q\n", rest[0])
+			usage(stderr)
+			return exitUsage
+		}
+		if cmd, ok = group[rest[1]]; !ok {
+			fmt.Fprintf(stderr, "enzonix: unknown command %q for %s\n", rest[1], rest[0])
+			usage(stderr)
+			return exitUsage
+		}
+		rest = rest[2:]
+	}
+
+	switch *output {
+	case formatTable, formatJSON, formatYAML, formatBIND:
+	default:
+		fmt.Fprintf(stderr, "enzonix: unknown output format %q\n", *output)
+		return exitUsage
+	}
+
+	resolved, err := resolveProfile(env, settings{
+		APIKey:     *apiKey,
+		BaseURL:    *baseURL,
+		ConfigPath: *configPath,
+		Profile:    *profile,
+		Timeout:    *timeout,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "enzonix: %v\n", strings.TrimPrefix(err.Error(), "enzonix: "))
+		return exitUsage
+	}
+
+	var (
+		opts []enzonix.Option
+		sink *enzonix.FileAuditSink
+	)
+	if *auditLog == "" {
+		*auditLog = env.get(envAuditLog)
+	}
+	if *auditLog != "" {
+		sink, err = enzonix.NewFileAuditSink(*auditLog, enzonix.FileAuditOptions{})
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return exitError
+		}
+		defer sink.Close()
+		opts = append(opts, enzonix.WithAuditSink(sink), enzonix.WithAuditActor(auditActor(env)))
+	}
+
+	client, err := resolved.NewClient(opts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return exitUsage
+	}
+
+	a := &app{
+		client: client,
+		audit:  sink,
+		format: *output,
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+	}
+
+	if err := cmd(ctx, a, rest); err != nil {
+		msg := err.Error()
+		if !strings.HasPrefix(msg, "enzonix:") {
+			msg = "enzonix: " + msg
+		}
+		fmt.Fprintln(stderr, msg)
+		return exitCode(err)
+	}
+	return exitOK
+}
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, `Usage: enzonix [global flags] <group> <command> [flags] [args]
+
+Commands:
+  domains list
+  domains create <name>
+  domains delete <domain>
+  domains check-ns <domain>
+  records list <domain> [--name NAME] [--type TYPE]
+  records create <domain> --name NAME --type TYPE --value VALUE [--ttl N] [--priority N] [--country CC,...]
+  records update <record-id> [--name NAME] [--type TYPE] [--value VALUE] [--ttl N] [--priority N] [--country CC,...]
+  records delete <record-id>
+  records upsert <domain> --name NAME --type TYPE --value VALUE [--ttl N] [--priority N] [--country CC,...]
+  zone export <domain> [--file PATH] [--format FMT]
+  zone import <file|-> [--content-type TYPE] [--format FMT --zone NAME]
+  key rotate [--save PATH]
+  audit list
+  audit verify
+  undo <entry-id>... [--last N] [--dry-run] [--yes] [--skip-conflicts] [--force]
+
+<domain> accepts either a domain ID or a domain name.
+
+Global flags:
+  --api-key KEY      API key (default $ENZONIX_API_KEY)
+  --base-url URL     API base URL (default $ENZONIX_BASE_URL)
+  --config PATH      config file (default $ENZONIX_CONFIG or ~/.config/enzonix/config.toml)
+  --profile NAME     config profile (default $ENZONIX_PROFILE or the file's profile key)
+  -o, --output FMT   table, json, yaml or bind
+  --timeout DUR      HTTP timeout, e.g. 30s
+  --audit-log PATH   journal changes to PATH (default $ENZONIX_AUDIT_LOG)
+`)
+}
+
+// newFlagSet returns a flag set for a subcommand that reports errors as
+// usage errors instead of exiting.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseFlags parses subcommand flags, allowing positional arguments to
+// precede flags.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, usageError{err}
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
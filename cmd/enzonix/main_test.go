@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+type cliResult struct {
+	code   int
+	stdout string
+	stderr string
+}
+
+func runCLI(t *testing.T, env map[string]string, args ...string) cliResult {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	e := environment{lookup: func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}}
+	code := run(context.Background(), args, e, strings.NewReader(""), &stdout, &stderr)
+	return cliResult{code: code, stdout: stdout.String(), stderr: stderr.String()}
+}
+
+func newFakeEnv(t *testing.T) (*fakeapi.Server, map[string]string) {
+	t.Helper()
+	fake := fakeapi.New("key")
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	config := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(config, nil, 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return fake, map[string]string{
+		"ENZONIX_API_KEY":  "key",
+		"ENZONIX_BASE_URL": server.URL,
+		"ENZONIX_CONFIG":   config,
+	}
+}
+
+func TestDomainsListJSON(t *testing.T) {
+	t.Parallel()
+
+	fake, env := newFakeEnv(t)
+	fake.AddDomain("example.com")
+
+	res := runCLI(t, env, "-o", "json", "domains", "list")
+	if res.code != exitOK {
+		t.Fatalf("unexpected exit code %d: %s", res.code, res.stderr)
+	}
+	var domains []map[string]any
+	if err := json.Unmarshal([]byte(res.stdout), &domains); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if len(domains) != 1 || domains[0]["name"] != "example.com." {
+		t.Fatalf("unexpected domains: %v", domains)
+	}
+}
+
+func TestRecordsLifecycle(t *testing.T) {
+	t.Parallel()
+
+	fake, env := newFakeEnv(t)
+	domain := fake.AddDomain("example.com")
+
+	res := runCLI(t, env, "records", "create", "example.com", "--name", "www", "--type", "a", "--value", "192.0.2.1", "--ttl", "300")
+	if res.code != exitOK {
+		t.Fatalf("create failed (%d): %s", res.code, res.stderr)
+	}
+
+	res = runCLI(t, env, "records", "upsert", domain.ID, "--name", "www", "--type", "A", "--value", "192.0.2.2")
+	if res.code != exitOK {
+		t.Fatalf("upsert failed (%d): %s", res.code, res.stderr)
+	}
+	records := fake.Records(domain.ID)
+	if len(records) != 1 || records[0].Value != "192.0.2.2" || records[0].TTL != 300 {
+		t.Fatalf("unexpected records after upsert: %#v", records)
+	}
+
+	res = runCLI(t, env, "-o", "bind", "records", "list", domain.ID)
+	if res.code != exitOK {
+		t.Fatalf("list failed (%d): %s", res.code, res.stderr)
+	}
+	if strings.TrimSpace(res.stdout) != "www\t300\tIN\tA\t192.0.2.2" {
+		t.Fatalf("unexpected bind output %q", res.stdout)
+	}
+
+	res = runCLI(t, env, "records", "update", records[0].ID, "--ttl", "60")
+	if res.code != exitOK {
+		t.Fatalf("update failed (%d): %s", res.code, res.stderr)
+	}
+	if got := fake.Records(domain.ID)[0].TTL; got != 60 {
+		t.Fatalf("expected ttl 60, got %d", got)
+	}
+
+	res = runCLI(t, env, "records", "delete", records[0].ID)
+	if res.code != exitOK {
+		t.Fatalf("delete failed (%d): %s", res.code, res.stderr)
+	}
+	if len(fake.Records(domain.ID)) != 0 {
+		t.Fatalf("expected record to be deleted")
+	}
+}
+
+func TestZoneExportAndImport(t *testing.T) {
+	t.Parallel()
+
+	fake, env := newFakeEnv(t)
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+
+	res := runCLI(t, env, "zone", "export", "example.com")
+	if res.code != exitOK {
+		t.Fatalf("export failed (%d): %s", res.code, res.stderr)
+	}
+	if !strings.Contains(res.stdout, "$ORIGIN example.com.") {
+		t.Fatalf("unexpected zone %q", res.stdout)
+	}
+
+	res = runCLI(t, env, "zone", "export", "example.com", "--format", "dnscontrol")
+	if res.code != exitOK || !strings.Contains(res.stdout, `A("www", "192.0.2.1", TTL(300)),`) {
+		t.Fatalf("unexpected DNSControl export (%d): %q %s", res.code, res.stdout, res.stderr)
+	}
+	if res := runCLI(t, env, "zone", "export", "example.com", "--format", "xml"); res.code != exitUsage {
+		t.Fatalf("expected usage error for an unknown format, got %d", res.code)
+	}
+
+	zone := filepath.Join(t.TempDir(), "zone.txt")
+	if err := os.WriteFile(zone, []byte("$ORIGIN example.org.\nmail 300 IN MX 10 mx.example.org.\n"), 0o644); err != nil {
+		t.Fatalf("write zone: %v", err)
+	}
+	res = runCLI(t, env, "zone", "import", zone)
+	if res.code != exitOK {
+		t.Fatalf("import failed (%d): %s", res.code, res.stderr)
+	}
+	if !strings.Contains(res.stdout, "Records created  1") {
+		t.Fatalf("unexpected import output %q", res.stdout)
+	}
+
+	records := filepath.Join(t.TempDir(), "records.csv")
+	if err := os.WriteFile(records, []byte("name,type,ttl,value\nwww,A,300,192.0.2.7\nloc,LOC,300,1 2 3\n"), 0o644); err != nil {
+		t.Fatalf("write records: %v", err)
+	}
+	res = runCLI(t, env, "zone", "import", records, "--format", "csv", "--zone", "example.net")
+	if res.code != exitOK || !strings.Contains(res.stdout, "Records created  1") || !strings.Contains(res.stderr, "warning: line 3") {
+		t.Fatalf("unexpected CSV import (%d): %q %s", res.code, res.stdout, res.stderr)
+	}
+	if res := runCLI(t, env, "zone", "import", records, "--format", "csv"); res.code != exitUsage {
+		t.Fatalf("expected usage error without --zone, got %d", res.code)
+	}
+
+	geo := filepath.Join(t.TempDir(), "route53.json")
+	if err := os.WriteFile(geo, []byte(`{"ResourceRecordSets": [{"Name": "www.example.org.", "Type": "A", "TTL": 60,
+		"SetIdentifier": "de", "GeoLocation": {"CountryCode": "DE"}, "ResourceRecords": [{"Value": "192.0.2.9"}]}]}`), 0o644); err != nil {
+		t.Fatalf("write records: %v", err)
+	}
+	if res := runCLI(t, env, "zone", "import", geo, "--format", "route53", "--zone", "example.org"); res.code == exitOK || !strings.Contains(res.stderr, "limited to countries") {
+		t.Fatalf("expected the geolocation import to fail (%d): %s", res.code, res.stderr)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	t.Parallel()
+
+	fake, env := newFakeEnv(t)
+
+	if res := runCLI(t, env, "records", "delete", "missing"); res.code != exitNotFound {
+		t.Fatalf("expected not found exit code, got %d", res.code)
+	}
+
+	fake.FailNext(http.StatusTooManyRequests, "slow down")
+	if res := runCLI(t, env, "domains", "list"); res.code != exitRateLimited {
+		t.Fatalf("expected rate limited exit code, got %d", res.code)
+	}
+
+	bad := map[string]string{}
+	for k, v := range env {
+		bad[k] = v
+	}
+	bad["ENZONIX_API_KEY"] = "wrong"
+	if res := runCLI(t, bad, "domains", "list"); res.code != exitAuth {
+		t.Fatalf("expected auth exit code, got %d", res.code)
+	}
+
+	if res := runCLI(t, env, "records", "create", "domain-x"); res.code != exitUsage {
+		t.Fatalf("expected usage exit code, got %d", res.code)
+	}
+	if res := runCLI(t, env, "bogus", "cmd"); res.code != exitUsage {
+		t.Fatalf("expected usage exit code, got %d", res.code)
+	}
+}
+
+func TestCredentialsFromConfigFile(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeapi.New("from-config")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	config := "# enzonix\napi_key = \"wrong\"\n\n[ops]\napi_key = \"from-config\"\nbase_url = \"" + server.URL + "\"\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	res := runCLI(t, map[string]string{}, "--config", path, "--profile", "ops", "domains", "list")
+	if res.code != exitOK {
+		t.Fatalf("unexpected exit code %d: %s", res.code, res.stderr)
+	}
+
+	res = runCLI(t, map[string]string{"ENZONIX_PROFILE": "ops"}, "--config", path, "domains", "list")
+	if res.code != exitOK {
+		t.Fatalf("unexpected exit code %d: %s", res.code, res.stderr)
+	}
+
+	res = runCLI(t, map[string]string{}, "--config", filepath.Join(t.TempDir(), "nope.toml"), "domains", "list")
+	if res.code != exitUsage {
+		t.Fatalf("expected usage error for missing explicit config, got %d", res.code)
+	}
+}
+
+func TestUndoFromAuditLog(t *testing.T) {
+	t.Parallel()
+
+	fake, env := newFakeEnv(t)
+	domain := fake.AddDomain("example.com")
+	www := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	env["ENZONIX_AUDIT_LOG"] = filepath.Join(t.TempDir(), "audit.jsonl")
+
+	if res := runCLI(t, env, "records", "update", www.ID, "--value", "203.0.113.1"); res.code != exitOK {
+		t.Fatalf("update failed (%d): %s", res.code, res.stderr)
+	}
+	if res := runCLI(t, env, "records", "delete", www.ID); res.code != exitOK {
+		t.Fatalf("delete failed (%d): %s", res.code, res.stderr)
+	}
+
+	res := runCLI(t, env, "audit", "list")
+	if res.code != exitOK || !strings.Contains(res.stdout, "UpdateRecord") || !strings.Contains(res.stdout, "DeleteRecord") {
+		t.Fatalf("unexpected audit list (%d): %s%s", res.code, res.stdout, res.stderr)
+	}
+
+	// Without --yes the empty stdin declines the plan.
+	res = runCLI(t, env, "undo", "--last", "2")
+	if res.code != exitError || !strings.Contains(res.stdout, "create") || !strings.Contains(res.stderr, "aborted") {
+		t.Fatalf("expected aborted undo (%d): %s%s", res.code, res.stdout, res.stderr)
+	}
+	if len(fake.Records(domain.ID)) != 0 {
+		t.Fatal("aborted undo changed records")
+	}
+
+	res = runCLI(t, env, "undo", "--last", "2", "--yes")
+	if res.code != exitOK {
+		t.Fatalf("undo failed (%d): %s", res.code, res.stderr)
+	}
+	records := fake.Records(domain.ID)
+	if len(records) != 1 || records[0].Value != "192.0.2.1" || records[0].TTL != 300 {
+		t.Fatalf("unexpected records after undo: %#v", records)
+	}
+
+	if res := runCLI(t, env, "audit", "verify"); res.code != exitOK {
+		t.Fatalf("verify failed (%d): %s", res.code, res.stderr)
+	}
+	delete(env, "ENZONIX_AUDIT_LOG")
+	if res := runCLI(t, env, "undo", "--last", "1"); res.code != exitUsage {
+		t.Fatalf("expected usage error without journal, got %d", res.code)
+	}
+}
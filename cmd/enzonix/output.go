@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/yamlite"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+	formatBIND  = "bind"
+)
+
+// render writes v in the selected output format. table writes the tabular
+// form; bind, when non-nil, writes the BIND form.
+func (a *app) render(v any, table func(w io.Writer), bind func(w io.Writer)) error {
+	switch a.format {
+	case formatJSON:
+		enc := json.NewEncoder(a.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case formatYAML:
+		data, err := yamlite.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = a.stdout.Write(data)
+		return err
+	case formatBIND:
+		if bind == nil {
+			return usageErrorf("bind output is only supported for records and zones")
+		}
+		bind(a.stdout)
+		return nil
+	default:
+		tw := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+		table(tw)
+		return tw.Flush()
+	}
+}
+
+func (a *app) printDomains(domains []enzonix.Domain) error {
+	return a.render(domains, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tACTIVE\tNAMESERVERS\tCREATED")
+		for _, d := range domains {
+			fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\n", d.ID, d.Name, d.Active, orDash(string(d.NameserverCheckStatus)), formatTime(d.CreatedAt))
+		}
+	}, nil)
+}
+
+func (a *app) printDomain(d *enzonix.Domain) error {
+	return a.render(d, func(w io.Writer) {
+		fmt.Fprintf(w, "ID\t%s\n", d.ID)
+		fmt.Fprintf(w, "Name\t%s\n", d.Name)
+		fmt.Fprintf(w, "Active\t%t\n", d.Active)
+		fmt.Fprintf(w, "Nameservers\t%s\n", orDash(string(d.NameserverCheckStatus)))
+		fmt.Fprintf(w, "Verified\t%s\n", formatTime(d.NameserverVerifiedAt))
+		fmt.Fprintf(w, "Created\t%s\n", formatTime(d.CreatedAt))
+	}, nil)
+}
+
+func (a *app) printRecords(records []enzonix.Record) error {
+	return a.render(records, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tTYPE\tTTL\tPRIORITY\tVALUE\tCOUNTRIES")
+		for _, r := range records {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", r.ID, r.Name, r.Type, r.TTL, formatPriority(r), r.Value, orDash(strings.Join(r.CountryCodes, ",")))
+		}
+	}, func(w io.Writer) {
+		for _, r := range records {
+			fmt.Fprintln(w, bindLine(r))
+		}
+	})
+}
+
+func (a *app) printRecord(r *enzonix.Record) error {
+	return a.render(r, func(w io.Writer) {
+		fmt.Fprintf(w, "ID\t%s\n", r.ID)
+		fmt.Fprintf(w, "Name\t%s\n", r.Name)
+		fmt.Fprintf(w, "Type\t%s\n", r.Type)
+		fmt.Fprintf(w, "TTL\t%d\n", r.TTL)
+		fmt.Fprintf(w, "Priority\t%s\n", formatPriority(*r))
+		fmt.Fprintf(w, "Value\t%s\n", r.Value)
+		fmt.Fprintf(w, "Countries\t%s\n", orDash(strings.Join(r.CountryCodes, ",")))
+	}, func(w io.Writer) {
+		fmt.Fprintln(w, bindLine(*r))
+	})
+}
+
+// bindLine renders a record as a single BIND zone file line.
+func bindLine(r enzonix.Record) string {
+	value := r.Value
+	switch strings.ToUpper(r.Type) {
+	case "TXT":
+		if !strings.HasPrefix(value, `"`) {
+			value = strconv.Quote(value)
+		}
+	case "MX", "SRV":
+		value = fmt.Sprintf("%d %s", r.Priority, value)
+	}
+	return fmt.Sprintf("%s\t%d\tIN\t%s\t%s", r.Name, r.TTL, strings.ToUpper(r.Type), value)
+}
+
+func formatPriority(r enzonix.Record) string {
+	switch strings.ToUpper(r.Type) {
+	case "MX", "SRV":
+		return strconv.Itoa(r.Priority)
+	}
+	return "-"
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
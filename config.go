@@ -0,0 +1,387 @@
+package enzonix
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables consulted by LoadConfig, ResolveProfile and
+// NewClientFromEnvironment. Each value variable also has a _FILE variant
+// (for example ENZONIX_API_KEY_FILE) naming a file that holds the value,
+// which suits secrets mounted by Kubernetes or Docker.
+const (
+	EnvAPIKey     = "ENZONIX_API_KEY"
+	EnvBaseURL    = "ENZONIX_BASE_URL"
+	EnvUserAgent  = "ENZONIX_USER_AGENT"
+	EnvTimeout    = "ENZONIX_TIMEOUT"
+	EnvRateLimit  = "ENZONIX_RATE_LIMIT"
+	EnvProfile    = "ENZONIX_PROFILE"
+	EnvConfigFile = "ENZONIX_CONFIG"
+)
+
+// DefaultProfileName is the profile used when none is selected.
+const DefaultProfileName = "default"
+
+// Profile holds the connection settings of a named credential profile.
+type Profile struct {
+	Name   string
+	APIKey string
+	// APIKeyFile holds the key when it is not given literally. Clients
+	// built by NewClient re-read it after a 401 response and write rotated
+	// keys back to it; APIKey is the key read when the profile was
+	// resolved.
+	APIKeyFile string
+	BaseURL    string
+	UserAgent  string
+	Timeout    time.Duration
+	// RateLimit caps outgoing requests per second. Zero means unlimited.
+	RateLimit float64
+}
+
+// Config is the parsed content of a config file.
+//
+// The file uses a TOML subset: top-level keys configure the "default"
+// profile, `[name]` sections define further profiles, and an optional
+// top-level `profile = "name"` selects the profile used by default.
+//
+//	profile = "staging"
+//	api_key = "..."
+//
+//	[staging]
+//	api_key_file = "/run/secrets/enzonix"
+//	base_url = "https://staging.api.ns.enzonix.com"
+//	timeout = "30s"
+//	rate_limit = 5
+type Config struct {
+	DefaultProfile string
+	Profiles       map[string]Profile
+}
+
+// LoadOptions controls where configuration is read from.
+type LoadOptions struct {
+	// Path of the config file. Defaults to $ENZONIX_CONFIG, then
+	// <user config dir>/enzonix/config.toml. A missing file is an error only
+	// when Path is set explicitly.
+	Path string
+	// Profile selects the profile. Defaults to $ENZONIX_PROFILE, then the
+	// file's `profile` key, then "default".
+	Profile string
+	// LookupEnv replaces os.LookupEnv, mainly for tests.
+	LookupEnv func(string) (string, bool)
+}
+
+func (o LoadOptions) getenv(key string) string {
+	lookup := o.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	v, _ := lookup(key)
+	return v
+}
+
+func (o LoadOptions) configPath() (path string, explicit bool) {
+	if o.Path != "" {
+		return o.Path, true
+	}
+	if p := o.getenv(EnvConfigFile); p != "" {
+		return p, true
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(dir, "enzonix", "config.toml"), false
+}
+
+// LoadConfig reads the config file selected by opts. When no file exists at
+// the default location an empty Config is returned.
+func LoadConfig(opts LoadOptions) (*Config, error) {
+	path, explicit := opts.configPath()
+	if path == "" {
+		return &Config{Profiles: map[string]Profile{}}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return &Config{Profiles: map[string]Profile{}}, nil
+		}
+		return nil, fmt.Errorf("enzonix: open config: %w", err)
+	}
+	defer f.Close()
+
+	cfg, err := ParseConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("enzonix: %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ParseConfig parses config file content. See Config for the format.
+func ParseConfig(r io.Reader) (*Config, error) {
+	cfg := &Config{Profiles: map[string]Profile{}}
+	section := DefaultProfileName
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section header", n)
+			}
+			section = strings.TrimSpace(strings.Trim(line, "[]"))
+			section = strings.TrimPrefix(section, "profiles.")
+			if unquoted, err := strconv.Unquote(section); err == nil {
+				section = unquoted
+			}
+			if section == "" {
+				return nil, fmt.Errorf("line %d: empty section name", n)
+			}
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		key = strings.TrimSpace(key)
+		value := strings.TrimSpace(raw)
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+
+		if key == "profile" && section == DefaultProfileName {
+			cfg.DefaultProfile = value
+			continue
+		}
+
+		p := cfg.Profiles[section]
+		p.Name = section
+		if err := p.set(key, value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		cfg.Profiles[section] = p
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ProfileNames returns the names of all profiles in sorted order.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveProfile loads the config file, selects a profile and applies
+// environment variable overrides on top of it. Secrets referenced through
+// api_key_file or _FILE variables are read at this point.
+func ResolveProfile(opts LoadOptions) (Profile, error) {
+	cfg, err := LoadConfig(opts)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	name := opts.Profile
+	if name == "" {
+		name = opts.getenv(EnvProfile)
+	}
+	explicit := name != ""
+	if name == "" {
+		name = cfg.DefaultProfile
+		explicit = name != ""
+	}
+	if name == "" {
+		name = DefaultProfileName
+	}
+
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		if explicit && name != DefaultProfileName {
+			return Profile{}, fmt.Errorf("enzonix: profile %q not found", name)
+		}
+		profile = Profile{Name: name}
+	}
+
+	overrides := []struct{ env, key string }{
+		{EnvAPIKey, "api_key"},
+		{EnvBaseURL, "base_url"},
+		{EnvUserAgent, "user_agent"},
+		{EnvTimeout, "timeout"},
+		{EnvRateLimit, "rate_limit"},
+	}
+	for _, o := range overrides {
+		value, ok, err := envOrFile(opts, o.env)
+		if err != nil {
+			return Profile{}, err
+		}
+		if !ok {
+			continue
+		}
+		if o.key == "api_key" {
+			profile.APIKeyFile = opts.getenv(o.env + "_FILE")
+		}
+		if err := profile.set(o.key, value); err != nil {
+			return Profile{}, fmt.Errorf("enzonix: %s: %w", o.env, err)
+		}
+	}
+
+	switch {
+	case profile.APIKey == "" && profile.APIKeyFile != "":
+		key, err := readSecretFile(profile.APIKeyFile)
+		if err != nil {
+			return Profile{}, err
+		}
+		profile.APIKey = key
+	case profile.APIKeyFile != "" && opts.getenv(EnvAPIKey+"_FILE") == "":
+		// A literal api_key wins over api_key_file.
+		profile.APIKeyFile = ""
+	}
+
+	return profile, nil
+}
+
+// Options converts the profile into client options. The API key is not
+// included; pass it to NewClient or use Profile.NewClient.
+func (p Profile) Options() []Option {
+	var opts []Option
+	if p.BaseURL != "" {
+		opts = append(opts, WithBaseURL(p.BaseURL))
+	}
+	if p.UserAgent != "" {
+		opts = append(opts, WithUserAgent(p.UserAgent))
+	}
+	if p.Timeout > 0 {
+		opts = append(opts, WithTimeout(p.Timeout))
+	}
+	if p.RateLimit > 0 {
+		opts = append(opts, WithRateLimit(p.RateLimit))
+	}
+	return opts
+}
+
+// NewClient creates a client from the profile. Extra options are applied
+// after the profile's own settings. A key kept in APIKeyFile is read
+// through FileCredentials.
+func (p Profile) NewClient(opts ...Option) (*Client, error) {
+	if p.APIKeyFile != "" {
+		creds, err := NewFileCredentials(p.APIKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return NewClientWithCredentials(creds, append(p.Options(), opts...)...)
+	}
+	return NewClient(p.APIKey, append(p.Options(), opts...)...)
+}
+
+// NewClientFromEnvironment creates a client from the environment and the
+// config file, as resolved by ResolveProfile with default LoadOptions.
+func NewClientFromEnvironment(opts ...Option) (*Client, error) {
+	profile, err := ResolveProfile(LoadOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if profile.APIKey == "" {
+		return nil, fmt.Errorf("enzonix: no api key: set %s, %s_FILE or api_key in profile %q", EnvAPIKey, EnvAPIKey, profile.Name)
+	}
+	return profile.NewClient(opts...)
+}
+
+func (p *Profile) set(key, value string) error {
+	switch key {
+	case "api_key":
+		p.APIKey = value
+	case "api_key_file":
+		p.APIKeyFile = value
+	case "base_url":
+		p.BaseURL = value
+	case "user_agent":
+		p.UserAgent = value
+	case "timeout":
+		d, err := parseTimeout(value)
+		if err != nil {
+			return err
+		}
+		p.Timeout = d
+	case "rate_limit":
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 {
+			return fmt.Errorf("invalid rate_limit %q", value)
+		}
+		p.RateLimit = rate
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
+
+// parseTimeout accepts Go durations ("30s") or whole seconds ("30").
+func parseTimeout(value string) (time.Duration, error) {
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid timeout %q", value)
+	}
+	return d, nil
+}
+
+// envOrFile reads name from the environment, or the file named by name_FILE.
+func envOrFile(opts LoadOptions, name string) (string, bool, error) {
+	value := opts.getenv(name)
+	file := opts.getenv(name + "_FILE")
+	switch {
+	case value != "" && file != "":
+		return "", false, fmt.Errorf("enzonix: both %s and %s_FILE are set", name, name)
+	case value != "":
+		return value, true, nil
+	case file != "":
+		secret, err := readSecretFile(file)
+		if err != nil {
+			return "", false, err
+		}
+		return secret, true, nil
+	}
+	return "", false, nil
+}
+
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("enzonix: read secret file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func stripComment(line string) string {
+	inQuote := false
+	for i, r := range line {
+		switch r {
+		case '"':
+			inQuote = !inQuote
+		case '#':
+			if !inQuote {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
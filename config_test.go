@@ -0,0 +1,218 @@
+package enzonix
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+func envMap(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := ParseConfig(strings.NewReader(`
+# default profile
+profile = "staging"
+api_key = "default-key"
+
+[staging]
+api_key = "staging-key" # inline comment
+base_url = "https://staging.example.test"
+user_agent = "ops#bot"
+timeout = "30s"
+rate_limit = 2.5
+
+[profiles.ci]
+api_key_file = "/run/secrets/enzonix"
+timeout = 5
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.DefaultProfile != "staging" {
+		t.Fatalf("unexpected default profile %q", cfg.DefaultProfile)
+	}
+	if got := cfg.ProfileNames(); strings.Join(got, ",") != "ci,default,staging" {
+		t.Fatalf("unexpected profiles %v", got)
+	}
+
+	staging := cfg.Profiles["staging"]
+	if staging.APIKey != "staging-key" || staging.BaseURL != "https://staging.example.test" {
+		t.Fatalf("unexpected staging profile %#v", staging)
+	}
+	if staging.UserAgent != "ops#bot" || staging.Timeout != 30*time.Second || staging.RateLimit != 2.5 {
+		t.Fatalf("unexpected staging profile %#v", staging)
+	}
+	if ci := cfg.Profiles["ci"]; ci.APIKeyFile != "/run/secrets/enzonix" || ci.Timeout != 5*time.Second {
+		t.Fatalf("unexpected ci profile %#v", ci)
+	}
+	if cfg.Profiles["default"].APIKey != "default-key" {
+		t.Fatalf("unexpected default profile %#v", cfg.Profiles["default"])
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		"api_key",
+		"[staging",
+		"unknown = 1",
+		"timeout = soon",
+		"rate_limit = -1",
+	} {
+		if _, err := ParseConfig(strings.NewReader(input)); err == nil {
+			t.Fatalf("expected error for %q", input)
+		}
+	}
+}
+
+func TestResolveProfile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secret, []byte("file-key\n"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+	path := filepath.Join(dir, "config.toml")
+	config := "api_key = \"default-key\"\n\n[ci]\napi_key_file = \"" + secret + "\"\nbase_url = \"https://ci.example.test\"\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	profile, err := ResolveProfile(LoadOptions{Path: path, LookupEnv: envMap(nil)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Name != "default" || profile.APIKey != "default-key" {
+		t.Fatalf("unexpected profile %#v", profile)
+	}
+
+	profile, err = ResolveProfile(LoadOptions{Path: path, LookupEnv: envMap(map[string]string{EnvProfile: "ci"})})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.APIKey != "file-key" || profile.BaseURL != "https://ci.example.test" {
+		t.Fatalf("unexpected profile %#v", profile)
+	}
+
+	profile, err = ResolveProfile(LoadOptions{Path: path, Profile: "ci", LookupEnv: envMap(map[string]string{
+		EnvAPIKey + "_FILE": secret,
+		EnvBaseURL:          "https://override.example.test",
+		EnvTimeout:          "2s",
+	})})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.APIKey != "file-key" || profile.BaseURL != "https://override.example.test" || profile.Timeout != 2*time.Second {
+		t.Fatalf("unexpected profile %#v", profile)
+	}
+
+	if _, err := ResolveProfile(LoadOptions{Path: path, Profile: "missing", LookupEnv: envMap(nil)}); err == nil {
+		t.Fatalf("expected error for unknown profile")
+	}
+	if _, err := ResolveProfile(LoadOptions{Path: filepath.Join(dir, "nope.toml"), LookupEnv: envMap(nil)}); err == nil {
+		t.Fatalf("expected error for missing explicit config")
+	}
+	if _, err := ResolveProfile(LoadOptions{Path: path, LookupEnv: envMap(map[string]string{
+		EnvAPIKey:           "a",
+		EnvAPIKey + "_FILE": secret,
+	})}); err == nil {
+		t.Fatalf("expected error when both variable and _FILE are set")
+	}
+}
+
+func TestProfileNewClient(t *testing.T) {
+	t.Parallel()
+
+	profile := Profile{
+		APIKey:    "key",
+		BaseURL:   "https://example.test",
+		UserAgent: "profile-agent",
+		Timeout:   3 * time.Second,
+		RateLimit: 10,
+	}
+	client, err := profile.NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.baseURL.String() != "https://example.test" || client.userAgent != "profile-agent" {
+		t.Fatalf("unexpected client settings")
+	}
+	if client.httpClient.Timeout != 3*time.Second {
+		t.Fatalf("unexpected timeout %v", client.httpClient.Timeout)
+	}
+	if client.limiter == nil {
+		t.Fatalf("expected rate limiter")
+	}
+}
+
+func TestProfileKeyFile(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeapi.New("new-key")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secret, []byte("old-key\n"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte("api_key_file = \""+secret+"\"\nbase_url = \""+server.URL+"\"\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	profile, err := ResolveProfile(LoadOptions{Path: path, LookupEnv: envMap(nil)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client, err := profile.NewClient()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The mounted secret is rotated; the client picks it up after a 401.
+	if err := os.WriteFile(secret, []byte("new-key\n"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+	if _, err := client.ListDomains(context.Background()); err != nil {
+		t.Fatalf("expected the refreshed key to be used, got %v", err)
+	}
+}
+
+func TestRateLimiterSpacing(t *testing.T) {
+	t.Parallel()
+
+	limiter := newRateLimiter(50)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.wait(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Fatalf("expected requests to be spaced, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter.next = time.Now().Add(time.Hour)
+	if err := limiter.wait(ctx); err == nil {
+		t.Fatalf("expected context error")
+	}
+}
@@ -0,0 +1,218 @@
+package enzonix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// CredentialProvider supplies the API key used for a request. It is
+// consulted for every request, so implementations must be safe for
+// concurrent use.
+type CredentialProvider interface {
+	APIKey(ctx context.Context) (string, error)
+}
+
+// CredentialUpdater is implemented by providers that can switch to a new
+// API key, which RotateAPIKeyAndSwap requires.
+type CredentialUpdater interface {
+	SetAPIKey(ctx context.Context, key string) error
+}
+
+// CredentialRefresher is implemented by providers that can reload their key
+// from an external source. The client calls Refresh once after a 401
+// response and retries the request if the key changed.
+type CredentialRefresher interface {
+	Refresh(ctx context.Context) error
+}
+
+// ErrCredentialsNotUpdatable is returned by RotateAPIKeyAndSwap when the
+// client's CredentialProvider does not implement CredentialUpdater.
+var ErrCredentialsNotUpdatable = errors.New("enzonix: credential provider cannot be updated")
+
+// KeyCredentials holds an API key in memory and allows it to be swapped
+// atomically. NewClient wraps its apiKey argument in KeyCredentials.
+type KeyCredentials struct {
+	key atomic.Value
+}
+
+// NewKeyCredentials returns a provider for a fixed, swappable key.
+func NewKeyCredentials(key string) *KeyCredentials {
+	kc := &KeyCredentials{}
+	kc.key.Store(key)
+	return kc
+}
+
+// APIKey implements CredentialProvider.
+func (k *KeyCredentials) APIKey(context.Context) (string, error) {
+	key, _ := k.key.Load().(string)
+	if key == "" {
+		return "", errors.New("enzonix: api key must not be empty")
+	}
+	return key, nil
+}
+
+// SetAPIKey implements CredentialUpdater.
+func (k *KeyCredentials) SetAPIKey(_ context.Context, key string) error {
+	if strings.TrimSpace(key) == "" {
+		return errors.New("enzonix: api key must not be empty")
+	}
+	k.key.Store(key)
+	return nil
+}
+
+// FileCredentials reads the API key from a file, such as a mounted
+// Kubernetes secret. The key is cached; Refresh re-reads the file and
+// SetAPIKey writes the new key back to it.
+type FileCredentials struct {
+	path string
+	mu   sync.RWMutex
+	key  string
+}
+
+// NewFileCredentials reads the key stored at path.
+func NewFileCredentials(path string) (*FileCredentials, error) {
+	fc := &FileCredentials{path: path}
+	if err := fc.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return fc, nil
+}
+
+// APIKey implements CredentialProvider.
+func (f *FileCredentials) APIKey(context.Context) (string, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.key, nil
+}
+
+// Refresh implements CredentialRefresher.
+func (f *FileCredentials) Refresh(context.Context) error {
+	key, err := readSecretFile(f.path)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return fmt.Errorf("enzonix: api key file %s is empty", f.path)
+	}
+	f.mu.Lock()
+	f.key = key
+	f.mu.Unlock()
+	return nil
+}
+
+// SetAPIKey implements CredentialUpdater by persisting key to the file.
+// The key is used from then on even if the file cannot be written, since
+// the previous key may already be revoked; the error then reports that
+// the file is stale.
+func (f *FileCredentials) SetAPIKey(_ context.Context, key string) error {
+	if strings.TrimSpace(key) == "" {
+		return errors.New("enzonix: api key must not be empty")
+	}
+	f.mu.Lock()
+	f.key = key
+	f.mu.Unlock()
+	if err := writeFileAtomic(f.path, []byte(key+"\n")); err != nil {
+		return fmt.Errorf("enzonix: api key file %s is stale: %w", f.path, err)
+	}
+	return nil
+}
+
+// KeyRotationHook is called by RotateAPIKeyAndSwap after the client has
+// switched to the new key, typically to persist it.
+type KeyRotationHook func(ctx context.Context, profile *ClientProfile) error
+
+// PersistAPIKeyToFile returns a hook that writes the rotated key to path
+// with owner-only permissions.
+func PersistAPIKeyToFile(path string) KeyRotationHook {
+	return func(_ context.Context, profile *ClientProfile) error {
+		return writeFileAtomic(path, []byte(profile.APIToken+"\n"))
+	}
+}
+
+// WithCredentialProvider replaces the API key passed to NewClient with a
+// provider consulted on every request.
+func WithCredentialProvider(provider CredentialProvider) Option {
+	return func(c *Client) error {
+		if provider == nil {
+			return errors.New("enzonix: credential provider must not be nil")
+		}
+		c.credentials = provider
+		return nil
+	}
+}
+
+// WithKeyRotationHook registers a hook run by RotateAPIKeyAndSwap. Hooks run
+// in registration order.
+func WithKeyRotationHook(hook KeyRotationHook) Option {
+	return func(c *Client) error {
+		if hook == nil {
+			return errors.New("enzonix: key rotation hook must not be nil")
+		}
+		c.rotationHooks = append(c.rotationHooks, hook)
+		return nil
+	}
+}
+
+// RotateAPIKeyAndSwap rotates the API key and atomically switches the client,
+// and every goroutine sharing it, to the new key before running the
+// registered KeyRotationHooks. The returned profile holds the new key even
+// when a hook fails, so the caller can persist it by other means.
+func (c *Client) RotateAPIKeyAndSwap(ctx context.Context) (*ClientProfile, error) {
+	updater, ok := c.credentials.(CredentialUpdater)
+	if !ok {
+		return nil, ErrCredentialsNotUpdatable
+	}
+
+	profile, err := c.RotateAPIKey(ctx)
+	if profile == nil {
+		return nil, err
+	}
+	// A failed audit write still returns the profile; the old key is
+	// already revoked, so the swap must happen regardless.
+	auditErr := err
+	if strings.TrimSpace(profile.APIToken) == "" {
+		return profile, errors.New("enzonix: rotation response did not include a new api token")
+	}
+
+	if err := updater.SetAPIKey(ctx, profile.APIToken); err != nil {
+		return profile, fmt.Errorf("enzonix: swap api key: %w", err)
+	}
+
+	for _, hook := range c.rotationHooks {
+		if err := hook(ctx, profile); err != nil {
+			return profile, errors.Join(fmt.Errorf("enzonix: key rotation hook: %w", err), auditErr)
+		}
+	}
+
+	return profile, auditErr
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("enzonix: write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("enzonix: write %s: %w", path, err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("enzonix: write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("enzonix: write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("enzonix: write %s: %w", path, err)
+	}
+	return nil
+}
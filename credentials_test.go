@@ -0,0 +1,172 @@
+package enzonix
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+type fixedCredentials string
+
+func (f fixedCredentials) APIKey(context.Context) (string, error) { return string(f), nil }
+
+func TestRotateAPIKeyAndSwap(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeapi.New("key")
+	fake.AddDomain("example.com")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	persisted := filepath.Join(t.TempDir(), "api-key")
+	client, err := NewClient("key", WithBaseURL(server.URL), WithKeyRotationHook(PersistAPIKeyToFile(persisted)))
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+	scoped := *client // a copy sharing the provider, as handed to other goroutines
+
+	profile, err := client.RotateAPIKeyAndSwap(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.APIToken != fake.APIKey() {
+		t.Fatalf("expected rotated token %q, got %q", fake.APIKey(), profile.APIToken)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := scoped.ListDomains(context.Background())
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("expected swapped key to be used, got %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(persisted)
+	if err != nil {
+		t.Fatalf("read persisted key: %v", err)
+	}
+	if strings.TrimSpace(string(data)) != profile.APIToken {
+		t.Fatalf("unexpected persisted key %q", data)
+	}
+}
+
+func TestRotateAPIKeyAndSwapStaleFile(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeapi.New("key")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "secrets")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+	path := filepath.Join(dir, "api-key")
+	if err := os.WriteFile(path, []byte("key\n"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	creds, err := NewFileCredentials(path)
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+	client, err := NewClientWithCredentials(creds, WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+
+	// The file can no longer be written; the client must still switch.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+	if _, err := client.RotateAPIKeyAndSwap(context.Background()); err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Fatalf("expected a stale file error, got %v", err)
+	}
+	if _, err := client.ListDomains(context.Background()); err != nil {
+		t.Fatalf("expected the new key to be used, got %v", err)
+	}
+}
+
+func TestRotateAPIKeyAndSwapRequiresUpdater(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeapi.New("key")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client, err := NewClientWithCredentials(fixedCredentials("key"), WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+	if _, err := client.RotateAPIKeyAndSwap(context.Background()); !errors.Is(err, ErrCredentialsNotUpdatable) {
+		t.Fatalf("expected ErrCredentialsNotUpdatable, got %v", err)
+	}
+	if len(fake.Requests()) != 0 {
+		t.Fatalf("expected no rotation request, got %v", fake.Requests())
+	}
+}
+
+func TestUnauthorizedRetriesWithRefreshedKey(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeapi.New("new-key")
+	domain := fake.AddDomain("example.com")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "api-key")
+	if err := os.WriteFile(path, []byte("old-key\n"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	creds, err := NewFileCredentials(path)
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+	client, err := NewClientWithCredentials(creds, WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+
+	// The secret is rotated on disk by another process.
+	if err := os.WriteFile(path, []byte("new-key\n"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+
+	value := "192.0.2.1"
+	if _, err := client.CreateRecord(context.Background(), CreateRecordRequest{
+		DomainID: domain.ID, Name: "www", Type: "A", Value: value,
+	}); err != nil {
+		t.Fatalf("expected retry with refreshed key to succeed, got %v", err)
+	}
+	if got := fake.Records(domain.ID); len(got) != 1 || got[0].Value != value {
+		t.Fatalf("unexpected records %#v", got)
+	}
+
+	// Another client rotates the key and the file is not updated correctly.
+	other, _ := NewClient("new-key", WithBaseURL(server.URL))
+	if _, err := other.RotateAPIKey(context.Background()); err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("still-wrong\n"), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	if _, err := client.ListDomains(context.Background()); !IsUnauthorized(err) {
+		t.Fatalf("expected unauthorized error, got %v", err)
+	}
+}
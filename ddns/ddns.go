@@ -0,0 +1,279 @@
+// Package ddns keeps A and AAAA records pointed at a host's current public
+// address. An Updater asks a Source for the address of each enabled family
+// and updates the records of its hosts only when the address differs from
+// the one it last applied.
+package ddns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+)
+
+// ErrNoDomain is returned for hosts outside every domain of the account.
+var ErrNoDomain = errors.New("ddns: no domain for host")
+
+// Options configures an Updater.
+type Options struct {
+	// Hosts are the fully qualified names to update, e.g.
+	// "home.example.com". Each must be in a domain of the account.
+	Hosts []string
+	// IPv4 and IPv6 determine the addresses for A and AAAA records. A nil
+	// source leaves records of that type alone.
+	IPv4 Source
+	IPv6 Source
+	// TTL of updated records. Zero keeps the records' TTL.
+	TTL int
+	// Interval between updates in Run; defaults to 5m.
+	Interval time.Duration
+	// MaxBackoff caps the delay after failed updates, which starts at 30s
+	// (or Interval, when shorter) and doubles; defaults to 30m.
+	MaxBackoff time.Duration
+	// CacheFile persists the applied addresses, so that one-shot runs make
+	// no API calls while the address is unchanged. Empty keeps the cache
+	// in memory.
+	CacheFile string
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Change is the outcome for one host and record type.
+type Change struct {
+	Host    string
+	Type    string
+	Address netip.Addr
+	// Previous holds the record values before the update.
+	Previous []string
+	// Changed reports whether a record was created or updated.
+	Changed bool
+	// Err is set when the update failed, or when it was made but could
+	// not be audited (see enzonix.IsAuditOnly).
+	Err error
+}
+
+// Updater updates records when the host's address changes.
+type Updater struct {
+	client *enzonix.Client
+	opts   Options
+
+	mu sync.Mutex
+	// applied maps "host/type" to the address last applied.
+	applied map[string]string
+}
+
+// New validates opts and loads the cache file, if any.
+func New(client *enzonix.Client, opts Options) (*Updater, error) {
+	if len(opts.Hosts) == 0 {
+		return nil, errors.New("ddns: at least one host is required")
+	}
+	if opts.IPv4 == nil && opts.IPv6 == nil {
+		return nil, errors.New("ddns: an IPv4 or IPv6 source is required")
+	}
+	for i, h := range opts.Hosts {
+		h = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(h), "."))
+		if h == "" {
+			return nil, errors.New("ddns: empty host")
+		}
+		opts.Hosts[i] = h
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Minute
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 30 * time.Minute
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	u := &Updater{client: client, opts: opts, applied: map[string]string{}}
+	if opts.CacheFile != "" {
+		data, err := os.ReadFile(opts.CacheFile)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("ddns: read cache: %w", err)
+		default:
+			if err := json.Unmarshal(data, &u.applied); err != nil {
+				return nil, fmt.Errorf("ddns: parse cache %s: %w", opts.CacheFile, err)
+			}
+		}
+	}
+	return u, nil
+}
+
+// Run updates every interval until ctx is done, backing off after
+// failures.
+func (u *Updater) Run(ctx context.Context) error {
+	initial := min(30*time.Second, u.opts.Interval)
+	backoff := initial
+	for {
+		delay := u.opts.Interval
+		if _, err := u.Update(ctx); err != nil && ctx.Err() == nil {
+			delay = backoff
+			backoff = min(backoff*2, u.opts.MaxBackoff)
+			u.opts.Logger.ErrorContext(ctx, "ddns update failed", slog.Duration("retry", delay), slog.Any("error", err))
+		} else {
+			backoff = initial
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Update determines the current addresses and updates the records that
+// differ from them. The returned error joins the errors of all changes.
+func (u *Updater) Update(ctx context.Context) ([]Change, error) {
+	var (
+		changes []Change
+		errs    []error
+		domains []enzonix.Domain
+	)
+	for _, fam := range []struct {
+		family Family
+		source Source
+	}{{IPv4, u.opts.IPv4}, {IPv6, u.opts.IPv6}} {
+		if fam.source == nil {
+			continue
+		}
+		addr, err := fam.source.Address(ctx, fam.family)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ddns: determine %s address: %w", fam.family, err))
+			continue
+		}
+		for _, host := range u.opts.Hosts {
+			change := Change{Host: host, Type: fam.family.Type(), Address: addr}
+			if u.cached(host, change.Type) == addr.String() {
+				changes = append(changes, change)
+				continue
+			}
+			if domains == nil {
+				if domains, err = u.client.ListDomains(ctx); err != nil {
+					return changes, errors.Join(append(errs, err)...)
+				}
+			}
+			change.Previous, change.Changed, change.Err = u.apply(ctx, domains, host, change.Type, addr)
+			if change.Err != nil {
+				errs = append(errs, change.Err)
+			}
+			// A change that could not be audited was still made.
+			if change.Err == nil || enzonix.IsAuditOnly(change.Err) {
+				u.remember(host, change.Type, addr.String())
+			}
+			if change.Changed {
+				u.opts.Logger.InfoContext(ctx, "ddns record updated", slog.String("host", host), slog.String("type", change.Type),
+					slog.String("address", addr.String()), slog.Any("previous", change.Previous))
+			}
+			changes = append(changes, change)
+		}
+	}
+	if err := u.save(); err != nil {
+		errs = append(errs, err)
+	}
+	return changes, errors.Join(errs...)
+}
+
+// apply makes addr the only value of the host's record set.
+func (u *Updater) apply(ctx context.Context, domains []enzonix.Domain, host, typ string, addr netip.Addr) ([]string, bool, error) {
+	domain, name, ok := SplitHost(domains, host)
+	if !ok {
+		return nil, false, fmt.Errorf("%w %s", ErrNoDomain, host)
+	}
+	set, err := u.client.GetRRSet(ctx, domain.ID, name, typ)
+	if err != nil {
+		return nil, false, fmt.Errorf("ddns: %s %s: %w", host, typ, err)
+	}
+	res, err := u.client.ReplaceRRSet(ctx, domain.ID, name, typ, []string{addr.String()}, u.opts.TTL)
+	if err != nil {
+		err = fmt.Errorf("ddns: update %s %s: %w", host, typ, err)
+		if !enzonix.IsAuditOnly(err) {
+			return set.Values(), false, err
+		}
+	}
+	return set.Values(), res.Changed(), err
+}
+
+func (u *Updater) cached(host, typ string) string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.applied[host+"/"+typ]
+}
+
+func (u *Updater) remember(host, typ, addr string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.applied[host+"/"+typ] = addr
+}
+
+// Forget clears the cache, so that the next update compares the addresses
+// with the records again.
+func (u *Updater) Forget() error {
+	u.mu.Lock()
+	u.applied = map[string]string{}
+	u.mu.Unlock()
+	return u.save()
+}
+
+func (u *Updater) save() error {
+	if u.opts.CacheFile == "" {
+		return nil
+	}
+	u.mu.Lock()
+	data, err := json.MarshalIndent(u.applied, "", "  ")
+	u.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(u.opts.CacheFile), "."+filepath.Base(u.opts.CacheFile)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("ddns: write cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("ddns: write cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("ddns: write cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), u.opts.CacheFile); err != nil {
+		return fmt.Errorf("ddns: write cache: %w", err)
+	}
+	return nil
+}
+
+// SplitHost finds the domain containing host, preferring the longest
+// match, and returns the record name relative to it ("@" for the apex).
+func SplitHost(domains []enzonix.Domain, host string) (enzonix.Domain, string, bool) {
+	host = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+	var (
+		best  enzonix.Domain
+		name  string
+		found bool
+	)
+	for _, d := range domains {
+		zone := strings.ToLower(strings.TrimSuffix(d.Name, "."))
+		if zone == "" || (found && len(zone) <= len(strings.TrimSuffix(best.Name, "."))) {
+			continue
+		}
+		switch {
+		case host == zone:
+			best, name, found = d, "@", true
+		case strings.HasSuffix(host, "."+zone):
+			best, name, found = d, strings.TrimSuffix(host, "."+zone), true
+		}
+	}
+	return best, name, found
+}
@@ -0,0 +1,163 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/enzonixtest"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+// echo serves an address that can be switched.
+func echo(t *testing.T, addr string) (*httptest.Server, *atomic.Value) {
+	t.Helper()
+	current := &atomic.Value{}
+	current.Store(addr)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, current.Load().(string)+"\n")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, current
+}
+
+func TestUpdater(t *testing.T) {
+	t.Parallel()
+
+	fake, client := enzonixtest.NewClient(t)
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "home", Type: "A", TTL: 60, Value: "198.51.100.1"})
+	srv, current := echo(t, "203.0.113.5")
+	cache := filepath.Join(t.TempDir(), "ddns.json")
+
+	opts := Options{
+		Hosts:     []string{"home.example.com", "Example.com."},
+		IPv4:      HTTPSource{URL: srv.URL},
+		CacheFile: cache,
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	u, err := New(client, opts)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	ctx := context.Background()
+
+	changes, err := u.Update(ctx)
+	if err != nil || len(changes) != 2 || !changes[0].Changed || !changes[1].Changed {
+		t.Fatalf("expected two changes, got %+v, %v", changes, err)
+	}
+	if len(changes[0].Previous) != 1 || changes[0].Previous[0] != "198.51.100.1" || len(changes[1].Previous) != 0 {
+		t.Fatalf("unexpected previous values %+v", changes)
+	}
+	for _, r := range fake.Records(domain.ID) {
+		if r.Value != "203.0.113.5" {
+			t.Fatalf("record not updated: %+v", r)
+		}
+		if r.Name == "home" && r.TTL != 60 {
+			t.Fatalf("TTL changed: %+v", r)
+		}
+	}
+
+	// A new updater with the same cache makes no API calls.
+	requests := len(fake.Requests())
+	u, _ = New(client, opts)
+	if changes, err := u.Update(ctx); err != nil || changes[0].Changed || changes[1].Changed {
+		t.Fatalf("expected no changes, got %+v, %v", changes, err)
+	}
+	if n := len(fake.Requests()); n != requests {
+		t.Fatalf("expected no API requests, got %d", n-requests)
+	}
+
+	current.Store("203.0.113.6")
+	if changes, err := u.Update(ctx); err != nil || !changes[0].Changed {
+		t.Fatalf("expected a change, got %+v, %v", changes, err)
+	}
+
+	current.Store("2001:db8::1")
+	if _, err := u.Update(ctx); err == nil {
+		t.Fatal("expected an error for an IPv6 answer to an IPv4 query")
+	}
+}
+
+func TestUpdaterUnknownHost(t *testing.T) {
+	t.Parallel()
+
+	fake, client := enzonixtest.NewClient(t)
+	fake.AddDomain("example.com")
+	u, err := New(client, Options{
+		Hosts: []string{"home.example.org", "v6.example.com"},
+		IPv6: SourceFunc(func(context.Context, Family) (netip.Addr, error) {
+			return netip.MustParseAddr("2001:db8::1"), nil
+		}),
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := u.Update(context.Background())
+	if err == nil || len(changes) != 2 || changes[0].Err == nil || !changes[1].Changed || changes[1].Type != "AAAA" {
+		t.Fatalf("expected one failed and one applied change, got %+v, %v", changes, err)
+	}
+}
+
+func TestSplitHost(t *testing.T) {
+	t.Parallel()
+
+	domains := []enzonix.Domain{{ID: "1", Name: "example.com."}, {ID: "2", Name: "lab.example.com"}}
+	for host, want := range map[string]string{
+		"example.com":         "1 @",
+		"www.example.com.":    "1 www",
+		"a.b.lab.example.com": "2 a.b",
+		"LAB.example.com":     "2 @",
+		"badexample.com":      "",
+	} {
+		d, name, ok := SplitHost(domains, host)
+		got := ""
+		if ok {
+			got = d.ID + " " + name
+		}
+		if got != want {
+			t.Errorf("SplitHost(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestUpdaterAuditFailure(t *testing.T) {
+	t.Parallel()
+
+	failing := enzonix.AuditSinkFunc(func(context.Context, enzonix.AuditEntry) error { return errors.New("disk full") })
+	fake, client := enzonixtest.NewClient(t, enzonix.WithAuditSink(failing))
+	domain := fake.AddDomain("example.com")
+	u, err := New(client, Options{
+		Hosts: []string{"home.example.com"},
+		IPv4: SourceFunc(func(context.Context, Family) (netip.Addr, error) {
+			return netip.MustParseAddr("203.0.113.5"), nil
+		}),
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// The record is created even though it could not be audited, and the
+	// address is remembered.
+	changes, err := u.Update(ctx)
+	if !enzonix.IsAuditOnly(err) || len(changes) != 1 || !changes[0].Changed || !enzonix.IsAuditOnly(changes[0].Err) {
+		t.Fatalf("expected an applied but unaudited change, got %+v, %v", changes, err)
+	}
+	if records := fake.Records(domain.ID); len(records) != 1 || records[0].Value != "203.0.113.5" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+	if changes, err := u.Update(ctx); err != nil || changes[0].Changed {
+		t.Fatalf("expected the cached address, got %+v, %v", changes, err)
+	}
+}
@@ -0,0 +1,184 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Family is an address family, mapped to the A or AAAA record type.
+type Family int
+
+const (
+	IPv4 Family = 4
+	IPv6 Family = 6
+)
+
+// Type returns the record type holding addresses of the family.
+func (f Family) Type() string {
+	if f == IPv6 {
+		return "AAAA"
+	}
+	return "A"
+}
+
+func (f Family) String() string {
+	if f == IPv6 {
+		return "IPv6"
+	}
+	return "IPv4"
+}
+
+func (f Family) matches(addr netip.Addr) bool {
+	if f == IPv6 {
+		return addr.Is6()
+	}
+	return addr.Is4()
+}
+
+// Source determines the current address of one family.
+type Source interface {
+	Address(ctx context.Context, family Family) (netip.Addr, error)
+}
+
+// SourceFunc adapts a function to Source.
+type SourceFunc func(ctx context.Context, family Family) (netip.Addr, error)
+
+// Address implements Source.
+func (f SourceFunc) Address(ctx context.Context, family Family) (netip.Addr, error) {
+	return f(ctx, family)
+}
+
+// HTTPSource asks an echo endpoint that returns the caller's address as
+// plain text, such as https://api.ipify.org. The connection is made over
+// the requested family, so one endpoint serving both can be used for A and
+// AAAA records.
+type HTTPSource struct {
+	URL string
+	// Timeout bounds the request; defaults to 10s.
+	Timeout time.Duration
+	// Transport defaults to a transport dialing only the requested family.
+	Transport http.RoundTripper
+}
+
+// Address implements Source.
+func (h HTTPSource) Address(ctx context.Context, family Family) (netip.Addr, error) {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	transport := h.Transport
+	if transport == nil {
+		network := "tcp4"
+		if family == IPv6 {
+			network = "tcp6"
+		}
+		var d net.Dialer
+		transport = &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+				return d.DialContext(ctx, network, addr)
+			},
+		}
+		defer transport.(*http.Transport).CloseIdleConnections()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.URL, nil)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	res, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1024))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return netip.Addr{}, fmt.Errorf("ddns: %s returned %d", h.URL, res.StatusCode)
+	}
+	return parseAddress(string(body), family)
+}
+
+// InterfaceSource uses the first global unicast address of a local
+// network interface, for hosts that are directly connected.
+type InterfaceSource struct {
+	Name string
+}
+
+// Address implements Source.
+func (s InterfaceSource) Address(_ context.Context, family Family) (netip.Addr, error) {
+	iface, err := net.InterfaceByName(s.Name)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	for _, a := range addrs {
+		prefix, err := netip.ParsePrefix(a.String())
+		if err != nil {
+			continue
+		}
+		addr := prefix.Addr().Unmap()
+		if family.matches(addr) && addr.IsGlobalUnicast() && !addr.IsPrivate() {
+			return addr, nil
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("ddns: interface %s has no public %s address", s.Name, family)
+}
+
+// CommandSource runs a command and parses its output, the first line of
+// which must be the address. The family is passed as $DDNS_FAMILY ("4" or
+// "6").
+type CommandSource struct {
+	Command []string
+	// Timeout bounds the command; defaults to 30s.
+	Timeout time.Duration
+}
+
+// Address implements Source.
+func (s CommandSource) Address(ctx context.Context, family Family) (netip.Addr, error) {
+	if len(s.Command) == 0 {
+		return netip.Addr{}, errors.New("ddns: empty command")
+	}
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.Command[0], s.Command[1:]...)
+	cmd.Env = append(cmd.Environ(), fmt.Sprintf("DDNS_FAMILY=%d", family))
+	out, err := cmd.Output()
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("ddns: %s: %w", s.Command[0], err)
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	return parseAddress(line, family)
+}
+
+func parseAddress(s string, family Family) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("ddns: invalid address %q", strings.TrimSpace(s))
+	}
+	addr = addr.Unmap()
+	if !family.matches(addr) {
+		return netip.Addr{}, fmt.Errorf("ddns: %s is not an %s address", addr, family)
+	}
+	return addr, nil
+}
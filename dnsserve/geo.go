@@ -0,0 +1,84 @@
+package dnsserve
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// GeoMap maps client subnets to ISO 3166-1 alpha-2 country codes.
+type GeoMap struct {
+	entries []geoEntry
+}
+
+type geoEntry struct {
+	prefix  netip.Prefix
+	country string
+}
+
+// ParseGeoMap reads a mapping with one "subnet country" pair per line, for
+// example "192.0.2.0/24 DE". Fields may also be separated by a comma;
+// blank lines and lines starting with # are ignored.
+func ParseGeoMap(r io.Reader) (*GeoMap, error) {
+	m := &GeoMap{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(strings.ReplaceAll(text, ",", " "))
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("dnsserve: geo map line %d: want \"subnet country\"", line)
+		}
+		prefix, err := netip.ParsePrefix(fields[0])
+		if err != nil {
+			addr, aerr := netip.ParseAddr(fields[0])
+			if aerr != nil {
+				return nil, fmt.Errorf("dnsserve: geo map line %d: %w", line, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		country := strings.ToUpper(fields[1])
+		if len(country) != 2 {
+			return nil, fmt.Errorf("dnsserve: geo map line %d: invalid country code %q", line, fields[1])
+		}
+		m.entries = append(m.entries, geoEntry{prefix: prefix.Masked(), country: country})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	// Longest prefixes first, so the first match is the most specific.
+	sort.SliceStable(m.entries, func(i, j int) bool {
+		return m.entries[i].prefix.Bits() > m.entries[j].prefix.Bits()
+	})
+	return m, nil
+}
+
+// LoadGeoMap reads a mapping file, see ParseGeoMap.
+func LoadGeoMap(path string) (*GeoMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseGeoMap(f)
+}
+
+// Country returns the country of addr, or "" when no subnet contains it.
+func (m *GeoMap) Country(addr netip.Addr) string {
+	if m == nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	for _, e := range m.entries {
+		if e.prefix.Contains(addr) {
+			return e.country
+		}
+	}
+	return ""
+}
@@ -0,0 +1,549 @@
+// Package dnsserve answers DNS queries from Enzonix zones, for integration
+// tests of services that resolve them. Zones are loaded through the API, a
+// backup snapshot or a BIND zone file and served authoritatively over UDP
+// and TCP, with wildcards, CNAME chasing within a zone and optional
+// simulation of geo answers from record country codes.
+//
+// The server also accepts dynamic updates (RFC 2136) for zones loaded
+// through the API, so that tools speaking DNS UPDATE, such as nsupdate,
+// DHCP servers or ACME clients, can change Enzonix records. Requests may
+// be signed with TSIG (RFC 8945).
+//
+// Secondary name servers can transfer the zones with AXFR and IXFR and
+// are sent NOTIFY messages when a zone's serial changes. Zones without an
+// SOA record get one whose serial follows the newest record change and
+// always increases when the content changes; with Options.SerialFile it
+// does so across restarts too.
+package dnsserve
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/dnswire"
+)
+
+// Options configures a Server.
+type Options struct {
+	// Geo maps clients to countries for records with CountryCodes. The
+	// client is identified by the EDNS client subnet option when present,
+	// else by its source address. Without a map, only default records are
+	// served, or all variants when a set has no default.
+	Geo *GeoMap
+	// Logger receives warnings about skipped records and serving errors.
+	// Defaults to slog.Default().
+	Logger *slog.Logger
+	// Keys are the TSIG keys accepted on signed requests. Responses to
+	// signed requests are signed with the same key.
+	Keys []TSIGKey
+	// Client applies dynamic updates to zones loaded through the API.
+	// Without a client, updates are refused.
+	Client *enzonix.Client
+	// Update admits dynamic updates; by default none are.
+	Update ACL
+	// Transfer admits zone transfers; by default none are.
+	Transfer ACL
+	// Notify lists secondaries, as "host[:port]", that are sent a NOTIFY
+	// whenever the serial of a zone changes.
+	Notify []string
+	// History is the number of versions of each zone kept for IXFR.
+	// Defaults to 16.
+	History int
+	// SerialFile persists the serials of synthesized SOA records, so that
+	// a restarted server never goes below a serial secondaries already
+	// hold. Without it, serials restart from the newest record change.
+	SerialFile string
+}
+
+// Server answers queries for a set of zones.
+type Server struct {
+	opts          Options
+	keys          map[string]dnswire.TSIGKey
+	notifyTargets []string
+
+	mu    sync.RWMutex
+	zones []*zoneIndex
+	// history holds the recent versions of each zone, oldest first.
+	history map[string][]*zoneIndex
+	// serials are the last synthesized serials, as kept in SerialFile.
+	serials map[string]savedSerial
+}
+
+// New returns a server for zones.
+func New(zones []Zone, opts Options) (*Server, error) {
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.History <= 0 {
+		opts.History = defaultHistory
+	}
+	s := &Server{opts: opts, keys: map[string]dnswire.TSIGKey{}, history: map[string][]*zoneIndex{}, serials: map[string]savedSerial{}}
+	for _, addr := range opts.Notify {
+		target, err := notifyTarget(addr)
+		if err != nil {
+			return nil, err
+		}
+		s.notifyTargets = append(s.notifyTargets, target)
+	}
+	for _, k := range opts.Keys {
+		key, err := k.wire()
+		if err != nil {
+			return nil, err
+		}
+		s.keys[key.Name] = key
+	}
+	if err := s.loadSerials(); err != nil {
+		return nil, err
+	}
+	if err := s.SetZones(zones); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetZones replaces the served zones, for example after reloading them.
+// Secondaries are notified of the zones whose serial changed.
+func (s *Server) SetZones(zones []Zone) error {
+	indexes := make([]*zoneIndex, 0, len(zones))
+	seen := map[string]bool{}
+	for _, z := range zones {
+		idx, err := buildIndex(z, s.opts.Logger)
+		if err != nil {
+			return err
+		}
+		if seen[idx.origin] {
+			return fmt.Errorf("dnsserve: zone %s given twice", z.Name)
+		}
+		seen[idx.origin] = true
+		indexes = append(indexes, idx)
+	}
+	// Most specific zones first.
+	sort.Slice(indexes, func(i, j int) bool { return len(indexes[i].origin) > len(indexes[j].origin) })
+	s.mu.Lock()
+	old := make(map[string]*zoneIndex, len(s.zones))
+	for _, z := range s.zones {
+		old[z.origin] = z
+	}
+	var changed []*zoneIndex
+	for _, idx := range indexes {
+		if s.track(idx, old[idx.origin]) {
+			changed = append(changed, idx)
+		}
+	}
+	for origin := range s.history {
+		if !seen[origin] {
+			delete(s.history, origin)
+		}
+	}
+	s.zones = indexes
+	s.mu.Unlock()
+	s.saveSerials()
+	for _, idx := range changed {
+		s.notify(idx)
+	}
+	return nil
+}
+
+// ListenAndServe serves UDP and TCP on addr until ctx is done.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", conn.LocalAddr().String())
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	return s.Serve(ctx, conn, ln)
+}
+
+// Serve answers queries on conn and ln until ctx is done, then closes
+// them. Either may be nil.
+func (s *Server) Serve(ctx context.Context, conn net.PacketConn, ln net.Listener) error {
+	if conn == nil && ln == nil {
+		return errors.New("dnsserve: nothing to serve on")
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	if conn != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.serveUDP(ctx, conn)
+		}()
+	}
+	if ln != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.serveTCP(ctx, ln)
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+	if conn != nil {
+		conn.Close()
+	}
+	if ln != nil {
+		ln.Close()
+	}
+	wg.Wait()
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) serveUDP(ctx context.Context, conn net.PacketConn) error {
+	buf := make([]byte, 65535)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		packet := buf[:n]
+		// Updates call the API; they must not hold up queries.
+		if n > 2 && (packet[2]>>3)&0xf == dnswire.OpcodeUpdate {
+			packet = append([]byte(nil), packet...)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.answerUDP(ctx, conn, packet, from)
+			}()
+			continue
+		}
+		s.answerUDP(ctx, conn, packet, from)
+	}
+}
+
+func (s *Server) answerUDP(ctx context.Context, conn net.PacketConn, packet []byte, from net.Addr) {
+	out, err := s.handle(ctx, packet, addrOf(from), true)
+	if err != nil {
+		s.opts.Logger.Debug("dnsserve: dropping query", slog.String("client", from.String()), slog.Any("error", err))
+		return
+	}
+	if _, err := conn.WriteTo(out[0], from); err != nil {
+		s.opts.Logger.Warn("dnsserve: write response", slog.String("client", from.String()), slog.Any("error", err))
+	}
+}
+
+func (s *Server) serveTCP(ctx context.Context, ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.serveConn(ctx, conn)
+	}
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	client := addrOf(conn.RemoteAddr())
+	for {
+		query, err := dnswire.ReadTCP(conn)
+		if err != nil {
+			return
+		}
+		out, err := s.handle(ctx, query, client, false)
+		if err != nil {
+			return
+		}
+		for _, msg := range out {
+			if err := dnswire.WriteTCP(conn, msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// request describes where a message came from.
+type request struct {
+	// source is the address the message came from and client the address
+	// answers are tailored to, taken from the EDNS client subnet option.
+	source netip.Addr
+	client netip.Addr
+	// key is the name of the TSIG key that signed the message, if any.
+	key string
+}
+
+// handle answers one packed message with one response, or several for
+// zone transfers over TCP. UDP responses are truncated to the size the
+// client advertised.
+func (s *Server) handle(ctx context.Context, raw []byte, client netip.Addr, udp bool) ([][]byte, error) {
+	query, err := dnswire.Unpack(raw)
+	if err != nil {
+		return nil, err
+	}
+	if query.Response {
+		return nil, errors.New("dnsserve: not a query")
+	}
+	req := request{source: client, client: client}
+	sig, err := dnswire.VerifyTSIG(raw, s.lookupKey, dnswire.TSIGParams{})
+	var tsigErr *dnswire.TSIGError
+	switch {
+	case errors.Is(err, dnswire.ErrNoTSIG):
+	case errors.As(err, &tsigErr):
+		s.opts.Logger.Warn("dnsserve: rejecting signed message", slog.String("client", client.String()), slog.Any("error", err))
+	case err != nil:
+		return nil, err
+	default:
+		req.key = sig.Key
+	}
+	edns := parseEDNS(query)
+	if edns.subnet.IsValid() {
+		req.client = edns.subnet.Addr()
+	}
+
+	var resps []*dnswire.Message
+	switch {
+	case tsigErr != nil:
+		resp := reply(query)
+		resp.Rcode = dnswire.RcodeNotAuth
+		resps = []*dnswire.Message{resp}
+	case isTransfer(query):
+		resps = s.transfer(query, req, udp)
+	default:
+		resps = []*dnswire.Message{s.respond(ctx, query, req)}
+	}
+	first := resps[0]
+	if edns.present {
+		first.Additional = append(first.Additional, edns.reply())
+	}
+	// Responses to unknown keys and bad signatures carry an unsigned TSIG
+	// record with the error (RFC 8945, section 5.3.2).
+	if tsigErr != nil && tsigErr.Code != dnswire.TSIGBadTime {
+		unsigned := *sig
+		unsigned.MAC, unsigned.Error, unsigned.Other = nil, tsigErr.Code, nil
+		first.Additional = append(first.Additional, unsigned.RR())
+		sig = nil
+	}
+
+	out := make([][]byte, 0, len(resps))
+	var prev []byte
+	if sig != nil {
+		prev = sig.MAC
+	}
+	for i, resp := range resps {
+		var msg []byte
+		if udp {
+			msg, err = dnswire.Truncate(resp, edns.size)
+		} else {
+			msg, err = resp.Pack()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if sig != nil {
+			// Each message of a multi-message response is signed over the
+			// previous one's MAC (RFC 8945, section 5.3.1).
+			params := dnswire.TSIGParams{Prev: prev, TimersOnly: i > 0}
+			if tsigErr != nil {
+				now := uint64(time.Now().Unix())
+				params.Error = tsigErr.Code
+				params.Other = binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint16(nil, uint16(now>>32)), uint32(now))
+			}
+			if msg, prev, err = dnswire.SignTSIG(msg, s.keys[sig.Key], params); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, msg)
+	}
+	return out, nil
+}
+
+func (s *Server) lookupKey(name string) (dnswire.TSIGKey, bool) {
+	key, ok := s.keys[strings.ToLower(name)]
+	return key, ok
+}
+
+// reply returns an empty response to msg.
+func reply(msg *dnswire.Message) *dnswire.Message {
+	return &dnswire.Message{Header: dnswire.Header{
+		ID:               msg.ID,
+		Response:         true,
+		Opcode:           msg.Opcode,
+		RecursionDesired: msg.RecursionDesired,
+	}, Questions: msg.Questions}
+}
+
+// respond builds the response to a message.
+func (s *Server) respond(ctx context.Context, query *dnswire.Message, req request) *dnswire.Message {
+	resp := reply(query)
+	switch query.Opcode {
+	case dnswire.OpcodeQuery:
+	case dnswire.OpcodeUpdate:
+		resp.Rcode = s.update(ctx, query, req)
+		return resp
+	default:
+		resp.Rcode = dnswire.RcodeNotImp
+		return resp
+	}
+	if len(query.Questions) != 1 {
+		resp.Rcode = dnswire.RcodeFormErr
+		return resp
+	}
+	q := query.Questions[0]
+	qname := strings.ToLower(dnswire.Fqdn(q.Name))
+	zone := s.zoneFor(qname)
+	if zone == nil || (q.Class != dnswire.ClassINET && q.Class != dnswire.ClassANY) {
+		resp.Rcode = dnswire.RcodeRefused
+		return resp
+	}
+	resp.Authoritative = true
+
+	country := s.opts.Geo.Country(req.client)
+	answers, exists := zone.lookup(qname, q.Type, country, s.opts.Geo != nil)
+	resp.Answers = answers
+	if !exists {
+		resp.Rcode = dnswire.RcodeNXDomain
+	}
+	// A chain ending in a CNAME to a name of the zone found no data there.
+	nodata := len(answers) == 0 ||
+		(q.Type != dnswire.TypeCNAME && answers[len(answers)-1].Type == dnswire.TypeCNAME && inZone(lastTarget(answers), zone.origin))
+	if !exists || nodata {
+		resp.Authority = []dnswire.RR{negativeSOA(zone.soa)}
+	}
+	return resp
+}
+
+func (s *Server) zoneFor(name string) *zoneIndex {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, z := range s.zones {
+		if inZone(name, z.origin) {
+			return z
+		}
+	}
+	return nil
+}
+
+// negativeSOA returns the SOA for negative answers, its TTL capped by the
+// SOA minimum (RFC 2308).
+func negativeSOA(soa dnswire.RR) dnswire.RR {
+	if n := len(soa.Data); n >= 4 {
+		if minimum := binary.BigEndian.Uint32(soa.Data[n-4:]); minimum < soa.TTL {
+			soa.TTL = minimum
+		}
+	}
+	return soa
+}
+
+func lastTarget(answers []dnswire.RR) string {
+	target, _, _ := dnswire.UnpackRData(dnswire.TypeCNAME, answers[len(answers)-1].Data)
+	return strings.ToLower(target)
+}
+
+func addrOf(addr net.Addr) netip.Addr {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ip, _ := netip.AddrFromSlice(a.IP)
+		return ip.Unmap()
+	case *net.TCPAddr:
+		ip, _ := netip.AddrFromSlice(a.IP)
+		return ip.Unmap()
+	}
+	return netip.Addr{}
+}
+
+// optionClientSubnet is the EDNS client subnet option code (RFC 7871).
+const optionClientSubnet = 8
+
+type ednsInfo struct {
+	present bool
+	size    int
+	subnet  netip.Prefix
+	// ecs is the raw client subnet option, echoed in the response.
+	ecs []byte
+}
+
+// parseEDNS reads the OPT record of a query. Without one the UDP payload
+// is limited to 512 bytes.
+func parseEDNS(query *dnswire.Message) ednsInfo {
+	info := ednsInfo{size: 512}
+	for _, rr := range query.Additional {
+		if rr.Type != dnswire.TypeOPT {
+			continue
+		}
+		info.present = true
+		if size := int(rr.Class); size > info.size {
+			info.size = min(size, dnswire.MaxUDPSize)
+		}
+		data := rr.Data
+		for len(data) >= 4 {
+			code := binary.BigEndian.Uint16(data)
+			length := int(binary.BigEndian.Uint16(data[2:]))
+			if 4+length > len(data) {
+				break
+			}
+			if code == optionClientSubnet {
+				info.ecs = append([]byte(nil), data[4:4+length]...)
+				info.subnet = parseSubnet(info.ecs)
+			}
+			data = data[4+length:]
+		}
+	}
+	return info
+}
+
+func parseSubnet(opt []byte) netip.Prefix {
+	if len(opt) < 4 {
+		return netip.Prefix{}
+	}
+	family, bits := binary.BigEndian.Uint16(opt), int(opt[2])
+	var raw []byte
+	switch family {
+	case 1:
+		raw = make([]byte, 4)
+	case 2:
+		raw = make([]byte, 16)
+	default:
+		return netip.Prefix{}
+	}
+	copy(raw, opt[4:])
+	addr, _ := netip.AddrFromSlice(raw)
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return netip.Prefix{}
+	}
+	return prefix
+}
+
+// reply returns the response OPT record, echoing the client subnet with
+// its full source prefix as scope.
+func (e ednsInfo) reply() dnswire.RR {
+	opt := dnswire.RR{Name: ".", Type: dnswire.TypeOPT, Class: dnswire.MaxUDPSize}
+	if e.ecs != nil {
+		ecs := append([]byte(nil), e.ecs...)
+		if len(ecs) >= 4 {
+			ecs[3] = ecs[2]
+		}
+		opt.Data = binary.BigEndian.AppendUint16(opt.Data, optionClientSubnet)
+		opt.Data = binary.BigEndian.AppendUint16(opt.Data, uint16(len(ecs)))
+		opt.Data = append(opt.Data, ecs...)
+	}
+	return opt
+}
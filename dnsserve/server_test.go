@@ -0,0 +1,238 @@
+package dnsserve
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/dnswire"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+var quiet = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func startServer(t *testing.T, zones []Zone, opts Options) string {
+	t.Helper()
+	if opts.Logger == nil {
+		opts.Logger = quiet
+	}
+	s, err := New(zones, opts)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen udp: %v", err)
+	}
+	ln, err := net.Listen("tcp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("listen tcp: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = s.Serve(ctx, conn, ln)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return conn.LocalAddr().String()
+}
+
+type result struct {
+	rcode     uint8
+	answers   []string
+	authority int
+}
+
+func ask(t *testing.T, addr, name, typ string, extra ...dnswire.RR) result {
+	t.Helper()
+	qtype, _ := dnswire.ParseType(typ)
+	query := dnswire.NewQuery(name, qtype)
+	query.Additional = extra
+	resp, err := dnswire.Exchange(context.Background(), addr, query)
+	if err != nil {
+		t.Fatalf("query %s %s: %v", name, typ, err)
+	}
+	if !resp.Authoritative && resp.Rcode != dnswire.RcodeRefused {
+		t.Fatalf("query %s %s: answer not authoritative", name, typ)
+	}
+	r := result{rcode: resp.Rcode, authority: len(resp.Authority)}
+	for _, rr := range resp.Answers {
+		value, priority, err := dnswire.UnpackRData(rr.Type, rr.Data)
+		if err != nil {
+			t.Fatalf("decode answer: %v", err)
+		}
+		if priority != 0 {
+			value = strconv.Itoa(priority) + " " + value
+		}
+		r.answers = append(r.answers, rr.Name+" "+dnswire.TypeString(rr.Type)+" "+value)
+	}
+	return r
+}
+
+func TestServeFromAPI(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeapi.New("key")
+	api := httptest.NewServer(fake)
+	defer api.Close()
+	client, err := enzonix.NewClient("key", enzonix.WithBaseURL(api.URL))
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	domain := fake.AddDomain("example.com")
+	fake.AddDomain("other.com")
+	for _, r := range []fakeapi.Record{
+		{Name: "@", Type: "NS", Value: "ns1.enzonix.com."},
+		{Name: "@", Type: "MX", Priority: 10, Value: "mx.example.com."},
+		{Name: "www", Type: "A", Value: "192.0.2.1"},
+		{Name: "www", Type: "AAAA", Value: "2001:db8::1"},
+		{Name: "alias", Type: "CNAME", Value: "www.example.com."},
+		{Name: "outside", Type: "CNAME", Value: "cdn.example.net."},
+		{Name: "*.apps", Type: "A", Value: "192.0.2.50"},
+		{Name: "a.b.deep", Type: "TXT", Value: "deep"},
+		{Name: "_sip._tcp", Type: "SRV", Priority: 5, Value: "10 5060 sip.example.com."},
+		{Name: "@", Type: "CAA", Value: `0 issue "letsencrypt.org"`},
+	} {
+		r.DomainID = domain.ID
+		fake.AddRecord(r)
+	}
+
+	ctx := context.Background()
+	zones, err := LoadZones(ctx, client, domain.ID)
+	if err != nil || len(zones) != 1 || strings.TrimSuffix(zones[0].Name, ".") != "example.com" {
+		t.Fatalf("unexpected zones %+v, %v", zones, err)
+	}
+	addr := startServer(t, zones, Options{})
+
+	cases := []struct {
+		name, typ string
+		want      result
+	}{
+		{"www.example.com", "A", result{answers: []string{"www.example.com. A 192.0.2.1"}}},
+		{"WWW.Example.com", "AAAA", result{answers: []string{"www.example.com. AAAA 2001:db8::1"}}},
+		{"example.com", "MX", result{answers: []string{"example.com. MX 10 mx.example.com."}}},
+		{"_sip._tcp.example.com", "SRV", result{answers: []string{"_sip._tcp.example.com. SRV 5 10 5060 sip.example.com."}}},
+		{"example.com", "CAA", result{answers: []string{`example.com. CAA 0 issue "letsencrypt.org"`}}},
+		{"example.com", "SOA", result{answers: nil, authority: 1}},
+		// CNAME chasing within the zone, and stopping at its edge.
+		{"alias.example.com", "A", result{answers: []string{"alias.example.com. CNAME www.example.com.", "www.example.com. A 192.0.2.1"}}},
+		{"alias.example.com", "MX", result{answers: []string{"alias.example.com. CNAME www.example.com."}, authority: 1}},
+		{"outside.example.com", "A", result{answers: []string{"outside.example.com. CNAME cdn.example.net."}}},
+		// Wildcards cover names whose closest existing ancestor owns them.
+		{"foo.apps.example.com", "A", result{answers: []string{"foo.apps.example.com. A 192.0.2.50"}}},
+		{"x.foo.apps.example.com", "A", result{answers: []string{"x.foo.apps.example.com. A 192.0.2.50"}}},
+		{"x.www.example.com", "A", result{rcode: dnswire.RcodeNXDomain, authority: 1}},
+		{"foo.apps.example.com", "TXT", result{authority: 1}},
+		// NODATA for existing names and empty non-terminals, else NXDOMAIN.
+		{"www.example.com", "TXT", result{authority: 1}},
+		{"b.deep.example.com", "A", result{authority: 1}},
+		{"missing.example.com", "A", result{rcode: dnswire.RcodeNXDomain, authority: 1}},
+		{"other.com", "A", result{rcode: dnswire.RcodeRefused}},
+	}
+	for _, tc := range cases {
+		got := ask(t, addr, tc.name, tc.typ)
+		if tc.typ == "SOA" {
+			if len(got.answers) != 1 || !strings.HasPrefix(got.answers[0], "example.com. SOA ns1.enzonix.com. hostmaster.example.com.") {
+				t.Fatalf("unexpected SOA %v", got.answers)
+			}
+			continue
+		}
+		if got.rcode != tc.want.rcode || got.authority != tc.want.authority || strings.Join(got.answers, "|") != strings.Join(tc.want.answers, "|") {
+			t.Fatalf("%s %s: got %+v, want %+v", tc.name, tc.typ, got, tc.want)
+		}
+	}
+
+	// TCP serves the same answers.
+	resp, err := dnswire.ExchangeTCP(ctx, addr, dnswire.NewQuery("www.example.com", dnswire.TypeA))
+	if err != nil || len(resp.Answers) != 1 {
+		t.Fatalf("unexpected TCP response %+v, %v", resp, err)
+	}
+}
+
+func TestServeGeo(t *testing.T) {
+	t.Parallel()
+
+	geo, err := ParseGeoMap(strings.NewReader(`
+# client subnets
+127.0.0.0/8 DE
+198.51.100.0/24,us
+198.51.100.128/25 CA
+`))
+	if err != nil {
+		t.Fatalf("geo map: %v", err)
+	}
+	zone := Zone{Name: "example.com", Records: []enzonix.Record{
+		{Name: "www", Type: "A", Value: "192.0.2.1"},
+		{Name: "www", Type: "A", Value: "192.0.2.2", CountryCodes: []string{"de", "AT"}},
+		{Name: "www", Type: "A", Value: "192.0.2.3", CountryCodes: []string{"US"}},
+		{Name: "eu", Type: "A", Value: "192.0.2.4", CountryCodes: []string{"FR"}},
+	}}
+
+	addr := startServer(t, []Zone{zone}, Options{Geo: geo})
+	// Queries come from 127.0.0.1, mapped to DE.
+	if got := ask(t, addr, "www.example.com", "A"); strings.Join(got.answers, "|") != "www.example.com. A 192.0.2.2" {
+		t.Fatalf("unexpected answer for DE %+v", got)
+	}
+	// The client subnet option takes precedence over the source address.
+	for subnet, want := range map[string]string{
+		"198.51.100.7":   "192.0.2.3",
+		"198.51.100.200": "192.0.2.1", // CA has no variant: default
+	} {
+		got := ask(t, addr, "www.example.com", "A", ecs(subnet))
+		if len(got.answers) != 1 || !strings.HasSuffix(got.answers[0], want) {
+			t.Fatalf("%s: unexpected answer %+v", subnet, got)
+		}
+	}
+	// Only variants for other countries: NODATA.
+	if got := ask(t, addr, "eu.example.com", "A"); len(got.answers) != 0 || got.rcode != dnswire.RcodeSuccess {
+		t.Fatalf("unexpected answer %+v", got)
+	}
+
+	// Without geo simulation only defaults are served.
+	plain := startServer(t, []Zone{zone}, Options{})
+	if got := ask(t, plain, "www.example.com", "A"); strings.Join(got.answers, "|") != "www.example.com. A 192.0.2.1" {
+		t.Fatalf("unexpected answer without geo %+v", got)
+	}
+}
+
+func ecs(ip string) dnswire.RR {
+	addr := net.ParseIP(ip).To4()
+	opt := []byte{0, 8, 0, 8, 0, 1, 32, 0}
+	opt = append(opt, addr...)
+	return dnswire.RR{Name: ".", Type: dnswire.TypeOPT, Class: 1232, Data: opt}
+}
+
+func TestParseZoneFile(t *testing.T) {
+	t.Parallel()
+
+	zone, err := ParseZoneFile(strings.NewReader(`$ORIGIN example.com.
+$TTL 600
+@   IN SOA ns1.example.com. hostmaster.example.com. 42 3600 600 86400 60
+www IN A 192.0.2.1
+txt IN TXT "hello" " world"
+`), "")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	addr := startServer(t, []Zone{zone}, Options{})
+	if got := ask(t, addr, "txt.example.com", "TXT"); strings.Join(got.answers, "|") != "txt.example.com. TXT hello world" {
+		t.Fatalf("unexpected TXT %+v", got)
+	}
+	resp, err := dnswire.Exchange(context.Background(), addr, dnswire.NewQuery("nope.example.com", dnswire.TypeA))
+	if err != nil || len(resp.Authority) != 1 || resp.Authority[0].TTL != 60 {
+		t.Fatalf("expected the zone's SOA with the minimum TTL, got %+v, %v", resp, err)
+	}
+	if serial, err := dnswire.SOASerial(resp.Authority[0].Data); err != nil || serial != 42 {
+		t.Fatalf("unexpected serial %d, %v", serial, err)
+	}
+}
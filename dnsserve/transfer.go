@@ -0,0 +1,424 @@
+package dnsserve
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/dnswire"
+)
+
+// defaultHistory is the number of zone versions kept for IXFR.
+const defaultHistory = 16
+
+// transferMessageSize is the size multi-message transfer responses are
+// filled to; TCP allows up to 64 KiB.
+const transferMessageSize = 16 << 10
+
+// NOTIFY attempts are retried when unanswered (RFC 1996, section 3.6).
+const (
+	notifyTimeout  = 2 * time.Second
+	notifyAttempts = 3
+)
+
+// isTransfer reports whether msg asks for a zone transfer.
+func isTransfer(msg *dnswire.Message) bool {
+	if msg.Opcode != dnswire.OpcodeQuery || len(msg.Questions) != 1 {
+		return false
+	}
+	typ := msg.Questions[0].Type
+	return typ == dnswire.TypeAXFR || typ == dnswire.TypeIXFR
+}
+
+// transfer answers an AXFR (RFC 5936) or IXFR (RFC 1995) query. IXFR
+// answers with the changes since the client's serial when that version is
+// still known, and with the full zone otherwise. Over UDP, AXFR is not
+// implemented and IXFR answers with the current SOA only, which tells
+// clients that are behind to retry over TCP.
+func (s *Server) transfer(query *dnswire.Message, req request, udp bool) []*dnswire.Message {
+	resp := reply(query)
+	q := query.Questions[0]
+	origin := strings.ToLower(dnswire.Fqdn(q.Name))
+	zone := s.zoneNamed(origin)
+	switch {
+	case udp && q.Type == dnswire.TypeAXFR:
+		resp.Rcode = dnswire.RcodeNotImp
+		return []*dnswire.Message{resp}
+	case zone == nil || (q.Class != dnswire.ClassINET && q.Class != dnswire.ClassANY):
+		resp.Rcode = dnswire.RcodeNotAuth
+		return []*dnswire.Message{resp}
+	case !s.opts.Transfer.allows(req.source, req.key):
+		s.opts.Logger.Warn("dnsserve: transfer refused by ACL", slog.String("zone", origin), slog.String("client", req.source.String()), slog.String("key", req.key))
+		resp.Rcode = dnswire.RcodeRefused
+		return []*dnswire.Message{resp}
+	}
+	resp.Authoritative = true
+
+	rrs := zone.axfr()
+	if q.Type == dnswire.TypeIXFR {
+		serial, ok := ixfrSerial(query, origin)
+		if !ok {
+			resp.Rcode = dnswire.RcodeFormErr
+			return []*dnswire.Message{resp}
+		}
+		if udp || !dnswire.SerialGreater(zone.serial(), serial) {
+			resp.Answers = []dnswire.RR{zone.soa}
+			return []*dnswire.Message{resp}
+		}
+		if old := s.version(origin, serial); old != nil {
+			rrs = zone.ixfr(old)
+		}
+	}
+	s.opts.Logger.Debug("dnsserve: zone transfer", slog.String("zone", origin), slog.String("type", dnswire.TypeString(q.Type)), slog.String("client", req.source.String()), slog.Int("records", len(rrs)))
+	return splitTransfer(resp, rrs)
+}
+
+// ixfrSerial returns the client's serial from the SOA in the authority
+// section of an IXFR query.
+func ixfrSerial(query *dnswire.Message, origin string) (uint32, bool) {
+	for _, rr := range query.Authority {
+		if rr.Type == dnswire.TypeSOA && dnswire.EqualNames(rr.Name, origin) {
+			serial, err := dnswire.SOASerial(rr.Data)
+			return serial, err == nil
+		}
+	}
+	return 0, false
+}
+
+// splitTransfer spreads rrs over as many messages as needed. Only the
+// first repeats the question.
+func splitTransfer(first *dnswire.Message, rrs []dnswire.RR) []*dnswire.Message {
+	msgs := []*dnswire.Message{first}
+	msg, size := first, 0
+	for _, rr := range rrs {
+		n := len(rr.Name) + 12 + len(rr.Data)
+		if size+n > transferMessageSize && len(msg.Answers) > 0 {
+			msg = &dnswire.Message{Header: first.Header}
+			msgs = append(msgs, msg)
+			size = 0
+		}
+		msg.Answers = append(msg.Answers, rr)
+		size += n
+	}
+	return msgs
+}
+
+// axfr returns the records of a full transfer: the SOA, the zone and the
+// SOA again.
+func (z *zoneIndex) axfr() []dnswire.RR {
+	rrs := make([]dnswire.RR, 0, len(z.records)+2)
+	rrs = append(rrs, z.soa)
+	rrs = append(rrs, z.records...)
+	return append(rrs, z.soa)
+}
+
+// ixfr returns the records of an incremental transfer from old, condensed
+// into a single difference sequence.
+func (z *zoneIndex) ixfr(old *zoneIndex) []dnswire.RR {
+	current := make(map[string]bool, len(z.records))
+	for _, rr := range z.records {
+		current[rrKey(rr)] = true
+	}
+	previous := make(map[string]bool, len(old.records))
+	rrs := []dnswire.RR{z.soa, old.soa}
+	for _, rr := range old.records {
+		previous[rrKey(rr)] = true
+		if !current[rrKey(rr)] {
+			rrs = append(rrs, rr)
+		}
+	}
+	rrs = append(rrs, z.soa)
+	for _, rr := range z.records {
+		if !previous[rrKey(rr)] {
+			rrs = append(rrs, rr)
+		}
+	}
+	return append(rrs, z.soa)
+}
+
+func (z *zoneIndex) serial() uint32 {
+	serial, _ := dnswire.SOASerial(z.soa.Data)
+	return serial
+}
+
+// transferRecords returns the records a secondary receives, sorted: the
+// answers given to clients without a known country.
+func (z *zoneIndex) transferRecords() []dnswire.RR {
+	var rrs []dnswire.RR
+	for _, entries := range z.nodes {
+		types := map[uint16]bool{}
+		for _, e := range entries {
+			if !types[e.rr.Type] {
+				types[e.rr.Type] = true
+				rrs = append(rrs, selectGeo(entries, e.rr.Type, "", false)...)
+			}
+		}
+	}
+	sort.Slice(rrs, func(i, j int) bool {
+		a, b := rrs[i], rrs[j]
+		switch {
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		case a.Type != b.Type:
+			return a.Type < b.Type
+		case a.TTL != b.TTL:
+			return a.TTL < b.TTL
+		}
+		return bytes.Compare(a.Data, b.Data) < 0
+	})
+	return rrs
+}
+
+func rrKey(rr dnswire.RR) string {
+	return fmt.Sprintf("%s/%d/%d/%x", rr.Name, rr.Type, rr.TTL, rr.Data)
+}
+
+// savedSerial is a synthesized serial and the digest of the records it
+// was given to.
+type savedSerial struct {
+	Serial uint32 `json:"serial"`
+	Digest string `json:"digest"`
+}
+
+func recordsDigest(rrs []dnswire.RR) string {
+	h := sha256.New()
+	for _, rr := range rrs {
+		fmt.Fprintln(h, rrKey(rr))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadSerials reads Options.SerialFile; a missing file is no error.
+func (s *Server) loadSerials() error {
+	if s.opts.SerialFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.opts.SerialFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("dnsserve: read serials: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.serials); err != nil {
+		return fmt.Errorf("dnsserve: parse serials %s: %w", s.opts.SerialFile, err)
+	}
+	return nil
+}
+
+// saveSerials writes Options.SerialFile. Zones are served whether or not
+// it can be written, so failures are logged.
+func (s *Server) saveSerials() {
+	if s.opts.SerialFile == "" {
+		return
+	}
+	s.mu.RLock()
+	data, err := json.MarshalIndent(s.serials, "", "  ")
+	s.mu.RUnlock()
+	if err == nil {
+		err = writeFileAtomic(s.opts.SerialFile, append(data, '\n'))
+	}
+	if err != nil {
+		s.opts.Logger.Error("dnsserve: write serials", slog.String("file", s.opts.SerialFile), slog.Any("error", err))
+	}
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func sameRecords(a, b []dnswire.RR) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if rrKey(a[i]) != rrKey(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// track carries a zone's serial over to its new index and keeps the
+// version for IXFR. A synthesized SOA keeps its serial while the content
+// is unchanged and is bumped past the previous serial when the content
+// changed without a newer UpdatedAt, as after a deletion. The previous
+// serial is the old index's, or the saved one when the zone is first
+// loaded. It reports whether the serial changed. s.mu must be held.
+func (s *Server) track(idx, old *zoneIndex) bool {
+	if idx.synthetic {
+		digest := recordsDigest(idx.records)
+		var prev uint32
+		var same, known bool
+		switch saved, ok := s.serials[idx.origin]; {
+		case old != nil && old.synthetic:
+			prev, same, known = old.serial(), sameRecords(idx.records, old.records), true
+		case old == nil && ok:
+			prev, same, known = saved.Serial, saved.Digest == digest, true
+		}
+		if known && (same || !dnswire.SerialGreater(idx.serial(), prev)) {
+			next := prev
+			if !same {
+				next++
+			}
+			if data, err := dnswire.SetSOASerial(idx.soa.Data, next); err == nil {
+				idx.soa.Data = data
+			}
+		}
+		s.serials[idx.origin] = savedSerial{Serial: idx.serial(), Digest: digest}
+	}
+	// A version replaces any kept under the same serial.
+	var history []*zoneIndex
+	for _, v := range s.history[idx.origin] {
+		if v.serial() != idx.serial() {
+			history = append(history, v)
+		}
+	}
+	history = append(history, idx)
+	if n := s.opts.History; len(history) > n {
+		history = history[len(history)-n:]
+	}
+	s.history[idx.origin] = history
+	return old != nil && idx.serial() != old.serial()
+}
+
+// version returns the kept version of a zone with the given serial.
+func (s *Server) version(origin string, serial uint32) *zoneIndex {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, v := range s.history[origin] {
+		if v.serial() == serial {
+			return v
+		}
+	}
+	return nil
+}
+
+// notify tells the secondaries that zone has a new serial (RFC 1996).
+func (s *Server) notify(zone *zoneIndex) {
+	for _, target := range s.notifyTargets {
+		go s.notifyOne(zone, target)
+	}
+}
+
+func (s *Server) notifyOne(zone *zoneIndex, target string) {
+	msg := dnswire.NewQuery(zone.origin, dnswire.TypeSOA)
+	msg.Opcode = dnswire.OpcodeNotify
+	msg.Authoritative = true
+	msg.Answers = []dnswire.RR{zone.soa}
+	log := s.opts.Logger.With(slog.String("zone", zone.origin), slog.String("secondary", target), slog.Uint64("serial", uint64(zone.serial())))
+	var err error
+	for attempt := 0; attempt < notifyAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		var resp *dnswire.Message
+		resp, err = dnswire.Exchange(ctx, target, msg)
+		cancel()
+		if err == nil && resp.Rcode != dnswire.RcodeSuccess {
+			err = fmt.Errorf("dnsserve: NOTIFY answered with rcode %d", resp.Rcode)
+		}
+		if err == nil {
+			log.Debug("dnsserve: secondary notified")
+			return
+		}
+	}
+	log.Warn("dnsserve: notify secondary", slog.Any("error", err))
+}
+
+// notifyTarget adds the default port to a secondary's address.
+func notifyTarget(addr string) (string, error) {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr, nil
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+	if host == "" {
+		return "", fmt.Errorf("dnsserve: invalid secondary address %q", addr)
+	}
+	return net.JoinHostPort(host, "53"), nil
+}
+
+// Watch keeps the zones loaded through the API current: it polls them
+// with the change watcher every interval, reloads the zones whose records
+// changed and notifies secondaries of the new serials. opts tune the
+// watcher; WithWatchHandler must not be among them. Watch returns when
+// ctx is done.
+func (s *Server) Watch(ctx context.Context, interval time.Duration, opts ...enzonix.WatchOption) error {
+	if s.opts.Client == nil {
+		return errors.New("dnsserve: Watch needs Options.Client")
+	}
+	s.mu.RLock()
+	var ids []string
+	for _, z := range s.zones {
+		if z.domainID != "" {
+			ids = append(ids, z.domainID)
+		}
+	}
+	s.mu.RUnlock()
+	if len(ids) == 0 {
+		return errors.New("dnsserve: no zones loaded through the API to watch")
+	}
+	events, err := s.opts.Client.Watch(ctx, ids, interval, opts...)
+	if err != nil {
+		return err
+	}
+	for event := range events {
+		// The changes found by one poll arrive back to back; reload each
+		// zone once for them.
+		pending := map[string]bool{event.DomainID: true}
+	drain:
+		for {
+			select {
+			case e, ok := <-events:
+				if !ok {
+					break drain
+				}
+				pending[e.DomainID] = true
+			default:
+				break drain
+			}
+		}
+		for id := range pending {
+			if zone := s.zoneByDomainID(id); zone != nil {
+				s.reload(ctx, zone)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) zoneByDomainID(id string) *zoneIndex {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, z := range s.zones {
+		if z.domainID == id {
+			return z
+		}
+	}
+	return nil
+}
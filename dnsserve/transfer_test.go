@@ -0,0 +1,297 @@
+package dnsserve
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/dnswire"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+// xfr transfers example.com over TCP, signed with key when given, and
+// returns the rcode and the records of all messages, checking each
+// message's signature.
+func xfr(t *testing.T, addr string, typ uint16, serial uint32, key *dnswire.TSIGKey) (uint8, []dnswire.RR) {
+	t.Helper()
+	query := dnswire.NewQuery("example.com", typ)
+	if typ == dnswire.TypeIXFR {
+		data, err := dnswire.PackRData(dnswire.TypeSOA, fmt.Sprintf("ns1.example.com. hostmaster.example.com. %d 3600 600 604800 300", serial), 0, "example.com.")
+		if err != nil {
+			t.Fatal(err)
+		}
+		query.Authority = []dnswire.RR{{Name: "example.com.", Type: dnswire.TypeSOA, Class: dnswire.ClassINET, Data: data}}
+	}
+	raw, err := query.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mac []byte
+	if key != nil {
+		if raw, mac, err = dnswire.SignTSIG(raw, *key, dnswire.TSIGParams{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := dnswire.WriteTCP(conn, raw); err != nil {
+		t.Fatal(err)
+	}
+
+	var rrs []dnswire.RR
+	for i := 0; ; i++ {
+		raw, err := dnswire.ReadTCP(conn)
+		if err != nil {
+			t.Fatalf("read message %d: %v", i, err)
+		}
+		resp, err := dnswire.Unpack(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != nil {
+			lookup := func(string) (dnswire.TSIGKey, bool) { return *key, true }
+			if _, err := dnswire.VerifyTSIG(raw, lookup, dnswire.TSIGParams{Prev: mac, TimersOnly: i > 0}); err != nil {
+				t.Fatalf("message %d signature: %v", i, err)
+			}
+			sig, _ := dnswire.ParseTSIG(resp.Additional[len(resp.Additional)-1])
+			mac = sig.MAC
+		}
+		if resp.Rcode != dnswire.RcodeSuccess {
+			return resp.Rcode, nil
+		}
+		rrs = append(rrs, resp.Answers...)
+		// The transfer ends with the SOA it started with.
+		last := rrs[len(rrs)-1]
+		if (len(rrs) == 1 && typ == dnswire.TypeIXFR) || (len(rrs) > 1 && last.Type == dnswire.TypeSOA && string(last.Data) == string(rrs[0].Data)) {
+			return resp.Rcode, rrs
+		}
+	}
+}
+
+// describe renders records as "name TYPE value", SOAs by serial.
+func describe(t *testing.T, rrs []dnswire.RR) string {
+	t.Helper()
+	parts := make([]string, 0, len(rrs))
+	for _, rr := range rrs {
+		if rr.Type == dnswire.TypeSOA {
+			serial, _ := dnswire.SOASerial(rr.Data)
+			parts = append(parts, fmt.Sprintf("SOA %d", serial))
+			continue
+		}
+		value, _, err := dnswire.UnpackRData(rr.Type, rr.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts = append(parts, rr.Name+" "+dnswire.TypeString(rr.Type)+" "+value)
+	}
+	return strings.Join(parts, "|")
+}
+
+func TestTransfer(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeapi.New("key")
+	api := httptest.NewServer(fake)
+	defer api.Close()
+	client, _ := enzonix.NewClient("key", enzonix.WithBaseURL(api.URL))
+	domain := fake.AddDomain("example.com")
+	for _, r := range []fakeapi.Record{
+		{Name: "@", Type: "NS", Value: "ns1.enzonix.com."},
+		{Name: "www", Type: "A", Value: "192.0.2.1"},
+		{Name: "www", Type: "A", Value: "192.0.2.9", CountryCodes: []string{"DE"}},
+	} {
+		r.DomainID = domain.ID
+		fake.AddRecord(r)
+	}
+	zones, err := LoadZones(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := ParseTSIGKey("xfr-key:c2VjcmV0LXNlY3JldC1zZWNyZXQ=")
+	wireKey, _ := key.wire()
+	addr := startServer(t, zones, Options{
+		Keys:     []TSIGKey{key},
+		Client:   client,
+		Update:   ACL{Networks: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}},
+		Transfer: ACL{Keys: []string{"xfr-key"}},
+	})
+
+	if rcode, _ := xfr(t, addr, dnswire.TypeAXFR, 0, nil); rcode != dnswire.RcodeRefused {
+		t.Fatalf("expected unsigned AXFR to be refused, got rcode %d", rcode)
+	}
+	query := dnswire.NewQuery("example.com", dnswire.TypeAXFR)
+	if resp, err := dnswire.Exchange(context.Background(), addr, query); err != nil || resp.Rcode != dnswire.RcodeNotImp {
+		t.Fatalf("expected NOTIMP for AXFR over UDP, got %+v, %v", resp, err)
+	}
+
+	_, rrs := xfr(t, addr, dnswire.TypeAXFR, 0, &wireKey)
+	serial0, _ := dnswire.SOASerial(rrs[0].Data)
+	if got, want := describe(t, rrs), fmt.Sprintf("SOA %[1]d|example.com. NS ns1.enzonix.com.|www.example.com. A 192.0.2.1|SOA %[1]d", serial0); got != want {
+		t.Fatalf("AXFR:\n got %s\nwant %s", got, want)
+	}
+
+	// An update bumps the serial; IXFR sends the difference.
+	if resp := newUpdate().add(t, "host.example.com", "A", 60, "192.0.2.10", 0).send(t, addr, nil); resp.Rcode != dnswire.RcodeSuccess {
+		t.Fatalf("update: rcode %d", resp.Rcode)
+	}
+	_, rrs = xfr(t, addr, dnswire.TypeIXFR, serial0, &wireKey)
+	serial1, _ := dnswire.SOASerial(rrs[0].Data)
+	if !dnswire.SerialGreater(serial1, serial0) {
+		t.Fatalf("serial %d not above %d", serial1, serial0)
+	}
+	if got, want := describe(t, rrs), fmt.Sprintf("SOA %[2]d|SOA %[1]d|SOA %[2]d|host.example.com. A 192.0.2.10|SOA %[2]d", serial0, serial1); got != want {
+		t.Fatalf("IXFR:\n got %s\nwant %s", got, want)
+	}
+
+	// Deleting the newest record moves UpdatedAt back, yet the serial
+	// still increases.
+	if resp := newUpdate().del(t, "host.example.com", "A", dnswire.ClassANY, "").send(t, addr, nil); resp.Rcode != dnswire.RcodeSuccess {
+		t.Fatalf("update: rcode %d", resp.Rcode)
+	}
+	_, rrs = xfr(t, addr, dnswire.TypeIXFR, serial1, &wireKey)
+	serial2 := serial1 + 1
+	if got, want := describe(t, rrs), fmt.Sprintf("SOA %[2]d|SOA %[1]d|host.example.com. A 192.0.2.10|SOA %[2]d|SOA %[2]d", serial1, serial2); got != want {
+		t.Fatalf("IXFR after delete:\n got %s\nwant %s", got, want)
+	}
+
+	// A current client gets the SOA alone; an unknown version the zone.
+	if _, rrs = xfr(t, addr, dnswire.TypeIXFR, serial2, &wireKey); describe(t, rrs) != fmt.Sprintf("SOA %d", serial2) {
+		t.Fatalf("IXFR when current: %s", describe(t, rrs))
+	}
+	if _, rrs = xfr(t, addr, dnswire.TypeIXFR, 12345, &wireKey); len(rrs) != 4 || rrs[1].Type != dnswire.TypeNS {
+		t.Fatalf("IXFR from unknown serial: %s", describe(t, rrs))
+	}
+}
+
+func TestTransferLargeZone(t *testing.T) {
+	t.Parallel()
+
+	zone := Zone{Name: "example.com"}
+	for i := 0; i < 1000; i++ {
+		zone.Records = append(zone.Records, enzonix.Record{Name: fmt.Sprintf("host-%d", i), Type: "TXT", Value: strings.Repeat("x", 40)})
+	}
+	key := TSIGKey{Name: "xfr-key", Secret: []byte("secret")}
+	wireKey, _ := key.wire()
+	addr := startServer(t, []Zone{zone}, Options{Keys: []TSIGKey{key}, Transfer: ACL{Keys: []string{"xfr-key"}}})
+	if _, rrs := xfr(t, addr, dnswire.TypeAXFR, 0, &wireKey); len(rrs) != 1002 {
+		t.Fatalf("got %d records, want 1002", len(rrs))
+	}
+}
+
+func TestSerialSurvivesRestart(t *testing.T) {
+	t.Parallel()
+
+	newer, older := time.Unix(1700000000, 0), time.Unix(1690000000, 0)
+	zone := func(records ...enzonix.Record) []Zone {
+		return []Zone{{Name: "example.com", Records: records}}
+	}
+	www := enzonix.Record{Name: "www", Type: "A", Value: "192.0.2.1", UpdatedAt: &older}
+	api := enzonix.Record{Name: "api", Type: "A", Value: "192.0.2.2", UpdatedAt: &newer}
+	opts := Options{SerialFile: filepath.Join(t.TempDir(), "serials.json")}
+	serial := func(s *Server) uint32 {
+		t.Helper()
+		return s.zoneNamed("example.com.").serial()
+	}
+
+	s, err := New(zone(www, api), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Deleting the newest record bumps the serial past its UpdatedAt.
+	if err := s.SetZones(zone(www)); err != nil {
+		t.Fatal(err)
+	}
+	bumped := serial(s)
+	if bumped != uint32(newer.Unix())+1 {
+		t.Fatalf("got serial %d after the delete, want %d", bumped, newer.Unix()+1)
+	}
+
+	// A restart with the same records keeps the serial; one with other
+	// records goes past it.
+	if s, err = New(zone(www), opts); err != nil || serial(s) != bumped {
+		t.Fatalf("restarted serial %d, want %d (%v)", serial(s), bumped, err)
+	}
+	www.Value = "192.0.2.3"
+	if s, err = New(zone(www), opts); err != nil || serial(s) != bumped+1 {
+		t.Fatalf("restarted serial %d after a change, want %d (%v)", serial(s), bumped+1, err)
+	}
+}
+
+func TestWatchNotifies(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeapi.New("key")
+	api := httptest.NewServer(fake)
+	defer api.Close()
+	client, _ := enzonix.NewClient("key", enzonix.WithBaseURL(api.URL))
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", Value: "192.0.2.1"})
+	zones, err := LoadZones(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A secondary that acknowledges NOTIFY messages.
+	secondary, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer secondary.Close()
+	notified := make(chan *dnswire.Message, 4)
+	go func() {
+		buf := make([]byte, dnswire.MaxUDPSize)
+		for {
+			n, from, err := secondary.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			msg, err := dnswire.Unpack(buf[:n])
+			if err != nil {
+				continue
+			}
+			notified <- msg
+			ack := reply(msg)
+			out, _ := ack.Pack()
+			_, _ = secondary.WriteTo(out, from)
+		}
+	}()
+
+	s, err := New(zones, Options{Logger: quiet, Client: client, Notify: []string{secondary.LocalAddr().String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial := s.zoneNamed("example.com.").serial()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Watch(ctx, 20*time.Millisecond, enzonix.WithWatchJitter(0))
+
+	// Wait for the watcher's baseline before changing the zone.
+	time.Sleep(100 * time.Millisecond)
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "new", Type: "A", Value: "192.0.2.2"})
+	select {
+	case msg := <-notified:
+		if msg.Opcode != dnswire.OpcodeNotify || len(msg.Answers) != 1 {
+			t.Fatalf("unexpected NOTIFY %+v", msg)
+		}
+		got, _ := dnswire.SOASerial(msg.Answers[0].Data)
+		if !dnswire.SerialGreater(got, serial) {
+			t.Fatalf("notified serial %d not above %d", got, serial)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no NOTIFY received")
+	}
+	if s.zoneNamed("example.com.").nodes["new.example.com."] == nil {
+		t.Fatal("zone not reloaded")
+	}
+}
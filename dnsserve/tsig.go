@@ -0,0 +1,85 @@
+package dnsserve
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/dnswire"
+)
+
+// TSIGKey is a shared secret for signed messages (RFC 8945).
+type TSIGKey struct {
+	Name string
+	// Algorithm is an HMAC algorithm such as "hmac-sha256" (the default),
+	// "hmac-sha512" or "hmac-md5".
+	Algorithm string
+	Secret    []byte
+}
+
+// ParseTSIGKey parses a key given as "[algorithm:]name:secret" with a
+// base64 secret, the form used by dig -y.
+func ParseTSIGKey(spec string) (TSIGKey, error) {
+	parts := strings.Split(strings.TrimSpace(spec), ":")
+	var key TSIGKey
+	switch len(parts) {
+	case 2:
+		key.Name = parts[0]
+	case 3:
+		key.Algorithm, key.Name = parts[0], parts[1]
+	default:
+		return TSIGKey{}, fmt.Errorf("dnsserve: TSIG key %q is not [algorithm:]name:secret", spec)
+	}
+	secret, err := base64.StdEncoding.DecodeString(parts[len(parts)-1])
+	if err != nil || len(secret) == 0 {
+		return TSIGKey{}, fmt.Errorf("dnsserve: TSIG key %s: invalid base64 secret", key.Name)
+	}
+	key.Secret = secret
+	if _, err := key.wire(); err != nil {
+		return TSIGKey{}, err
+	}
+	return key, nil
+}
+
+func (k TSIGKey) wire() (dnswire.TSIGKey, error) {
+	alg := k.Algorithm
+	if alg == "" {
+		alg = dnswire.HmacSHA256
+	}
+	canonical, ok := dnswire.TSIGAlgorithm(alg)
+	if !ok {
+		return dnswire.TSIGKey{}, fmt.Errorf("dnsserve: TSIG key %s: unsupported algorithm %s", k.Name, alg)
+	}
+	if strings.TrimSpace(k.Name) == "" || len(k.Secret) == 0 {
+		return dnswire.TSIGKey{}, fmt.Errorf("dnsserve: TSIG key needs a name and a secret")
+	}
+	return dnswire.TSIGKey{Name: dnswire.Fqdn(strings.ToLower(k.Name)), Algorithm: canonical, Secret: k.Secret}, nil
+}
+
+// ACL admits requests by source network or TSIG key. The zero ACL admits
+// nothing.
+type ACL struct {
+	Networks []netip.Prefix
+	// Keys are names of TSIG keys; requests signed with one are admitted
+	// from anywhere.
+	Keys []string
+}
+
+// allows reports whether a request from client, signed with key (empty
+// for unsigned requests), is admitted.
+func (a ACL) allows(client netip.Addr, key string) bool {
+	if key != "" {
+		for _, k := range a.Keys {
+			if dnswire.EqualNames(k, key) {
+				return true
+			}
+		}
+	}
+	for _, n := range a.Networks {
+		if n.Contains(client) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,438 @@
+package dnsserve
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/dnswire"
+)
+
+// updateTimeout bounds the API calls made for one update.
+const updateTimeout = 30 * time.Second
+
+// update applies a dynamic update (RFC 2136) and returns the response
+// code. Prerequisites are checked against the records returned by the API
+// and the changes applied with CreateRecord and DeleteRecord; records with
+// country codes are left alone. The API offers no transactions, so a
+// failure part way leaves the changes made so far in place.
+func (s *Server) update(ctx context.Context, msg *dnswire.Message, req request) uint8 {
+	if len(msg.Questions) != 1 || msg.Questions[0].Type != dnswire.TypeSOA {
+		return dnswire.RcodeFormErr
+	}
+	origin := strings.ToLower(dnswire.Fqdn(msg.Questions[0].Name))
+	zone := s.zoneNamed(origin)
+	if zone == nil {
+		return dnswire.RcodeNotAuth
+	}
+	log := s.opts.Logger.With(slog.String("zone", origin), slog.String("client", req.source.String()), slog.String("key", req.key))
+	if !s.opts.Update.allows(req.source, req.key) {
+		log.Warn("dnsserve: update refused by ACL")
+		return dnswire.RcodeRefused
+	}
+	if zone.domainID == "" || s.opts.Client == nil {
+		log.Warn("dnsserve: update refused, zone not loaded through the API")
+		return dnswire.RcodeRefused
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+	records, err := s.opts.Client.ListDomainRecords(ctx, zone.domainID)
+	if err != nil {
+		log.Error("dnsserve: update: list records", slog.Any("error", err))
+		return dnswire.RcodeServFail
+	}
+	state := newUpdateState(zone, records)
+	if rcode := state.checkPrerequisites(msg.Answers); rcode != dnswire.RcodeSuccess {
+		return rcode
+	}
+	if rcode := state.prescan(msg.Authority); rcode != dnswire.RcodeSuccess {
+		return rcode
+	}
+	for _, rr := range msg.Authority {
+		state.apply(rr)
+	}
+
+	creates, deletes := state.changes()
+	if len(creates) == 0 && len(deletes) == 0 {
+		return dnswire.RcodeSuccess
+	}
+	// Deleting first keeps the API from rejecting, say, a CNAME that
+	// replaces other records.
+	for _, r := range deletes {
+		if err := s.opts.Client.DeleteRecord(ctx, r.ID); err != nil {
+			log.Error("dnsserve: update: delete record", slog.String("name", r.Name), slog.String("type", r.Type), slog.Any("error", err))
+			s.reload(ctx, zone)
+			return dnswire.RcodeServFail
+		}
+	}
+	for _, rr := range creates {
+		payload, err := createRequest(rr, zone)
+		if err == nil {
+			_, err = s.opts.Client.CreateRecord(ctx, payload)
+		}
+		if err != nil {
+			log.Error("dnsserve: update: create record", slog.String("name", rr.Name), slog.String("type", dnswire.TypeString(rr.Type)), slog.Any("error", err))
+			s.reload(ctx, zone)
+			return dnswire.RcodeServFail
+		}
+	}
+	log.Info("dnsserve: update applied", slog.Int("created", len(creates)), slog.Int("deleted", len(deletes)))
+	s.reload(ctx, zone)
+	return dnswire.RcodeSuccess
+}
+
+// zoneNamed returns the zone with the given origin.
+func (s *Server) zoneNamed(origin string) *zoneIndex {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, z := range s.zones {
+		if z.origin == origin {
+			return z
+		}
+	}
+	return nil
+}
+
+// reload refreshes a zone loaded through the API, so that answers reflect
+// an update at once.
+func (s *Server) reload(ctx context.Context, zone *zoneIndex) {
+	records, err := s.opts.Client.ListDomainRecords(ctx, zone.domainID)
+	if err == nil {
+		var idx *zoneIndex
+		idx, err = buildIndex(Zone{Name: zone.origin, DomainID: zone.domainID, Records: records}, s.opts.Logger)
+		if err == nil {
+			s.replaceZone(idx)
+		}
+	}
+	if err != nil {
+		s.opts.Logger.Error("dnsserve: reload zone", slog.String("zone", zone.origin), slog.Any("error", err))
+	}
+}
+
+func (s *Server) replaceZone(idx *zoneIndex) {
+	s.mu.Lock()
+	zones := make([]*zoneIndex, len(s.zones))
+	changed := false
+	for i, z := range s.zones {
+		if z.origin == idx.origin {
+			changed = s.track(idx, z)
+			z = idx
+		}
+		zones[i] = z
+	}
+	s.zones = zones
+	s.mu.Unlock()
+	if changed {
+		s.saveSerials()
+		s.notify(idx)
+	}
+}
+
+// liveRR is a record of the zone as an update sees it.
+type liveRR struct {
+	rr dnswire.RR
+	// record is the API record; nil for records added by the update.
+	record  *enzonix.Record
+	deleted bool
+	// fixed marks the synthesized SOA, which updates cannot change.
+	fixed bool
+}
+
+type updateState struct {
+	origin string
+	rrs    []*liveRR
+}
+
+func newUpdateState(zone *zoneIndex, records []enzonix.Record) *updateState {
+	state := &updateState{origin: zone.origin}
+	hasSOA := false
+	for i := range records {
+		r := &records[i]
+		if len(r.CountryCodes) > 0 {
+			continue
+		}
+		typ, ok := dnswire.ParseType(r.Type)
+		if !ok || (!updatable(typ) && typ != dnswire.TypeSOA) {
+			continue
+		}
+		data, err := dnswire.PackRData(typ, r.Value, r.Priority, zone.origin)
+		if err != nil {
+			continue
+		}
+		owner := dnswire.Absolute(r.Name, zone.origin)
+		hasSOA = hasSOA || (typ == dnswire.TypeSOA && owner == zone.origin)
+		state.rrs = append(state.rrs, &liveRR{
+			rr:     dnswire.RR{Name: owner, Type: typ, Class: dnswire.ClassINET, TTL: uint32(max(r.TTL, 0)), Data: data},
+			record: r,
+		})
+	}
+	if !hasSOA {
+		state.rrs = append(state.rrs, &liveRR{rr: zone.soa, fixed: true})
+	}
+	return state
+}
+
+// rrset returns the live records of name and typ; dnswire.TypeANY matches
+// every type.
+func (u *updateState) rrset(name string, typ uint16) []*liveRR {
+	var out []*liveRR
+	for _, l := range u.rrs {
+		if !l.deleted && l.rr.Name == name && (typ == dnswire.TypeANY || l.rr.Type == typ) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// checkPrerequisites evaluates the prerequisite section (RFC 2136,
+// section 3.2).
+func (u *updateState) checkPrerequisites(prereqs []dnswire.RR) uint8 {
+	type rrsetKey struct {
+		name string
+		typ  uint16
+	}
+	var (
+		keys  []rrsetKey
+		exact = map[rrsetKey][]dnswire.RR{}
+	)
+	for _, rr := range prereqs {
+		name := strings.ToLower(rr.Name)
+		if rr.TTL != 0 {
+			return dnswire.RcodeFormErr
+		}
+		if !inZone(name, u.origin) {
+			return dnswire.RcodeNotZone
+		}
+		switch rr.Class {
+		case dnswire.ClassANY:
+			switch {
+			case len(rr.Data) != 0:
+				return dnswire.RcodeFormErr
+			case rr.Type == dnswire.TypeANY && len(u.rrset(name, dnswire.TypeANY)) == 0:
+				return dnswire.RcodeNXDomain
+			case rr.Type != dnswire.TypeANY && len(u.rrset(name, rr.Type)) == 0:
+				return dnswire.RcodeNXRRSet
+			}
+		case dnswire.ClassNONE:
+			switch {
+			case len(rr.Data) != 0:
+				return dnswire.RcodeFormErr
+			case rr.Type == dnswire.TypeANY && len(u.rrset(name, dnswire.TypeANY)) > 0:
+				return dnswire.RcodeYXDomain
+			case rr.Type != dnswire.TypeANY && len(u.rrset(name, rr.Type)) > 0:
+				return dnswire.RcodeYXRRSet
+			}
+		case dnswire.ClassINET:
+			if rr.Type == dnswire.TypeANY {
+				return dnswire.RcodeFormErr
+			}
+			key := rrsetKey{name, rr.Type}
+			if _, ok := exact[key]; !ok {
+				keys = append(keys, key)
+			}
+			exact[key] = append(exact[key], rr)
+		default:
+			return dnswire.RcodeFormErr
+		}
+	}
+	// Value-dependent prerequisites compare whole RRsets.
+	for _, key := range keys {
+		live := u.rrset(key.name, key.typ)
+		for _, want := range exact[key] {
+			if !containsRData(live, want) {
+				return dnswire.RcodeNXRRSet
+			}
+		}
+		for _, l := range live {
+			found := false
+			for _, want := range exact[key] {
+				found = found || sameRData(key.typ, l.rr.Data, want.Data)
+			}
+			if !found {
+				return dnswire.RcodeNXRRSet
+			}
+		}
+	}
+	return dnswire.RcodeSuccess
+}
+
+// prescan validates the update section before anything is changed (RFC
+// 2136, section 3.4.1).
+func (u *updateState) prescan(updates []dnswire.RR) uint8 {
+	for _, rr := range updates {
+		if !inZone(strings.ToLower(rr.Name), u.origin) {
+			return dnswire.RcodeNotZone
+		}
+		switch rr.Class {
+		case dnswire.ClassINET:
+			if rr.Type == dnswire.TypeSOA {
+				continue
+			}
+			if !updatable(rr.Type) {
+				if metaType(rr.Type) {
+					return dnswire.RcodeFormErr
+				}
+				return dnswire.RcodeRefused
+			}
+			if _, _, err := dnswire.UnpackRData(rr.Type, rr.Data); err != nil {
+				return dnswire.RcodeFormErr
+			}
+		case dnswire.ClassANY:
+			if rr.TTL != 0 || len(rr.Data) != 0 || (metaType(rr.Type) && rr.Type != dnswire.TypeANY) {
+				return dnswire.RcodeFormErr
+			}
+		case dnswire.ClassNONE:
+			if rr.TTL != 0 || metaType(rr.Type) {
+				return dnswire.RcodeFormErr
+			}
+		default:
+			return dnswire.RcodeFormErr
+		}
+	}
+	return dnswire.RcodeSuccess
+}
+
+// apply performs one update operation on the state (RFC 2136, section
+// 3.4.2). The SOA, which the server synthesizes, and the apex NS records
+// are never removed by RRset deletions.
+func (u *updateState) apply(rr dnswire.RR) {
+	name := strings.ToLower(rr.Name)
+	apexProtected := func(typ uint16) bool {
+		return name == u.origin && (typ == dnswire.TypeSOA || typ == dnswire.TypeNS)
+	}
+	switch rr.Class {
+	case dnswire.ClassINET:
+		if rr.Type == dnswire.TypeSOA {
+			return
+		}
+		cnames := u.rrset(name, dnswire.TypeCNAME)
+		others := len(u.rrset(name, dnswire.TypeANY)) - len(cnames)
+		switch {
+		case rr.Type == dnswire.TypeCNAME && others > 0:
+			return
+		case rr.Type != dnswire.TypeCNAME && len(cnames) > 0:
+			return
+		}
+		if containsRData(u.rrset(name, rr.Type), rr) {
+			return
+		}
+		if rr.Type == dnswire.TypeCNAME {
+			for _, l := range cnames {
+				l.deleted = true
+			}
+		}
+		rr.Name = name
+		u.rrs = append(u.rrs, &liveRR{rr: rr})
+	case dnswire.ClassANY:
+		for _, l := range u.rrset(name, rr.Type) {
+			if !apexProtected(l.rr.Type) {
+				l.deleted = true
+			}
+		}
+	case dnswire.ClassNONE:
+		if rr.Type == dnswire.TypeSOA {
+			return
+		}
+		set := u.rrset(name, rr.Type)
+		for _, l := range set {
+			if !sameRData(rr.Type, l.rr.Data, rr.Data) {
+				continue
+			}
+			if rr.Type == dnswire.TypeNS && name == u.origin && len(u.rrset(name, rr.Type)) == 1 {
+				return
+			}
+			l.deleted = true
+		}
+	}
+}
+
+// changes returns the records to create and the API records to delete.
+func (u *updateState) changes() (creates []dnswire.RR, deletes []enzonix.Record) {
+	for _, l := range u.rrs {
+		switch {
+		case l.fixed:
+		case l.record == nil && !l.deleted:
+			creates = append(creates, l.rr)
+		case l.record != nil && l.deleted:
+			deletes = append(deletes, *l.record)
+		}
+	}
+	return creates, deletes
+}
+
+// createRequest converts a record added by an update to an API request.
+func createRequest(rr dnswire.RR, zone *zoneIndex) (enzonix.CreateRecordRequest, error) {
+	value, priority, err := dnswire.UnpackRData(rr.Type, rr.Data)
+	if err != nil {
+		return enzonix.CreateRecordRequest{}, err
+	}
+	switch rr.Type {
+	case dnswire.TypeNS, dnswire.TypeCNAME, dnswire.TypePTR, dnswire.TypeMX, dnswire.TypeSRV:
+		// Targets are stored without the trailing dot unless they are
+		// single labels, which would read as relative.
+		i := strings.LastIndexByte(value, ' ') + 1
+		if target := strings.TrimSuffix(value[i:], "."); strings.Contains(target, ".") {
+			value = value[:i] + target
+		}
+	}
+	name := "@"
+	if rr.Name != zone.origin {
+		name = strings.TrimSuffix(rr.Name, "."+zone.origin)
+	}
+	ttl := int(rr.TTL)
+	req := enzonix.CreateRecordRequest{
+		DomainID: zone.domainID,
+		Name:     name,
+		Type:     dnswire.TypeString(rr.Type),
+		Value:    value,
+		TTL:      &ttl,
+	}
+	if rr.Type == dnswire.TypeMX || rr.Type == dnswire.TypeSRV {
+		req.Priority = &priority
+	}
+	return req, nil
+}
+
+// updatable reports whether records of typ can be added by updates.
+func updatable(typ uint16) bool {
+	switch typ {
+	case dnswire.TypeA, dnswire.TypeAAAA, dnswire.TypeNS, dnswire.TypeCNAME, dnswire.TypePTR,
+		dnswire.TypeMX, dnswire.TypeTXT, dnswire.TypeSRV, dnswire.TypeCAA:
+		return true
+	}
+	return false
+}
+
+// metaType reports query-only types, which cannot be stored.
+func metaType(typ uint16) bool {
+	switch typ {
+	case dnswire.TypeOPT, dnswire.TypeTSIG, dnswire.TypeIXFR, dnswire.TypeAXFR, dnswire.TypeANY, 253, 254:
+		return true
+	}
+	return false
+}
+
+func containsRData(set []*liveRR, rr dnswire.RR) bool {
+	for _, l := range set {
+		if sameRData(rr.Type, l.rr.Data, rr.Data) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameRData compares rdata, ignoring the case of embedded names.
+func sameRData(typ uint16, a, b []byte) bool {
+	va, pa, errA := dnswire.UnpackRData(typ, a)
+	vb, pb, errB := dnswire.UnpackRData(typ, b)
+	if errA != nil || errB != nil {
+		return string(a) == string(b)
+	}
+	if typ == dnswire.TypeTXT || typ == dnswire.TypeCAA {
+		return va == vb && pa == pb
+	}
+	return strings.EqualFold(va, vb) && pa == pb
+}
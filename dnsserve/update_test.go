@@ -0,0 +1,254 @@
+package dnsserve
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"net/netip"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/dnswire"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+// updateMsg builds an UPDATE for example.com.
+type updateMsg struct {
+	msg *dnswire.Message
+}
+
+func newUpdate() *updateMsg {
+	msg := dnswire.NewQuery("example.com", dnswire.TypeSOA)
+	msg.Opcode = dnswire.OpcodeUpdate
+	return &updateMsg{msg: msg}
+}
+
+func (u *updateMsg) rr(t *testing.T, name, typ string, class uint16, ttl uint32, value string, priority int) dnswire.RR {
+	t.Helper()
+	qtype, _ := dnswire.ParseType(typ)
+	rr := dnswire.RR{Name: dnswire.Fqdn(name), Type: qtype, Class: class, TTL: ttl}
+	if value != "" {
+		data, err := dnswire.PackRData(qtype, value, priority, "example.com.")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr.Data = data
+	}
+	return rr
+}
+
+func (u *updateMsg) prereq(t *testing.T, name, typ string, class uint16, value string) *updateMsg {
+	u.msg.Answers = append(u.msg.Answers, u.rr(t, name, typ, class, 0, value, 0))
+	return u
+}
+
+func (u *updateMsg) add(t *testing.T, name, typ string, ttl uint32, value string, priority int) *updateMsg {
+	u.msg.Authority = append(u.msg.Authority, u.rr(t, name, typ, dnswire.ClassINET, ttl, value, priority))
+	return u
+}
+
+func (u *updateMsg) del(t *testing.T, name, typ string, class uint16, value string) *updateMsg {
+	u.msg.Authority = append(u.msg.Authority, u.rr(t, name, typ, class, 0, value, 0))
+	return u
+}
+
+// send signs the update with key, when given, and returns the response
+// after checking its signature.
+func (u *updateMsg) send(t *testing.T, addr string, key *dnswire.TSIGKey) *dnswire.Message {
+	t.Helper()
+	raw, err := u.msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mac []byte
+	if key != nil {
+		if raw, mac, err = dnswire.SignTSIG(raw, *key, dnswire.TSIGParams{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(raw); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, dnswire.MaxUDPSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	resp, err := dnswire.Unpack(buf[:n])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != nil && resp.Rcode != dnswire.RcodeNotAuth {
+		lookup := func(string) (dnswire.TSIGKey, bool) { return *key, true }
+		if _, err := dnswire.VerifyTSIG(buf[:n], lookup, dnswire.TSIGParams{Prev: mac}); err != nil {
+			t.Fatalf("response signature: %v", err)
+		}
+	}
+	return resp
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeapi.New("key")
+	api := httptest.NewServer(fake)
+	defer api.Close()
+	client, err := enzonix.NewClient("key", enzonix.WithBaseURL(api.URL))
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+	domain := fake.AddDomain("example.com")
+	for _, r := range []fakeapi.Record{
+		{Name: "@", Type: "NS", Value: "ns1.enzonix.com."},
+		{Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"},
+		{Name: "www", Type: "A", TTL: 300, Value: "192.0.2.9", CountryCodes: []string{"DE"}},
+		{Name: "_acme-challenge", Type: "TXT", Value: "old-token"},
+	} {
+		r.DomainID = domain.ID
+		fake.AddRecord(r)
+	}
+	zones, err := LoadZones(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keySpec := "hmac-sha256:update-key:c2VjcmV0LXNlY3JldC1zZWNyZXQ="
+	key, err := ParseTSIGKey(keySpec)
+	if err != nil {
+		t.Fatalf("parse key: %v", err)
+	}
+	wireKey, _ := key.wire()
+	addr := startServer(t, zones, Options{Keys: []TSIGKey{key}, Client: client, Update: ACL{Keys: []string{"update-key"}}})
+
+	// certbot-style: replace the challenge and add a host.
+	resp := newUpdate().
+		prereq(t, "www.example.com", "A", dnswire.ClassANY, "").
+		del(t, "_acme-challenge.example.com", "TXT", dnswire.ClassANY, "").
+		add(t, "_acme-challenge.example.com", "TXT", 60, "new-token", 0).
+		add(t, "host.example.com", "A", 120, "192.0.2.10", 0).
+		add(t, "example.com", "MX", 3600, "mx.example.com.", 10).
+		send(t, addr, &wireKey)
+	if resp.Rcode != dnswire.RcodeSuccess {
+		t.Fatalf("unexpected rcode %d", resp.Rcode)
+	}
+	records := map[string]string{}
+	for _, r := range fake.Records(domain.ID) {
+		records[r.Name+" "+r.Type+" "+r.Value] = ""
+	}
+	for _, want := range []string{"_acme-challenge TXT new-token", "host A 192.0.2.10", "@ MX mx.example.com", "www A 192.0.2.9"} {
+		if _, ok := records[want]; !ok {
+			t.Fatalf("missing %q in %v", want, records)
+		}
+	}
+	if _, ok := records["_acme-challenge TXT old-token"]; ok {
+		t.Fatalf("old token not deleted: %v", records)
+	}
+	// The zone is reloaded after the update.
+	if got := ask(t, addr, "host.example.com", "A"); len(got.answers) != 1 {
+		t.Fatalf("update not served: %+v", got)
+	}
+
+	// Deleting a single value leaves the rest of the set, including geo
+	// variants, and the apex NS set survives an ANY deletion.
+	resp = newUpdate().
+		del(t, "www.example.com", "A", dnswire.ClassNONE, "192.0.2.1").
+		del(t, "example.com", "ANY", dnswire.ClassANY, "").
+		send(t, addr, &wireKey)
+	if resp.Rcode != dnswire.RcodeSuccess {
+		t.Fatalf("unexpected rcode %d", resp.Rcode)
+	}
+	var left []string
+	for _, r := range fake.Records(domain.ID) {
+		left = append(left, r.Name+" "+r.Type)
+	}
+	sort.Strings(left)
+	if want := "@ NS|_acme-challenge TXT|host A|www A"; strings.Join(left, "|") != want {
+		t.Fatalf("unexpected records %q, want %q", strings.Join(left, "|"), want)
+	}
+
+	for name, tc := range map[string]struct {
+		update *updateMsg
+		key    *dnswire.TSIGKey
+		rcode  uint8
+	}{
+		"unsigned":           {newUpdate().add(t, "x.example.com", "A", 60, "192.0.2.1", 0), nil, dnswire.RcodeRefused},
+		"bad key":            {newUpdate().add(t, "x.example.com", "A", 60, "192.0.2.1", 0), &dnswire.TSIGKey{Name: "update-key.", Algorithm: dnswire.HmacSHA256, Secret: []byte("wrong")}, dnswire.RcodeNotAuth},
+		"name exists":        {newUpdate().prereq(t, "host.example.com", "ANY", dnswire.ClassNONE, ""), &wireKey, dnswire.RcodeYXDomain},
+		"name missing":       {newUpdate().prereq(t, "nope.example.com", "ANY", dnswire.ClassANY, ""), &wireKey, dnswire.RcodeNXDomain},
+		"rrset exists":       {newUpdate().prereq(t, "host.example.com", "A", dnswire.ClassNONE, ""), &wireKey, dnswire.RcodeYXRRSet},
+		"rrset differs":      {newUpdate().prereq(t, "host.example.com", "A", dnswire.ClassINET, "192.0.2.11"), &wireKey, dnswire.RcodeNXRRSet},
+		"rrset matches":      {newUpdate().prereq(t, "host.example.com", "A", dnswire.ClassINET, "192.0.2.10"), &wireKey, dnswire.RcodeSuccess},
+		"outside zone":       {newUpdate().add(t, "www.example.org", "A", 60, "192.0.2.1", 0), &wireKey, dnswire.RcodeNotZone},
+		"cname next to data": {newUpdate().add(t, "host.example.com", "CNAME", 60, "www.example.com.", 0), &wireKey, dnswire.RcodeSuccess},
+	} {
+		if resp := tc.update.send(t, addr, tc.key); resp.Rcode != tc.rcode {
+			t.Errorf("%s: got rcode %d, want %d", name, resp.Rcode, tc.rcode)
+		}
+	}
+	// A CNAME is not added next to other data.
+	for _, r := range fake.Records(domain.ID) {
+		if r.Type == "CNAME" {
+			t.Fatalf("CNAME added next to other data: %+v", r)
+		}
+	}
+
+	other := newUpdate()
+	other.msg.Questions[0].Name = "example.org."
+	if resp := other.send(t, addr, &wireKey); resp.Rcode != dnswire.RcodeNotAuth {
+		t.Fatalf("expected NOTAUTH for a zone not served, got %d", resp.Rcode)
+	}
+}
+
+func TestUpdateByNetwork(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeapi.New("key")
+	api := httptest.NewServer(fake)
+	defer api.Close()
+	client, _ := enzonix.NewClient("key", enzonix.WithBaseURL(api.URL))
+	domain := fake.AddDomain("example.com")
+	zones, err := LoadZones(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acl := ACL{Networks: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}}
+	addr := startServer(t, zones, Options{Client: client, Update: acl})
+	if resp := newUpdate().add(t, "dhcp-client.example.com", "A", 600, "192.0.2.77", 0).send(t, addr, nil); resp.Rcode != dnswire.RcodeSuccess {
+		t.Fatalf("unexpected rcode %d", resp.Rcode)
+	}
+	if r := fake.Records(domain.ID); len(r) != 1 || r[0].Name != "dhcp-client" || r[0].TTL != 600 {
+		t.Fatalf("unexpected records %+v", r)
+	}
+
+	// Zones from files cannot be updated.
+	static := startServer(t, []Zone{{Name: "example.com"}}, Options{Client: client, Update: acl})
+	if resp := newUpdate().add(t, "x.example.com", "A", 600, "192.0.2.1", 0).send(t, static, nil); resp.Rcode != dnswire.RcodeRefused {
+		t.Fatalf("expected REFUSED for a static zone, got %d", resp.Rcode)
+	}
+}
+
+func TestParseTSIGKey(t *testing.T) {
+	t.Parallel()
+
+	key, err := ParseTSIGKey("hmac-md5:dhcp.:c2VjcmV0")
+	if err != nil || key.Name != "dhcp." || string(key.Secret) != "secret" {
+		t.Fatalf("unexpected key %+v, %v", key, err)
+	}
+	if w, _ := key.wire(); w.Algorithm != dnswire.HmacMD5 {
+		t.Fatalf("unexpected algorithm %s", w.Algorithm)
+	}
+	for _, spec := range []string{"name", "hmac-sha3:name:c2VjcmV0", "name:not base64!"} {
+		if _, err := ParseTSIGKey(spec); err == nil {
+			t.Errorf("ParseTSIGKey(%q): expected an error", spec)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package dnsserve
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/backup"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/dnswire"
+	"github.com/Enzonix-LLC/dns-sdk-go/zonefile"
+)
+
+// Zone is the content served for one domain. Record names are relative to
+// Name, as returned by the API.
+type Zone struct {
+	Name string
+	// DomainID is set for zones loaded through the API; only those accept
+	// dynamic updates.
+	DomainID string
+	Records  []enzonix.Record
+}
+
+// LoadZones fetches zones through the API. Without domain IDs every domain
+// visible to the client is loaded.
+func LoadZones(ctx context.Context, client *enzonix.Client, domainIDs ...string) ([]Zone, error) {
+	domains, err := client.ListDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(domainIDs))
+	for _, id := range domainIDs {
+		wanted[id] = true
+	}
+	var zones []Zone
+	for _, d := range domains {
+		if len(domainIDs) > 0 && !wanted[d.ID] {
+			continue
+		}
+		delete(wanted, d.ID)
+		records, err := client.ListDomainRecords(ctx, d.ID)
+		if err != nil {
+			return nil, fmt.Errorf("dnsserve: load %s: %w", d.Name, err)
+		}
+		zones = append(zones, Zone{Name: d.Name, DomainID: d.ID, Records: records})
+	}
+	for id := range wanted {
+		return nil, fmt.Errorf("dnsserve: domain %s not found", id)
+	}
+	return zones, nil
+}
+
+// SnapshotZone returns the zone of a backup snapshot, as loaded by
+// backup.Backup.Load.
+func SnapshotZone(snap backup.Snapshot, zone *backup.Zone) Zone {
+	return Zone{Name: snap.Domain.Name, Records: zone.Records}
+}
+
+// ParseZoneFile reads a BIND zone file. origin names the zone unless the
+// file sets $ORIGIN.
+func ParseZoneFile(r io.Reader, origin string) (Zone, error) {
+	parsed, err := zonefile.Parse(r, origin)
+	if err != nil {
+		return Zone{}, err
+	}
+	if parsed.Origin == "" {
+		return Zone{}, fmt.Errorf("dnsserve: zone file has no origin")
+	}
+	zone := Zone{Name: strings.TrimSuffix(parsed.Origin, ".")}
+	for _, rec := range parsed.Records {
+		if rec.Class != "IN" {
+			continue
+		}
+		zone.Records = append(zone.Records, enzonix.Record{
+			Name:     rec.Name,
+			Type:     rec.Type,
+			TTL:      rec.TTL,
+			Priority: rec.Priority,
+			Value:    rec.Value,
+		})
+	}
+	return zone, nil
+}
+
+// defaultTTL applies to records stored without a TTL.
+const defaultTTL = 300
+
+type entry struct {
+	rr dnswire.RR
+	// countries holds the upper-case country codes the entry is limited
+	// to; empty for the default answer.
+	countries []string
+}
+
+// zoneIndex is a zone prepared for lookups.
+type zoneIndex struct {
+	origin   string
+	domainID string
+	soa      dnswire.RR
+	nodes    map[string][]entry
+	// exists holds every owner name and its ancestors within the zone, so
+	// that empty non-terminals answer NODATA rather than NXDOMAIN.
+	exists map[string]bool
+	// records are the zone's records as transferred, without the SOA.
+	records []dnswire.RR
+	// synthetic marks an SOA made up by the server, whose serial it
+	// manages.
+	synthetic bool
+}
+
+func buildIndex(zone Zone, logger *slog.Logger) (*zoneIndex, error) {
+	if zone.Name == "" {
+		return nil, fmt.Errorf("dnsserve: zone without a name")
+	}
+	idx := &zoneIndex{
+		origin:   dnswire.Fqdn(strings.ToLower(zone.Name)),
+		domainID: zone.DomainID,
+		nodes:    map[string][]entry{},
+		exists:   map[string]bool{},
+	}
+	idx.exists[idx.origin] = true
+
+	var serial uint32 = 1
+	for _, r := range zone.Records {
+		typ, ok := dnswire.ParseType(r.Type)
+		if ok {
+			switch typ {
+			case dnswire.TypeOPT, dnswire.TypeTSIG, dnswire.TypeAXFR, dnswire.TypeIXFR, dnswire.TypeANY:
+				ok = false
+			}
+		}
+		if !ok {
+			logger.Warn("dnsserve: skipping record of unsupported type", slog.String("zone", zone.Name), slog.String("name", r.Name), slog.String("type", r.Type))
+			continue
+		}
+		data, err := dnswire.PackRData(typ, r.Value, r.Priority, idx.origin)
+		if err != nil {
+			logger.Warn("dnsserve: skipping invalid record", slog.String("zone", zone.Name), slog.String("name", r.Name), slog.String("type", r.Type), slog.Any("error", err))
+			continue
+		}
+		ttl := r.TTL
+		if ttl <= 0 {
+			ttl = defaultTTL
+		}
+		owner := dnswire.Absolute(r.Name, idx.origin)
+		if !inZone(owner, idx.origin) {
+			logger.Warn("dnsserve: skipping record outside the zone", slog.String("zone", zone.Name), slog.String("name", r.Name))
+			continue
+		}
+		rr := dnswire.RR{Name: owner, Type: typ, Class: dnswire.ClassINET, TTL: uint32(ttl), Data: data}
+		if typ == dnswire.TypeSOA && owner == idx.origin {
+			idx.soa = rr
+			continue
+		}
+		countries := make([]string, 0, len(r.CountryCodes))
+		for _, cc := range r.CountryCodes {
+			countries = append(countries, strings.ToUpper(strings.TrimSpace(cc)))
+		}
+		idx.nodes[owner] = append(idx.nodes[owner], entry{rr: rr, countries: countries})
+		for name := owner; name != idx.origin; name = parent(name) {
+			idx.exists[name] = true
+		}
+		if r.UpdatedAt != nil && r.UpdatedAt.Unix() > int64(serial) && r.UpdatedAt.Unix() <= 0xffffffff {
+			serial = uint32(r.UpdatedAt.Unix())
+		}
+	}
+
+	if idx.soa.Data == nil {
+		soa, err := synthesizeSOA(idx, serial)
+		if err != nil {
+			return nil, err
+		}
+		idx.soa, idx.synthetic = soa, true
+	}
+	idx.records = idx.transferRecords()
+	return idx, nil
+}
+
+// synthesizeSOA builds the SOA record of zones that have none, naming the
+// first apex NS as primary and using the newest record change as serial.
+func synthesizeSOA(idx *zoneIndex, serial uint32) (dnswire.RR, error) {
+	mname := "ns1." + idx.origin
+	for _, e := range idx.nodes[idx.origin] {
+		if e.rr.Type == dnswire.TypeNS {
+			if name, _, err := dnswire.UnpackRData(e.rr.Type, e.rr.Data); err == nil {
+				mname = name
+				break
+			}
+		}
+	}
+	value := fmt.Sprintf("%s hostmaster.%s %d 3600 600 604800 %d", mname, idx.origin, serial, defaultTTL)
+	data, err := dnswire.PackRData(dnswire.TypeSOA, value, 0, idx.origin)
+	if err != nil {
+		return dnswire.RR{}, err
+	}
+	return dnswire.RR{Name: idx.origin, Type: dnswire.TypeSOA, Class: dnswire.ClassINET, TTL: 3600, Data: data}, nil
+}
+
+// maxChase bounds CNAME chains followed within a zone.
+const maxChase = 8
+
+// lookup answers qname and qtype from the zone for a client in country,
+// which is empty when unknown. It returns the answers and whether the
+// final name exists.
+func (z *zoneIndex) lookup(qname string, qtype uint16, country string, geo bool) ([]dnswire.RR, bool) {
+	var answers []dnswire.RR
+	name := qname
+	for i := 0; i <= maxChase; i++ {
+		entries, exists := z.node(name)
+		if !exists {
+			return answers, false
+		}
+		if qtype != dnswire.TypeCNAME && qtype != dnswire.TypeANY {
+			if cname := selectGeo(entries, dnswire.TypeCNAME, country, geo); len(cname) > 0 {
+				rr := withOwner(cname[0], name)
+				answers = append(answers, rr)
+				target, _, err := dnswire.UnpackRData(rr.Type, rr.Data)
+				if err != nil || !inZone(strings.ToLower(target), z.origin) {
+					// Resolvers chase targets outside the zone.
+					return answers, true
+				}
+				name = strings.ToLower(target)
+				continue
+			}
+		}
+		for _, rr := range selectGeo(entries, qtype, country, geo) {
+			answers = append(answers, withOwner(rr, name))
+		}
+		if name == z.origin && (qtype == dnswire.TypeSOA || qtype == dnswire.TypeANY) {
+			answers = append(answers, z.soa)
+		}
+		return answers, true
+	}
+	return answers, true
+}
+
+// node returns the entries at name, synthesized from a wildcard when the
+// name does not exist, and whether the name exists at all.
+func (z *zoneIndex) node(name string) ([]entry, bool) {
+	if z.exists[name] {
+		return z.nodes[name], true
+	}
+	// The closest encloser's wildcard covers names below it (RFC 4592).
+	for encloser := parent(name); ; encloser = parent(encloser) {
+		if z.exists[encloser] {
+			entries, ok := z.nodes["*."+encloser]
+			return entries, ok
+		}
+		if encloser == z.origin || encloser == "." {
+			return nil, false
+		}
+	}
+}
+
+// selectGeo filters entries of typ for a client in country. Variants for
+// the country win; otherwise the default entries apply. Without geo
+// simulation, sets that only have country variants answer with all of
+// them.
+func selectGeo(entries []entry, typ uint16, country string, geo bool) []dnswire.RR {
+	var matched, defaults, all []dnswire.RR
+	for _, e := range entries {
+		if typ != dnswire.TypeANY && e.rr.Type != typ {
+			continue
+		}
+		all = append(all, e.rr)
+		if len(e.countries) == 0 {
+			defaults = append(defaults, e.rr)
+			continue
+		}
+		for _, cc := range e.countries {
+			if country != "" && cc == country {
+				matched = append(matched, e.rr)
+				break
+			}
+		}
+	}
+	switch {
+	case len(matched) > 0:
+		return matched
+	case len(defaults) > 0 || geo:
+		return defaults
+	}
+	return all
+}
+
+func withOwner(rr dnswire.RR, owner string) dnswire.RR {
+	rr.Name = owner
+	return rr
+}
+
+func parent(name string) string {
+	if i := strings.IndexByte(name, '.'); i >= 0 && i < len(name)-1 {
+		return name[i+1:]
+	}
+	return "."
+}
+
+func inZone(name, origin string) bool {
+	return name == origin || strings.HasSuffix(name, "."+origin)
+}
@@ -96,10 +96,7 @@ func TestCheckNameserver(t *testing.T) {
 		}
 		json.NewEncoder(w).Encode(NameserverCheckResponse{
 			Domain: Domain{ID: "domain-1", Name: "example.com."},
-			Check: struct {
-				Valid  bool   `json:"valid"`
-				Status string `json:"status"`
-			}{Valid: true, Status: "valid"},
+			Check:  NameserverCheck{Valid: true, Status: NameserverValid},
 		})
 	}))
 	defer server.Close()
@@ -113,7 +110,7 @@ func TestCheckNameserver(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if !resp.Check.Valid {
+	if !resp.Check.Valid || !resp.Check.Status.IsVerified() {
 		t.Fatalf("expected valid check")
 	}
 }
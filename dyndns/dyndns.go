@@ -0,0 +1,349 @@
+// Package dyndns implements the dyndns2 update protocol spoken by routers
+// and NAS devices, so that they can update Enzonix records without an API
+// key. A Handler answers requests like
+//
+//	GET /nic/update?hostname=home.example.com&myip=203.0.113.5
+//
+// authenticated with HTTP basic auth against per-hostname credentials, and
+// replies with the protocol's return codes: "good", "nochg", "nohost",
+// "badauth", "notfqdn", "numhost" and "911".
+package dyndns
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/ddns"
+)
+
+// Return codes of the dyndns2 protocol.
+const (
+	Good    = "good"
+	NoChg   = "nochg"
+	NoHost  = "nohost"
+	BadAuth = "badauth"
+	NotFQDN = "notfqdn"
+	NumHost = "numhost"
+	DNSErr  = "911"
+)
+
+// maxHosts is the number of hostnames accepted in one request.
+const maxHosts = 20
+
+// Credential allows Username to update Host.
+type Credential struct {
+	Host     string
+	Username string
+	Password string
+}
+
+// ParseCredentials reads credentials, one "host username password" line
+// each. Blank lines and lines starting with # are ignored.
+func ParseCredentials(r io.Reader) ([]Credential, error) {
+	var creds []Credential
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("dyndns: line %d: want host, username and password", line)
+		}
+		creds = append(creds, Credential{Host: fields[0], Username: fields[1], Password: fields[2]})
+	}
+	return creds, scanner.Err()
+}
+
+// LoadCredentials reads a credentials file; see ParseCredentials.
+func LoadCredentials(path string) ([]Credential, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	creds, err := ParseCredentials(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return creds, nil
+}
+
+// Options configures a Handler.
+type Options struct {
+	Credentials []Credential
+	// TTL of updated records. Zero keeps the records' TTL.
+	TTL int
+	// TrustProxy takes the client address from X-Forwarded-For when a
+	// request has no myip parameter. Enable it only behind a proxy that
+	// sets the header.
+	TrustProxy bool
+	// DomainCacheTTL is how long the account's domain list is reused;
+	// defaults to 1m.
+	DomainCacheTTL time.Duration
+	// AddressCacheTTL is how long an applied address is trusted before
+	// the record is checked again, so that changes made elsewhere are
+	// corrected; defaults to 10m.
+	AddressCacheTTL time.Duration
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Handler serves dyndns2 update requests.
+type Handler struct {
+	client *enzonix.Client
+	opts   Options
+	// creds maps a lowercased host to the SHA-256 digests of its
+	// "username:password" pairs.
+	creds map[string][][32]byte
+
+	mu        sync.Mutex
+	domains   []enzonix.Domain
+	domainsAt time.Time
+	// applied maps "host/type" to the address last applied, so that
+	// devices polling with an unchanged address make no API calls.
+	applied map[string]appliedAddr
+}
+
+type appliedAddr struct {
+	addr string
+	at   time.Time
+}
+
+// New returns a handler updating records through client.
+func New(client *enzonix.Client, opts Options) *Handler {
+	if opts.DomainCacheTTL <= 0 {
+		opts.DomainCacheTTL = time.Minute
+	}
+	if opts.AddressCacheTTL <= 0 {
+		opts.AddressCacheTTL = 10 * time.Minute
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	h := &Handler{client: client, opts: opts, creds: map[string][][32]byte{}, applied: map[string]appliedAddr{}}
+	for _, c := range opts.Credentials {
+		host := normalizeHost(c.Host)
+		h.creds[host] = append(h.creds[host], sha256.Sum256([]byte(c.Username+":"+c.Password)))
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="dyndns"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, BadAuth+"\n")
+		return
+	}
+
+	var hosts []string
+	for _, host := range strings.Split(r.FormValue("hostname"), ",") {
+		if host = normalizeHost(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	switch {
+	case len(hosts) == 0:
+		io.WriteString(w, NotFQDN+"\n")
+		return
+	case len(hosts) > maxHosts:
+		io.WriteString(w, NumHost+"\n")
+		return
+	}
+	// Credentials that match none of the hosts are rejected outright;
+	// hosts the user may not update answer nohost.
+	authorized := make([]bool, len(hosts))
+	anyAuthorized := false
+	for i, host := range hosts {
+		authorized[i] = h.authorized(host, user, pass)
+		anyAuthorized = anyAuthorized || authorized[i]
+	}
+	if !anyAuthorized {
+		h.opts.Logger.WarnContext(r.Context(), "dyndns authentication failed", slog.String("user", user), slog.Any("hosts", hosts))
+		io.WriteString(w, BadAuth+"\n")
+		return
+	}
+
+	addrs, err := h.addresses(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i, host := range hosts {
+		code := NoHost
+		if authorized[i] {
+			code = h.update(r.Context(), host, addrs)
+		}
+		fmt.Fprintln(w, code)
+	}
+}
+
+// update applies addrs to host and returns its response line.
+func (h *Handler) update(ctx context.Context, host string, addrs []netip.Addr) string {
+	if !strings.Contains(host, ".") {
+		return NotFQDN
+	}
+	changed := false
+	var (
+		domain enzonix.Domain
+		name   string
+		looked bool
+	)
+	for _, addr := range addrs {
+		typ := ddns.IPv4.Type()
+		if addr.Is6() {
+			typ = ddns.IPv6.Type()
+		}
+		if h.cached(host, typ) == addr.String() {
+			continue
+		}
+		if !looked {
+			domains, err := h.listDomains(ctx)
+			if err != nil {
+				h.opts.Logger.ErrorContext(ctx, "dyndns list domains", slog.Any("error", err))
+				return DNSErr
+			}
+			var ok bool
+			if domain, name, ok = ddns.SplitHost(domains, host); !ok {
+				return NoHost
+			}
+			looked = true
+		}
+		res, err := h.client.ReplaceRRSet(ctx, domain.ID, name, typ, []string{addr.String()}, h.opts.TTL)
+		if err != nil {
+			h.opts.Logger.ErrorContext(ctx, "dyndns update", slog.String("host", host), slog.String("type", typ), slog.Any("error", err))
+			// The record was updated; only its audit entry is missing.
+			if !enzonix.IsAuditOnly(err) {
+				return DNSErr
+			}
+		}
+		h.remember(host, typ, addr.String())
+		if res.Changed() {
+			changed = true
+			h.opts.Logger.InfoContext(ctx, "dyndns record updated", slog.String("host", host), slog.String("type", typ), slog.String("address", addr.String()))
+		}
+	}
+	code := NoChg
+	if changed {
+		code = Good
+	}
+	parts := []string{code}
+	for _, addr := range addrs {
+		parts = append(parts, addr.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func (h *Handler) authorized(host, user, pass string) bool {
+	digest := sha256.Sum256([]byte(user + ":" + pass))
+	ok := false
+	for _, want := range h.creds[host] {
+		if subtle.ConstantTimeCompare(digest[:], want[:]) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// addresses returns the myip addresses, at most one per family, or the
+// client's address when there are none.
+func (h *Handler) addresses(r *http.Request) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	var have4, have6 bool
+	for _, s := range strings.Split(r.FormValue("myip"), ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid myip %q", s)
+		}
+		addr = addr.Unmap()
+		if (addr.Is4() && have4) || (addr.Is6() && have6) {
+			return nil, errors.New("more than one address per family in myip")
+		}
+		have4, have6 = have4 || addr.Is4(), have6 || addr.Is6()
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) > 0 {
+		return addrs, nil
+	}
+	remote := r.RemoteAddr
+	if h.opts.TrustProxy {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			remote = strings.TrimSpace(first)
+		}
+	}
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	addr, err := netip.ParseAddr(remote)
+	if err != nil {
+		return nil, errors.New("cannot determine client address")
+	}
+	return []netip.Addr{addr.Unmap()}, nil
+}
+
+func (h *Handler) listDomains(ctx context.Context) ([]enzonix.Domain, error) {
+	h.mu.Lock()
+	if h.domains != nil && time.Since(h.domainsAt) < h.opts.DomainCacheTTL {
+		defer h.mu.Unlock()
+		return h.domains, nil
+	}
+	h.mu.Unlock()
+
+	domains, err := h.client.ListDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	h.domains, h.domainsAt = domains, time.Now()
+	h.mu.Unlock()
+	return domains, nil
+}
+
+func (h *Handler) cached(host, typ string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	a := h.applied[host+"/"+typ]
+	if time.Since(a.at) >= h.opts.AddressCacheTTL {
+		return ""
+	}
+	return a.addr
+}
+
+func (h *Handler) remember(host, typ, addr string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.applied[host+"/"+typ] = appliedAddr{addr: addr, at: time.Now()}
+}
+
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+}
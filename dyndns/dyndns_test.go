@@ -0,0 +1,162 @@
+package dyndns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/enzonixtest"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+func newTestGateway(t *testing.T, clientOpts ...enzonix.Option) (*fakeapi.Server, *httptest.Server) {
+	t.Helper()
+	fake, client := enzonixtest.NewClient(t, clientOpts...)
+	creds, err := ParseCredentials(strings.NewReader(`
+# host user password
+home.example.com  router  s3cret
+nas.example.com   router  s3cret
+nas.example.com   nas     other
+`))
+	if err != nil {
+		t.Fatalf("credentials: %v", err)
+	}
+	h := New(client, Options{Credentials: creds, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	mux := http.NewServeMux()
+	mux.Handle("/nic/update", h)
+	gw := httptest.NewServer(mux)
+	t.Cleanup(gw.Close)
+	return fake, gw
+}
+
+func get(t *testing.T, gw *httptest.Server, user, pass, query string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, gw.URL+"/nic/update?"+query, nil)
+	if user != "" {
+		req.SetBasicAuth(user, pass)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, strings.TrimSpace(string(body))
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	fake, gw := newTestGateway(t)
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "home", Type: "A", TTL: 120, Value: "198.51.100.1"})
+
+	for _, tc := range []struct {
+		user, pass, query string
+		status            int
+		body              string
+	}{
+		{"", "", "hostname=home.example.com", http.StatusUnauthorized, "badauth"},
+		{"router", "wrong", "hostname=home.example.com&myip=203.0.113.5", http.StatusOK, "badauth"},
+		{"nas", "other", "hostname=home.example.com&myip=203.0.113.5", http.StatusOK, "badauth"},
+		{"router", "s3cret", "myip=203.0.113.5", http.StatusOK, "notfqdn"},
+		{"router", "s3cret", "hostname=home.example.com&myip=203.0.113.5", http.StatusOK, "good 203.0.113.5"},
+		{"router", "s3cret", "hostname=home.example.com&myip=203.0.113.5", http.StatusOK, "nochg 203.0.113.5"},
+		{"router", "s3cret", "hostname=HOME.example.com,other.example.com&myip=203.0.113.5", http.StatusOK, "nochg 203.0.113.5\nnohost"},
+		{"nas", "other", "hostname=nas.example.com&myip=203.0.113.9,2001:db8::9", http.StatusOK, "good 203.0.113.9 2001:db8::9"},
+		{"nas", "other", "hostname=nas.example.com&myip=not-an-ip", http.StatusBadRequest, "invalid myip \"not-an-ip\""},
+	} {
+		status, body := get(t, gw, tc.user, tc.pass, tc.query)
+		if status != tc.status || body != tc.body {
+			t.Errorf("%s as %q: got %d %q, want %d %q", tc.query, tc.user, status, body, tc.status, tc.body)
+		}
+	}
+
+	got := map[string]string{}
+	for _, r := range fake.Records(domain.ID) {
+		got[r.Name+" "+r.Type] = r.Value
+		if r.Name == "home" && r.TTL != 120 {
+			t.Errorf("TTL changed: %+v", r)
+		}
+	}
+	if len(got) != 3 || got["home A"] != "203.0.113.5" || got["nas A"] != "203.0.113.9" || got["nas AAAA"] != "2001:db8::9" {
+		t.Fatalf("unexpected records %v", got)
+	}
+}
+
+func TestHandlerAuditFailure(t *testing.T) {
+	t.Parallel()
+
+	failing := enzonix.AuditSinkFunc(func(context.Context, enzonix.AuditEntry) error { return errors.New("disk full") })
+	fake, gw := newTestGateway(t, enzonix.WithAuditSink(failing))
+	domain := fake.AddDomain("example.com")
+
+	// The record was created, so the client must not retry.
+	if _, body := get(t, gw, "router", "s3cret", "hostname=home.example.com&myip=203.0.113.5"); body != "good 203.0.113.5" {
+		t.Fatalf("got %q", body)
+	}
+	if records := fake.Records(domain.ID); len(records) != 1 || records[0].Value != "203.0.113.5" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+}
+
+func TestHandlerClientAddress(t *testing.T) {
+	t.Parallel()
+
+	fake, gw := newTestGateway(t)
+	domain := fake.AddDomain("example.com")
+	if _, body := get(t, gw, "router", "s3cret", "hostname=home.example.com"); body != "good 127.0.0.1" {
+		t.Fatalf("unexpected response %q", body)
+	}
+	if r := fake.Records(domain.ID); len(r) != 1 || r[0].Value != "127.0.0.1" {
+		t.Fatalf("unexpected records %+v", r)
+	}
+}
+
+func TestHandlerUnknownDomain(t *testing.T) {
+	t.Parallel()
+
+	fake, gw := newTestGateway(t)
+	fake.AddDomain("example.org")
+	if _, body := get(t, gw, "router", "s3cret", "hostname=home.example.com&myip=203.0.113.5"); body != "nohost" {
+		t.Fatalf("unexpected response %q", body)
+	}
+}
+
+func TestHandlerAddressCacheExpires(t *testing.T) {
+	t.Parallel()
+
+	fake, client := enzonixtest.NewClient(t)
+	domain := fake.AddDomain("example.com")
+	h := New(client, Options{
+		Credentials:     []Credential{{Host: "home.example.com", Username: "router", Password: "s3cret"}},
+		AddressCacheTTL: time.Millisecond,
+		Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	gw := httptest.NewServer(h)
+	t.Cleanup(gw.Close)
+
+	if _, body := get(t, gw, "router", "s3cret", "hostname=home.example.com&myip=203.0.113.5"); body != "good 203.0.113.5" {
+		t.Fatalf("got %q", body)
+	}
+	// The record is removed outside the gateway; once the cached address
+	// expires, the next poll restores it.
+	records := fake.Records(domain.ID)
+	if err := client.DeleteRecord(context.Background(), records[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, body := get(t, gw, "router", "s3cret", "hostname=home.example.com&myip=203.0.113.5"); body != "good 203.0.113.5" {
+		t.Fatalf("expected the record to be restored, got %q", body)
+	}
+	if records := fake.Records(domain.ID); len(records) != 1 || records[0].Value != "203.0.113.5" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+}
@@ -0,0 +1,68 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+)
+
+// writeDNSControl renders a dnsconfig.js domain block. DNSControl has no
+// country variants; they are reported.
+func writeDNSControl(w io.Writer, zone string, records []enzonix.Record) ([]Warning, error) {
+	records, warnings := flatten(records)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "// Records of %s exported from Enzonix.\n", zone)
+	fmt.Fprintln(bw, `var REG_NONE = NewRegistrar("none");`)
+	fmt.Fprintln(bw, `var DSP_ENZONIX = NewDnsProvider("enzonix");`)
+	fmt.Fprintln(bw)
+	fmt.Fprintf(bw, "D(%s, REG_NONE, DnsProvider(DSP_ENZONIX),\n", jsString(zone))
+	for _, r := range records {
+		args, err := dnsControlArgs(r, zone)
+		if err != nil {
+			warnings = append(warnings, Warning{Record: r, Message: err.Error() + "; skipped"})
+			continue
+		}
+		fmt.Fprintf(bw, "\t%s(%s, TTL(%d)),\n", r.Type, strings.Join(append([]string{jsString(r.Name)}, args...), ", "), r.TTL)
+	}
+	fmt.Fprintln(bw, "END);")
+	return warnings, bw.Flush()
+}
+
+// dnsControlArgs returns the arguments of a record function after the
+// name.
+func dnsControlArgs(r enzonix.Record, zone string) ([]string, error) {
+	switch r.Type {
+	case "A", "AAAA", "TXT":
+		return []string{jsString(r.Value)}, nil
+	case "CNAME", "NS", "PTR":
+		return []string{jsString(fqdn(r.Value, zone))}, nil
+	case "MX":
+		return []string{fmt.Sprint(r.Priority), jsString(fqdn(r.Value, zone))}, nil
+	case "SRV":
+		weight, port, target, err := splitSRV(r.Value)
+		if err != nil {
+			return nil, err
+		}
+		return []string{fmt.Sprint(r.Priority), fmt.Sprint(weight), fmt.Sprint(port), jsString(fqdn(target, zone))}, nil
+	case "CAA":
+		flags, tag, value, err := splitCAA(r.Value)
+		if err != nil {
+			return nil, err
+		}
+		args := []string{jsString(tag), jsString(value)}
+		if flags&128 != 0 {
+			args = append(args, "CAA_CRITICAL")
+		}
+		return args, nil
+	}
+	return nil, fmt.Errorf("record type not supported by DNSControl")
+}
+
+func jsString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
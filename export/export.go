@@ -0,0 +1,229 @@
+// Package export renders a domain's records in formats other tools read:
+// structured JSON and YAML, CSV for spreadsheets, Terraform resources,
+// OctoDNS YAML and DNSControl JavaScript. ExportBindZone covers BIND.
+//
+// TTLs, priorities and country codes are kept wherever the format can
+// express them. Records a format cannot express are adapted or skipped,
+// and each adaptation is reported as a Warning.
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/yamlite"
+)
+
+// Format is an export format.
+type Format string
+
+// Export formats.
+const (
+	JSON       Format = "json"
+	YAML       Format = "yaml"
+	CSV        Format = "csv"
+	Terraform  Format = "terraform"
+	OctoDNS    Format = "octodns"
+	DNSControl Format = "dnscontrol"
+)
+
+// Formats lists the supported formats.
+func Formats() []Format {
+	return []Format{JSON, YAML, CSV, Terraform, OctoDNS, DNSControl}
+}
+
+// ParseFormat returns the format named s; "tf" stands for Terraform.
+func ParseFormat(s string) (Format, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "tf" {
+		return Terraform, nil
+	}
+	for _, f := range Formats() {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("export: unknown format %q", s)
+}
+
+// Warning reports a record the format cannot express as is.
+type Warning struct {
+	Record  enzonix.Record
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s %s %s: %s", w.Record.Name, w.Record.Type, w.Record.Value, w.Message)
+}
+
+// Zone is the document of the JSON and YAML exports.
+type Zone struct {
+	Domain  string   `json:"domain"`
+	Records []Record `json:"records"`
+}
+
+// Record is a record in the JSON and YAML exports.
+type Record struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	TTL          int      `json:"ttl"`
+	Priority     int      `json:"priority,omitempty"`
+	Value        string   `json:"value"`
+	CountryCodes []string `json:"country_codes,omitempty"`
+}
+
+// csvHeader is the header row of CSV exports.
+var csvHeader = []string{"name", "type", "ttl", "priority", "value", "country_codes"}
+
+// Write renders the records of domain to w. Records are sorted by name and
+// type; SOA records are left out.
+func Write(w io.Writer, f Format, domain enzonix.Domain, records []enzonix.Record) ([]Warning, error) {
+	zone := strings.TrimSuffix(domain.Name, ".")
+	records = sorted(records)
+	switch f {
+	case JSON, YAML:
+		doc := Zone{Domain: zone, Records: make([]Record, 0, len(records))}
+		for _, r := range records {
+			doc.Records = append(doc.Records, Record{Name: r.Name, Type: r.Type, TTL: r.TTL, Priority: r.Priority, Value: r.Value, CountryCodes: r.CountryCodes})
+		}
+		var data []byte
+		var err error
+		if f == JSON {
+			data, err = json.MarshalIndent(doc, "", "  ")
+			data = append(data, '\n')
+		} else {
+			data, err = yamlite.Marshal(doc)
+		}
+		if err != nil {
+			return nil, err
+		}
+		_, err = w.Write(data)
+		return nil, err
+	case CSV:
+		cw := csv.NewWriter(w)
+		cw.Write(csvHeader)
+		for _, r := range records {
+			cw.Write([]string{r.Name, r.Type, strconv.Itoa(r.TTL), strconv.Itoa(r.Priority), r.Value, strings.Join(r.CountryCodes, " ")})
+		}
+		cw.Flush()
+		return nil, cw.Error()
+	case Terraform:
+		return writeTerraform(w, domain, records)
+	case OctoDNS:
+		return writeOctoDNS(w, zone, records)
+	case DNSControl:
+		return writeDNSControl(w, zone, records)
+	}
+	return nil, fmt.Errorf("export: unknown format %q", f)
+}
+
+// Domain exports the records of domainID through client.
+func Domain(ctx context.Context, client *enzonix.Client, domainID string, f Format, w io.Writer) ([]Warning, error) {
+	domains, err := client.ListDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range domains {
+		if d.ID != domainID {
+			continue
+		}
+		records, err := client.ListDomainRecords(ctx, domainID)
+		if err != nil {
+			return nil, err
+		}
+		return Write(w, f, d, records)
+	}
+	return nil, fmt.Errorf("export: domain %s not found", domainID)
+}
+
+func sorted(records []enzonix.Record) []enzonix.Record {
+	out := make([]enzonix.Record, 0, len(records))
+	for _, r := range records {
+		if !strings.EqualFold(r.Type, "SOA") {
+			r.Type = strings.ToUpper(r.Type)
+			out = append(out, r)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Name != b.Name {
+			return a.Name == "@" || (b.Name != "@" && a.Name < b.Name)
+		}
+		return a.Type < b.Type
+	})
+	return out
+}
+
+// flatten drops country variants for formats without geo answers. Sets
+// with a default answer keep it; sets made of variants only keep all of
+// them, unrestricted.
+func flatten(records []enzonix.Record) ([]enzonix.Record, []Warning) {
+	hasDefault := map[string]bool{}
+	for _, r := range records {
+		if len(r.CountryCodes) == 0 {
+			hasDefault[r.Name+" "+r.Type] = true
+		}
+	}
+	var out []enzonix.Record
+	var warnings []Warning
+	for _, r := range records {
+		if len(r.CountryCodes) > 0 {
+			if hasDefault[r.Name+" "+r.Type] {
+				warnings = append(warnings, Warning{Record: r, Message: "country variant dropped; the format has no geo answers"})
+				continue
+			}
+			warnings = append(warnings, Warning{Record: r, Message: "country codes dropped; the format has no geo answers"})
+			r.CountryCodes = nil
+		}
+		out = append(out, r)
+	}
+	return out, warnings
+}
+
+// fqdn returns a target name fully qualified with a trailing dot. "@" and
+// single labels are relative to zone; other names without a trailing dot
+// are taken as absolute, as the API stores them.
+func fqdn(name, zone string) string {
+	switch {
+	case name == "@" || name == "":
+		return zone + "."
+	case strings.HasSuffix(name, "."):
+		return name
+	case strings.Contains(name, "."):
+		return name + "."
+	}
+	return name + "." + zone + "."
+}
+
+// splitSRV parses an SRV value, "weight port target".
+func splitSRV(value string) (weight, port int, target string, err error) {
+	fields := strings.Fields(value)
+	if len(fields) == 3 {
+		weight, err = strconv.Atoi(fields[0])
+		if err == nil {
+			port, err = strconv.Atoi(fields[1])
+		}
+		if err == nil {
+			return weight, port, fields[2], nil
+		}
+	}
+	return 0, 0, "", fmt.Errorf("SRV value %q is not \"weight port target\"", value)
+}
+
+// splitCAA parses a CAA value, "flags tag \"value\"".
+func splitCAA(value string) (flags int, tag, v string, err error) {
+	fields := strings.SplitN(value, " ", 3)
+	if len(fields) == 3 {
+		if flags, err = strconv.Atoi(fields[0]); err == nil {
+			return flags, fields[1], strings.Trim(fields[2], `"`), nil
+		}
+	}
+	return 0, "", "", fmt.Errorf("CAA value %q is not \"flags tag value\"", value)
+}
@@ -0,0 +1,172 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/yamlite"
+)
+
+var (
+	testDomain  = enzonix.Domain{ID: "dom-1", Name: "example.com."}
+	testRecords = []enzonix.Record{
+		{ID: "r1", Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"},
+		{ID: "r2", Name: "www", Type: "A", TTL: 300, Value: "192.0.2.9", CountryCodes: []string{"DE"}},
+		{ID: "r3", Name: "@", Type: "MX", TTL: 3600, Priority: 10, Value: "mx.example.com"},
+		{ID: "r4", Name: "@", Type: "TXT", TTL: 3600, Value: "v=spf1 include:_spf.example.net; -all"},
+		{ID: "r5", Name: "_sip._tcp", Type: "SRV", TTL: 600, Priority: 5, Value: "10 5060 sip"},
+		{ID: "r6", Name: "@", Type: "CAA", TTL: 3600, Value: `0 issue "letsencrypt.org"`},
+		{ID: "r7", Name: "eu", Type: "CNAME", TTL: 300, Value: "www.example.com", CountryCodes: []string{"FR"}},
+		{ID: "r8", Name: "@", Type: "SOA", TTL: 3600, Value: "ns1.enzonix.com. hostmaster.example.com. 1 3600 600 604800 300"},
+	}
+)
+
+func render(t *testing.T, f Format) (string, []Warning) {
+	t.Helper()
+	var b bytes.Buffer
+	warnings, err := Write(&b, f, testDomain, testRecords)
+	if err != nil {
+		t.Fatalf("%s: %v", f, err)
+	}
+	return b.String(), warnings
+}
+
+func TestStructured(t *testing.T) {
+	t.Parallel()
+
+	out, _ := render(t, JSON)
+	var zone Zone
+	if err := json.Unmarshal([]byte(out), &zone); err != nil {
+		t.Fatal(err)
+	}
+	if zone.Domain != "example.com" || len(zone.Records) != 7 || zone.Records[0].Name != "@" {
+		t.Fatalf("unexpected JSON export %+v", zone)
+	}
+	out, _ = render(t, YAML)
+	var fromYAML Zone
+	if err := yamlite.Unmarshal([]byte(out), &fromYAML); err != nil {
+		t.Fatal(err)
+	}
+	if len(fromYAML.Records) != 7 || fromYAML.Records[6].CountryCodes[0] != "DE" {
+		t.Fatalf("unexpected YAML export %+v", fromYAML)
+	}
+
+	out, _ = render(t, CSV)
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 8 || strings.Join(rows[2], ",") != "@,MX,3600,10,mx.example.com," || rows[5][5] != "FR" {
+		t.Fatalf("unexpected CSV export %q", rows)
+	}
+}
+
+func TestTerraform(t *testing.T) {
+	t.Parallel()
+
+	out, warnings := render(t, Terraform)
+	if len(warnings) != 0 {
+		t.Fatalf("unexpected warnings %v", warnings)
+	}
+	for _, want := range []string{
+		"resource \"enzonix_record\" \"apex_mx\" {\n  domain_id = \"dom-1\"\n  name      = \"@\"\n  type      = \"MX\"\n  value     = \"mx.example.com\"\n  ttl       = 3600\n  priority  = 10\n}",
+		`value     = "0 issue \"letsencrypt.org\""`,
+		`country_codes = ["DE"]`,
+		"resource \"enzonix_record\" \"www_a_2\"",
+		"import {\n  to = enzonix_record.www_a_2\n  id = \"r2\"\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if got := hclString("${var} %{if}"); got != `"$${var} %%{if}"` {
+		t.Fatalf("template sequences not escaped: %s", got)
+	}
+}
+
+func TestOctoDNS(t *testing.T) {
+	t.Parallel()
+
+	out, warnings := render(t, OctoDNS)
+	if len(warnings) != 2 {
+		t.Fatalf("expected warnings for the country codes, got %v", warnings)
+	}
+	doc, err := yamlite.Parse([]byte(out))
+	if err != nil {
+		t.Fatalf("parse: %v\n%s", err, out)
+	}
+	zone := doc.(map[string]any)
+	apex := zone[""].([]any)
+	if len(apex) != 3 {
+		t.Fatalf("unexpected apex %v", apex)
+	}
+	mx := apex[1].(map[string]any)["value"].(map[string]any)
+	if mx["exchange"] != "mx.example.com." {
+		t.Fatalf("unexpected MX %v", mx)
+	}
+	if txt := apex[2].(map[string]any)["value"]; txt != `v=spf1 include:_spf.example.net\; -all` {
+		t.Fatalf("semicolon not escaped: %v", txt)
+	}
+	if srv := zone["_sip._tcp"].(map[string]any)["value"].(map[string]any); srv["target"] != "sip.example.com." {
+		t.Fatalf("unexpected SRV %v", srv)
+	}
+	if www := zone["www"].(map[string]any); www["value"] != "192.0.2.1" {
+		t.Fatalf("country variant not dropped: %v", www)
+	}
+}
+
+func TestDNSControl(t *testing.T) {
+	t.Parallel()
+
+	out, warnings := render(t, DNSControl)
+	if len(warnings) != 2 {
+		t.Fatalf("expected warnings for the country codes, got %v", warnings)
+	}
+	for _, want := range []string{
+		`D("example.com", REG_NONE, DnsProvider(DSP_ENZONIX),`,
+		`MX("@", 10, "mx.example.com.", TTL(3600)),`,
+		`SRV("_sip._tcp", 5, 10, 5060, "sip.example.com.", TTL(600)),`,
+		`CAA("@", "issue", "letsencrypt.org", TTL(3600)),`,
+		`CNAME("eu", "www.example.com.", TTL(300)),`,
+		"END);",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "192.0.2.9") || strings.Contains(out, "SOA") {
+		t.Fatalf("unexpected records in\n%s", out)
+	}
+}
+
+func TestDomain(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeapi.New("key")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client, _ := enzonix.NewClient("key", enzonix.WithBaseURL(server.URL))
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+
+	var b bytes.Buffer
+	if _, err := Domain(context.Background(), client, domain.ID, CSV, &b); err != nil {
+		t.Fatal(err)
+	}
+	if want := "name,type,ttl,priority,value,country_codes\nwww,A,300,0,192.0.2.1,\n"; b.String() != want {
+		t.Fatalf("got %q, want %q", b.String(), want)
+	}
+	if _, err := Domain(context.Background(), client, "missing", CSV, &b); err == nil {
+		t.Fatal("expected an error for an unknown domain")
+	}
+	if f, err := ParseFormat("TF"); err != nil || f != Terraform {
+		t.Fatalf("ParseFormat: %v, %v", f, err)
+	}
+}
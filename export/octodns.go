@@ -0,0 +1,116 @@
+package export
+
+import (
+	"io"
+	"strings"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/yamlite"
+)
+
+// octoRecord is one record set of an OctoDNS zone file.
+type octoRecord struct {
+	Type   string `json:"type"`
+	TTL    int    `json:"ttl"`
+	Value  any    `json:"value,omitempty"`
+	Values []any  `json:"values,omitempty"`
+}
+
+type octoMX struct {
+	Exchange   string `json:"exchange"`
+	Preference int    `json:"preference"`
+}
+
+type octoSRV struct {
+	Port     int    `json:"port"`
+	Priority int    `json:"priority"`
+	Target   string `json:"target"`
+	Weight   int    `json:"weight"`
+}
+
+type octoCAA struct {
+	Flags int    `json:"flags"`
+	Tag   string `json:"tag"`
+	Value string `json:"value"`
+}
+
+// octoTypes are the record types OctoDNS zone files hold.
+var octoTypes = map[string]bool{"A": true, "AAAA": true, "CAA": true, "CNAME": true, "MX": true, "NS": true, "PTR": true, "SRV": true, "TXT": true}
+
+// writeOctoDNS renders an OctoDNS YAML zone file: record sets keyed by
+// name, the apex under an empty key and a list where a name has several
+// types. OctoDNS keeps one TTL per set and has no country variants outside
+// its dynamic records; both are reported.
+func writeOctoDNS(w io.Writer, zone string, records []enzonix.Record) ([]Warning, error) {
+	records, warnings := flatten(records)
+	sets := map[string][]*octoRecord{}
+	var current *octoRecord
+	currentKey := ""
+	for _, r := range records {
+		if !octoTypes[r.Type] {
+			warnings = append(warnings, Warning{Record: r, Message: "record type not supported by OctoDNS; skipped"})
+			continue
+		}
+		value, err := octoValue(r, zone)
+		if err != nil {
+			warnings = append(warnings, Warning{Record: r, Message: err.Error() + "; skipped"})
+			continue
+		}
+		name := r.Name
+		if name == "@" {
+			name = ""
+		}
+		if key := name + " " + r.Type; current == nil || key != currentKey {
+			current, currentKey = &octoRecord{Type: r.Type, TTL: r.TTL}, key
+			sets[name] = append(sets[name], current)
+		} else if r.TTL != current.TTL {
+			warnings = append(warnings, Warning{Record: r, Message: "OctoDNS keeps one TTL per set; using the first record's"})
+		}
+		current.Values = append(current.Values, value)
+	}
+
+	doc := make(map[string]any, len(sets))
+	for name, list := range sets {
+		for _, set := range list {
+			if len(set.Values) == 1 {
+				set.Value, set.Values = set.Values[0], nil
+			}
+		}
+		if len(list) == 1 {
+			doc[name] = list[0]
+		} else {
+			doc[name] = list
+		}
+	}
+	data, err := yamlite.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(append([]byte("---\n"), data...))
+	return warnings, err
+}
+
+func octoValue(r enzonix.Record, zone string) (any, error) {
+	switch r.Type {
+	case "CNAME", "NS", "PTR":
+		return fqdn(r.Value, zone), nil
+	case "MX":
+		return octoMX{Exchange: fqdn(r.Value, zone), Preference: r.Priority}, nil
+	case "SRV":
+		weight, port, target, err := splitSRV(r.Value)
+		if err != nil {
+			return nil, err
+		}
+		return octoSRV{Port: port, Priority: r.Priority, Target: fqdn(target, zone), Weight: weight}, nil
+	case "CAA":
+		flags, tag, value, err := splitCAA(r.Value)
+		if err != nil {
+			return nil, err
+		}
+		return octoCAA{Flags: flags, Tag: tag, Value: value}, nil
+	case "TXT":
+		// OctoDNS requires semicolons to be escaped.
+		return strings.ReplaceAll(r.Value, ";", `\;`), nil
+	}
+	return r.Value, nil
+}
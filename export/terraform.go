@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+)
+
+// writeTerraform renders enzonix_record resources. Records with an ID get
+// an import block, so that `terraform plan` adopts the live records instead
+// of creating them again.
+func writeTerraform(w io.Writer, domain enzonix.Domain, records []enzonix.Record) ([]Warning, error) {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Records of %s exported from Enzonix.\n", strings.TrimSuffix(domain.Name, "."))
+	used := map[string]int{}
+	for _, r := range records {
+		label := terraformLabel(r, used)
+		attrs := [][2]string{
+			{"domain_id", hclString(domain.ID)},
+			{"name", hclString(r.Name)},
+			{"type", hclString(r.Type)},
+			{"value", hclString(r.Value)},
+			{"ttl", strconv.Itoa(r.TTL)},
+		}
+		if r.Priority != 0 || r.Type == "MX" || r.Type == "SRV" {
+			attrs = append(attrs, [2]string{"priority", strconv.Itoa(r.Priority)})
+		}
+		if len(r.CountryCodes) > 0 {
+			codes := make([]string, len(r.CountryCodes))
+			for i, cc := range r.CountryCodes {
+				codes[i] = hclString(cc)
+			}
+			attrs = append(attrs, [2]string{"country_codes", "[" + strings.Join(codes, ", ") + "]"})
+		}
+		// Align the equals signs the way terraform fmt does.
+		width := 0
+		for _, a := range attrs {
+			width = max(width, len(a[0]))
+		}
+		fmt.Fprintf(bw, "\nresource \"enzonix_record\" %s {\n", hclString(label))
+		for _, a := range attrs {
+			fmt.Fprintf(bw, "  %-*s = %s\n", width, a[0], a[1])
+		}
+		fmt.Fprintln(bw, "}")
+		if r.ID != "" {
+			fmt.Fprintf(bw, "\nimport {\n  to = enzonix_record.%s\n  id = %s\n}\n", label, hclString(r.ID))
+		}
+	}
+	return nil, bw.Flush()
+}
+
+// terraformLabel returns a unique resource name such as "www_a", "apex_mx"
+// or "www_a_2".
+func terraformLabel(r enzonix.Record, used map[string]int) string {
+	name := r.Name
+	if name == "@" || name == "" {
+		name = "apex"
+	}
+	var b strings.Builder
+	for _, c := range strings.ToLower(name + "_" + r.Type) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '_', c == '-':
+			b.WriteRune(c)
+		case c == '*':
+			b.WriteString("wildcard")
+		default:
+			b.WriteByte('_')
+		}
+	}
+	label := strings.Trim(b.String(), "_")
+	if label == "" || (label[0] >= '0' && label[0] <= '9') || label[0] == '-' {
+		label = "r_" + label
+	}
+	used[label]++
+	if n := used[label]; n > 1 {
+		label += "_" + strconv.Itoa(n)
+	}
+	return label
+}
+
+// hclString quotes s as an HCL string, escaping template sequences.
+func hclString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case (c == '$' || c == '%') && i+1 < len(s) && s[i+1] == '{':
+			b.WriteByte(c)
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
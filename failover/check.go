@@ -0,0 +1,104 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Check probes an endpoint; a nil error means healthy.
+type Check interface {
+	Check(ctx context.Context) error
+}
+
+// CheckFunc adapts a function to Check.
+type CheckFunc func(ctx context.Context) error
+
+// Check implements Check.
+func (f CheckFunc) Check(ctx context.Context) error { return f(ctx) }
+
+// HTTPCheck requests a URL and expects a successful status.
+type HTTPCheck struct {
+	URL string
+	// Method defaults to GET.
+	Method string
+	// Header is added to the request, e.g. a Host header.
+	Header http.Header
+	// ExpectStatus is the required status code. Zero accepts 2xx and 3xx.
+	ExpectStatus int
+	// Timeout bounds the request; defaults to 5s.
+	Timeout time.Duration
+	// Client defaults to a client that does not follow redirects.
+	Client *http.Client
+}
+
+var noRedirectClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+// Check implements Check.
+func (h HTTPCheck) Check(ctx context.Context) error {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	method := h.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, h.URL, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range h.Header {
+		req.Header[k] = v
+	}
+	if host := h.Header.Get("Host"); host != "" {
+		req.Host = host
+	}
+	client := h.Client
+	if client == nil {
+		client = noRedirectClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	res.Body.Close()
+
+	switch {
+	case h.ExpectStatus != 0 && res.StatusCode != h.ExpectStatus:
+		return fmt.Errorf("failover: %s returned %d, want %d", h.URL, res.StatusCode, h.ExpectStatus)
+	case h.ExpectStatus == 0 && (res.StatusCode < 200 || res.StatusCode >= 400):
+		return fmt.Errorf("failover: %s returned %d", h.URL, res.StatusCode)
+	}
+	return nil
+}
+
+// TCPCheck expects a TCP connection to Address ("host:port") to succeed.
+type TCPCheck struct {
+	Address string
+	// Timeout bounds the connection attempt; defaults to 5s.
+	Timeout time.Duration
+}
+
+// Check implements Check.
+func (t TCPCheck) Check(ctx context.Context) error {
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", t.Address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,311 @@
+// Package failover switches a record between a primary and a secondary
+// target based on health checks. A Controller probes the targets every
+// interval; after enough consecutive primary failures it points the
+// record at the secondary, lowering its TTL for the incident, and it
+// switches back once the primary has been healthy for a while.
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+)
+
+// State is the target a record currently points at.
+type State string
+
+const (
+	StatePrimary   State = "primary"
+	StateSecondary State = "secondary"
+)
+
+// Target is a record value and the check deciding whether it is healthy.
+type Target struct {
+	Value string
+	// Check is nil for targets assumed healthy.
+	Check Check
+}
+
+// Config describes the record to manage.
+type Config struct {
+	DomainID string
+	// Name and Type identify the record; the record without country codes
+	// is managed.
+	Name      string
+	Type      string
+	Primary   Target
+	Secondary Target
+	// Interval between probes; defaults to 30s.
+	Interval time.Duration
+	// FailThreshold is the number of consecutive primary failures that
+	// trigger a failover; defaults to 3.
+	FailThreshold int
+	// RecoverThreshold is the number of consecutive primary successes
+	// required to switch back; defaults to 5.
+	RecoverThreshold int
+	// IncidentTTL is set on the record while failed over, so that the
+	// switch back propagates quickly. Zero leaves the TTL alone.
+	IncidentTTL int
+	// TTL is set when switching back, and is required with IncidentTTL:
+	// a controller restarted during an incident has no other way to know
+	// the TTL to restore.
+	TTL int
+	// OnEvent is called after every switch and every failed update.
+	OnEvent func(Event)
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Event reports a switch.
+type Event struct {
+	Name   string
+	From   State
+	To     State
+	Record *enzonix.Record
+	// Reason is the last check error when failing over.
+	Reason error
+	// Err is set when updating the record failed; the switch is retried
+	// on the next probe. An update that was made but could not be audited
+	// still switches, with Err reporting the audit error.
+	Err  error
+	Time time.Time
+}
+
+// Metrics is a snapshot of the controller's counters.
+type Metrics struct {
+	State                State
+	Probes               int
+	PrimaryFailures      int
+	SecondaryFailures    int
+	ConsecutiveFailures  int
+	ConsecutiveSuccesses int
+	Failovers            int
+	Failbacks            int
+	UpdateErrors         int
+	LastProbe            time.Time
+	LastSwitch           time.Time
+	LastError            error
+}
+
+// Controller manages one record.
+type Controller struct {
+	client *enzonix.Client
+	cfg    Config
+
+	mu      sync.Mutex
+	metrics Metrics
+	loaded  bool
+}
+
+// New validates cfg and returns a controller. The initial state is read
+// from the record on the first probe.
+func New(client *enzonix.Client, cfg Config) (*Controller, error) {
+	switch {
+	case cfg.DomainID == "":
+		return nil, errors.New("failover: domain id is required")
+	case cfg.Name == "" || cfg.Type == "":
+		return nil, errors.New("failover: record name and type are required")
+	case cfg.Primary.Value == "" || cfg.Secondary.Value == "":
+		return nil, errors.New("failover: primary and secondary values are required")
+	case cfg.Primary.Check == nil:
+		return nil, errors.New("failover: the primary needs a check")
+	case cfg.IncidentTTL > 0 && cfg.TTL <= 0:
+		return nil, errors.New("failover: a TTL to restore is required with an incident TTL")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.FailThreshold <= 0 {
+		cfg.FailThreshold = 3
+	}
+	if cfg.RecoverThreshold <= 0 {
+		cfg.RecoverThreshold = 5
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Controller{client: client, cfg: cfg, metrics: Metrics{State: StatePrimary}}, nil
+}
+
+// Run probes every interval until ctx is done.
+func (c *Controller) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Probe(ctx); err != nil && ctx.Err() == nil {
+			c.cfg.Logger.ErrorContext(ctx, "failover probe failed", slog.String("name", c.cfg.Name), slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// State returns the current state.
+func (c *Controller) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.metrics.State
+}
+
+// Metrics returns a snapshot of the counters.
+func (c *Controller) Metrics() Metrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.metrics
+}
+
+// Probe runs the checks once and switches the record when a threshold is
+// reached. It returns record lookup and update errors.
+func (c *Controller) Probe(ctx context.Context) error {
+	if !c.isLoaded() {
+		if err := c.load(ctx); err != nil {
+			return err
+		}
+	}
+
+	primaryErr := c.cfg.Primary.Check.Check(ctx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	c.mu.Lock()
+	m := &c.metrics
+	m.Probes++
+	m.LastProbe = time.Now()
+	if primaryErr != nil {
+		m.PrimaryFailures++
+		m.ConsecutiveFailures++
+		m.ConsecutiveSuccesses = 0
+		m.LastError = primaryErr
+	} else {
+		m.ConsecutiveSuccesses++
+		m.ConsecutiveFailures = 0
+	}
+	state := m.State
+	failover := state == StatePrimary && m.ConsecutiveFailures >= c.cfg.FailThreshold
+	failback := state == StateSecondary && m.ConsecutiveSuccesses >= c.cfg.RecoverThreshold
+	c.mu.Unlock()
+
+	switch {
+	case failover:
+		// Failing over to an unhealthy secondary would not help.
+		if check := c.cfg.Secondary.Check; check != nil {
+			if err := check.Check(ctx); err != nil {
+				c.mu.Lock()
+				c.metrics.SecondaryFailures++
+				c.mu.Unlock()
+				c.cfg.Logger.WarnContext(ctx, "failover skipped, secondary unhealthy", slog.String("name", c.cfg.Name), slog.Any("error", err))
+				return nil
+			}
+		}
+		return c.switchTo(ctx, StateSecondary, primaryErr)
+	case failback:
+		return c.switchTo(ctx, StatePrimary, nil)
+	}
+	return nil
+}
+
+func (c *Controller) isLoaded() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loaded
+}
+
+// load derives the initial state from the record, so that a restarted
+// controller resumes a failover in progress.
+func (c *Controller) load(ctx context.Context) error {
+	record, err := c.record(ctx)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loaded = true
+	if sameValue(record.Value, c.cfg.Secondary.Value) {
+		c.metrics.State = StateSecondary
+	}
+	return nil
+}
+
+func (c *Controller) switchTo(ctx context.Context, to State, reason error) error {
+	record, err := c.record(ctx)
+	if err == nil {
+		req := enzonix.UpdateRecordRequest{}
+		if to == StateSecondary {
+			req.Value = &c.cfg.Secondary.Value
+			if c.cfg.IncidentTTL > 0 {
+				req.TTL = &c.cfg.IncidentTTL
+			}
+		} else {
+			req.Value = &c.cfg.Primary.Value
+			if c.cfg.TTL > 0 && c.cfg.TTL != record.TTL {
+				req.TTL = &c.cfg.TTL
+			}
+		}
+		record, err = c.client.UpdateRecord(ctx, record.ID, req)
+	}
+
+	switched := err == nil || enzonix.IsAuditOnly(err)
+	c.mu.Lock()
+	from := c.metrics.State
+	event := Event{Name: c.cfg.Name, From: from, To: to, Reason: reason, Err: err, Time: time.Now()}
+	if !switched {
+		c.metrics.UpdateErrors++
+		c.metrics.LastError = err
+	} else {
+		event.Record = record
+		c.metrics.State = to
+		c.metrics.LastSwitch = event.Time
+		c.metrics.ConsecutiveFailures, c.metrics.ConsecutiveSuccesses = 0, 0
+		if to == StateSecondary {
+			c.metrics.Failovers++
+		} else {
+			c.metrics.Failbacks++
+		}
+	}
+	c.mu.Unlock()
+
+	switch {
+	case !switched:
+		c.cfg.Logger.ErrorContext(ctx, "failover update failed", slog.String("name", c.cfg.Name), slog.String("to", string(to)), slog.Any("error", err))
+	case err != nil:
+		c.cfg.Logger.ErrorContext(ctx, "failover switched without audit entry", slog.String("name", c.cfg.Name), slog.String("from", string(from)), slog.String("to", string(to)), slog.Any("error", err))
+	default:
+		c.cfg.Logger.WarnContext(ctx, "failover switched", slog.String("name", c.cfg.Name), slog.String("from", string(from)), slog.String("to", string(to)))
+	}
+	if c.cfg.OnEvent != nil {
+		c.cfg.OnEvent(event)
+	}
+	if err != nil {
+		return fmt.Errorf("failover: switch %s to %s: %w", c.cfg.Name, to, err)
+	}
+	return nil
+}
+
+// record finds the managed record: the one of the name and type without
+// country codes.
+func (c *Controller) record(ctx context.Context) (*enzonix.Record, error) {
+	records, err := c.client.ListDomainRecords(ctx, c.cfg.DomainID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		if len(r.CountryCodes) == 0 && strings.EqualFold(r.Type, c.cfg.Type) && sameValue(r.Name, c.cfg.Name) {
+			return &r, nil
+		}
+	}
+	return nil, fmt.Errorf("failover: no %s record named %s", c.cfg.Type, c.cfg.Name)
+}
+
+func sameValue(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
@@ -0,0 +1,215 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/enzonixtest"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+// endpoint is a health endpoint whose status can be switched.
+func endpoint(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	status := &atomic.Int32{}
+	status.Store(http.StatusOK)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, status
+}
+
+func TestControllerFailsOverAndBack(t *testing.T) {
+	t.Parallel()
+
+	fake, client := enzonixtest.NewClient(t)
+	domain := fake.AddDomain("example.com")
+	record := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 3600, Value: "192.0.2.1"})
+	primary, primaryStatus := endpoint(t)
+	secondary, _ := endpoint(t)
+
+	var events []Event
+	c, err := New(client, Config{
+		DomainID:         domain.ID,
+		Name:             "www",
+		Type:             "A",
+		Primary:          Target{Value: "192.0.2.1", Check: HTTPCheck{URL: primary.URL + "/healthz"}},
+		Secondary:        Target{Value: "192.0.2.2", Check: HTTPCheck{URL: secondary.URL, ExpectStatus: http.StatusOK}},
+		FailThreshold:    2,
+		RecoverThreshold: 3,
+		IncidentTTL:      60,
+		TTL:              3600,
+		OnEvent:          func(e Event) { events = append(events, e) },
+		Logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	ctx := context.Background()
+	probe := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			if err := c.Probe(ctx); err != nil {
+				t.Fatalf("probe: %v", err)
+			}
+		}
+	}
+	current := func() fakeapi.Record {
+		for _, r := range fake.Records(domain.ID) {
+			if r.ID == record.ID {
+				return r
+			}
+		}
+		t.Fatal("record gone")
+		return fakeapi.Record{}
+	}
+
+	probe(2)
+	if c.State() != StatePrimary || len(events) != 0 {
+		t.Fatalf("unexpected switch while healthy: %v", events)
+	}
+
+	primaryStatus.Store(http.StatusServiceUnavailable)
+	probe(1)
+	if c.State() != StatePrimary {
+		t.Fatal("failed over before the threshold")
+	}
+	probe(1)
+	if c.State() != StateSecondary || current().Value != "192.0.2.2" || current().TTL != 60 {
+		t.Fatalf("expected failover, got state %s and record %+v", c.State(), current())
+	}
+	if len(events) != 1 || events[0].To != StateSecondary || events[0].Reason == nil {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	// Hysteresis: a flapping primary does not switch back.
+	primaryStatus.Store(http.StatusOK)
+	probe(2)
+	primaryStatus.Store(http.StatusInternalServerError)
+	probe(1)
+	primaryStatus.Store(http.StatusOK)
+	probe(2)
+	if c.State() != StateSecondary {
+		t.Fatal("switched back before the primary recovered")
+	}
+	probe(1)
+	if c.State() != StatePrimary || current().Value != "192.0.2.1" || current().TTL != 3600 {
+		t.Fatalf("expected failback with the original TTL, got state %s and record %+v", c.State(), current())
+	}
+
+	m := c.Metrics()
+	if m.Failovers != 1 || m.Failbacks != 1 || m.Probes != 10 || m.PrimaryFailures != 3 || m.State != StatePrimary {
+		t.Fatalf("unexpected metrics %+v", m)
+	}
+
+	// A restarted controller resumes from the record, and restores the
+	// configured TTL on failback.
+	api := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "api", Type: "A", TTL: 60, Value: "192.0.2.2"})
+	resumed, _ := New(client, Config{
+		DomainID: domain.ID, Name: "api", Type: "A",
+		Primary:          Target{Value: "192.0.2.1", Check: HTTPCheck{URL: primary.URL}},
+		Secondary:        Target{Value: "192.0.2.2"},
+		RecoverThreshold: 1,
+		IncidentTTL:      60,
+		TTL:              300,
+		Logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := resumed.Probe(ctx); err != nil || resumed.State() != StatePrimary {
+		t.Fatalf("expected resumed failback, got %s, %v", resumed.State(), err)
+	}
+	for _, r := range fake.Records(domain.ID) {
+		if r.ID == api.ID && (r.Value != "192.0.2.1" || r.TTL != 300) {
+			t.Fatalf("expected the configured TTL after a restart, got %+v", r)
+		}
+	}
+
+	if _, err := New(client, Config{
+		DomainID: domain.ID, Name: "api", Type: "A",
+		Primary:     Target{Value: "192.0.2.1", Check: HTTPCheck{URL: primary.URL}},
+		Secondary:   Target{Value: "192.0.2.2"},
+		IncidentTTL: 60,
+	}); err == nil {
+		t.Fatal("expected an error for an incident TTL without a TTL to restore")
+	}
+}
+
+func TestControllerSkipsUnhealthySecondary(t *testing.T) {
+	t.Parallel()
+
+	fake, client := enzonixtest.NewClient(t)
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "db", Type: "A", TTL: 300, Value: "192.0.2.1"})
+
+	// A closed listener refuses connections.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := ln.Addr().String()
+	ln.Close()
+
+	c, err := New(client, Config{
+		DomainID: domain.ID, Name: "db", Type: "A",
+		Primary:       Target{Value: "192.0.2.1", Check: TCPCheck{Address: down}},
+		Secondary:     Target{Value: "192.0.2.2", Check: TCPCheck{Address: down}},
+		FailThreshold: 1,
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Probe(context.Background()); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if m := c.Metrics(); m.State != StatePrimary || m.SecondaryFailures != 1 || m.LastError == nil {
+		t.Fatalf("expected to stay on the primary, got %+v", m)
+	}
+	if r := fake.Records(domain.ID)[0]; r.Value != "192.0.2.1" {
+		t.Fatalf("record changed: %+v", r)
+	}
+}
+
+func TestControllerSwitchesWithoutAudit(t *testing.T) {
+	t.Parallel()
+
+	failing := enzonix.AuditSinkFunc(func(context.Context, enzonix.AuditEntry) error { return errors.New("disk full") })
+	fake, client := enzonixtest.NewClient(t, enzonix.WithAuditSink(failing))
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	primary, primaryStatus := endpoint(t)
+	primaryStatus.Store(http.StatusServiceUnavailable)
+
+	var events []Event
+	c, err := New(client, Config{
+		DomainID: domain.ID, Name: "www", Type: "A",
+		Primary:       Target{Value: "192.0.2.1", Check: HTTPCheck{URL: primary.URL}},
+		Secondary:     Target{Value: "192.0.2.2"},
+		FailThreshold: 1,
+		OnEvent:       func(e Event) { events = append(events, e) },
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Probe(context.Background()); !enzonix.IsAuditOnly(err) {
+		t.Fatalf("expected audit-only error, got %v", err)
+	}
+	if m := c.Metrics(); m.State != StateSecondary || m.Failovers != 1 || m.UpdateErrors != 0 {
+		t.Fatalf("expected the unaudited update to count as a failover, got %+v", m)
+	}
+	if len(events) != 1 || events[0].Record == nil || !enzonix.IsAuditOnly(events[0].Err) {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if r := fake.Records(domain.ID)[0]; r.Value != "192.0.2.2" {
+		t.Fatalf("record not switched: %+v", r)
+	}
+}
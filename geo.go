@@ -0,0 +1,593 @@
+package enzonix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// isoCountries lists the officially assigned ISO 3166-1 alpha-2 codes.
+var isoCountries = func() map[string]bool {
+	codes := strings.Fields(`
+		AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ
+		BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ
+		CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ
+		DE DJ DK DM DO DZ
+		EC EE EG EH ER ES ET
+		FI FJ FK FM FO FR
+		GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY
+		HK HM HN HR HT HU
+		ID IE IL IM IN IO IQ IR IS IT
+		JE JM JO JP
+		KE KG KH KI KM KN KP KR KW KY KZ
+		LA LB LC LI LK LR LS LT LU LV LY
+		MA MC MD ME MF MG MH MK ML MM MN MO MP MQ MR MS MT MU MV MW MX MY MZ
+		NA NC NE NF NG NI NL NO NP NR NU NZ
+		OM
+		PA PE PF PG PH PK PL PM PN PR PS PT PW PY
+		QA
+		RE RO RS RU RW
+		SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS ST SV SX SY SZ
+		TC TD TF TG TH TJ TK TL TM TN TO TR TT TV TW TZ
+		UA UG UM US UY UZ
+		VA VC VE VG VI VN VU
+		WF WS
+		YE YT
+		ZA ZM ZW`)
+	m := make(map[string]bool, len(codes))
+	for _, c := range codes {
+		m[c] = true
+	}
+	return m
+}()
+
+// countryAliases maps common non-ISO codes to their ISO equivalent.
+var countryAliases = map[string]string{"UK": "GB", "EL": "GR"}
+
+// ErrInvalidCountryCode is returned for codes that are not ISO 3166-1
+// alpha-2 country codes.
+var ErrInvalidCountryCode = errors.New("enzonix: invalid country code")
+
+// NormalizeCountryCode upper-cases and validates an ISO 3166-1 alpha-2
+// code. The exceptionally reserved UK and EL map to GB and GR.
+func NormalizeCountryCode(code string) (string, error) {
+	c := strings.ToUpper(strings.TrimSpace(code))
+	if alias, ok := countryAliases[c]; ok {
+		c = alias
+	}
+	if !isoCountries[c] {
+		return "", fmt.Errorf("%w: %q", ErrInvalidCountryCode, code)
+	}
+	return c, nil
+}
+
+// IsCountryCode reports whether code is an ISO 3166-1 alpha-2 code, in any
+// case.
+func IsCountryCode(code string) bool {
+	_, err := NormalizeCountryCode(code)
+	return err == nil
+}
+
+// NormalizeCountryCodes normalizes codes, sorted and without duplicates.
+// All invalid codes are reported in one error.
+func NormalizeCountryCodes(codes []string) ([]string, error) {
+	seen := make(map[string]bool, len(codes))
+	out := make([]string, 0, len(codes))
+	var errs []error
+	for _, code := range codes {
+		c, err := NormalizeCountryCode(code)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return out, errors.Join(errs...)
+}
+
+// CountryCodes returns every ISO 3166-1 alpha-2 code, sorted.
+func CountryCodes() []string {
+	out := make([]string, 0, len(isoCountries))
+	for c := range isoCountries {
+		out = append(out, c)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Region names a preset group of countries.
+type Region string
+
+const (
+	RegionEU           Region = "EU"
+	RegionEEA          Region = "EEA"
+	RegionEurope       Region = "EUROPE"
+	RegionNorthAmerica Region = "NA"
+	RegionSouthAmerica Region = "SA"
+	RegionLatAm        Region = "LATAM"
+	RegionAPAC         Region = "APAC"
+	RegionMENA         Region = "MENA"
+	RegionAfrica       Region = "AFRICA"
+	RegionOceania      Region = "OCEANIA"
+)
+
+var regions = map[Region]string{
+	RegionEU: "AT BE BG CY CZ DE DK EE ES FI FR GR HR HU IE IT LT LU LV MT NL PL PT RO SE SI SK",
+	RegionEEA: "AT BE BG CY CZ DE DK EE ES FI FR GR HR HU IE IT LT LU LV MT NL PL PT RO SE SI SK " +
+		"IS LI NO",
+	RegionEurope: "AD AL AT AX BA BE BG BY CH CY CZ DE DK EE ES FI FO FR GB GG GI GR HR HU IE IM IS IT " +
+		"JE LI LT LU LV MC MD ME MK MT NL NO PL PT RO RS RU SE SI SJ SK SM UA VA",
+	RegionNorthAmerica: "AG AI AW BB BL BM BQ BS BZ CA CR CU CW DM DO GD GL GP GT HN HT JM KN KY LC MF MQ " +
+		"MS MX NI PA PM PR SV SX TC TT US VC VG VI",
+	RegionSouthAmerica: "AR BO BR CL CO EC FK GF GY PE PY SR UY VE",
+	RegionLatAm:        "AR BO BR CL CO CR CU DO EC GT HN MX NI PA PE PR PY SV UY VE",
+	RegionAPAC: "AU BD BN BT CN FJ HK ID IN JP KH KI KP KR LA LK MH MM MN MO MV MY NP NR NZ PG PH PK " +
+		"PW SB SG TH TL TO TV TW VN VU WS",
+	RegionMENA: "AE BH DZ EG IL IQ IR JO KW LB LY MA OM PS QA SA SY TN YE",
+	RegionAfrica: "AO BF BI BJ BW CD CF CG CI CM CV DJ DZ EG EH ER ET GA GH GM GN GQ GW KE KM LR LS LY " +
+		"MA MG ML MR MU MW MZ NA NE NG RE RW SC SD SH SL SN SO SS ST SZ TD TG TN TZ UG YT ZA ZM ZW",
+	RegionOceania: "AS AU CK FJ FM GU KI MH MP NC NF NR NU NZ PF PG PN PW SB TK TO TV UM VU WF WS",
+}
+
+// Countries returns the sorted country codes of the region, or nil for
+// unknown regions.
+func (r Region) Countries() []string {
+	list, ok := regions[Region(strings.ToUpper(string(r)))]
+	if !ok {
+		return nil
+	}
+	codes := strings.Fields(list)
+	sort.Strings(codes)
+	return codes
+}
+
+// Regions returns the names of the preset regions, sorted.
+func Regions() []Region {
+	out := make([]Region, 0, len(regions))
+	for r := range regions {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// GeoAnswer is the record data served to a group of countries.
+type GeoAnswer struct {
+	Value    string
+	Priority int
+	// TTL overrides the set's TTL when positive.
+	TTL int
+}
+
+// GeoVariant serves an answer to a list of countries.
+type GeoVariant struct {
+	Countries []string
+	Answer    GeoAnswer
+}
+
+// GeoRecordSet is the geo routing table of one name and type: a default
+// record, served to countries without a variant, plus per-country
+// variants. Each maps to one record; variants carry their countries in
+// CountryCodes.
+type GeoRecordSet struct {
+	Name string
+	Type string
+	TTL  int
+	// Default is nil when countries without a variant get no answer.
+	Default  *GeoAnswer
+	Variants []GeoVariant
+}
+
+// NewGeoRecordSet returns an empty set for name and type. ttl applies to
+// answers without their own TTL.
+func NewGeoRecordSet(name, typ string, ttl int) *GeoRecordSet {
+	return &GeoRecordSet{Name: name, Type: strings.ToUpper(typ), TTL: ttl}
+}
+
+// SetDefault sets the answer for countries without a variant.
+func (s *GeoRecordSet) SetDefault(answer GeoAnswer) *GeoRecordSet {
+	s.Default = &answer
+	return s
+}
+
+// Route serves answer to countries. Countries are normalized; routing a
+// country that already has a variant moves it to this answer. Variants
+// with an identical answer are merged.
+func (s *GeoRecordSet) Route(answer GeoAnswer, countries ...string) error {
+	codes, err := NormalizeCountryCodes(countries)
+	if err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return errors.New("enzonix: route needs at least one country")
+	}
+	moved := make(map[string]bool, len(codes))
+	for _, c := range codes {
+		moved[c] = true
+	}
+	variants := s.Variants[:0]
+	merged := false
+	for _, v := range s.Variants {
+		kept := v.Countries[:0:0]
+		for _, c := range v.Countries {
+			if !moved[c] {
+				kept = append(kept, c)
+			}
+		}
+		v.Countries = kept
+		if v.Answer == answer && !merged {
+			v.Countries, _ = NormalizeCountryCodes(append(v.Countries, codes...))
+			merged = true
+		}
+		if len(v.Countries) > 0 {
+			variants = append(variants, v)
+		}
+	}
+	if !merged {
+		variants = append(variants, GeoVariant{Countries: codes, Answer: answer})
+	}
+	s.Variants = variants
+	return nil
+}
+
+// RouteRegion serves answer to every country of the regions.
+func (s *GeoRecordSet) RouteRegion(answer GeoAnswer, regions ...Region) error {
+	var codes []string
+	for _, r := range regions {
+		countries := r.Countries()
+		if countries == nil {
+			return fmt.Errorf("enzonix: unknown region %q", r)
+		}
+		codes = append(codes, countries...)
+	}
+	return s.Route(answer, codes...)
+}
+
+// Resolve returns the answer served to country, and false when it gets
+// none.
+func (s *GeoRecordSet) Resolve(country string) (GeoAnswer, bool) {
+	if c, err := NormalizeCountryCode(country); err == nil {
+		for _, v := range s.Variants {
+			for _, vc := range v.Countries {
+				if strings.EqualFold(vc, c) {
+					return v.Answer, true
+				}
+			}
+		}
+	}
+	if s.Default != nil {
+		return *s.Default, true
+	}
+	return GeoAnswer{}, false
+}
+
+// Records returns the set as records of domainID: the default without
+// country codes, then one record per variant.
+func (s *GeoRecordSet) Records(domainID string) []Record {
+	var out []Record
+	add := func(a GeoAnswer, countries []string) {
+		ttl := s.TTL
+		if a.TTL > 0 {
+			ttl = a.TTL
+		}
+		out = append(out, Record{
+			DomainID:     domainID,
+			Name:         s.Name,
+			Type:         s.Type,
+			TTL:          ttl,
+			Priority:     a.Priority,
+			Value:        a.Value,
+			CountryCodes: countries,
+		})
+	}
+	if s.Default != nil {
+		add(*s.Default, nil)
+	}
+	for _, v := range s.Variants {
+		codes, _ := NormalizeCountryCodes(v.Countries)
+		add(v.Answer, codes)
+	}
+	return out
+}
+
+// GeoRecordSetFromRecords builds the set of name and type from records,
+// ignoring other records. It fails when several records lack country
+// codes, as only one default is possible.
+func GeoRecordSetFromRecords(name, typ string, records []Record) (*GeoRecordSet, error) {
+	set := NewGeoRecordSet(name, typ, 0)
+	for _, r := range records {
+		if !sameRecordName(r.Name, name) || !strings.EqualFold(r.Type, typ) {
+			continue
+		}
+		answer := GeoAnswer{Value: r.Value, Priority: r.Priority, TTL: r.TTL}
+		if len(r.CountryCodes) == 0 {
+			if set.Default != nil {
+				return nil, fmt.Errorf("enzonix: %s %s has several records without country codes", name, typ)
+			}
+			set.Default = &answer
+			continue
+		}
+		set.Variants = append(set.Variants, GeoVariant{Countries: r.CountryCodes, Answer: answer})
+	}
+	return set, nil
+}
+
+// GeoIssueKind classifies a GeoIssue.
+type GeoIssueKind string
+
+const (
+	// GeoOverlap means a country maps to several answers.
+	GeoOverlap GeoIssueKind = "overlap"
+	// GeoGap means countries map to no answer.
+	GeoGap GeoIssueKind = "gap"
+	// GeoInvalidCountry means a record carries an invalid country code.
+	GeoInvalidCountry GeoIssueKind = "invalid-country"
+)
+
+// GeoIssue is a problem found by CheckGeoRecords or GeoRecordSet.Check.
+type GeoIssue struct {
+	Name string
+	Type string
+	Kind GeoIssueKind
+	// Countries lists the affected countries; "" stands for the default
+	// record, for several defaults.
+	Countries []string
+	// Values lists the competing answers of an overlap.
+	Values []string
+}
+
+// String describes the issue.
+func (i GeoIssue) String() string {
+	countries := strings.Join(i.Countries, ",")
+	switch i.Kind {
+	case GeoOverlap:
+		if len(i.Countries) == 1 && i.Countries[0] == "" {
+			countries = "default"
+		}
+		return fmt.Sprintf("%s %s: %s maps to %d answers (%s)", i.Name, i.Type, countries, len(i.Values), strings.Join(i.Values, ", "))
+	case GeoGap:
+		return fmt.Sprintf("%s %s: %d countries have no answer (%s)", i.Name, i.Type, len(i.Countries), countries)
+	}
+	return fmt.Sprintf("%s %s: invalid country codes %s", i.Name, i.Type, countries)
+}
+
+// GeoCheckError reports the issues that stopped ApplyGeoRecordSet.
+type GeoCheckError struct {
+	Issues []GeoIssue
+}
+
+// Error satisfies the error interface.
+func (e *GeoCheckError) Error() string {
+	parts := make([]string, 0, len(e.Issues))
+	for _, i := range e.Issues {
+		parts = append(parts, i.String())
+	}
+	return "enzonix: geo routing: " + strings.Join(parts, "; ")
+}
+
+// Check verifies that every country maps to exactly one answer.
+func (s *GeoRecordSet) Check() []GeoIssue {
+	return checkGeo(s.Name, s.Type, s.Records(""))
+}
+
+// CheckGeoRecords checks the geo routing of every name and type of a zone
+// that has records with country codes.
+func CheckGeoRecords(records []Record) []GeoIssue {
+	type key struct{ name, typ string }
+	groups := map[key][]Record{}
+	var order []key
+	geo := map[key]bool{}
+	for _, r := range records {
+		k := key{strings.ToLower(strings.TrimSuffix(r.Name, ".")), strings.ToUpper(r.Type)}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], r)
+		if len(r.CountryCodes) > 0 {
+			geo[k] = true
+		}
+	}
+	var issues []GeoIssue
+	for _, k := range order {
+		if geo[k] {
+			issues = append(issues, checkGeo(groups[k][0].Name, k.typ, groups[k])...)
+		}
+	}
+	return issues
+}
+
+func checkGeo(name, typ string, records []Record) []GeoIssue {
+	var (
+		issues   []GeoIssue
+		invalid  []string
+		defaults []string
+		answers  = map[string][]string{}
+	)
+	for _, r := range records {
+		value := describeValue(r)
+		if len(r.CountryCodes) == 0 {
+			defaults = append(defaults, value)
+			continue
+		}
+		for _, code := range r.CountryCodes {
+			c, err := NormalizeCountryCode(code)
+			if err != nil {
+				invalid = append(invalid, code)
+				continue
+			}
+			answers[c] = append(answers[c], value)
+		}
+	}
+	if len(invalid) > 0 {
+		issues = append(issues, GeoIssue{Name: name, Type: typ, Kind: GeoInvalidCountry, Countries: invalid})
+	}
+	if len(defaults) > 1 {
+		issues = append(issues, GeoIssue{Name: name, Type: typ, Kind: GeoOverlap, Countries: []string{""}, Values: defaults})
+	}
+	var gaps []string
+	for _, c := range CountryCodes() {
+		switch values := answers[c]; {
+		case len(values) > 1:
+			issues = append(issues, GeoIssue{Name: name, Type: typ, Kind: GeoOverlap, Countries: []string{c}, Values: values})
+		case len(values) == 0 && len(defaults) == 0:
+			gaps = append(gaps, c)
+		}
+	}
+	if len(gaps) > 0 {
+		issues = append(issues, GeoIssue{Name: name, Type: typ, Kind: GeoGap, Countries: gaps})
+	}
+	return issues
+}
+
+// GeoApplyOptions configures ApplyGeoRecordSet.
+type GeoApplyOptions struct {
+	// AllowGaps applies sets without a default that leave countries
+	// without an answer.
+	AllowGaps bool
+	// DryRun computes the changes without making them. The returned
+	// records have no IDs for creations.
+	DryRun bool
+}
+
+// GeoApplyResult reports the changes made by ApplyGeoRecordSet.
+type GeoApplyResult struct {
+	Created []Record
+	Updated []Record
+	Deleted []Record
+}
+
+// ApplyGeoRecordSet reconciles the live records of the set's name and type
+// in domainID with the set. Records that match are kept; records are
+// updated in place where possible, then missing ones created and surplus
+// ones deleted, so that countries briefly see both answers rather than
+// none. Sets failing Check are refused with a *GeoCheckError.
+//
+// The default record is never turned into a variant or back, because the
+// API cannot clear country codes; such changes create and delete instead.
+func (c *Client) ApplyGeoRecordSet(ctx context.Context, domainID string, set *GeoRecordSet, opts GeoApplyOptions) (*GeoApplyResult, error) {
+	if err := requireID(domainID, "domain id"); err != nil {
+		return nil, err
+	}
+	var issues []GeoIssue
+	for _, issue := range set.Check() {
+		if issue.Kind == GeoGap && opts.AllowGaps {
+			continue
+		}
+		issues = append(issues, issue)
+	}
+	if len(issues) > 0 {
+		return nil, &GeoCheckError{Issues: issues}
+	}
+
+	records, err := c.ListDomainRecords(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+	var live []Record
+	for _, r := range records {
+		if sameRecordName(r.Name, set.Name) && strings.EqualFold(r.Type, set.Type) {
+			live = append(live, r)
+		}
+	}
+	want := set.Records(domainID)
+	for _, w := range want {
+		if w.TTL <= 0 {
+			return nil, fmt.Errorf("enzonix: geo record %s %s needs a positive TTL", w.Name, describeValue(w))
+		}
+	}
+
+	updates, creates, deletes := planGeo(live, want)
+	res := &GeoApplyResult{}
+	if opts.DryRun {
+		res.Updated, res.Created, res.Deleted = updates, creates, deletes
+		return res, nil
+	}
+	var audit batchAudit
+	for _, r := range updates {
+		updated, err := c.UpdateRecord(ctx, r.ID, UpdateRecordRequest{
+			Value:        &r.Value,
+			TTL:          &r.TTL,
+			Priority:     &r.Priority,
+			CountryCodes: r.CountryCodes,
+		})
+		if !audit.absorb(err) {
+			return res, audit.join(fmt.Errorf("enzonix: geo update %s %s: %w", r.Name, describeValue(r), err))
+		}
+		res.Updated = append(res.Updated, *updated)
+	}
+	for _, r := range creates {
+		created, err := c.CreateRecord(ctx, createRequestFor(r))
+		if !audit.absorb(err) {
+			return res, audit.join(fmt.Errorf("enzonix: geo create %s %s: %w", r.Name, describeValue(r), err))
+		}
+		res.Created = append(res.Created, *created)
+	}
+	for _, r := range deletes {
+		if err := c.DeleteRecord(ctx, r.ID); !audit.absorb(err) {
+			return res, audit.join(fmt.Errorf("enzonix: geo delete %s %s: %w", r.Name, describeValue(r), err))
+		}
+		res.Deleted = append(res.Deleted, r)
+	}
+	return res, audit.join(nil)
+}
+
+// planGeo pairs live records with wanted ones. Exact matches are kept;
+// records with the same countries, then any two variants, become updates
+// carrying the live ID.
+func planGeo(live, want []Record) (updates, creates, deletes []Record) {
+	used := make([]bool, len(live))
+	pending := make([]Record, 0, len(want))
+	for _, w := range want {
+		matched := false
+		for i, l := range live {
+			if !used[i] && sameRecordState(l, w) {
+				used[i], matched = true, true
+				break
+			}
+		}
+		if !matched {
+			pending = append(pending, w)
+		}
+	}
+
+	pair := func(match func(l, w Record) bool) {
+		rest := pending[:0]
+		for _, w := range pending {
+			matched := false
+			for i, l := range live {
+				if !used[i] && match(l, w) {
+					used[i], matched = true, true
+					w.ID = l.ID
+					updates = append(updates, w)
+					break
+				}
+			}
+			if !matched {
+				rest = append(rest, w)
+			}
+		}
+		pending = rest
+	}
+	pair(func(l, w Record) bool { return sameCountries(l.CountryCodes, w.CountryCodes) })
+	pair(func(l, w Record) bool { return len(l.CountryCodes) > 0 && len(w.CountryCodes) > 0 })
+
+	creates = pending
+	for i, l := range live {
+		if !used[i] {
+			deletes = append(deletes, l)
+		}
+	}
+	return updates, creates, deletes
+}
+
+func sameCountries(a, b []string) bool {
+	na, _ := NormalizeCountryCodes(a)
+	nb, _ := NormalizeCountryCodes(b)
+	return strings.Join(na, ",") == strings.Join(nb, ",")
+}
@@ -0,0 +1,199 @@
+package enzonix
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+func TestCountryCodes(t *testing.T) {
+	t.Parallel()
+
+	if n := len(CountryCodes()); n != 249 {
+		t.Fatalf("expected 249 ISO 3166-1 codes, got %d", n)
+	}
+	for in, want := range map[string]string{"de": "DE", " us ": "US", "uk": "GB", "El": "GR"} {
+		if got, err := NormalizeCountryCode(in); err != nil || got != want {
+			t.Fatalf("%q: got %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "XX", "EU", "DEU", "1A"} {
+		if IsCountryCode(bad) {
+			t.Fatalf("%q accepted as a country code", bad)
+		}
+	}
+	codes, err := NormalizeCountryCodes([]string{"fr", "DE", "de", "ZZ", "QQ"})
+	if len(codes) != 2 || codes[0] != "DE" || codes[1] != "FR" || !errors.Is(err, ErrInvalidCountryCode) || !strings.Contains(err.Error(), "QQ") {
+		t.Fatalf("unexpected result %v, %v", codes, err)
+	}
+
+	for _, r := range Regions() {
+		countries := r.Countries()
+		if len(countries) == 0 {
+			t.Fatalf("region %s is empty", r)
+		}
+		for _, c := range countries {
+			if !IsCountryCode(c) {
+				t.Fatalf("region %s contains invalid code %s", r, c)
+			}
+		}
+	}
+	if n := len(RegionEU.Countries()); n != 27 {
+		t.Fatalf("expected 27 EU members, got %d", n)
+	}
+	if Region("mars").Countries() != nil {
+		t.Fatal("expected no countries for an unknown region")
+	}
+}
+
+func TestGeoRecordSetCheck(t *testing.T) {
+	t.Parallel()
+
+	set := NewGeoRecordSet("www", "a", 300)
+	if err := set.RouteRegion(GeoAnswer{Value: "192.0.2.10"}, RegionEU); err != nil {
+		t.Fatalf("route region: %v", err)
+	}
+	if err := set.Route(GeoAnswer{Value: "192.0.2.20"}, "us", "CA", "de"); err != nil {
+		t.Fatalf("route: %v", err)
+	}
+	if err := set.Route(GeoAnswer{Value: "192.0.2.20"}, "XX"); !errors.Is(err, ErrInvalidCountryCode) {
+		t.Fatalf("expected invalid code error, got %v", err)
+	}
+	// DE moved from the EU variant.
+	if a, ok := set.Resolve("de"); !ok || a.Value != "192.0.2.20" {
+		t.Fatalf("unexpected answer for DE %+v", a)
+	}
+	if a, _ := set.Resolve("FR"); a.Value != "192.0.2.10" {
+		t.Fatalf("unexpected answer for FR %+v", a)
+	}
+
+	issues := set.Check()
+	if len(issues) != 1 || issues[0].Kind != GeoGap || len(issues[0].Countries) != 249-29 {
+		t.Fatalf("expected a gap for unrouted countries, got %v", issues)
+	}
+	set.SetDefault(GeoAnswer{Value: "192.0.2.1"})
+	if issues := set.Check(); len(issues) != 0 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	if a, ok := set.Resolve("JP"); !ok || a.Value != "192.0.2.1" {
+		t.Fatalf("expected the default for JP, got %+v", a)
+	}
+
+	// A zone where FR maps to two answers and codes are invalid.
+	issues = CheckGeoRecords([]Record{
+		{Name: "www", Type: "A", Value: "192.0.2.1"},
+		{Name: "www", Type: "A", Value: "192.0.2.2", CountryCodes: []string{"FR", "DE"}},
+		{Name: "www", Type: "A", Value: "192.0.2.3", CountryCodes: []string{"fr", "XX"}},
+		{Name: "api", Type: "A", Value: "192.0.2.4"},
+		{Name: "api", Type: "A", Value: "192.0.2.5"},
+	})
+	var kinds []string
+	for _, i := range issues {
+		kinds = append(kinds, string(i.Kind)+":"+strings.Join(i.Countries, ","))
+	}
+	if strings.Join(kinds, " ") != "invalid-country:XX overlap:FR" {
+		t.Fatalf("unexpected issues %v", kinds)
+	}
+}
+
+func TestApplyGeoRecordSet(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeClient(t)
+	domain := fake.AddDomain("example.com")
+	def := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	us := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.2", CountryCodes: []string{"US"}})
+	stale := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.3", CountryCodes: []string{"JP"}})
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "api", Type: "A", TTL: 300, Value: "192.0.2.9"})
+	ctx := context.Background()
+
+	set := NewGeoRecordSet("www", "A", 300).SetDefault(GeoAnswer{Value: "192.0.2.1"})
+	if err := set.Route(GeoAnswer{Value: "192.0.2.20"}, "US", "CA"); err != nil {
+		t.Fatal(err)
+	}
+	if err := set.RouteRegion(GeoAnswer{Value: "192.0.2.30"}, RegionEU); err != nil {
+		t.Fatal(err)
+	}
+	if err := set.Route(GeoAnswer{Value: "192.0.2.40"}, "AU"); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := client.ApplyGeoRecordSet(ctx, domain.ID, set, GeoApplyOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(plan.Updated) != 2 || len(plan.Created) != 1 || len(plan.Deleted) != 0 || len(fake.Records(domain.ID)) != 4 {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+
+	res, err := client.ApplyGeoRecordSet(ctx, domain.ID, set, GeoApplyOptions{})
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if len(res.Updated) != 2 || res.Updated[0].ID != us.ID || res.Updated[1].ID != stale.ID || len(res.Created) != 1 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+
+	live, err := client.ListDomainRecords(ctx, domain.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issues := CheckGeoRecords(live); len(issues) != 0 {
+		t.Fatalf("applied zone has issues %v", issues)
+	}
+	got, err := GeoRecordSetFromRecords("www", "A", live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for country, want := range map[string]string{"US": "192.0.2.20", "CA": "192.0.2.20", "FR": "192.0.2.30", "AU": "192.0.2.40", "JP": "192.0.2.1"} {
+		if a, _ := got.Resolve(country); a.Value != want {
+			t.Fatalf("%s: got %q, want %q", country, a.Value, want)
+		}
+	}
+	if got.Default == nil || live[0].ID != def.ID {
+		t.Fatalf("expected the default record to be kept, got %+v", live)
+	}
+
+	// Applying again changes nothing; dropping a variant deletes it.
+	set.Variants = set.Variants[:2]
+	res, err = client.ApplyGeoRecordSet(ctx, domain.ID, set, GeoApplyOptions{})
+	if err != nil || len(res.Updated)+len(res.Created) != 0 || len(res.Deleted) != 1 || res.Deleted[0].Value != "192.0.2.40" {
+		t.Fatalf("unexpected result %+v, %v", res, err)
+	}
+
+	// Sets leaving countries without an answer are refused by default.
+	gappy := NewGeoRecordSet("www", "A", 300)
+	_ = gappy.Route(GeoAnswer{Value: "192.0.2.20"}, "US")
+	var checkErr *GeoCheckError
+	if _, err := client.ApplyGeoRecordSet(ctx, domain.ID, gappy, GeoApplyOptions{}); !errors.As(err, &checkErr) || checkErr.Issues[0].Kind != GeoGap {
+		t.Fatalf("expected a gap error, got %v", err)
+	}
+	if _, err := client.ApplyGeoRecordSet(ctx, domain.ID, gappy, GeoApplyOptions{AllowGaps: true, DryRun: true}); err != nil {
+		t.Fatalf("expected gaps to be allowed, got %v", err)
+	}
+}
+
+func TestApplyGeoRecordSetAuditErrors(t *testing.T) {
+	t.Parallel()
+
+	failing := AuditSinkFunc(func(context.Context, AuditEntry) error { return errors.New("disk full") })
+	fake, client := newFakeClient(t, WithAuditSink(failing))
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.2", CountryCodes: []string{"US"}})
+	ctx := context.Background()
+
+	set := NewGeoRecordSet("www", "A", 300).SetDefault(GeoAnswer{Value: "192.0.2.1"})
+	if err := set.Route(GeoAnswer{Value: "192.0.2.20"}, "US"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.ApplyGeoRecordSet(ctx, domain.ID, set, GeoApplyOptions{})
+	if !IsAuditOnly(err) {
+		t.Fatalf("expected audit-only error, got %v", err)
+	}
+	if len(res.Updated) != 1 || len(res.Created) != 1 || len(fake.Records(domain.ID)) != 2 {
+		t.Fatalf("apply stopped after the first unaudited change: %+v", res)
+	}
+}
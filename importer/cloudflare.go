@@ -0,0 +1,92 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// cfRecord is a DNS record as the Cloudflare API lists it.
+type cfRecord struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Content  string  `json:"content"`
+	TTL      int     `json:"ttl"`
+	Priority *int    `json:"priority"`
+	Proxied  bool    `json:"proxied"`
+	Data     *cfData `json:"data"`
+}
+
+// cfData holds the structured fields of SRV and CAA records.
+type cfData struct {
+	Flags    int    `json:"flags"`
+	Tag      string `json:"tag"`
+	Value    string `json:"value"`
+	Priority int    `json:"priority"`
+	Weight   int    `json:"weight"`
+	Port     int    `json:"port"`
+	Target   string `json:"target"`
+}
+
+// cfAutoTTL is the TTL Cloudflare reports as 1, "automatic".
+const cfAutoTTL = 300
+
+// cloudflare reads the response of the list DNS records endpoint, either
+// the whole envelope or its result array. Proxied records are imported
+// with the origin address they hide.
+func (res *Result) cloudflare(data []byte) error {
+	var records []cfRecord
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &records); err != nil {
+			return err
+		}
+	} else {
+		var envelope struct {
+			Result []cfRecord `json:"result"`
+		}
+		if err := json.Unmarshal(trimmed, &envelope); err != nil {
+			return err
+		}
+		records = envelope.Result
+	}
+
+	for _, r := range records {
+		entry := r.Name + " " + r.Type
+		name, ok := res.relative(r.Name)
+		if !ok {
+			res.warn(entry, "name outside %s; skipped", res.Zone)
+			continue
+		}
+		ttl := r.TTL
+		if ttl == 1 {
+			ttl = cfAutoTTL
+		}
+		if r.Proxied {
+			res.warn(entry, "proxied through Cloudflare; imported with the origin address")
+		}
+		priority, value := 0, r.Content
+		if r.Priority != nil {
+			priority = *r.Priority
+		}
+		switch strings.ToUpper(r.Type) {
+		case "TXT", "SPF":
+			value = joinStrings(value)
+		case "SRV":
+			if r.Data != nil {
+				priority, value = r.Data.Priority, fmt.Sprintf("%d %d %s", r.Data.Weight, r.Data.Port, r.Data.Target)
+			} else if f := strings.Fields(value); len(f) == 4 {
+				// Older exports carry the priority in the content.
+				priority, _ = strconv.Atoi(f[0])
+				value = strings.Join(f[1:], " ")
+			}
+		case "CAA":
+			if r.Data != nil {
+				value = fmt.Sprintf("%d %s %q", r.Data.Flags, r.Data.Tag, r.Data.Value)
+			}
+		}
+		res.add(entry, name, r.Type, ttl, priority, value, nil)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// csv reads the CSV written by the export package. Columns are found by
+// the header row, so spreadsheets may reorder them or add their own; name,
+// type and value are required. Country codes are separated by spaces or
+// commas.
+func (res *Result) csv(data []byte) error {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("missing header row")
+	}
+	col := map[string]int{}
+	for i, h := range rows[0] {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, required := range []string{"name", "type", "value"} {
+		if _, ok := col[required]; !ok {
+			return fmt.Errorf("missing %s column", required)
+		}
+	}
+
+	for i, row := range rows[1:] {
+		get := func(name string) string {
+			if c, ok := col[name]; ok && c < len(row) {
+				return strings.TrimSpace(row[c])
+			}
+			return ""
+		}
+		entry := "line " + strconv.Itoa(i+2)
+		name := get("name")
+		if name == "" && get("type") == "" {
+			continue
+		}
+		if strings.HasSuffix(name, ".") {
+			rel, ok := res.relative(name)
+			if !ok {
+				res.warn(entry, "name %s outside %s; skipped", name, res.Zone)
+				continue
+			}
+			name = rel
+		} else if name == "" {
+			name = "@"
+		}
+		ttl, priority := 0, 0
+		if s := get("ttl"); s != "" {
+			if ttl, err = strconv.Atoi(s); err != nil {
+				res.warn(entry, "malformed TTL %q; skipped", s)
+				continue
+			}
+		}
+		if s := get("priority"); s != "" {
+			if priority, err = strconv.Atoi(s); err != nil {
+				res.warn(entry, "malformed priority %q; skipped", s)
+				continue
+			}
+		}
+		countries := strings.FieldsFunc(strings.ToUpper(get("country_codes")), func(r rune) bool { return r == ' ' || r == ',' })
+		res.add(entry, name, get("type"), ttl, priority, get("value"), countries)
+	}
+	return nil
+}
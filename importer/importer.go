@@ -0,0 +1,313 @@
+// Package importer reads zones exported from other DNS providers and tools
+// into record requests for the Enzonix API: Cloudflare JSON, Route 53
+// list-resource-record-sets JSON, OctoDNS YAML, tinydns data files and the
+// CSV written by the export package.
+//
+// Provider quirks are resolved on the way in: alias records become CNAMEs
+// where they can, weighted and other routed sets are merged, geolocation
+// sets keep their country codes, and escaped or chunked TXT strings are
+// joined. Entries with no Enzonix equivalent are skipped; each skip or
+// adaptation is reported as a Warning.
+//
+// A Result is loaded either through ImportBindZone, with Import, or by
+// reconciling a domain against it, passing Provider to mirror.Sync.
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/zonefile"
+)
+
+// Format is an import format.
+type Format string
+
+// Import formats.
+const (
+	Cloudflare Format = "cloudflare"
+	Route53    Format = "route53"
+	OctoDNS    Format = "octodns"
+	TinyDNS    Format = "tinydns"
+	CSV        Format = "csv"
+)
+
+// Formats lists the supported formats.
+func Formats() []Format {
+	return []Format{Cloudflare, Route53, OctoDNS, TinyDNS, CSV}
+}
+
+// ParseFormat returns the format named s; "r53" stands for Route 53 and
+// "djbdns" for tinydns.
+func ParseFormat(s string) (Format, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case "r53":
+		return Route53, nil
+	case "djbdns":
+		return TinyDNS, nil
+	}
+	for _, f := range Formats() {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("importer: unknown format %q", s)
+}
+
+// DefaultTTL is used for records whose source has no TTL.
+const DefaultTTL = 3600
+
+// Warning reports a source entry that was skipped or adapted.
+type Warning struct {
+	// Entry identifies the entry in the source, such as "www A" or
+	// "line 12".
+	Entry   string
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Entry + ": " + w.Message
+}
+
+// Result is an imported zone. Record names are relative to Zone, "@" for
+// the apex, and targets are fully qualified without a trailing dot, as the
+// API stores them. DomainID is left empty.
+type Result struct {
+	// Zone is the zone name without a trailing dot.
+	Zone     string
+	Records  []enzonix.CreateRecordRequest
+	Warnings []Warning
+
+	seen map[string]bool
+}
+
+// supported are the record types Enzonix domains hold.
+var supported = map[string]bool{"A": true, "AAAA": true, "CAA": true, "CNAME": true, "MX": true, "NS": true, "PTR": true, "SRV": true, "TXT": true}
+
+// Parse reads a zone in format f. zone is the zone the source describes;
+// entries outside it are skipped. The CSV and OctoDNS formats carry names
+// relative to the zone, the others fully qualified names.
+func Parse(r io.Reader, f Format, zone string) (*Result, error) {
+	zone = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(zone), "."))
+	if zone == "" {
+		return nil, fmt.Errorf("importer: zone name must not be empty")
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Zone: zone, seen: map[string]bool{}}
+	switch f {
+	case Cloudflare:
+		err = res.cloudflare(data)
+	case Route53:
+		err = res.route53(data)
+	case OctoDNS:
+		err = res.octodns(data)
+	case TinyDNS:
+		err = res.tinydns(data)
+	case CSV:
+		err = res.csv(data)
+	default:
+		err = fmt.Errorf("unknown format %q", f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("importer: %s: %w", f, err)
+	}
+	res.resolveCNAMEs()
+	return res, nil
+}
+
+func (res *Result) warn(entry, format string, args ...any) {
+	res.Warnings = append(res.Warnings, Warning{Entry: entry, Message: fmt.Sprintf(format, args...)})
+}
+
+// relative converts a fully qualified owner name to one relative to the
+// zone. ok is false for names outside the zone.
+func (res *Result) relative(name string) (rel string, ok bool) {
+	name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+	switch {
+	case name == res.Zone || name == "@" || name == "":
+		return "@", true
+	case strings.HasSuffix(name, "."+res.Zone):
+		return strings.TrimSuffix(name, "."+res.Zone), true
+	}
+	return "", false
+}
+
+// add appends a record unless an identical one is already there. SPF
+// records become TXT records; other unsupported types are skipped.
+func (res *Result) add(entry, name, typ string, ttl, priority int, value string, countries []string) {
+	typ = strings.ToUpper(typ)
+	if typ == "SPF" {
+		res.warn(entry, "SPF record type is obsolete; imported as TXT")
+		typ = "TXT"
+	}
+	if !supported[typ] {
+		res.warn(entry, "record type %s not supported; skipped", typ)
+		return
+	}
+	if value == "" {
+		res.warn(entry, "empty value; skipped")
+		return
+	}
+	switch typ {
+	case "CNAME", "MX", "NS", "PTR":
+		value = strings.TrimSuffix(value, ".")
+	case "SRV":
+		if f := strings.Fields(value); len(f) == 3 {
+			value = f[0] + " " + f[1] + " " + strings.TrimSuffix(f[2], ".")
+		}
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	countries = slices.Clone(countries)
+	slices.Sort(countries)
+	key := fmt.Sprintf("%s\x00%s\x00%d\x00%s\x00%s", name, typ, priority, value, strings.Join(countries, ","))
+	if res.seen[key] {
+		return
+	}
+	res.seen[key] = true
+
+	req := enzonix.CreateRecordRequest{Name: name, Type: typ, Value: value, TTL: &ttl, CountryCodes: countries}
+	if typ == "MX" || typ == "SRV" {
+		req.Priority = &priority
+	}
+	res.Records = append(res.Records, req)
+}
+
+// resolveCNAMEs keeps the zone valid after routed sets were merged: a name
+// with a CNAME holds nothing else, and only one CNAME per set of country
+// codes. The first CNAME is kept; CNAMEs next to other records are
+// dropped, since those records were listed explicitly.
+func (res *Result) resolveCNAMEs() {
+	other := map[string]bool{}
+	for _, r := range res.Records {
+		if r.Type != "CNAME" {
+			other[r.Name] = true
+		}
+	}
+	kept := map[string]string{}
+	out := res.Records[:0]
+	for _, r := range res.Records {
+		if r.Type == "CNAME" {
+			entry := r.Name + " CNAME"
+			if other[r.Name] {
+				res.warn(entry, "CNAME to %s conflicts with other records at %s; skipped", r.Value, r.Name)
+				continue
+			}
+			key := r.Name + " " + strings.Join(r.CountryCodes, ",")
+			if target, ok := kept[key]; ok {
+				res.warn(entry, "CNAME to %s conflicts with the CNAME to %s; skipped", r.Value, target)
+				continue
+			}
+			kept[key] = r.Value
+		}
+		out = append(out, r)
+	}
+	res.Records = out
+}
+
+// Bind renders the records as a BIND zone file for ImportBindZone. Zone
+// files have no country codes, so records limited to countries are left
+// out and reported.
+func (res *Result) Bind() ([]byte, []Warning) {
+	zone := &zonefile.Zone{Origin: res.Zone + ".", DefaultTTL: DefaultTTL}
+	var warnings []Warning
+	for _, r := range res.Records {
+		if len(r.CountryCodes) > 0 {
+			warnings = append(warnings, Warning{Entry: r.Name + " " + r.Type, Message: "country codes cannot be expressed in a zone file; skipped"})
+			continue
+		}
+		rec := zonefile.Record{Name: r.Name, TTL: *r.TTL, Type: r.Type, Value: r.Value}
+		if r.Priority != nil {
+			rec.Priority = *r.Priority
+		}
+		switch r.Type {
+		case "CNAME", "MX", "NS", "PTR":
+			rec.Value = absolute(rec.Value)
+		case "SRV":
+			if f := strings.Fields(rec.Value); len(f) == 3 {
+				rec.Value = f[0] + " " + f[1] + " " + absolute(f[2])
+			}
+		}
+		zone.Records = append(zone.Records, rec)
+	}
+	var b strings.Builder
+	zonefile.Write(&b, zone)
+	return []byte(b.String()), warnings
+}
+
+// absolute adds the trailing dot to a fully qualified target; single
+// labels and "@" stay relative to the origin.
+func absolute(name string) string {
+	if name == "@" || strings.HasSuffix(name, ".") || !strings.Contains(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// Import creates the zone and its records through ImportBindZone. It
+// refuses records limited to countries, which a zone file cannot carry;
+// reconcile such zones through Provider instead.
+func (res *Result) Import(ctx context.Context, client *enzonix.Client) (*enzonix.BindImportResponse, error) {
+	data, warnings := res.Bind()
+	if len(warnings) > 0 {
+		return nil, fmt.Errorf("importer: %d records are limited to countries; use mirror.Sync with Provider", len(warnings))
+	}
+	return client.ImportBindZone(ctx, data, "text/plain")
+}
+
+// unescape decodes backslash escapes: \DDD in octal, as Route 53 and
+// tinydns write them, and \ before any other character.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 < len(s) && isOctal(s[i+1]) && isOctal(s[i+2]) && isOctal(s[i+3]) {
+			b.WriteByte((s[i+1]-'0')<<6 | (s[i+2]-'0')<<3 | (s[i+3] - '0'))
+			i += 3
+			continue
+		}
+		i++
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isOctal(c byte) bool { return c >= '0' && c <= '7' }
+
+// joinStrings joins a sequence of quoted character-strings, `"a" "b"`,
+// into one value. Values that are not quoted are returned unchanged.
+func joinStrings(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, `"`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '"' {
+			continue
+		}
+		j := i + 1
+		for ; j < len(s) && s[j] != '"'; j++ {
+			if s[j] == '\\' {
+				j++
+			}
+		}
+		b.WriteString(unescape(s[i+1 : min(j, len(s))]))
+		i = j
+	}
+	return b.String()
+}
@@ -0,0 +1,274 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/export"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/enzonixtest"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+	"github.com/Enzonix-LLC/dns-sdk-go/mirror"
+)
+
+func parse(t *testing.T, f Format, input string) *Result {
+	t.Helper()
+	res, err := Parse(strings.NewReader(input), f, "example.com.")
+	if err != nil {
+		t.Fatalf("%s: %v", f, err)
+	}
+	return res
+}
+
+// describe renders the records as "name TYPE ttl priority value [codes]".
+func describe(res *Result) []string {
+	var out []string
+	for _, r := range res.Records {
+		s := fmt.Sprintf("%s %s %d", r.Name, r.Type, *r.TTL)
+		if r.Priority != nil {
+			s += fmt.Sprintf(" %d", *r.Priority)
+		}
+		s += " " + r.Value
+		if len(r.CountryCodes) > 0 {
+			s += " " + strings.Join(r.CountryCodes, ",")
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func expect(t *testing.T, res *Result, want []string, warnings int) {
+	t.Helper()
+	if got := describe(res); !slices.Equal(got, want) {
+		t.Errorf("records:\n got %q\nwant %q", got, want)
+	}
+	if len(res.Warnings) != warnings {
+		t.Errorf("got %d warnings, want %d: %v", len(res.Warnings), warnings, res.Warnings)
+	}
+}
+
+func TestCloudflare(t *testing.T) {
+	t.Parallel()
+
+	res := parse(t, Cloudflare, `{"success": true, "result": [
+		{"name": "example.com", "type": "A", "content": "192.0.2.1", "ttl": 1, "proxied": true},
+		{"name": "example.com", "type": "MX", "content": "mx.example.com", "ttl": 3600, "priority": 10},
+		{"name": "example.com", "type": "TXT", "content": "\"v=spf1 -all\"", "ttl": 3600},
+		{"name": "_sip._tcp.example.com", "type": "SRV", "content": "10 5060 sip.example.com", "ttl": 600,
+			"data": {"priority": 5, "weight": 10, "port": 5060, "target": "sip.example.com"}},
+		{"name": "example.com", "type": "CAA", "content": "0 issue \"letsencrypt.org\"", "ttl": 3600,
+			"data": {"flags": 0, "tag": "issue", "value": "letsencrypt.org"}},
+		{"name": "example.com", "type": "HTTPS", "content": "1 . alpn=h2", "ttl": 300},
+		{"name": "other.org", "type": "A", "content": "192.0.2.2", "ttl": 300}
+	]}`)
+	expect(t, res, []string{
+		"@ A 300 192.0.2.1",
+		"@ MX 3600 10 mx.example.com",
+		"@ TXT 3600 v=spf1 -all",
+		"_sip._tcp SRV 600 5 10 5060 sip.example.com",
+		`@ CAA 3600 0 issue "letsencrypt.org"`,
+	}, 3)
+}
+
+func TestRoute53(t *testing.T) {
+	t.Parallel()
+
+	res := parse(t, Route53, `{"ResourceRecordSets": [
+		{"Name": "example.com.", "Type": "NS", "TTL": 172800, "ResourceRecords": [{"Value": "ns-1.awsdns-00.com."}]},
+		{"Name": "example.com.", "Type": "SOA", "TTL": 900, "ResourceRecords": [{"Value": "ns-1.awsdns-00.com. awsdns-hostmaster.amazon.com. 1 7200 900 1209600 86400"}]},
+		{"Name": "example.com.", "Type": "A", "AliasTarget": {"HostedZoneId": "Z2", "DNSName": "lb-1.elb.amazonaws.com.", "EvaluateTargetHealth": false}},
+		{"Name": "example.com.", "Type": "MX", "TTL": 300, "ResourceRecords": [{"Value": "10 mx1.example.com."}, {"Value": "20 mx2.example.com."}]},
+		{"Name": "example.com.", "Type": "TXT", "TTL": 300, "ResourceRecords": [{"Value": "\"v=spf1 include:_spf.example.net \" \"-all\""}]},
+		{"Name": "\\052.example.com.", "Type": "CNAME", "TTL": 60, "ResourceRecords": [{"Value": "www.example.com."}]},
+		{"Name": "app.example.com.", "Type": "A", "AliasTarget": {"HostedZoneId": "Z2", "DNSName": "d111.cloudfront.net."}},
+		{"Name": "www.example.com.", "Type": "A", "TTL": 60, "SetIdentifier": "blue", "Weight": 70, "ResourceRecords": [{"Value": "192.0.2.1"}]},
+		{"Name": "www.example.com.", "Type": "A", "TTL": 60, "SetIdentifier": "green", "Weight": 30, "ResourceRecords": [{"Value": "192.0.2.2"}, {"Value": "192.0.2.1"}]},
+		{"Name": "geo.example.com.", "Type": "A", "TTL": 60, "SetIdentifier": "default", "GeoLocation": {"CountryCode": "*"}, "ResourceRecords": [{"Value": "192.0.2.10"}]},
+		{"Name": "geo.example.com.", "Type": "A", "TTL": 60, "SetIdentifier": "de", "GeoLocation": {"CountryCode": "DE"}, "ResourceRecords": [{"Value": "192.0.2.11"}]},
+		{"Name": "geo.example.com.", "Type": "A", "TTL": 60, "SetIdentifier": "eu", "GeoLocation": {"ContinentCode": "EU"}, "ResourceRecords": [{"Value": "192.0.2.12"}]},
+		{"Name": "ha.example.com.", "Type": "A", "TTL": 60, "SetIdentifier": "b", "Failover": "SECONDARY", "ResourceRecords": [{"Value": "192.0.2.21"}]},
+		{"Name": "lb.example.com.", "Type": "A", "SetIdentifier": "a", "Weight": 50, "AliasTarget": {"HostedZoneId": "Z2", "DNSName": "lb-a.example.net."}},
+		{"Name": "lb.example.com.", "Type": "A", "SetIdentifier": "b", "Weight": 50, "AliasTarget": {"HostedZoneId": "Z2", "DNSName": "lb-b.example.net."}},
+		{"Name": "mail.example.com.", "Type": "MX", "TTL": 300, "ResourceRecords": [{"Value": "10 mx1.example.com."}]},
+		{"Name": "mail.example.com.", "Type": "A", "AliasTarget": {"HostedZoneId": "Z2", "DNSName": "mail-lb.example.net."}}
+	]}`)
+	expect(t, res, []string{
+		"@ MX 300 10 mx1.example.com",
+		"@ MX 300 20 mx2.example.com",
+		"@ TXT 300 v=spf1 include:_spf.example.net -all",
+		"* CNAME 60 www.example.com",
+		"app CNAME 300 d111.cloudfront.net",
+		"www A 60 192.0.2.1",
+		"www A 60 192.0.2.2",
+		"geo A 60 192.0.2.10",
+		"geo A 60 192.0.2.11 DE",
+		"lb CNAME 300 lb-a.example.net",
+		"mail MX 300 10 mx1.example.com",
+	}, 13)
+	for _, want := range []string{"conflicts with the CNAME to lb-a.example.net", "conflicts with other records at mail"} {
+		if !slices.ContainsFunc(res.Warnings, func(w Warning) bool { return strings.Contains(w.Message, want) }) {
+			t.Errorf("missing warning %q in %v", want, res.Warnings)
+		}
+	}
+}
+
+func TestOctoDNSRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	records := []enzonix.Record{
+		{Name: "@", Type: "MX", TTL: 3600, Priority: 10, Value: "mx.example.com"},
+		{Name: "@", Type: "TXT", TTL: 3600, Value: "v=spf1 include:_spf.example.net; -all"},
+		{Name: "_sip._tcp", Type: "SRV", TTL: 600, Priority: 5, Value: "10 5060 sip.example.com"},
+		{Name: "@", Type: "CAA", TTL: 3600, Value: `0 issue "letsencrypt.org"`},
+		{Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"},
+		{Name: "www", Type: "A", TTL: 300, Value: "192.0.2.2"},
+	}
+	var b bytes.Buffer
+	if _, err := export.Write(&b, export.OctoDNS, enzonix.Domain{Name: "example.com."}, records); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, parse(t, OctoDNS, b.String()), []string{
+		`@ CAA 3600 0 issue "letsencrypt.org"`,
+		"@ MX 3600 10 mx.example.com",
+		"@ TXT 3600 v=spf1 include:_spf.example.net; -all",
+		"_sip._tcp SRV 600 5 10 5060 sip.example.com",
+		"www A 300 192.0.2.1",
+		"www A 300 192.0.2.2",
+	}, 0)
+
+	res := parse(t, OctoDNS, `---
+'':
+  type: A
+  value: 192.0.2.1
+  dynamic:
+    pools: {}
+old:
+  type: MX
+  values:
+  - priority: 5
+    value: mx.example.com.
+`)
+	expect(t, res, []string{"@ A 3600 192.0.2.1", "old MX 3600 5 mx.example.com"}, 1)
+}
+
+func TestTinyDNS(t *testing.T) {
+	t.Parallel()
+
+	res := parse(t, TinyDNS, `# example.com
+Zexample.com:ns1.example.com.:hostmaster.example.com.
+.example.com:192.0.2.53:a:259200
+=www.example.com:192.0.2.1:300
+@example.com:192.0.2.25:mx1:10
+'example.com:v=spf1\072 -all\040ok:300
+'example.com:v=spf1\072 -all\040ok:300
+Cftp.example.com:www.example.com
+&sub.example.com::ns.other.org
+:v6.example.com:28:\040\001\015\270\000\000\000\000\000\000\000\000\000\000\000\001
+:loc.example.com:29:\000
++lo.example.com:192.0.2.7:300::in
+-off.example.com:192.0.2.8
+`)
+	expect(t, res, []string{
+		"a.ns A 259200 192.0.2.53",
+		"www A 300 192.0.2.1",
+		"@ MX 86400 10 mx1.mx.example.com",
+		"mx1.mx A 86400 192.0.2.25",
+		"@ TXT 300 v=spf1: -all ok",
+		"ftp CNAME 86400 www.example.com",
+		"sub NS 259200 ns.other.org",
+		"v6 AAAA 86400 2001:db8::1",
+		"lo A 300 192.0.2.7",
+	}, 3)
+
+	rev, err := Parse(strings.NewReader("=www.example.com:192.0.2.1:300\n"), TinyDNS, "2.0.192.in-addr.arpa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, rev, []string{"1 PTR 300 www.example.com"}, 0)
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	records := []enzonix.Record{
+		{Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"},
+		{Name: "www", Type: "A", TTL: 300, Value: "192.0.2.9", CountryCodes: []string{"DE", "AT"}},
+		{Name: "@", Type: "MX", TTL: 3600, Priority: 10, Value: "mx.example.com"},
+		{Name: "@", Type: "TXT", TTL: 3600, Value: `say "hi", twice`},
+	}
+	var b bytes.Buffer
+	if _, err := export.Write(&b, export.CSV, enzonix.Domain{Name: "example.com."}, records); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, parse(t, CSV, b.String()), []string{
+		"@ MX 3600 10 mx.example.com",
+		`@ TXT 3600 say "hi", twice`,
+		"www A 300 192.0.2.1",
+		"www A 300 192.0.2.9 AT,DE",
+	}, 0)
+
+	res := parse(t, CSV, "Type,Name,Value,TTL\nA,api.example.com.,192.0.2.3,\nA,x.other.org.,192.0.2.4,\nLOC,@,1 2 3,\n")
+	expect(t, res, []string{"api A 3600 192.0.2.3"}, 2)
+
+	if _, err := Parse(strings.NewReader("name,value\n"), CSV, "example.com"); err == nil {
+		t.Fatal("expected an error for a missing type column")
+	}
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	fake, client := enzonixtest.NewClient(t)
+	res := parse(t, CSV, "name,type,ttl,priority,value,country_codes\n"+
+		"www,A,300,,192.0.2.1,\n@,MX,3600,10,mx.example.com,\n_sip._tcp,SRV,600,5,10 5060 sip.example.com,\n@,TXT,3600,,v=spf1 -all,\n")
+	data, warnings := res.Bind()
+	if len(warnings) != 0 || !strings.Contains(string(data), "@\t3600\tIN\tMX\t10 mx.example.com.\n") {
+		t.Fatalf("unexpected zone file %v\n%s", warnings, data)
+	}
+	resp, err := res.Import(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.RecordsCreated != 4 || len(fake.Records(resp.Domain.ID)) != 4 {
+		t.Fatalf("unexpected import %+v", resp)
+	}
+
+	geo := parse(t, CSV, "name,type,value,country_codes\nwww,A,192.0.2.9,DE\n")
+	if _, err := geo.Import(context.Background(), client); err == nil {
+		t.Fatal("expected Import to refuse country codes")
+	}
+}
+
+func TestProviderSync(t *testing.T) {
+	t.Parallel()
+
+	fake, client := enzonixtest.NewClient(t)
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "old", Type: "A", TTL: 300, Value: "192.0.2.5"})
+
+	res := parse(t, CSV, "name,type,ttl,value,country_codes\nwww,A,300,192.0.2.1,\nwww,A,300,192.0.2.9,DE\n")
+	result, err := mirror.Sync(context.Background(), res.Provider(), mirror.NewEnzonix(client), res.Zone, mirror.SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changes) != 2 {
+		t.Fatalf("unexpected changes %v", result.Changes)
+	}
+	records, err := client.ListDomainRecords(context.Background(), domain.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range records {
+		got = append(got, r.Value+" "+strings.Join(r.CountryCodes, ","))
+	}
+	slices.Sort(got)
+	if want := []string{"192.0.2.1 ", "192.0.2.9 DE"}; !slices.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
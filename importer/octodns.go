@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/yamlite"
+)
+
+// octoSet is one record set of an OctoDNS zone file.
+type octoSet struct {
+	Type    string            `json:"type"`
+	TTL     int               `json:"ttl"`
+	Value   json.RawMessage   `json:"value"`
+	Values  []json.RawMessage `json:"values"`
+	Geo     json.RawMessage   `json:"geo"`
+	Dynamic json.RawMessage   `json:"dynamic"`
+}
+
+// octoValue holds the fields of the structured OctoDNS values. MX records
+// written by old OctoDNS versions use priority and value.
+type octoValue struct {
+	Exchange   string `json:"exchange"`
+	Preference *int   `json:"preference"`
+	Priority   int    `json:"priority"`
+	Value      string `json:"value"`
+	Port       int    `json:"port"`
+	Target     string `json:"target"`
+	Weight     int    `json:"weight"`
+	Flags      int    `json:"flags"`
+	Tag        string `json:"tag"`
+}
+
+// octodns reads an OctoDNS YAML zone file. Names are relative to the
+// zone, the apex under an empty key, and targets fully qualified. Geo and
+// dynamic rules are dropped in favour of the set's plain values, and
+// escaped semicolons in TXT values are restored.
+func (res *Result) octodns(data []byte) error {
+	var doc map[string]json.RawMessage
+	if err := yamlite.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(doc))
+	for name := range doc {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, key := range names {
+		var sets []octoSet
+		raw := bytes.TrimSpace(doc[key])
+		if len(raw) > 0 && raw[0] == '[' {
+			if err := json.Unmarshal(raw, &sets); err != nil {
+				return fmt.Errorf("%q: %w", key, err)
+			}
+		} else {
+			var set octoSet
+			if err := json.Unmarshal(raw, &set); err != nil {
+				return fmt.Errorf("%q: %w", key, err)
+			}
+			sets = []octoSet{set}
+		}
+		name := strings.ToLower(key)
+		if name == "" {
+			name = "@"
+		}
+
+		for _, set := range sets {
+			entry := name + " " + set.Type
+			if len(set.Geo) > 0 || len(set.Dynamic) > 0 {
+				res.warn(entry, "geo and dynamic rules dropped; imported with the default values")
+			}
+			values := set.Values
+			if len(set.Value) > 0 {
+				values = append(values, set.Value)
+			}
+			for _, v := range values {
+				priority, value, err := octoRecord(set.Type, v)
+				if err != nil {
+					res.warn(entry, "%v; skipped", err)
+					continue
+				}
+				res.add(entry, name, set.Type, set.TTL, priority, value, nil)
+			}
+		}
+	}
+	return nil
+}
+
+// octoRecord converts one OctoDNS value to a priority and an Enzonix
+// value.
+func octoRecord(typ string, raw json.RawMessage) (int, string, error) {
+	switch strings.ToUpper(typ) {
+	case "MX", "SRV", "CAA":
+		var v octoValue
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return 0, "", fmt.Errorf("malformed %s value %s", typ, raw)
+		}
+		switch strings.ToUpper(typ) {
+		case "MX":
+			if v.Preference != nil {
+				return *v.Preference, v.Exchange, nil
+			}
+			return v.Priority, v.Value, nil
+		case "SRV":
+			return v.Priority, fmt.Sprintf("%d %d %s", v.Weight, v.Port, v.Target), nil
+		}
+		return 0, fmt.Sprintf("%d %s %q", v.Flags, v.Tag, v.Value), nil
+	}
+
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return 0, "", err
+	}
+	var value string
+	switch v := v.(type) {
+	case string:
+		value = v
+	case float64:
+		// YAML reads unquoted digits as numbers.
+		value = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return 0, "", fmt.Errorf("malformed %s value %s", typ, raw)
+	}
+	if t := strings.ToUpper(typ); t == "TXT" || t == "SPF" {
+		value = strings.ReplaceAll(value, `\;`, ";")
+	}
+	return 0, value, nil
+}
@@ -0,0 +1,51 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/mirror"
+)
+
+// Provider returns a read-only mirror.Provider holding the imported zone,
+// the source for reconciling a domain against the import:
+//
+//	res, err := importer.Parse(f, importer.Route53, "example.com")
+//	...
+//	_, err = mirror.Sync(ctx, res.Provider(), mirror.NewEnzonix(client), res.Zone, mirror.SyncOptions{})
+//
+// Unlike Import, this keeps records limited to countries and updates an
+// existing domain in place.
+func (res *Result) Provider() mirror.Provider {
+	return resultProvider{res}
+}
+
+type resultProvider struct{ res *Result }
+
+func (p resultProvider) Name() string { return "import:" + p.res.Zone }
+
+func (p resultProvider) Capabilities() mirror.Capabilities {
+	return mirror.Capabilities{CountryCodes: true}
+}
+
+func (p resultProvider) ListZones(ctx context.Context) ([]mirror.Zone, error) {
+	return []mirror.Zone{{ID: p.res.Zone, Name: p.res.Zone}}, nil
+}
+
+func (p resultProvider) ListRecords(ctx context.Context, zone mirror.Zone) ([]enzonix.Record, error) {
+	records := make([]enzonix.Record, 0, len(p.res.Records))
+	for i, r := range p.res.Records {
+		rec := enzonix.Record{ID: strconv.Itoa(i + 1), Name: r.Name, Type: r.Type, TTL: *r.TTL, Value: r.Value, CountryCodes: r.CountryCodes}
+		if r.Priority != nil {
+			rec.Priority = *r.Priority
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func (p resultProvider) ApplyChanges(ctx context.Context, zone mirror.Zone, changes []mirror.Change) error {
+	return errors.New("importer: imported zones are read-only")
+}
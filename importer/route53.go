@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// r53Set is a resource record set as list-resource-record-sets prints it.
+type r53Set struct {
+	Name            string `json:"Name"`
+	Type            string `json:"Type"`
+	TTL             int    `json:"TTL"`
+	ResourceRecords []struct {
+		Value string `json:"Value"`
+	} `json:"ResourceRecords"`
+	AliasTarget *struct {
+		DNSName string `json:"DNSName"`
+	} `json:"AliasTarget"`
+	SetIdentifier string `json:"SetIdentifier"`
+	Weight        *int   `json:"Weight"`
+	Region        string `json:"Region"`
+	Failover      string `json:"Failover"`
+	GeoLocation   *struct {
+		ContinentCode   string `json:"ContinentCode"`
+		CountryCode     string `json:"CountryCode"`
+		SubdivisionCode string `json:"SubdivisionCode"`
+	} `json:"GeoLocation"`
+	MultiValueAnswer bool `json:"MultiValueAnswer"`
+}
+
+// r53AliasTTL is the TTL given to alias records, which have none.
+const r53AliasTTL = 300
+
+// route53 reads the output of aws route53 list-resource-record-sets.
+//
+// Alias records become CNAMEs, except at the apex where no CNAME may be.
+// Weighted, latency and multivalue sets are merged into one set; failover
+// sets keep the primary. When merged sets leave several CNAME targets at
+// a name, or a CNAME next to other records, the first CNAME wins only
+// where nothing else is at the name. Geolocation sets keep their country,
+// "*" being the default answer; continent sets have no equivalent and are
+// skipped. The SOA and the apex NS records belong to the hosted zone and
+// are left out.
+func (res *Result) route53(data []byte) error {
+	var doc struct {
+		ResourceRecordSets []r53Set `json:"ResourceRecordSets"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	for _, set := range doc.ResourceRecordSets {
+		fqdn := unescape(set.Name)
+		entry := fqdn + " " + set.Type
+		if set.SetIdentifier != "" {
+			entry += " (" + set.SetIdentifier + ")"
+		}
+		name, ok := res.relative(fqdn)
+		if !ok {
+			res.warn(entry, "name outside %s; skipped", res.Zone)
+			continue
+		}
+		if set.Type == "SOA" || (set.Type == "NS" && name == "@") {
+			continue
+		}
+
+		var countries []string
+		switch {
+		case set.GeoLocation != nil:
+			geo := set.GeoLocation
+			switch {
+			case geo.CountryCode == "*":
+			case geo.CountryCode != "":
+				countries = []string{geo.CountryCode}
+				if geo.SubdivisionCode != "" {
+					res.warn(entry, "subdivision %s widened to country %s", geo.SubdivisionCode, geo.CountryCode)
+				}
+			default:
+				res.warn(entry, "continent %s has no equivalent; skipped", geo.ContinentCode)
+				continue
+			}
+		case set.Failover == "SECONDARY":
+			res.warn(entry, "failover secondary skipped; only the primary is imported")
+			continue
+		case set.Failover != "":
+			res.warn(entry, "failover routing dropped; imported as a plain record set")
+		case set.Weight != nil:
+			res.warn(entry, "weighted routing dropped; merged with the other sets of %s", set.Type)
+		case set.Region != "":
+			res.warn(entry, "latency routing dropped; merged with the other sets of %s", set.Type)
+		case set.MultiValueAnswer:
+			res.warn(entry, "multivalue answer merged with the other sets of %s", set.Type)
+		}
+
+		if set.AliasTarget != nil {
+			target := unescape(set.AliasTarget.DNSName)
+			if name == "@" {
+				res.warn(entry, "alias to %s at the apex has no equivalent; skipped", target)
+				continue
+			}
+			if set.Type != "A" && set.Type != "AAAA" && set.Type != "CNAME" {
+				res.warn(entry, "alias of type %s has no equivalent; skipped", set.Type)
+				continue
+			}
+			res.warn(entry, "alias to %s imported as a CNAME", target)
+			res.add(entry, name, "CNAME", r53AliasTTL, 0, target, countries)
+			continue
+		}
+
+		for _, rr := range set.ResourceRecords {
+			priority, value := 0, rr.Value
+			switch set.Type {
+			case "TXT", "SPF":
+				value = joinStrings(value)
+			case "MX", "SRV":
+				if p, rest, ok := strings.Cut(value, " "); ok {
+					if n, err := strconv.Atoi(p); err == nil {
+						priority, value = n, rest
+					}
+				}
+			}
+			res.add(entry, name, set.Type, set.TTL, priority, value, countries)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// tinydns TTL defaults, as tinydns-data applies them.
+const (
+	tinyTTL   = 86400
+	tinyNSTTL = 259200
+)
+
+// tinydns reads a tinydns-data file. Apex name servers and SOA records
+// belong to the old provider and are left out; location-restricted lines
+// are imported for everyone. Generic ":" lines are decoded for AAAA, SRV
+// and CAA.
+func (res *Result) tinydns(data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || line[0] == '#' || line[0] == '-' || line[0] == '%' || line[0] == 'Z' {
+			continue
+		}
+		entry := "line " + strconv.Itoa(n)
+		f := strings.Split(line[1:], ":")
+		field := func(i int) string {
+			if i < len(f) {
+				return f[i]
+			}
+			return ""
+		}
+		ttlField := func(i, def int) int {
+			if ttl, err := strconv.Atoi(field(i)); err == nil {
+				return ttl
+			}
+			return def
+		}
+		fqdn := strings.ToLower(unescape(field(0)))
+		name, ok := res.relative(fqdn)
+		if !ok && line[0] != '=' && line[0] != '6' {
+			res.warn(entry, "name %s outside %s; skipped", fqdn, res.Zone)
+			continue
+		}
+		if lo := map[byte]int{'+': 4, '=': 4, '@': 6, '.': 5, '&': 5, '\'': 4, '^': 4, 'C': 4, ':': 5, '6': 4, '3': 4}[line[0]]; lo > 0 && field(lo) != "" {
+			res.warn(entry, "location %s dropped; imported for all clients", field(lo))
+		}
+
+		switch line[0] {
+		case '+', '=':
+			ttl := ttlField(2, tinyTTL)
+			if ok {
+				res.add(entry, name, "A", ttl, 0, field(1), nil)
+			}
+			if line[0] == '=' {
+				if addr, err := netip.ParseAddr(field(1)); err == nil {
+					res.reverse(entry, addr, fqdn, ttl)
+				}
+			}
+		case '6', '3':
+			raw, err := hex.DecodeString(field(1))
+			if err != nil || len(raw) != 16 {
+				res.warn(entry, "malformed IPv6 address %q; skipped", field(1))
+				continue
+			}
+			addr := netip.AddrFrom16([16]byte(raw))
+			ttl := ttlField(2, tinyTTL)
+			if ok {
+				res.add(entry, name, "AAAA", ttl, 0, addr.String(), nil)
+			}
+			if line[0] == '6' {
+				res.reverse(entry, addr, fqdn, ttl)
+			}
+		case '.', '&':
+			host := tinyHost(field(2), "ns", fqdn)
+			ttl := ttlField(3, tinyNSTTL)
+			if name == "@" {
+				res.warn(entry, "apex name server %s belongs to the old provider; skipped", host)
+			} else {
+				res.add(entry, name, "NS", ttl, 0, host, nil)
+			}
+			res.glue(entry, host, field(1), ttl)
+		case '@':
+			host := tinyHost(field(2), "mx", fqdn)
+			dist, _ := strconv.Atoi(field(3))
+			ttl := ttlField(4, tinyTTL)
+			res.add(entry, name, "MX", ttl, dist, host, nil)
+			res.glue(entry, host, field(1), ttl)
+		case '\'':
+			res.add(entry, name, "TXT", ttlField(2, tinyTTL), 0, unescape(field(1)), nil)
+		case '^':
+			res.add(entry, name, "PTR", ttlField(2, tinyTTL), 0, unescape(field(1)), nil)
+		case 'C':
+			res.add(entry, name, "CNAME", ttlField(2, tinyTTL), 0, unescape(field(1)), nil)
+		case 'S':
+			host := tinyHost(field(2), "srv", fqdn)
+			prio, _ := strconv.Atoi(field(4))
+			weight, _ := strconv.Atoi(field(5))
+			ttl := ttlField(6, tinyTTL)
+			res.add(entry, name, "SRV", ttl, prio, fmt.Sprintf("%d %s %s", weight, field(3), host), nil)
+			res.glue(entry, host, field(1), ttl)
+		case ':':
+			typ, priority, value, err := tinyGeneric(field(1), unescape(field(2)))
+			if err != nil {
+				res.warn(entry, "%v; skipped", err)
+				continue
+			}
+			res.add(entry, name, typ, ttlField(3, tinyTTL), priority, value, nil)
+		default:
+			res.warn(entry, "unknown line type %q; skipped", line[0])
+		}
+	}
+	return scanner.Err()
+}
+
+// tinyHost expands the host field of NS, MX and SRV lines: names without
+// a dot are prefixed to "<kind>.<fqdn>", as tinydns-data does.
+func tinyHost(host, kind, fqdn string) string {
+	host = strings.ToLower(unescape(host))
+	if strings.Contains(host, ".") {
+		return host
+	}
+	return host + "." + kind + "." + fqdn
+}
+
+// glue adds the address record of a host named on an NS, MX or SRV line.
+func (res *Result) glue(entry, host, ip string, ttl int) {
+	if ip == "" {
+		return
+	}
+	if name, ok := res.relative(host); ok {
+		res.add(entry, name, "A", ttl, 0, ip, nil)
+	}
+}
+
+// reverse adds the PTR record of an "=" or "6" line when the reverse name
+// falls inside the zone.
+func (res *Result) reverse(entry string, addr netip.Addr, fqdn string, ttl int) {
+	var labels []string
+	if addr.Is4() {
+		for _, b := range addr.As4() {
+			labels = append([]string{strconv.Itoa(int(b))}, labels...)
+		}
+		labels = append(labels, "in-addr", "arpa")
+	} else {
+		for _, b := range addr.As16() {
+			labels = append([]string{fmt.Sprintf("%x", b>>4), fmt.Sprintf("%x", b&15)}, labels...)
+		}
+		labels = append(labels, "ip6", "arpa")
+	}
+	if name, ok := res.relative(strings.Join(labels, ".")); ok {
+		res.add(entry, name, "PTR", ttl, 0, fqdn, nil)
+	}
+}
+
+// tinyGeneric decodes the rdata of a generic line.
+func tinyGeneric(typeField, rdata string) (typ string, priority int, value string, err error) {
+	n, err := strconv.Atoi(typeField)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("malformed record type %q", typeField)
+	}
+	data := []byte(rdata)
+	switch n {
+	case 28:
+		if len(data) == 16 {
+			return "AAAA", 0, netip.AddrFrom16([16]byte(data)).String(), nil
+		}
+	case 33:
+		if len(data) > 6 {
+			if target, ok := wireName(data[6:]); ok {
+				return "SRV", int(binary.BigEndian.Uint16(data)), fmt.Sprintf("%d %d %s", binary.BigEndian.Uint16(data[2:]), binary.BigEndian.Uint16(data[4:]), target), nil
+			}
+		}
+	case 257:
+		if len(data) > 2 && len(data) >= 2+int(data[1]) {
+			tag := string(data[2 : 2+data[1]])
+			return "CAA", 0, fmt.Sprintf("%d %s %q", data[0], tag, data[2+data[1]:]), nil
+		}
+	default:
+		return "", 0, "", fmt.Errorf("generic record type %d not supported", n)
+	}
+	return "", 0, "", fmt.Errorf("malformed type %d data", n)
+}
+
+// wireName decodes an uncompressed domain name in wire format.
+func wireName(data []byte) (string, bool) {
+	var labels []string
+	for len(data) > 0 {
+		l := int(data[0])
+		if l == 0 {
+			return strings.Join(labels, "."), len(labels) > 0
+		}
+		if l > 63 || 1+l > len(data) {
+			break
+		}
+		labels = append(labels, string(data[1:1+l]))
+		data = data[1+l:]
+	}
+	return "", false
+}
@@ -0,0 +1,434 @@
+// Package dnswire packs and unpacks DNS messages (RFC 1035) for the SDK's
+// DNS client and servers. It covers the record types Enzonix serves and
+// keeps rdata in uncompressed wire form; names inside rdata are expanded
+// while unpacking so that messages can be re-packed without context.
+package dnswire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Record types.
+const (
+	TypeA     uint16 = 1
+	TypeNS    uint16 = 2
+	TypeCNAME uint16 = 5
+	TypeSOA   uint16 = 6
+	TypePTR   uint16 = 12
+	TypeMX    uint16 = 15
+	TypeTXT   uint16 = 16
+	TypeAAAA  uint16 = 28
+	TypeSRV   uint16 = 33
+	TypeOPT   uint16 = 41
+	TypeTSIG  uint16 = 250
+	TypeIXFR  uint16 = 251
+	TypeAXFR  uint16 = 252
+	TypeANY   uint16 = 255
+	TypeCAA   uint16 = 257
+)
+
+// Classes.
+const (
+	ClassINET uint16 = 1
+	ClassNONE uint16 = 254
+	ClassANY  uint16 = 255
+)
+
+// Opcodes.
+const (
+	OpcodeQuery  uint8 = 0
+	OpcodeNotify uint8 = 4
+	OpcodeUpdate uint8 = 5
+)
+
+// Response codes.
+const (
+	RcodeSuccess  uint8 = 0
+	RcodeFormErr  uint8 = 1
+	RcodeServFail uint8 = 2
+	RcodeNXDomain uint8 = 3
+	RcodeNotImp   uint8 = 4
+	RcodeRefused  uint8 = 5
+	RcodeYXDomain uint8 = 6
+	RcodeYXRRSet  uint8 = 7
+	RcodeNXRRSet  uint8 = 8
+	RcodeNotAuth  uint8 = 9
+	RcodeNotZone  uint8 = 10
+)
+
+var typeNames = map[uint16]string{
+	TypeA: "A", TypeNS: "NS", TypeCNAME: "CNAME", TypeSOA: "SOA", TypePTR: "PTR",
+	TypeMX: "MX", TypeTXT: "TXT", TypeAAAA: "AAAA", TypeSRV: "SRV", TypeOPT: "OPT",
+	TypeTSIG: "TSIG", TypeIXFR: "IXFR", TypeAXFR: "AXFR", TypeANY: "ANY", TypeCAA: "CAA",
+}
+
+// TypeString returns the mnemonic of a record type, e.g. "MX".
+func TypeString(t uint16) string {
+	if s, ok := typeNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("TYPE%d", t)
+}
+
+// ParseType returns the record type for a mnemonic such as "aaaa".
+func ParseType(s string) (uint16, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	for t, name := range typeNames {
+		if name == s {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// ErrShort is returned for truncated or malformed messages.
+var ErrShort = errors.New("dnswire: message too short")
+
+// Header is the fixed message header without the section counts.
+type Header struct {
+	ID                 uint16
+	Response           bool
+	Opcode             uint8
+	Authoritative      bool
+	Truncated          bool
+	RecursionDesired   bool
+	RecursionAvailable bool
+	Rcode              uint8
+}
+
+// Question is an entry of the question section. Name is fully qualified
+// with a trailing dot.
+type Question struct {
+	Name  string
+	Type  uint16
+	Class uint16
+}
+
+// RR is a resource record with raw, uncompressed rdata.
+type RR struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	TTL   uint32
+	Data  []byte
+}
+
+// Message is a DNS message.
+type Message struct {
+	Header
+	Questions  []Question
+	Answers    []RR
+	Authority  []RR
+	Additional []RR
+}
+
+// Pack encodes the message without name compression.
+func (m *Message) Pack() ([]byte, error) {
+	b := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(b[0:], m.ID)
+	var flags uint16
+	if m.Response {
+		flags |= 1 << 15
+	}
+	flags |= uint16(m.Opcode&0xf) << 11
+	if m.Authoritative {
+		flags |= 1 << 10
+	}
+	if m.Truncated {
+		flags |= 1 << 9
+	}
+	if m.RecursionDesired {
+		flags |= 1 << 8
+	}
+	if m.RecursionAvailable {
+		flags |= 1 << 7
+	}
+	flags |= uint16(m.Rcode & 0xf)
+	binary.BigEndian.PutUint16(b[2:], flags)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.Questions)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.Answers)))
+	binary.BigEndian.PutUint16(b[8:], uint16(len(m.Authority)))
+	binary.BigEndian.PutUint16(b[10:], uint16(len(m.Additional)))
+
+	var err error
+	for _, q := range m.Questions {
+		if b, err = AppendName(b, q.Name); err != nil {
+			return nil, err
+		}
+		b = binary.BigEndian.AppendUint16(b, q.Type)
+		b = binary.BigEndian.AppendUint16(b, q.Class)
+	}
+	for _, section := range [][]RR{m.Answers, m.Authority, m.Additional} {
+		for _, rr := range section {
+			if b, err = AppendRR(b, rr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b, nil
+}
+
+// AppendRR appends the wire form of rr to b.
+func AppendRR(b []byte, rr RR) ([]byte, error) {
+	if len(rr.Data) > 0xffff {
+		return nil, fmt.Errorf("dnswire: rdata of %s too long", rr.Name)
+	}
+	b, err := AppendName(b, rr.Name)
+	if err != nil {
+		return nil, err
+	}
+	b = binary.BigEndian.AppendUint16(b, rr.Type)
+	b = binary.BigEndian.AppendUint16(b, rr.Class)
+	b = binary.BigEndian.AppendUint32(b, rr.TTL)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(rr.Data)))
+	return append(b, rr.Data...), nil
+}
+
+// AppendName appends the uncompressed wire form of a domain name.
+func AppendName(b []byte, name string) ([]byte, error) {
+	name = Fqdn(name)
+	if name == "." {
+		return append(b, 0), nil
+	}
+	if len(name) > 254 {
+		return nil, fmt.Errorf("dnswire: name %q too long", name)
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return nil, fmt.Errorf("dnswire: invalid name %q", name)
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0), nil
+}
+
+// Unpack decodes a message.
+func Unpack(msg []byte) (*Message, error) {
+	if len(msg) < 12 {
+		return nil, ErrShort
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	m := &Message{Header: Header{
+		ID:                 binary.BigEndian.Uint16(msg[0:]),
+		Response:           flags&(1<<15) != 0,
+		Opcode:             uint8(flags>>11) & 0xf,
+		Authoritative:      flags&(1<<10) != 0,
+		Truncated:          flags&(1<<9) != 0,
+		RecursionDesired:   flags&(1<<8) != 0,
+		RecursionAvailable: flags&(1<<7) != 0,
+		Rcode:              uint8(flags & 0xf),
+	}}
+	counts := [4]int{
+		int(binary.BigEndian.Uint16(msg[4:])),
+		int(binary.BigEndian.Uint16(msg[6:])),
+		int(binary.BigEndian.Uint16(msg[8:])),
+		int(binary.BigEndian.Uint16(msg[10:])),
+	}
+
+	off := 12
+	for i := 0; i < counts[0]; i++ {
+		name, next, err := readName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		if next+4 > len(msg) {
+			return nil, ErrShort
+		}
+		m.Questions = append(m.Questions, Question{
+			Name:  name,
+			Type:  binary.BigEndian.Uint16(msg[next:]),
+			Class: binary.BigEndian.Uint16(msg[next+2:]),
+		})
+		off = next + 4
+	}
+
+	sections := []*[]RR{&m.Answers, &m.Authority, &m.Additional}
+	for s, section := range sections {
+		for i := 0; i < counts[s+1]; i++ {
+			rr, next, err := readRR(msg, off)
+			if err != nil {
+				return nil, err
+			}
+			*section = append(*section, rr)
+			off = next
+		}
+	}
+	return m, nil
+}
+
+// RROffsets returns the offset of every resource record in msg, in order
+// across the answer, authority and additional sections. TSIG signing uses
+// it to strip the signature record.
+func RROffsets(msg []byte) ([]int, error) {
+	if len(msg) < 12 {
+		return nil, ErrShort
+	}
+	off := 12
+	for i := 0; i < int(binary.BigEndian.Uint16(msg[4:])); i++ {
+		_, next, err := readName(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = next + 4
+	}
+	total := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) + int(binary.BigEndian.Uint16(msg[10:]))
+	offsets := make([]int, 0, total)
+	for i := 0; i < total; i++ {
+		offsets = append(offsets, off)
+		_, next, err := readRR(msg, off)
+		if err != nil {
+			return nil, err
+		}
+		off = next
+	}
+	return offsets, nil
+}
+
+func readRR(msg []byte, off int) (RR, int, error) {
+	name, off, err := readName(msg, off)
+	if err != nil {
+		return RR{}, 0, err
+	}
+	if off+10 > len(msg) {
+		return RR{}, 0, ErrShort
+	}
+	rr := RR{
+		Name:  name,
+		Type:  binary.BigEndian.Uint16(msg[off:]),
+		Class: binary.BigEndian.Uint16(msg[off+2:]),
+		TTL:   binary.BigEndian.Uint32(msg[off+4:]),
+	}
+	length := int(binary.BigEndian.Uint16(msg[off+8:]))
+	off += 10
+	if off+length > len(msg) {
+		return RR{}, 0, ErrShort
+	}
+	data, err := expandRData(msg, off, length, rr.Type)
+	if err != nil {
+		return RR{}, 0, err
+	}
+	rr.Data = data
+	return rr, off + length, nil
+}
+
+// expandRData copies rdata, decompressing embedded names of the types
+// that may use compression.
+func expandRData(msg []byte, off, length int, typ uint16) ([]byte, error) {
+	end := off + length
+	raw := msg[off:end]
+	var (
+		prefix int // fixed bytes before the first name
+		names  int
+	)
+	switch typ {
+	case TypeNS, TypeCNAME, TypePTR:
+		names = 1
+	case TypeMX:
+		prefix, names = 2, 1
+	case TypeSRV:
+		prefix, names = 6, 1
+	case TypeSOA:
+		names = 2
+	default:
+		return append([]byte(nil), raw...), nil
+	}
+	if prefix > length {
+		return nil, ErrShort
+	}
+	out := append([]byte(nil), raw[:prefix]...)
+	pos := off + prefix
+	for i := 0; i < names; i++ {
+		name, next, err := readName(msg, pos)
+		if err != nil {
+			return nil, err
+		}
+		if next > end {
+			return nil, ErrShort
+		}
+		if out, err = AppendName(out, name); err != nil {
+			return nil, err
+		}
+		pos = next
+	}
+	return append(out, msg[pos:end]...), nil
+}
+
+// readName reads a possibly compressed name at off and returns it with the
+// offset just past it.
+func readName(msg []byte, off int) (string, int, error) {
+	var (
+		b      strings.Builder
+		next   = -1
+		jumps  = 0
+		length = 0
+	)
+	for {
+		if off >= len(msg) {
+			return "", 0, ErrShort
+		}
+		c := int(msg[off])
+		switch c & 0xc0 {
+		case 0x00:
+			if c == 0 {
+				if next < 0 {
+					next = off + 1
+				}
+				if b.Len() == 0 {
+					return ".", next, nil
+				}
+				return b.String(), next, nil
+			}
+			if off+1+c > len(msg) {
+				return "", 0, ErrShort
+			}
+			length += c + 1
+			if length > 255 {
+				return "", 0, errors.New("dnswire: name too long")
+			}
+			b.Write(msg[off+1 : off+1+c])
+			b.WriteByte('.')
+			off += 1 + c
+		case 0xc0:
+			if off+1 >= len(msg) {
+				return "", 0, ErrShort
+			}
+			if jumps++; jumps > 32 {
+				return "", 0, errors.New("dnswire: compression loop")
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3fff)
+		default:
+			return "", 0, errors.New("dnswire: unsupported label type")
+		}
+	}
+}
+
+// ReadName decodes the uncompressed name at the start of b and returns it
+// with the remaining bytes.
+func ReadName(b []byte) (string, []byte, error) {
+	name, next, err := readName(b, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return name, b[next:], nil
+}
+
+// Fqdn returns name with a trailing dot.
+func Fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// EqualNames compares two names case-insensitively, ignoring a trailing
+// dot.
+func EqualNames(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
@@ -0,0 +1,85 @@
+package dnswire
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRDataRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("x", 300)
+	cases := []struct {
+		typ      uint16
+		value    string
+		priority int
+		want     string
+	}{
+		{TypeA, "192.0.2.1", 0, "192.0.2.1"},
+		{TypeAAAA, "2001:db8::1", 0, "2001:db8::1"},
+		{TypeCNAME, "target.example.net", 0, "target.example.net."},
+		{TypeNS, "ns1", 0, "ns1.example.com."},
+		{TypeMX, "mx.example.com.", 10, "mx.example.com."},
+		{TypeSRV, "5 5060 sip.example.com.", 20, "5 5060 sip.example.com."},
+		{TypeTXT, long, 0, long},
+		{TypeCAA, `0 issue "letsencrypt.org"`, 0, `0 issue "letsencrypt.org"`},
+		{TypeSOA, "ns1 hostmaster 7 3600 600 86400 300", 0, "ns1.example.com. hostmaster.example.com. 7 3600 600 86400 300"},
+	}
+	for _, tc := range cases {
+		data, err := PackRData(tc.typ, tc.value, tc.priority, "example.com")
+		if err != nil {
+			t.Fatalf("%s: pack: %v", TypeString(tc.typ), err)
+		}
+		value, priority, err := UnpackRData(tc.typ, data)
+		if err != nil || value != tc.want || priority != tc.priority {
+			t.Fatalf("%s: got %q/%d, %v; want %q/%d", TypeString(tc.typ), value, priority, err, tc.want, tc.priority)
+		}
+	}
+
+	if _, err := PackRData(TypeA, "2001:db8::1", 0, ""); err == nil {
+		t.Fatal("expected an IPv6 address to be rejected for A")
+	}
+}
+
+func TestUnpackCompressed(t *testing.T) {
+	t.Parallel()
+
+	// A response to "www.example.com. MX" whose answer owner and exchange
+	// are compressed against the question.
+	msg := []byte{
+		0x12, 0x34, 0x84, 0x00, 0, 1, 0, 1, 0, 0, 0, 0,
+		3, 'w', 'w', 'w', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+		0, 15, 0, 1,
+		0xc0, 12, 0, 15, 0, 1, 0, 0, 0x0e, 0x10, 0, 7,
+		0, 10, 2, 'm', 'x', 0xc0, 16,
+	}
+	m, err := Unpack(msg)
+	if err != nil {
+		t.Fatalf("unpack: %v", err)
+	}
+	if !m.Response || !m.Authoritative || m.ID != 0x1234 || len(m.Answers) != 1 {
+		t.Fatalf("unexpected message %+v", m)
+	}
+	rr := m.Answers[0]
+	value, priority, err := UnpackRData(rr.Type, rr.Data)
+	if rr.Name != "www.example.com." || rr.TTL != 3600 || value != "mx.example.com." || priority != 10 || err != nil {
+		t.Fatalf("unexpected answer %+v: %q %d %v", rr, value, priority, err)
+	}
+
+	// Re-packing yields an equivalent uncompressed message.
+	packed, err := m.Pack()
+	if err != nil {
+		t.Fatalf("pack: %v", err)
+	}
+	again, err := Unpack(packed)
+	if err != nil || again.Answers[0].Name != rr.Name || string(again.Answers[0].Data) != string(rr.Data) {
+		t.Fatalf("round trip changed the message: %+v, %v", again, err)
+	}
+
+	loop := append([]byte(nil), msg[:12]...)
+	loop[5] = 1
+	loop = append(loop, 0xc0, 12, 0, 1, 0, 1)
+	if _, err := Unpack(loop); err == nil {
+		t.Fatal("expected a compression loop to be rejected")
+	}
+}
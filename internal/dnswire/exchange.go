@@ -0,0 +1,141 @@
+package dnswire
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// MaxUDPSize is the largest response read over UDP. Queries carry no EDNS
+// option, so compliant servers truncate at 512 bytes.
+const MaxUDPSize = 4096
+
+// NewQuery returns a recursion-free query with a random ID.
+func NewQuery(name string, typ uint16) *Message {
+	var id [2]byte
+	_, _ = rand.Read(id[:])
+	return &Message{
+		Header:    Header{ID: binary.BigEndian.Uint16(id[:])},
+		Questions: []Question{{Name: Fqdn(name), Type: typ, Class: ClassINET}},
+	}
+}
+
+// Exchange sends msg to server ("host:port") over UDP and repeats it over
+// TCP when the response is truncated. The context bounds both attempts.
+func Exchange(ctx context.Context, server string, msg *Message) (*Message, error) {
+	query, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := exchange(ctx, "udp", server, query, msg.ID)
+	if err != nil || !resp.Truncated {
+		return resp, err
+	}
+	return exchange(ctx, "tcp", server, query, msg.ID)
+}
+
+// ExchangeTCP sends msg to server over TCP only.
+func ExchangeTCP(ctx context.Context, server string, msg *Message) (*Message, error) {
+	query, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+	return exchange(ctx, "tcp", server, query, msg.ID)
+}
+
+func exchange(ctx context.Context, network, server string, query []byte, id uint16) (*Message, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	var raw []byte
+	if network == "tcp" {
+		if err := WriteTCP(conn, query); err != nil {
+			return nil, ctxErr(ctx, err)
+		}
+		if raw, err = ReadTCP(conn); err != nil {
+			return nil, ctxErr(ctx, err)
+		}
+	} else {
+		if _, err := conn.Write(query); err != nil {
+			return nil, ctxErr(ctx, err)
+		}
+		buf := make([]byte, MaxUDPSize)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return nil, ctxErr(ctx, err)
+			}
+			// Ignore stray datagrams that do not answer this query.
+			if n >= 2 && binary.BigEndian.Uint16(buf) == id {
+				raw = buf[:n]
+				break
+			}
+		}
+	}
+
+	resp, err := Unpack(raw)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ID != id || !resp.Response {
+		return nil, fmt.Errorf("dnswire: unexpected response from %s", server)
+	}
+	return resp, nil
+}
+
+// ctxErr prefers the context's error over the I/O error it caused.
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// ReadTCP reads one length-prefixed message from a TCP stream.
+func ReadTCP(r io.Reader) ([]byte, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// WriteTCP writes one length-prefixed message to a TCP stream.
+func WriteTCP(w io.Writer, msg []byte) error {
+	if len(msg) > 0xffff {
+		return errors.New("dnswire: message too long for TCP")
+	}
+	_, err := w.Write(append(binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(msg)), uint16(len(msg))), msg...))
+	return err
+}
+
+// Truncate returns packed resp cut down to fit size bytes: when it does not
+// fit, all records are dropped and the TC bit is set so that the client
+// retries over TCP.
+func Truncate(resp *Message, size int) ([]byte, error) {
+	b, err := resp.Pack()
+	if err != nil || len(b) <= size {
+		return b, err
+	}
+	short := &Message{Header: resp.Header, Questions: resp.Questions}
+	short.Truncated = true
+	return short.Pack()
+}
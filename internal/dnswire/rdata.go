@@ -0,0 +1,249 @@
+package dnswire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// PackRData converts an Enzonix-style value to rdata. Values follow the
+// zonefile package: MX and SRV priorities are passed separately, SRV values
+// are "weight port target", TXT values are unquoted and CAA values are
+// "flags tag \"value\"".
+//
+// Target names with a trailing dot are absolute. "@" and single labels are
+// relative to origin; other dotted names are taken as absolute, matching
+// how the API stores them.
+func PackRData(typ uint16, value string, priority int, origin string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	switch typ {
+	case TypeA, TypeAAAA:
+		addr, err := netip.ParseAddr(value)
+		if err != nil || (typ == TypeA) != addr.Is4() {
+			return nil, fmt.Errorf("dnswire: invalid %s address %q", TypeString(typ), value)
+		}
+		return addr.AsSlice(), nil
+	case TypeNS, TypeCNAME, TypePTR:
+		return AppendName(nil, targetName(value, origin))
+	case TypeMX:
+		if err := checkUint16(priority, "MX priority"); err != nil {
+			return nil, err
+		}
+		b := binary.BigEndian.AppendUint16(nil, uint16(priority))
+		return AppendName(b, targetName(value, origin))
+	case TypeSRV:
+		fields := strings.Fields(value)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("dnswire: SRV value %q is not \"weight port target\"", value)
+		}
+		if err := checkUint16(priority, "SRV priority"); err != nil {
+			return nil, err
+		}
+		b := binary.BigEndian.AppendUint16(nil, uint16(priority))
+		for _, f := range fields[:2] {
+			n, err := strconv.ParseUint(f, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("dnswire: invalid SRV value %q", value)
+			}
+			b = binary.BigEndian.AppendUint16(b, uint16(n))
+		}
+		return AppendName(b, targetName(fields[2], origin))
+	case TypeTXT:
+		var b []byte
+		for {
+			chunk := value
+			if len(chunk) > 255 {
+				chunk = chunk[:255]
+			}
+			b = append(b, byte(len(chunk)))
+			b = append(b, chunk...)
+			value = value[len(chunk):]
+			if value == "" {
+				return b, nil
+			}
+		}
+	case TypeCAA:
+		fields := strings.SplitN(value, " ", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("dnswire: CAA value %q is not \"flags tag value\"", value)
+		}
+		flags, err := strconv.ParseUint(fields[0], 10, 8)
+		if err != nil || fields[1] == "" || len(fields[1]) > 255 {
+			return nil, fmt.Errorf("dnswire: invalid CAA value %q", value)
+		}
+		b := []byte{byte(flags), byte(len(fields[1]))}
+		b = append(b, fields[1]...)
+		return append(b, unquote(fields[2])...), nil
+	case TypeSOA:
+		fields := strings.Fields(value)
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("dnswire: SOA value %q needs 7 fields", value)
+		}
+		b, err := AppendName(nil, targetName(fields[0], origin))
+		if err != nil {
+			return nil, err
+		}
+		if b, err = AppendName(b, targetName(fields[1], origin)); err != nil {
+			return nil, err
+		}
+		for _, f := range fields[2:] {
+			n, err := strconv.ParseUint(f, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("dnswire: invalid SOA value %q", value)
+			}
+			b = binary.BigEndian.AppendUint32(b, uint32(n))
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("dnswire: unsupported record type %s", TypeString(typ))
+}
+
+// UnpackRData is the inverse of PackRData. Names are returned fully
+// qualified.
+func UnpackRData(typ uint16, data []byte) (value string, priority int, err error) {
+	switch typ {
+	case TypeA, TypeAAAA:
+		addr, ok := netip.AddrFromSlice(data)
+		if !ok || (typ == TypeA) != (len(data) == 4) {
+			return "", 0, ErrShort
+		}
+		return addr.String(), 0, nil
+	case TypeNS, TypeCNAME, TypePTR:
+		name, _, err := ReadName(data)
+		return name, 0, err
+	case TypeMX:
+		if len(data) < 3 {
+			return "", 0, ErrShort
+		}
+		name, _, err := ReadName(data[2:])
+		return name, int(binary.BigEndian.Uint16(data)), err
+	case TypeSRV:
+		if len(data) < 7 {
+			return "", 0, ErrShort
+		}
+		name, _, err := ReadName(data[6:])
+		value := fmt.Sprintf("%d %d %s", binary.BigEndian.Uint16(data[2:]), binary.BigEndian.Uint16(data[4:]), name)
+		return value, int(binary.BigEndian.Uint16(data)), err
+	case TypeTXT:
+		var b strings.Builder
+		for len(data) > 0 {
+			n := int(data[0])
+			if 1+n > len(data) {
+				return "", 0, ErrShort
+			}
+			b.Write(data[1 : 1+n])
+			data = data[1+n:]
+		}
+		return b.String(), 0, nil
+	case TypeCAA:
+		if len(data) < 2 || 2+int(data[1]) > len(data) {
+			return "", 0, ErrShort
+		}
+		tag := string(data[2 : 2+int(data[1])])
+		return fmt.Sprintf("%d %s %s", data[0], tag, strconv.Quote(string(data[2+int(data[1]):]))), 0, nil
+	case TypeSOA:
+		mname, rest, err := ReadName(data)
+		if err != nil {
+			return "", 0, err
+		}
+		rname, rest, err := ReadName(rest)
+		if err != nil {
+			return "", 0, err
+		}
+		if len(rest) != 20 {
+			return "", 0, ErrShort
+		}
+		fields := []string{mname, rname}
+		for i := 0; i < 5; i++ {
+			fields = append(fields, strconv.FormatUint(uint64(binary.BigEndian.Uint32(rest[i*4:])), 10))
+		}
+		return strings.Join(fields, " "), 0, nil
+	}
+	return "", 0, fmt.Errorf("dnswire: unsupported record type %s", TypeString(typ))
+}
+
+// Absolute returns the fully qualified owner name of an Enzonix record
+// name ("@", "www" or an absolute name) within origin.
+func Absolute(name, origin string) string {
+	origin = Fqdn(strings.ToLower(origin))
+	switch {
+	case name == "" || name == "@":
+		return origin
+	case strings.HasSuffix(name, "."):
+		return strings.ToLower(name)
+	case origin == ".":
+		return strings.ToLower(name) + "."
+	}
+	lower := strings.ToLower(name)
+	if lower+"." == origin || strings.HasSuffix(lower+".", "."+origin) {
+		return lower + "."
+	}
+	return lower + "." + origin
+}
+
+// SOASerial returns the serial number of SOA rdata.
+func SOASerial(data []byte) (uint32, error) {
+	_, rest, err := ReadName(data)
+	if err != nil {
+		return 0, err
+	}
+	if _, rest, err = ReadName(rest); err != nil {
+		return 0, err
+	}
+	if len(rest) < 4 {
+		return 0, ErrShort
+	}
+	return binary.BigEndian.Uint32(rest), nil
+}
+
+// SetSOASerial returns a copy of SOA rdata with the serial replaced.
+func SetSOASerial(data []byte, serial uint32) ([]byte, error) {
+	_, rest, err := ReadName(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, rest, err = ReadName(rest); err != nil {
+		return nil, err
+	}
+	if len(rest) < 20 {
+		return nil, ErrShort
+	}
+	out := append([]byte(nil), data...)
+	binary.BigEndian.PutUint32(out[len(data)-len(rest):], serial)
+	return out, nil
+}
+
+// SerialGreater compares SOA serials in sequence space arithmetic (RFC
+// 1982): it reports whether a is newer than b.
+func SerialGreater(a, b uint32) bool {
+	return a != b && int32(a-b) > 0
+}
+
+func targetName(name, origin string) string {
+	switch {
+	case name == "@":
+		return Fqdn(origin)
+	case strings.HasSuffix(name, "."):
+		return name
+	case origin != "" && !strings.Contains(name, "."):
+		return name + "." + Fqdn(origin)
+	}
+	return name + "."
+}
+
+func unquote(s string) string {
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return strings.Trim(s, `"`)
+}
+
+func checkUint16(n int, what string) error {
+	if n < 0 || n > 0xffff {
+		return errors.New("dnswire: " + what + " out of range")
+	}
+	return nil
+}
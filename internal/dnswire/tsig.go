@@ -0,0 +1,285 @@
+package dnswire
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+)
+
+// TSIG algorithm names (RFC 8945).
+const (
+	HmacMD5    = "hmac-md5.sig-alg.reg.int."
+	HmacSHA1   = "hmac-sha1."
+	HmacSHA224 = "hmac-sha224."
+	HmacSHA256 = "hmac-sha256."
+	HmacSHA384 = "hmac-sha384."
+	HmacSHA512 = "hmac-sha512."
+)
+
+var tsigHashes = map[string]func() hash.Hash{
+	HmacMD5:    md5.New,
+	HmacSHA1:   sha1.New,
+	HmacSHA224: sha256.New224,
+	HmacSHA256: sha256.New,
+	HmacSHA384: sha512.New384,
+	HmacSHA512: sha512.New,
+}
+
+// TSIG error codes, carried in the TSIG record of responses.
+const (
+	TSIGBadSig   uint16 = 16
+	TSIGBadKey   uint16 = 17
+	TSIGBadTime  uint16 = 18
+	TSIGBadTrunc uint16 = 22
+)
+
+// DefaultFudge is the permitted clock skew of signatures, in seconds.
+const DefaultFudge = 300
+
+// ErrNoTSIG is returned by VerifyTSIG for unsigned messages.
+var ErrNoTSIG = errors.New("dnswire: message is not signed")
+
+// TSIGKey is a shared secret. Name and Algorithm are fully qualified
+// lower-case names.
+type TSIGKey struct {
+	Name      string
+	Algorithm string
+	Secret    []byte
+}
+
+// TSIGAlgorithm returns the canonical algorithm name for names like
+// "hmac-sha256" or "HMAC-MD5.SIG-ALG.REG.INT".
+func TSIGAlgorithm(name string) (string, bool) {
+	name = Fqdn(strings.ToLower(strings.TrimSpace(name)))
+	if name == "hmac-md5." {
+		name = HmacMD5
+	}
+	_, ok := tsigHashes[name]
+	return name, ok
+}
+
+// TSIG is the content of a TSIG record.
+type TSIG struct {
+	// Key is the key name, the owner of the record.
+	Key        string
+	Algorithm  string
+	TimeSigned uint64
+	Fudge      uint16
+	MAC        []byte
+	OrigID     uint16
+	Error      uint16
+	Other      []byte
+}
+
+// RR returns the TSIG record.
+func (t *TSIG) RR() RR {
+	data, _ := AppendName(nil, strings.ToLower(t.Algorithm))
+	data = appendUint48(data, t.TimeSigned)
+	data = binary.BigEndian.AppendUint16(data, t.Fudge)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(t.MAC)))
+	data = append(data, t.MAC...)
+	data = binary.BigEndian.AppendUint16(data, t.OrigID)
+	data = binary.BigEndian.AppendUint16(data, t.Error)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(t.Other)))
+	data = append(data, t.Other...)
+	return RR{Name: strings.ToLower(t.Key), Type: TypeTSIG, Class: ClassANY, Data: data}
+}
+
+// ParseTSIG decodes a TSIG record.
+func ParseTSIG(rr RR) (*TSIG, error) {
+	if rr.Type != TypeTSIG {
+		return nil, fmt.Errorf("dnswire: %s record is not a TSIG", TypeString(rr.Type))
+	}
+	alg, rest, err := ReadName(rr.Data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < 10 {
+		return nil, ErrShort
+	}
+	t := &TSIG{Key: strings.ToLower(rr.Name), Algorithm: strings.ToLower(alg)}
+	t.TimeSigned = uint64(binary.BigEndian.Uint16(rest))<<32 | uint64(binary.BigEndian.Uint32(rest[2:]))
+	t.Fudge = binary.BigEndian.Uint16(rest[6:])
+	macLen := int(binary.BigEndian.Uint16(rest[8:]))
+	rest = rest[10:]
+	if len(rest) < macLen+6 {
+		return nil, ErrShort
+	}
+	t.MAC = append([]byte(nil), rest[:macLen]...)
+	rest = rest[macLen:]
+	t.OrigID = binary.BigEndian.Uint16(rest)
+	t.Error = binary.BigEndian.Uint16(rest[2:])
+	otherLen := int(binary.BigEndian.Uint16(rest[4:]))
+	if len(rest) != 6+otherLen {
+		return nil, ErrShort
+	}
+	t.Other = append([]byte(nil), rest[6:]...)
+	return t, nil
+}
+
+// TSIGError is a failed verification. Code is the TSIG error to report;
+// TSIG is the record that failed.
+type TSIGError struct {
+	Code uint16
+	TSIG *TSIG
+}
+
+func (e *TSIGError) Error() string {
+	switch e.Code {
+	case TSIGBadSig:
+		return "dnswire: TSIG signature mismatch"
+	case TSIGBadKey:
+		return fmt.Sprintf("dnswire: unknown TSIG key %s", e.TSIG.Key)
+	case TSIGBadTime:
+		return "dnswire: TSIG signature expired"
+	case TSIGBadTrunc:
+		return "dnswire: TSIG MAC truncated too far"
+	}
+	return fmt.Sprintf("dnswire: TSIG error %d", e.Code)
+}
+
+// TSIGParams are the context of a signature.
+type TSIGParams struct {
+	// Prev is the request MAC when signing or verifying a response, and
+	// the previous message's MAC in a multi-message response.
+	Prev []byte
+	// TimersOnly covers only the timers instead of all TSIG variables, for
+	// the messages after the first of a multi-message response.
+	TimersOnly bool
+	// Error is the TSIG error code of a signed error response.
+	Error uint16
+	// Other is the other data of a signed response; BADTIME responses
+	// carry the server time.
+	Other []byte
+	// Time defaults to the current time.
+	Time time.Time
+}
+
+func (p TSIGParams) now() time.Time {
+	if p.Time.IsZero() {
+		return time.Now()
+	}
+	return p.Time
+}
+
+// SignTSIG appends a TSIG record signing the packed message msg and
+// returns the signed message with its MAC.
+func SignTSIG(msg []byte, key TSIGKey, p TSIGParams) ([]byte, []byte, error) {
+	if len(msg) < 12 {
+		return nil, nil, ErrShort
+	}
+	newHash, ok := tsigHashes[strings.ToLower(key.Algorithm)]
+	if !ok {
+		return nil, nil, fmt.Errorf("dnswire: unsupported TSIG algorithm %s", key.Algorithm)
+	}
+	t := &TSIG{
+		Key:        key.Name,
+		Algorithm:  key.Algorithm,
+		TimeSigned: uint64(p.now().Unix()),
+		Fudge:      DefaultFudge,
+		OrigID:     binary.BigEndian.Uint16(msg),
+		Error:      p.Error,
+		Other:      p.Other,
+	}
+	mac := hmac.New(newHash, key.Secret)
+	writeTSIGDigest(mac, msg, t, p)
+	t.MAC = mac.Sum(nil)
+
+	signed, err := AppendRR(append([]byte(nil), msg...), t.RR())
+	if err != nil {
+		return nil, nil, err
+	}
+	binary.BigEndian.PutUint16(signed[10:], binary.BigEndian.Uint16(signed[10:])+1)
+	return signed, t.MAC, nil
+}
+
+// VerifyTSIG checks the TSIG record ending msg against the key returned by
+// lookup for its name. It returns ErrNoTSIG for unsigned messages and a
+// *TSIGError for bad signatures; the TSIG record is returned with either.
+func VerifyTSIG(msg []byte, lookup func(name string) (TSIGKey, bool), p TSIGParams) (*TSIG, error) {
+	offsets, err := RROffsets(msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(offsets) == 0 || binary.BigEndian.Uint16(msg[10:]) == 0 {
+		return nil, ErrNoTSIG
+	}
+	start := offsets[len(offsets)-1]
+	rr, _, err := readRR(msg, start)
+	if err != nil {
+		return nil, err
+	}
+	if rr.Type != TypeTSIG {
+		return nil, ErrNoTSIG
+	}
+	t, err := ParseTSIG(rr)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := lookup(t.Key)
+	if !ok || !strings.EqualFold(Fqdn(key.Algorithm), t.Algorithm) {
+		return t, &TSIGError{Code: TSIGBadKey, TSIG: t}
+	}
+	newHash, ok := tsigHashes[t.Algorithm]
+	if !ok {
+		return t, &TSIGError{Code: TSIGBadKey, TSIG: t}
+	}
+
+	stripped := append([]byte(nil), msg[:start]...)
+	binary.BigEndian.PutUint16(stripped, t.OrigID)
+	binary.BigEndian.PutUint16(stripped[10:], binary.BigEndian.Uint16(stripped[10:])-1)
+	mac := hmac.New(newHash, key.Secret)
+	writeTSIGDigest(mac, stripped, t, p)
+	want := mac.Sum(nil)
+
+	// Truncated MACs must keep at least half the hash and 10 bytes.
+	switch n := len(t.MAC); {
+	case n > len(want) || n == 0:
+		return t, &TSIGError{Code: TSIGBadSig, TSIG: t}
+	case n < len(want) && (n < 10 || n < len(want)/2):
+		return t, &TSIGError{Code: TSIGBadTrunc, TSIG: t}
+	case !hmac.Equal(t.MAC, want[:n]):
+		return t, &TSIGError{Code: TSIGBadSig, TSIG: t}
+	}
+	now := p.now().Unix()
+	if diff := now - int64(t.TimeSigned); diff > int64(t.Fudge) || -diff > int64(t.Fudge) {
+		return t, &TSIGError{Code: TSIGBadTime, TSIG: t}
+	}
+	return t, nil
+}
+
+func writeTSIGDigest(h hash.Hash, msg []byte, t *TSIG, p TSIGParams) {
+	if len(p.Prev) > 0 {
+		h.Write(binary.BigEndian.AppendUint16(nil, uint16(len(p.Prev))))
+		h.Write(p.Prev)
+	}
+	h.Write(msg)
+	var b []byte
+	if !p.TimersOnly {
+		b, _ = AppendName(b, strings.ToLower(t.Key))
+		b = binary.BigEndian.AppendUint16(b, ClassANY)
+		b = binary.BigEndian.AppendUint32(b, 0)
+		b, _ = AppendName(b, strings.ToLower(t.Algorithm))
+	}
+	b = appendUint48(b, t.TimeSigned)
+	b = binary.BigEndian.AppendUint16(b, t.Fudge)
+	if !p.TimersOnly {
+		b = binary.BigEndian.AppendUint16(b, t.Error)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(t.Other)))
+		b = append(b, t.Other...)
+	}
+	h.Write(b)
+}
+
+func appendUint48(b []byte, v uint64) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(v>>32))
+	return binary.BigEndian.AppendUint32(b, uint32(v))
+}
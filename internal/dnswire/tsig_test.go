@@ -0,0 +1,69 @@
+package dnswire
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTSIG(t *testing.T) {
+	key := TSIGKey{Name: "update-key.", Algorithm: HmacSHA256, Secret: []byte("0123456789abcdef")}
+	lookup := func(name string) (TSIGKey, bool) { return key, name == key.Name }
+	now := time.Unix(1700000000, 0)
+
+	query := NewQuery("example.com", TypeSOA)
+	query.Opcode = OpcodeUpdate
+	raw, err := query.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, mac, err := SignTSIG(raw, key, TSIGParams{Time: now})
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	msg, err := Unpack(signed)
+	if err != nil || len(msg.Additional) != 1 || msg.Additional[0].Type != TypeTSIG {
+		t.Fatalf("unexpected signed message %+v, %v", msg, err)
+	}
+	sig, err := VerifyTSIG(signed, lookup, TSIGParams{Time: now.Add(time.Minute)})
+	if err != nil || sig.Key != "update-key." || string(sig.MAC) != string(mac) {
+		t.Fatalf("verify: %+v, %v", sig, err)
+	}
+
+	var tsigErr *TSIGError
+	tampered := append([]byte(nil), signed...)
+	tampered[3] ^= 0x10
+	if _, err := VerifyTSIG(tampered, lookup, TSIGParams{Time: now}); !errors.As(err, &tsigErr) || tsigErr.Code != TSIGBadSig {
+		t.Fatalf("expected BADSIG, got %v", err)
+	}
+	other := func(string) (TSIGKey, bool) { return TSIGKey{}, false }
+	if _, err := VerifyTSIG(signed, other, TSIGParams{Time: now}); !errors.As(err, &tsigErr) || tsigErr.Code != TSIGBadKey {
+		t.Fatalf("expected BADKEY, got %v", err)
+	}
+	if _, err := VerifyTSIG(signed, lookup, TSIGParams{Time: now.Add(time.Hour)}); !errors.As(err, &tsigErr) || tsigErr.Code != TSIGBadTime {
+		t.Fatalf("expected BADTIME, got %v", err)
+	}
+	if _, err := VerifyTSIG(raw, lookup, TSIGParams{}); !errors.Is(err, ErrNoTSIG) {
+		t.Fatalf("expected ErrNoTSIG, got %v", err)
+	}
+
+	// A multi-message response chains the MACs.
+	resp, _ := (&Message{Header: Header{ID: query.ID, Response: true}}).Pack()
+	first, firstMAC, err := SignTSIG(resp, key, TSIGParams{Prev: mac, Time: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := SignTSIG(resp, key, TSIGParams{Prev: firstMAC, TimersOnly: true, Time: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyTSIG(first, lookup, TSIGParams{Prev: mac, Time: now}); err != nil {
+		t.Fatalf("verify first response: %v", err)
+	}
+	if _, err := VerifyTSIG(second, lookup, TSIGParams{Prev: firstMAC, TimersOnly: true, Time: now}); err != nil {
+		t.Fatalf("verify second response: %v", err)
+	}
+	if _, err := VerifyTSIG(second, lookup, TSIGParams{Prev: mac, TimersOnly: true, Time: now}); err == nil {
+		t.Fatal("expected the chain to be checked")
+	}
+}
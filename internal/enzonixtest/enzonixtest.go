@@ -0,0 +1,26 @@
+// Package enzonixtest provides test helpers for packages built on the SDK.
+// It lives apart from fakeapi, which the SDK's own tests import and which
+// therefore cannot depend on the SDK.
+package enzonixtest
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+// NewClient starts a fake API, closed when the test ends, and returns it
+// with a client talking to it under the key "key".
+func NewClient(t testing.TB, opts ...enzonix.Option) (*fakeapi.Server, *enzonix.Client) {
+	t.Helper()
+	fake := fakeapi.New("key")
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	client, err := enzonix.NewClient("key", append([]enzonix.Option{enzonix.WithBaseURL(server.URL)}, opts...)...)
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+	return fake, client
+}
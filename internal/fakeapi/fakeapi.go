@@ -0,0 +1,544 @@
+// Package fakeapi implements an in-memory stand-in for the Enzonix client
+// API. It is used by tests across the module and intentionally does not
+// depend on the SDK package so that in-package tests can use it too.
+package fakeapi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const prefix = "/api/client"
+
+// Domain mirrors the JSON shape of an Enzonix domain.
+type Domain struct {
+	ID                    string     `json:"id"`
+	ClientID              string     `json:"client_id"`
+	Name                  string     `json:"name"`
+	Active                bool       `json:"active"`
+	CreatedAt             *time.Time `json:"created_at"`
+	UpdatedAt             *time.Time `json:"updated_at"`
+	NameserverLastChecked *time.Time `json:"nameserver_last_checked_at"`
+	NameserverVerifiedAt  *time.Time `json:"nameserver_verified_at"`
+	NameserverCheckStatus string     `json:"nameserver_check_status"`
+}
+
+// Record mirrors the JSON shape of an Enzonix record.
+type Record struct {
+	ID           string     `json:"id"`
+	DomainID     string     `json:"domain_id"`
+	Name         string     `json:"name"`
+	Type         string     `json:"type"`
+	TTL          int        `json:"ttl"`
+	CountryCodes []string   `json:"country_codes"`
+	Priority     int        `json:"priority"`
+	Value        string     `json:"value"`
+	CreatedAt    *time.Time `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
+
+type recordPayload struct {
+	DomainID     string   `json:"domain_id"`
+	Name         *string  `json:"name"`
+	Type         *string  `json:"type"`
+	Value        *string  `json:"value"`
+	TTL          *int     `json:"ttl"`
+	Priority     *int     `json:"priority"`
+	CountryCodes []string `json:"country_codes"`
+}
+
+type failure struct {
+	status  int
+	message string
+}
+
+// Server is an http.Handler serving the subset of the API used by the SDK.
+type Server struct {
+	mu       sync.Mutex
+	apiKey   string
+	clientID string
+	seq      int
+	clock    time.Time
+	domains  []*Domain
+	records  []*Record
+	nsStatus map[string]string
+	failures []failure
+	requests []string
+}
+
+// New returns an empty fake API accepting apiKey as bearer token.
+func New(apiKey string) *Server {
+	return &Server{
+		apiKey:   apiKey,
+		clientID: "client-1",
+		clock:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		nsStatus: map[string]string{},
+	}
+}
+
+// APIKey returns the currently accepted API key.
+func (s *Server) APIKey() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apiKey
+}
+
+// AddDomain seeds a domain and returns it.
+func (s *Server) AddDomain(name string) Domain {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.addDomainLocked(name)
+}
+
+// AddRecord seeds a record. ID and timestamps are assigned when empty.
+func (s *Server) AddRecord(r Record) Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.addRecordLocked(r)
+}
+
+// Domains returns a copy of all domains.
+func (s *Server) Domains() []Domain {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Domain, 0, len(s.domains))
+	for _, d := range s.domains {
+		out = append(out, *d)
+	}
+	return out
+}
+
+// Records returns a copy of the records of a domain.
+func (s *Server) Records(domainID string) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.recordsLocked(domainID)
+}
+
+// SetNameserverStatus sets the status reported by check-nameserver for a
+// domain. "valid" marks the domain as verified.
+func (s *Server) SetNameserverStatus(domainID, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nsStatus[domainID] = status
+}
+
+// FailNext makes the next request fail with the given status and message.
+// Calls queue up.
+func (s *Server) FailNext(status int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{status: status, message: message})
+}
+
+// Requests returns the "METHOD path" log of all requests served.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	s.seq++
+	w.Header().Set("X-Request-ID", fmt.Sprintf("req-%d", s.seq))
+
+	if len(s.failures) > 0 {
+		f := s.failures[0]
+		s.failures = s.failures[1:]
+		writeError(w, f.status, f.message)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+s.apiKey {
+		writeError(w, http.StatusUnauthorized, "invalid api key")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case path == "/domains" && r.Method == http.MethodGet:
+		out := make([]Domain, 0, len(s.domains))
+		for _, d := range s.domains {
+			out = append(out, *d)
+		}
+		writeJSON(w, out)
+	case path == "/domains" && r.Method == http.MethodPost:
+		var payload struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Name == "" {
+			writeError(w, http.StatusUnprocessableEntity, "name is required")
+			return
+		}
+		if s.domainByNameLocked(payload.Name) != nil {
+			writeError(w, http.StatusConflict, "domain already exists")
+			return
+		}
+		writeJSON(w, s.addDomainLocked(payload.Name))
+	case len(parts) == 2 && parts[0] == "domains" && r.Method == http.MethodDelete:
+		if s.domainLocked(parts[1]) == nil {
+			writeError(w, http.StatusNotFound, "domain not found")
+			return
+		}
+		s.deleteDomainLocked(parts[1])
+		w.WriteHeader(http.StatusOK)
+	case len(parts) == 3 && parts[0] == "domains" && parts[2] == "check-nameserver":
+		d := s.domainLocked(parts[1])
+		if d == nil {
+			writeError(w, http.StatusNotFound, "domain not found")
+			return
+		}
+		status := s.nsStatus[d.ID]
+		if status == "" {
+			status = "pending"
+		}
+		now := s.tickLocked()
+		d.NameserverLastChecked = &now
+		d.NameserverCheckStatus = status
+		if status == "valid" && d.NameserverVerifiedAt == nil {
+			d.NameserverVerifiedAt = &now
+		}
+		writeJSON(w, map[string]any{
+			"domain": d,
+			"check":  map[string]any{"valid": status == "valid", "status": status},
+		})
+	case len(parts) == 3 && parts[0] == "domains" && parts[2] == "records":
+		if s.domainLocked(parts[1]) == nil {
+			writeError(w, http.StatusNotFound, "domain not found")
+			return
+		}
+		writeJSON(w, s.recordsLocked(parts[1]))
+	case len(parts) == 4 && parts[0] == "domains" && parts[2] == "export" && parts[3] == "bind":
+		d := s.domainLocked(parts[1])
+		if d == nil {
+			writeError(w, http.StatusNotFound, "domain not found")
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, s.exportLocked(d))
+	case path == "/records" && r.Method == http.MethodPost:
+		var payload recordPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+		if s.domainLocked(payload.DomainID) == nil {
+			writeError(w, http.StatusNotFound, "domain not found")
+			return
+		}
+		if payload.Name == nil || payload.Type == nil || payload.Value == nil {
+			writeError(w, http.StatusUnprocessableEntity, "name, type and value are required")
+			return
+		}
+		rec := Record{DomainID: payload.DomainID, Name: *payload.Name, Type: strings.ToUpper(*payload.Type), Value: *payload.Value, TTL: 3600, CountryCodes: payload.CountryCodes}
+		if payload.TTL != nil {
+			rec.TTL = *payload.TTL
+		}
+		if payload.Priority != nil {
+			rec.Priority = *payload.Priority
+		}
+		writeJSON(w, s.addRecordLocked(rec))
+	case len(parts) == 2 && parts[0] == "records" && r.Method == http.MethodPut:
+		rec := s.recordLocked(parts[1])
+		if rec == nil {
+			writeError(w, http.StatusNotFound, "record not found")
+			return
+		}
+		var payload recordPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body")
+			return
+		}
+		if payload.Name != nil {
+			rec.Name = *payload.Name
+		}
+		if payload.Type != nil {
+			rec.Type = strings.ToUpper(*payload.Type)
+		}
+		if payload.Value != nil {
+			rec.Value = *payload.Value
+		}
+		if payload.TTL != nil {
+			rec.TTL = *payload.TTL
+		}
+		if payload.Priority != nil {
+			rec.Priority = *payload.Priority
+		}
+		if payload.CountryCodes != nil {
+			rec.CountryCodes = payload.CountryCodes
+		}
+		now := s.tickLocked()
+		rec.UpdatedAt = &now
+		writeJSON(w, rec)
+	case len(parts) == 2 && parts[0] == "records" && r.Method == http.MethodDelete:
+		if s.recordLocked(parts[1]) == nil {
+			writeError(w, http.StatusNotFound, "record not found")
+			return
+		}
+		s.deleteRecordLocked(parts[1])
+		w.WriteHeader(http.StatusOK)
+	case path == "/import/bind" && r.Method == http.MethodPost:
+		body, _ := io.ReadAll(r.Body)
+		s.importLocked(w, body)
+	case path == "/rotate-api-key" && r.Method == http.MethodPost:
+		s.apiKey = fmt.Sprintf("rotated-key-%d", s.seq)
+		now := s.tickLocked()
+		writeJSON(w, map[string]any{
+			"id":           s.clientID,
+			"name":         "Test Client",
+			"email":        "ops@example.com",
+			"api_token":    s.apiKey,
+			"domain_limit": 100,
+			"created_at":   now,
+			"updated_at":   now,
+		})
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) tickLocked() time.Time {
+	s.clock = s.clock.Add(time.Second)
+	return s.clock
+}
+
+func (s *Server) nextIDLocked(kind string) string {
+	s.seq++
+	return fmt.Sprintf("%s-%d", kind, s.seq)
+}
+
+func (s *Server) addDomainLocked(name string) *Domain {
+	now := s.tickLocked()
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	d := &Domain{
+		ID:                    s.nextIDLocked("domain"),
+		ClientID:              s.clientID,
+		Name:                  name,
+		Active:                true,
+		CreatedAt:             &now,
+		UpdatedAt:             &now,
+		NameserverCheckStatus: "pending",
+	}
+	s.domains = append(s.domains, d)
+	return d
+}
+
+func (s *Server) addRecordLocked(r Record) *Record {
+	now := s.tickLocked()
+	rec := r
+	if rec.ID == "" {
+		rec.ID = s.nextIDLocked("record")
+	}
+	if rec.CreatedAt == nil {
+		rec.CreatedAt = &now
+	}
+	if rec.UpdatedAt == nil {
+		rec.UpdatedAt = &now
+	}
+	if rec.TTL == 0 {
+		rec.TTL = 3600
+	}
+	rec.Type = strings.ToUpper(rec.Type)
+	s.records = append(s.records, &rec)
+	return &rec
+}
+
+func (s *Server) domainLocked(id string) *Domain {
+	for _, d := range s.domains {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+func (s *Server) domainByNameLocked(name string) *Domain {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	for _, d := range s.domains {
+		if strings.TrimSuffix(strings.ToLower(d.Name), ".") == name {
+			return d
+		}
+	}
+	return nil
+}
+
+func (s *Server) recordLocked(id string) *Record {
+	for _, r := range s.records {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *Server) recordsLocked(domainID string) []Record {
+	out := []Record{}
+	for _, r := range s.records {
+		if r.DomainID == domainID {
+			out = append(out, *r)
+		}
+	}
+	return out
+}
+
+func (s *Server) deleteDomainLocked(id string) {
+	domains := s.domains[:0]
+	for _, d := range s.domains {
+		if d.ID != id {
+			domains = append(domains, d)
+		}
+	}
+	s.domains = domains
+	records := s.records[:0]
+	for _, r := range s.records {
+		if r.DomainID != id {
+			records = append(records, r)
+		}
+	}
+	s.records = records
+}
+
+func (s *Server) deleteRecordLocked(id string) {
+	records := s.records[:0]
+	for _, r := range s.records {
+		if r.ID != id {
+			records = append(records, r)
+		}
+	}
+	s.records = records
+}
+
+func (s *Server) exportLocked(d *Domain) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "$ORIGIN %s\n$TTL 3600\n", d.Name)
+	records := s.recordsLocked(d.ID)
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Name < records[j].Name })
+	for _, r := range records {
+		value := r.Value
+		if r.Type == "TXT" {
+			value = strconv.Quote(value)
+		}
+		if r.Type == "MX" || r.Type == "SRV" {
+			value = fmt.Sprintf("%d %s", r.Priority, value)
+		}
+		fmt.Fprintf(&b, "%s\t%d\tIN\t%s\t%s\n", r.Name, r.TTL, r.Type, value)
+	}
+	return b.String()
+}
+
+func (s *Server) importLocked(w http.ResponseWriter, body []byte) {
+	var (
+		origin     string
+		defaultTTL = 3600
+		parsed     []Record
+		errs       []string
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, ";"); i >= 0 && !strings.Contains(line, `"`) {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		switch strings.ToUpper(fields[0]) {
+		case "$ORIGIN":
+			if len(fields) > 1 {
+				origin = fields[1]
+			}
+			continue
+		case "$TTL":
+			if len(fields) > 1 {
+				if ttl, err := strconv.Atoi(fields[1]); err == nil {
+					defaultTTL = ttl
+				}
+			}
+			continue
+		}
+		rec := Record{Name: fields[0], TTL: defaultTTL}
+		rest := fields[1:]
+		if len(rest) > 0 {
+			if ttl, err := strconv.Atoi(rest[0]); err == nil {
+				rec.TTL = ttl
+				rest = rest[1:]
+			}
+		}
+		if len(rest) > 0 && strings.EqualFold(rest[0], "IN") {
+			rest = rest[1:]
+		}
+		if len(rest) < 2 {
+			errs = append(errs, "invalid line: "+line)
+			continue
+		}
+		rec.Type = strings.ToUpper(rest[0])
+		rdata := rest[1:]
+		if (rec.Type == "MX" || rec.Type == "SRV") && len(rdata) > 1 {
+			if prio, err := strconv.Atoi(rdata[0]); err == nil {
+				rec.Priority = prio
+				rdata = rdata[1:]
+			}
+		}
+		rec.Value = strings.Join(rdata, " ")
+		if rec.Type == "TXT" {
+			if unquoted, err := strconv.Unquote(rec.Value); err == nil {
+				rec.Value = unquoted
+			}
+		}
+		if rec.Type == "SOA" {
+			continue
+		}
+		parsed = append(parsed, rec)
+	}
+
+	if origin == "" {
+		writeError(w, http.StatusUnprocessableEntity, "zone has no $ORIGIN")
+		return
+	}
+	d := s.domainByNameLocked(origin)
+	if d == nil {
+		d = s.addDomainLocked(origin)
+	}
+	created := []Record{}
+	for _, rec := range parsed {
+		rec.DomainID = d.ID
+		created = append(created, *s.addRecordLocked(rec))
+	}
+	writeJSON(w, map[string]any{
+		"domain":          d,
+		"records_created": len(created),
+		"records":         created,
+		"partial_success": len(errs) > 0,
+		"errors":          errs,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
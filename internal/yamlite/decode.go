@@ -0,0 +1,289 @@
+package yamlite
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Unmarshal parses a YAML document into v. The document is converted to
+// JSON first, so v is decoded with encoding/json semantics and struct tags.
+func Unmarshal(data []byte, v any) error {
+	value, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// Parse parses a YAML document into map[string]any, []any and scalar
+// values (string, float64, bool or nil).
+func Parse(data []byte) (any, error) {
+	p := &parser{}
+	for n, raw := range strings.Split(string(data), "\n") {
+		text := strings.TrimRight(stripComment(raw), " \t\r")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.ContainsRune(text[:len(text)-len(strings.TrimLeft(text, " \t"))], '\t') {
+			return nil, fmt.Errorf("yamlite: line %d: tabs are not allowed for indentation", n+1)
+		}
+		p.lines = append(p.lines, line{num: n + 1, indent: len(text) - len(strings.TrimLeft(text, " ")), text: trimmed})
+	}
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+	value, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("yamlite: line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return value, nil
+}
+
+type line struct {
+	num    int
+	indent int
+	text   string
+}
+
+type parser struct {
+	lines []line
+	pos   int
+}
+
+func (p *parser) parseBlock(indent int) (any, error) {
+	if strings.HasPrefix(p.lines[p.pos].text, "- ") || p.lines[p.pos].text == "-" {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *parser) parseSequence(indent int) (any, error) {
+	out := []any{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("yamlite: line %d: unexpected indentation", l.num)
+		}
+		if !(strings.HasPrefix(l.text, "- ") || l.text == "-") {
+			break
+		}
+
+		rest := strings.TrimSpace(strings.TrimPrefix(l.text, "-"))
+		if rest == "" {
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				value, err := p.parseBlock(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, value)
+			} else {
+				out = append(out, nil)
+			}
+			continue
+		}
+
+		if _, _, isMap := splitKey(rest); isMap && !strings.HasPrefix(rest, "[") && !strings.HasPrefix(rest, `"`) {
+			// A mapping that starts on the dash line: re-read it as a
+			// mapping indented to the first key.
+			childIndent := indent + (len(l.text) - len(rest))
+			p.lines[p.pos] = line{num: l.num, indent: childIndent, text: rest}
+			value, err := p.parseMapping(childIndent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, value)
+			continue
+		}
+
+		value, err := parseScalar(rest, l.num)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, value)
+		p.pos++
+	}
+	return out, nil
+}
+
+func (p *parser) parseMapping(indent int) (any, error) {
+	out := map[string]any{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("yamlite: line %d: unexpected indentation", l.num)
+		}
+		if strings.HasPrefix(l.text, "- ") {
+			break
+		}
+
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return nil, fmt.Errorf("yamlite: line %d: expected key: value", l.num)
+		}
+		if _, dup := out[key]; dup {
+			return nil, fmt.Errorf("yamlite: line %d: duplicate key %q", l.num, key)
+		}
+		p.pos++
+
+		if rest != "" {
+			value, err := parseScalar(rest, l.num)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = value
+			continue
+		}
+
+		// Nested block, which for sequences may share the parent's indent.
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			if next.indent > indent || (next.indent == indent && strings.HasPrefix(next.text, "- ")) {
+				value, err := p.parseBlock(next.indent)
+				if err != nil {
+					return nil, err
+				}
+				out[key] = value
+				continue
+			}
+		}
+		out[key] = nil
+	}
+	return out, nil
+}
+
+// splitKey splits "key: value" at the first unquoted colon followed by a
+// space or the end of the line.
+func splitKey(text string) (key, rest string, ok bool) {
+	inQuote := byte(0)
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case inQuote != 0:
+			if c == inQuote {
+				inQuote = 0
+			}
+		case c == '"' || c == '\'':
+			inQuote = c
+		case c == ':' && (i+1 == len(text) || text[i+1] == ' '):
+			raw := strings.TrimSpace(text[:i])
+			key = raw
+			if unquoted, err := unquote(raw); err == nil {
+				key = unquoted
+			}
+			// A quoted empty key is a key; OctoDNS uses it for the apex.
+			return key, strings.TrimSpace(text[i+1:]), raw != ""
+		}
+	}
+	return "", "", false
+}
+
+func parseScalar(text string, num int) (any, error) {
+	switch {
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("yamlite: line %d: unterminated flow sequence", num)
+		}
+		inner := strings.TrimSpace(text[1 : len(text)-1])
+		out := []any{}
+		if inner == "" {
+			return out, nil
+		}
+		for _, item := range splitFlow(inner) {
+			value, err := parseScalar(strings.TrimSpace(item), num)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, value)
+		}
+		return out, nil
+	case text == "{}":
+		return map[string]any{}, nil
+	case strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'"):
+		s, err := unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("yamlite: line %d: %v", num, err)
+		}
+		return s, nil
+	}
+
+	switch strings.ToLower(text) {
+	case "null", "~":
+		return nil, nil
+	case "true", "yes", "on":
+		return true, nil
+	case "false", "no", "off":
+		return false, nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f, nil
+	}
+	return text, nil
+}
+
+func splitFlow(s string) []string {
+	var (
+		parts   []string
+		start   int
+		inQuote byte
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inQuote != 0:
+			if c == inQuote {
+				inQuote = 0
+			}
+		case c == '"' || c == '\'':
+			inQuote = c
+		case c == ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unquote(s string) (string, error) {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	if len(s) >= 2 && s[0] == '"' {
+		return strconv.Unquote(s)
+	}
+	return s, fmt.Errorf("not a quoted string: %s", s)
+}
+
+func stripComment(s string) string {
+	inQuote := byte(0)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inQuote != 0:
+			if c == inQuote {
+				inQuote = 0
+			}
+		case c == '"' || c == '\'':
+			inQuote = c
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
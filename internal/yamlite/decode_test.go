@@ -0,0 +1,94 @@
+package yamlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	type rule struct {
+		Name   string   `json:"name"`
+		Types  []string `json:"types"`
+		MinTTL int      `json:"min_ttl"`
+		Note   string   `json:"note"`
+	}
+	type document struct {
+		Version int    `json:"version"`
+		Enabled bool   `json:"enabled"`
+		Rules   []rule `json:"rules"`
+		Owners  []string
+	}
+
+	input := `
+# policy
+version: 2
+enabled: yes
+rules:
+  - name: protect-apex-ns   # trailing comment
+    types: [NS, "SOA"]
+  - name: "min ttl"
+    min_ttl: 60
+    note: 'it''s # not a comment'
+Owners:
+- ops
+- sre
+`
+	var got document
+	if err := Unmarshal([]byte(input), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := document{
+		Version: 2,
+		Enabled: true,
+		Rules: []rule{
+			{Name: "protect-apex-ns", Types: []string{"NS", "SOA"}},
+			{Name: "min ttl", MinTTL: 60, Note: "it's # not a comment"},
+		},
+		Owners: []string{"ops", "sre"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected result:\n got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	in := map[string]any{
+		"records": []any{
+			map[string]any{"name": "@", "ttl": float64(300), "countries": []any{"DE"}},
+			map[string]any{"name": "www", "value": "v=spf1 -all", "empty": []any{}},
+		},
+		"count": float64(2),
+		"":      "apex",
+	}
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	out, err := Parse(data)
+	if err != nil {
+		t.Fatalf("parse: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("round trip mismatch:\n%s\n%#v", data, out)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		"a: 1\n  b: 2\n",
+		"a: 1\na: 2\n",
+		"just text\n",
+		"a: [1, 2\n",
+	} {
+		if _, err := Parse([]byte(input)); err == nil {
+			t.Fatalf("expected error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Package yamlite implements the small subset of YAML needed by the SDK's
+// tools: block mappings, block sequences and plain or double-quoted scalars.
+// Values are converted through encoding/json so struct tags are honoured.
+package yamlite
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// object preserves the key order of a JSON object.
+type object struct {
+	keys   []string
+	values []any
+}
+
+// Marshal renders v as a YAML document.
+func Marshal(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := decodeOrdered(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	switch n := node.(type) {
+	case *object:
+		if len(n.keys) == 0 {
+			b.WriteString("{}\n")
+		} else {
+			writeObject(&b, n, 0)
+		}
+	case []any:
+		if len(n) == 0 {
+			b.WriteString("[]\n")
+		} else {
+			writeArray(&b, n, 0)
+		}
+	default:
+		b.WriteString(scalar(n))
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		obj := &object{}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyTok.(string)
+			val, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.keys = append(obj.keys, key)
+			obj.values = append(obj.values, val)
+		}
+		_, err := dec.Token()
+		return obj, err
+	case '[':
+		arr := []any{}
+		for dec.More() {
+			val, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+	return nil, fmt.Errorf("yamlite: unexpected delimiter %v", delim)
+}
+
+func writeObject(b *bytes.Buffer, obj *object, indent int) {
+	pad := strings.Repeat(" ", indent)
+	for i, key := range obj.keys {
+		b.WriteString(pad)
+		b.WriteString(scalarKey(key))
+		b.WriteByte(':')
+		writeValue(b, obj.values[i], indent)
+	}
+}
+
+func writeArray(b *bytes.Buffer, arr []any, indent int) {
+	pad := strings.Repeat(" ", indent)
+	for _, item := range arr {
+		b.WriteString(pad)
+		b.WriteByte('-')
+		if obj, ok := item.(*object); ok && len(obj.keys) > 0 {
+			// The first key shares the dash line; the rest align with it.
+			var nested bytes.Buffer
+			writeObject(&nested, obj, indent+2)
+			b.WriteByte(' ')
+			b.Write(bytes.TrimPrefix(nested.Bytes(), []byte(strings.Repeat(" ", indent+2))))
+			continue
+		}
+		writeValue(b, item, indent)
+	}
+}
+
+func writeValue(b *bytes.Buffer, v any, indent int) {
+	switch n := v.(type) {
+	case *object:
+		if len(n.keys) == 0 {
+			b.WriteString(" {}\n")
+			return
+		}
+		b.WriteByte('\n')
+		writeObject(b, n, indent+2)
+	case []any:
+		if len(n) == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+		b.WriteByte('\n')
+		writeArray(b, n, indent+2)
+	default:
+		b.WriteByte(' ')
+		b.WriteString(scalar(n))
+		b.WriteByte('\n')
+	}
+}
+
+func scalarKey(key string) string {
+	if needsQuote(key) {
+		return strconv.Quote(key)
+	}
+	return key
+}
+
+func scalar(v any) string {
+	switch n := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(n)
+	case json.Number:
+		return n.String()
+	case string:
+		if needsQuote(n) {
+			return strconv.Quote(n)
+		}
+		return n
+	default:
+		return fmt.Sprint(n)
+	}
+}
+
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "null", "~", "true", "false", "yes", "no", "on", "off":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if strings.TrimSpace(s) != s {
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	return strings.Contains(s, ": ") || strings.Contains(s, " #") ||
+		strings.ContainsAny(s, "\n\t\"\\")
+}
@@ -0,0 +1,40 @@
+package yamlite
+
+import "testing"
+
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+
+	type record struct {
+		Name   string   `json:"name"`
+		TTL    int      `json:"ttl"`
+		Tags   []string `json:"tags"`
+		Value  string   `json:"value"`
+		Active bool     `json:"active"`
+	}
+
+	got, err := Marshal([]record{
+		{Name: "www", TTL: 300, Tags: []string{"DE", "FR"}, Value: "1.1.1.1", Active: true},
+		{Name: "@", TTL: 60, Tags: nil, Value: "v=spf1 -all"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `- name: www
+  ttl: 300
+  tags:
+    - DE
+    - FR
+  value: 1.1.1.1
+  active: true
+- name: "@"
+  ttl: 60
+  tags: null
+  value: v=spf1 -all
+  active: false
+`
+	if string(got) != want {
+		t.Fatalf("unexpected yaml:\n%s", got)
+	}
+}
@@ -0,0 +1,99 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+)
+
+// Enzonix is the Provider of an Enzonix account.
+type Enzonix struct {
+	Client *enzonix.Client
+}
+
+// NewEnzonix returns the provider of the account client authenticates as.
+func NewEnzonix(client *enzonix.Client) *Enzonix {
+	return &Enzonix{Client: client}
+}
+
+// Name implements Provider.
+func (e *Enzonix) Name() string { return "enzonix" }
+
+// Capabilities implements Provider. Enzonix stores every record type and
+// limits records to countries.
+func (e *Enzonix) Capabilities() Capabilities {
+	return Capabilities{CountryCodes: true}
+}
+
+// ListZones implements Provider.
+func (e *Enzonix) ListZones(ctx context.Context) ([]Zone, error) {
+	domains, err := e.Client.ListDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	zones := make([]Zone, 0, len(domains))
+	for _, d := range domains {
+		zones = append(zones, Zone{ID: d.ID, Name: normalizeZone(d.Name)})
+	}
+	return zones, nil
+}
+
+// ListRecords implements Provider.
+func (e *Enzonix) ListRecords(ctx context.Context, zone Zone) ([]enzonix.Record, error) {
+	return e.Client.ListDomainRecords(ctx, zone.ID)
+}
+
+// CreateZone implements ZoneCreator.
+func (e *Enzonix) CreateZone(ctx context.Context, name string) (Zone, error) {
+	domain, err := e.Client.CreateDomain(ctx, name)
+	if err != nil {
+		return Zone{}, err
+	}
+	return Zone{ID: domain.ID, Name: normalizeZone(domain.Name)}, nil
+}
+
+// ApplyChanges implements Provider. The API has no transactions; a
+// failure leaves the changes before it applied. Changes that were made but
+// could not be audited do not stop the others; their errors are returned
+// joined at the end.
+func (e *Enzonix) ApplyChanges(ctx context.Context, zone Zone, changes []Change) error {
+	var audit []error
+	for _, c := range changes {
+		var err error
+		switch c.Kind {
+		case Delete:
+			err = e.Client.DeleteRecord(ctx, c.Previous.ID)
+		case Update:
+			r := c.Record
+			_, err = e.Client.UpdateRecord(ctx, c.Previous.ID, enzonix.UpdateRecordRequest{
+				Value:        &r.Value,
+				TTL:          &r.TTL,
+				Priority:     &r.Priority,
+				CountryCodes: r.CountryCodes,
+			})
+		case Create:
+			r := c.Record
+			_, err = e.Client.CreateRecord(ctx, enzonix.CreateRecordRequest{
+				DomainID:     zone.ID,
+				Name:         r.Name,
+				Type:         r.Type,
+				Value:        r.Value,
+				TTL:          &r.TTL,
+				Priority:     &r.Priority,
+				CountryCodes: r.CountryCodes,
+			})
+		default:
+			err = fmt.Errorf("unknown change kind %q", c.Kind)
+		}
+		if err != nil {
+			err = fmt.Errorf("%s: %w", c, err)
+			if !enzonix.IsAuditOnly(err) {
+				return errors.Join(append(audit, err)...)
+			}
+			audit = append(audit, err)
+		}
+	}
+	return errors.Join(audit...)
+}
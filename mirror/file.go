@@ -0,0 +1,165 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+)
+
+// FileProvider keeps each zone in a JSON file, <zone>.json in Dir, for
+// mirroring to disk and for testing without a second provider. Record IDs
+// are assigned by the provider.
+type FileProvider struct {
+	Dir string
+	// Caps are the capabilities the provider reports, so that a more
+	// limited target can be simulated. The zero value supports no country
+	// codes.
+	Caps Capabilities
+
+	mu sync.Mutex
+}
+
+type fileZone struct {
+	Name    string           `json:"name"`
+	Records []enzonix.Record `json:"records"`
+}
+
+// Name implements Provider.
+func (f *FileProvider) Name() string { return "file:" + f.Dir }
+
+// Capabilities implements Provider.
+func (f *FileProvider) Capabilities() Capabilities { return f.Caps }
+
+// ListZones implements Provider.
+func (f *FileProvider) ListZones(ctx context.Context) ([]Zone, error) {
+	paths, err := filepath.Glob(filepath.Join(f.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	zones := make([]Zone, 0, len(paths))
+	for _, p := range paths {
+		name := strings.TrimSuffix(filepath.Base(p), ".json")
+		zones = append(zones, Zone{ID: name, Name: name})
+	}
+	return zones, nil
+}
+
+// ListRecords implements Provider.
+func (f *FileProvider) ListRecords(ctx context.Context, zone Zone) ([]enzonix.Record, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	z, err := f.load(zone)
+	if err != nil {
+		return nil, err
+	}
+	return z.Records, nil
+}
+
+// CreateZone implements ZoneCreator.
+func (f *FileProvider) CreateZone(ctx context.Context, name string) (Zone, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	zone := Zone{ID: normalizeZone(name), Name: normalizeZone(name)}
+	if _, err := os.Stat(f.path(zone)); err == nil {
+		return Zone{}, fmt.Errorf("mirror: zone %s exists", zone.Name)
+	}
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return Zone{}, err
+	}
+	return zone, f.save(zone, &fileZone{Name: zone.Name, Records: []enzonix.Record{}})
+}
+
+// ApplyChanges implements Provider. The zone file is rewritten once, after
+// all changes applied.
+func (f *FileProvider) ApplyChanges(ctx context.Context, zone Zone, changes []Change) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	z, err := f.load(zone)
+	if err != nil {
+		return err
+	}
+	next := 0
+	for _, r := range z.Records {
+		if id, err := strconv.Atoi(r.ID); err == nil && id > next {
+			next = id
+		}
+	}
+	for _, c := range changes {
+		i := -1
+		if c.Kind != Create {
+			for j, r := range z.Records {
+				if r.ID == c.Previous.ID {
+					i = j
+				}
+			}
+			if i < 0 {
+				return fmt.Errorf("mirror: %s: record %s not found", c, c.Previous.ID)
+			}
+		}
+		switch c.Kind {
+		case Create:
+			next++
+			r := c.Record
+			r.ID = strconv.Itoa(next)
+			z.Records = append(z.Records, r)
+		case Update:
+			r := c.Record
+			r.ID = c.Previous.ID
+			z.Records[i] = r
+		case Delete:
+			z.Records = append(z.Records[:i], z.Records[i+1:]...)
+		default:
+			return fmt.Errorf("mirror: unknown change kind %q", c.Kind)
+		}
+	}
+	return f.save(zone, z)
+}
+
+func (f *FileProvider) path(zone Zone) string {
+	return filepath.Join(f.Dir, normalizeZone(zone.Name)+".json")
+}
+
+func (f *FileProvider) load(zone Zone) (*fileZone, error) {
+	data, err := os.ReadFile(f.path(zone))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s on %s", ErrZoneNotFound, zone.Name, f.Name())
+	}
+	if err != nil {
+		return nil, err
+	}
+	var z fileZone
+	if err := json.Unmarshal(data, &z); err != nil {
+		return nil, fmt.Errorf("mirror: %s: %w", f.path(zone), err)
+	}
+	return &z, nil
+}
+
+// save writes the zone file through a temporary file, so that readers
+// never see a partial zone.
+func (f *FileProvider) save(zone Zone, z *fileZone) error {
+	data, err := json.MarshalIndent(z, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(f.Dir, "."+filepath.Base(f.path(zone))+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(zone))
+}
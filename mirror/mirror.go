@@ -0,0 +1,312 @@
+// Package mirror replicates zones between DNS providers, so that an
+// Enzonix zone can be served by a second provider as well.
+//
+// A Provider lists zones and their records and applies record changes.
+// Enzonix wraps a Client and FileProvider keeps zones in JSON files. Sync
+// makes a target zone match a source zone, adapting records the target
+// cannot store, such as records limited to countries, and reporting each
+// adaptation as a warning.
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+)
+
+// Zone is a zone as a provider knows it. Name has no trailing dot.
+type Zone struct {
+	ID   string
+	Name string
+}
+
+// Capabilities describes what a provider can store.
+type Capabilities struct {
+	// Types are the record types the provider accepts; nil accepts all.
+	Types []string
+	// CountryCodes reports whether records can be limited to countries.
+	CountryCodes bool
+	// MinTTL is the lowest TTL the provider accepts.
+	MinTTL int
+}
+
+func (c Capabilities) supports(typ string) bool {
+	return c.Types == nil || slices.ContainsFunc(c.Types, func(t string) bool { return strings.EqualFold(t, typ) })
+}
+
+// ChangeKind classifies a Change.
+type ChangeKind string
+
+// Change kinds.
+const (
+	Create ChangeKind = "create"
+	Update ChangeKind = "update"
+	Delete ChangeKind = "delete"
+)
+
+// Change is one record change. Record is the wanted record for creates and
+// updates; Previous is the provider's record, with its ID, for updates and
+// deletes.
+type Change struct {
+	Kind     ChangeKind
+	Record   enzonix.Record
+	Previous enzonix.Record
+}
+
+func (c Change) String() string {
+	r := c.Record
+	if c.Kind == Delete {
+		r = c.Previous
+	}
+	return fmt.Sprintf("%s %s %s %s", c.Kind, r.Name, r.Type, r.Value)
+}
+
+// Provider is a DNS provider holding zones. Record names are relative to
+// the zone, "@" for the apex, as in the Enzonix API.
+type Provider interface {
+	// Name identifies the provider in warnings and errors.
+	Name() string
+	Capabilities() Capabilities
+	ListZones(ctx context.Context) ([]Zone, error)
+	ListRecords(ctx context.Context, zone Zone) ([]enzonix.Record, error)
+	// ApplyChanges applies changes in order.
+	ApplyChanges(ctx context.Context, zone Zone, changes []Change) error
+}
+
+// ZoneCreator is implemented by providers that can create zones. Sync
+// creates missing target zones through it.
+type ZoneCreator interface {
+	CreateZone(ctx context.Context, name string) (Zone, error)
+}
+
+// ErrZoneNotFound is returned by Sync for zones a provider does not have.
+var ErrZoneNotFound = errors.New("mirror: zone not found")
+
+// Warning reports a source record the target cannot store as is.
+type Warning struct {
+	Record  enzonix.Record
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s %s %s: %s", w.Record.Name, w.Record.Type, w.Record.Value, w.Message)
+}
+
+// SyncOptions configures Sync.
+type SyncOptions struct {
+	// DryRun plans the changes without applying them.
+	DryRun bool
+	// KeepExtra leaves target records that the source lacks in place
+	// instead of deleting them.
+	KeepExtra bool
+	// Ignore lists record types left alone on both sides; SOA is always
+	// ignored.
+	Ignore []string
+}
+
+// SyncResult reports what Sync did, or would do on a dry run.
+type SyncResult struct {
+	Zone     Zone
+	Changes  []Change
+	Warnings []Warning
+}
+
+// Sync makes the zone named zone on dst match the one on src. Records are
+// adapted to dst's capabilities first: unsupported types are skipped,
+// TTLs are raised to the minimum and, when dst cannot limit records to
+// countries, country variants are dropped in favour of the set's default
+// answer, or merged into one set when there is no default. Changes are
+// applied as deletes, updates and creates, in that order.
+func Sync(ctx context.Context, src, dst Provider, zone string, opts SyncOptions) (*SyncResult, error) {
+	srcZone, err := findZone(ctx, src, zone)
+	if err != nil {
+		return nil, err
+	}
+	records, err := src.ListRecords(ctx, srcZone)
+	if err != nil {
+		return nil, fmt.Errorf("mirror: %s: list %s: %w", src.Name(), zone, err)
+	}
+	res := &SyncResult{}
+	want := adapt(filter(records, opts.Ignore), dst.Capabilities(), res)
+
+	dstZone, err := findZone(ctx, dst, zone)
+	var live []enzonix.Record
+	switch {
+	case errors.Is(err, ErrZoneNotFound):
+		creator, ok := dst.(ZoneCreator)
+		if !ok {
+			return nil, err
+		}
+		if opts.DryRun {
+			dstZone = Zone{Name: normalizeZone(zone)}
+			break
+		}
+		if dstZone, err = creator.CreateZone(ctx, normalizeZone(zone)); err != nil {
+			return nil, fmt.Errorf("mirror: %s: create %s: %w", dst.Name(), zone, err)
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if live, err = dst.ListRecords(ctx, dstZone); err != nil {
+			return nil, fmt.Errorf("mirror: %s: list %s: %w", dst.Name(), zone, err)
+		}
+	}
+	res.Zone = dstZone
+	res.Changes = plan(filter(live, opts.Ignore), want, opts.KeepExtra)
+	if opts.DryRun || len(res.Changes) == 0 {
+		return res, nil
+	}
+	if err := dst.ApplyChanges(ctx, dstZone, res.Changes); err != nil {
+		return res, fmt.Errorf("mirror: %s: apply %s: %w", dst.Name(), zone, err)
+	}
+	return res, nil
+}
+
+func findZone(ctx context.Context, p Provider, name string) (Zone, error) {
+	zones, err := p.ListZones(ctx)
+	if err != nil {
+		return Zone{}, fmt.Errorf("mirror: %s: list zones: %w", p.Name(), err)
+	}
+	for _, z := range zones {
+		if normalizeZone(z.Name) == normalizeZone(name) {
+			return z, nil
+		}
+	}
+	return Zone{}, fmt.Errorf("%w: %s on %s", ErrZoneNotFound, name, p.Name())
+}
+
+func normalizeZone(name string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+}
+
+// filter drops SOA records and the ignored types.
+func filter(records []enzonix.Record, ignore []string) []enzonix.Record {
+	var out []enzonix.Record
+	for _, r := range records {
+		if strings.EqualFold(r.Type, "SOA") || slices.ContainsFunc(ignore, func(t string) bool { return strings.EqualFold(t, r.Type) }) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// adapt returns the records as caps can store them, adding a warning to
+// res for every adaptation.
+func adapt(records []enzonix.Record, caps Capabilities, res *SyncResult) []enzonix.Record {
+	// Sets with a default answer lose their country variants; the others
+	// keep every variant, unrestricted.
+	hasDefault := map[string]bool{}
+	for _, r := range records {
+		if len(r.CountryCodes) == 0 {
+			hasDefault[setKey(r)] = true
+		}
+	}
+	var out []enzonix.Record
+	for _, r := range records {
+		if !caps.supports(r.Type) {
+			res.Warnings = append(res.Warnings, Warning{Record: r, Message: "record type not supported by the target; skipped"})
+			continue
+		}
+		r = clean(r)
+		r.ID = ""
+		if len(r.CountryCodes) > 0 && !caps.CountryCodes {
+			if hasDefault[setKey(r)] {
+				res.Warnings = append(res.Warnings, Warning{Record: r, Message: "target cannot limit records to countries; variant dropped in favour of the default"})
+				continue
+			}
+			res.Warnings = append(res.Warnings, Warning{Record: r, Message: "target cannot limit records to countries; served to all clients"})
+			r.CountryCodes = nil
+		}
+		if r.TTL < caps.MinTTL {
+			res.Warnings = append(res.Warnings, Warning{Record: r, Message: fmt.Sprintf("TTL raised to the target's minimum of %d", caps.MinTTL)})
+			r.TTL = caps.MinTTL
+		}
+		if !slices.ContainsFunc(out, func(o enzonix.Record) bool { return sameRecord(o, r) }) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// clean strips provider-specific fields and normalizes names and types.
+func clean(r enzonix.Record) enzonix.Record {
+	name := strings.ToLower(strings.TrimSuffix(r.Name, "."))
+	if name == "" {
+		name = "@"
+	}
+	var countries []string
+	for _, cc := range r.CountryCodes {
+		countries = append(countries, strings.ToUpper(cc))
+	}
+	slices.Sort(countries)
+	return enzonix.Record{
+		ID:           r.ID,
+		Name:         name,
+		Type:         strings.ToUpper(r.Type),
+		TTL:          r.TTL,
+		CountryCodes: countries,
+		Priority:     r.Priority,
+		Value:        r.Value,
+	}
+}
+
+func setKey(r enzonix.Record) string {
+	r = clean(r)
+	return r.Name + " " + r.Type
+}
+
+func sameRecord(a, b enzonix.Record) bool {
+	a, b = clean(a), clean(b)
+	return a.Name == b.Name && a.Type == b.Type && a.Value == b.Value && a.TTL == b.TTL &&
+		a.Priority == b.Priority && slices.Equal(a.CountryCodes, b.CountryCodes)
+}
+
+// plan computes the changes turning live into want. Records that match
+// are kept; remaining records sharing a name and type are updated in
+// place. A country variant is never paired with a record without country
+// codes, since an update cannot clear the codes; it is deleted and the
+// record created instead, as planGeo does.
+func plan(live, want []enzonix.Record, keepExtra bool) []Change {
+	used := make([]bool, len(live))
+	var pending []enzonix.Record
+	for _, w := range want {
+		matched := false
+		for i, l := range live {
+			if !used[i] && sameRecord(l, w) {
+				used[i], matched = true, true
+				break
+			}
+		}
+		if !matched {
+			pending = append(pending, w)
+		}
+	}
+
+	var deletes, updates, creates []Change
+	for _, w := range pending {
+		matched := false
+		for i, l := range live {
+			if !used[i] && setKey(l) == setKey(w) && (len(l.CountryCodes) > 0) == (len(w.CountryCodes) > 0) {
+				used[i], matched = true, true
+				updates = append(updates, Change{Kind: Update, Record: w, Previous: l})
+				break
+			}
+		}
+		if !matched {
+			creates = append(creates, Change{Kind: Create, Record: w})
+		}
+	}
+	if !keepExtra {
+		for i, l := range live {
+			if !used[i] {
+				deletes = append(deletes, Change{Kind: Delete, Previous: l})
+			}
+		}
+	}
+	return append(append(deletes, updates...), creates...)
+}
@@ -0,0 +1,179 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/enzonixtest"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+func summary(records []enzonix.Record) string {
+	var lines []string
+	for _, r := range records {
+		line := r.Name + " " + r.Type + " " + r.Value
+		if len(r.CountryCodes) > 0 {
+			line += " " + strings.Join(r.CountryCodes, ",")
+		}
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "|")
+}
+
+func TestSyncToFile(t *testing.T) {
+	t.Parallel()
+
+	fake, client := enzonixtest.NewClient(t)
+	domain := fake.AddDomain("example.com")
+	www := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	for _, r := range []fakeapi.Record{
+		{Name: "www", Type: "A", TTL: 300, Value: "192.0.2.9", CountryCodes: []string{"DE"}},
+		{Name: "eu", Type: "A", TTL: 300, Value: "192.0.2.20", CountryCodes: []string{"FR"}},
+		{Name: "@", Type: "CAA", TTL: 300, Value: `0 issue "letsencrypt.org"`},
+		{Name: "fast", Type: "A", TTL: 10, Value: "192.0.2.30"},
+	} {
+		r.DomainID = domain.ID
+		fake.AddRecord(r)
+	}
+
+	ctx := context.Background()
+	src := NewEnzonix(client)
+	dst := &FileProvider{Dir: t.TempDir(), Caps: Capabilities{Types: []string{"A", "AAAA", "CNAME", "MX", "TXT"}, MinTTL: 60}}
+
+	// The target zone is created on the first run.
+	res, err := Sync(ctx, src, dst, "example.com", SyncOptions{})
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if len(res.Changes) != 3 || len(res.Warnings) != 4 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	records, err := dst.ListRecords(ctx, res.Zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := summary(records), "eu A 192.0.2.20|fast A 192.0.2.30|www A 192.0.2.1"; got != want {
+		t.Fatalf("mirrored records:\n got %s\nwant %s", got, want)
+	}
+	for _, r := range records {
+		if r.Name == "fast" && r.TTL != 60 {
+			t.Fatalf("TTL not raised: %+v", r)
+		}
+	}
+
+	// A second run changes nothing; a source change is mirrored in place.
+	if res, err := Sync(ctx, src, dst, "example.com", SyncOptions{}); err != nil || len(res.Changes) != 0 {
+		t.Fatalf("expected no changes, got %+v, %v", res, err)
+	}
+	value := "192.0.2.5"
+	if _, err := client.UpdateRecord(ctx, www.ID, enzonix.UpdateRecordRequest{Value: &value}); err != nil {
+		t.Fatal(err)
+	}
+	res, err = Sync(ctx, src, dst, "example.com", SyncOptions{DryRun: true})
+	if err != nil || len(res.Changes) != 1 || res.Changes[0].Kind != Update {
+		t.Fatalf("unexpected dry run %+v, %v", res, err)
+	}
+	if records, _ := dst.ListRecords(ctx, res.Zone); strings.Contains(summary(records), "192.0.2.5") {
+		t.Fatal("dry run applied changes")
+	}
+	if _, err := Sync(ctx, src, dst, "example.com", SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := dst.ListRecords(ctx, res.Zone); !strings.Contains(summary(records), "www A 192.0.2.5") {
+		t.Fatalf("update not mirrored: %s", summary(records))
+	}
+
+	if _, err := Sync(ctx, src, dst, "example.org", SyncOptions{}); !errors.Is(err, ErrZoneNotFound) {
+		t.Fatalf("expected ErrZoneNotFound, got %v", err)
+	}
+}
+
+func TestSyncToEnzonix(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	src := &FileProvider{Dir: t.TempDir()}
+	zone, err := src.CreateZone(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = src.ApplyChanges(ctx, zone, []Change{
+		{Kind: Create, Record: enzonix.Record{Name: "@", Type: "MX", TTL: 3600, Priority: 10, Value: "mx.example.com."}},
+		{Kind: Create, Record: enzonix.Record{Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"}},
+		{Kind: Create, Record: enzonix.Record{Name: "geo", Type: "A", TTL: 300, Value: "192.0.2.50"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake, client := enzonixtest.NewClient(t)
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "old", Type: "A", TTL: 300, Value: "192.0.2.99"})
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "@", Type: "NS", TTL: 300, Value: "ns1.enzonix.com."})
+	// A country variant cannot become the default answer in place: the
+	// update would keep its country codes.
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "geo", Type: "A", TTL: 300, Value: "192.0.2.51", CountryCodes: []string{"DE"}})
+
+	opts := SyncOptions{Ignore: []string{"NS"}}
+	res, err := Sync(ctx, src, NewEnzonix(client), "example.com.", opts)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if len(res.Warnings) != 0 || len(res.Changes) != 5 || res.Changes[0].Kind != Delete || res.Changes[1].Kind != Delete {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	records, err := client.ListDomainRecords(ctx, domain.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := summary(records), "@ MX mx.example.com.|@ NS ns1.enzonix.com.|geo A 192.0.2.50|www A 192.0.2.1"; got != want {
+		t.Fatalf("records:\n got %s\nwant %s", got, want)
+	}
+
+	res, err = Sync(ctx, src, NewEnzonix(client), "example.com.", opts)
+	if err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if len(res.Changes) != 0 {
+		t.Fatalf("second sync not idempotent: %v", res.Changes)
+	}
+}
+
+func TestSyncToEnzonixAuditFailure(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	src := &FileProvider{Dir: t.TempDir()}
+	zone, err := src.CreateZone(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = src.ApplyChanges(ctx, zone, []Change{
+		{Kind: Create, Record: enzonix.Record{Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"}},
+		{Kind: Create, Record: enzonix.Record{Name: "api", Type: "A", TTL: 300, Value: "192.0.2.2"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failing := enzonix.AuditSinkFunc(func(context.Context, enzonix.AuditEntry) error { return errors.New("disk full") })
+	fake, client := enzonixtest.NewClient(t, enzonix.WithAuditSink(failing))
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "old", Type: "A", TTL: 300, Value: "192.0.2.99"})
+
+	if _, err := Sync(ctx, src, NewEnzonix(client), "example.com.", SyncOptions{}); !enzonix.IsAuditOnly(err) {
+		t.Fatalf("expected audit-only error, got %v", err)
+	}
+	records, err := client.ListDomainRecords(ctx, domain.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := summary(records), "api A 192.0.2.2|www A 192.0.2.1"; got != want {
+		t.Fatalf("sync stopped after the first unaudited change:\n got %s\nwant %s", got, want)
+	}
+}
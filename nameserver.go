@@ -0,0 +1,298 @@
+package enzonix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// NameserverStatus is the outcome of a nameserver check.
+type NameserverStatus string
+
+const (
+	// NameserverPending means the check has not concluded yet.
+	NameserverPending NameserverStatus = "pending"
+	// NameserverValid means the domain delegates to Enzonix.
+	NameserverValid NameserverStatus = "valid"
+	// NameserverInvalid means the domain delegates elsewhere.
+	NameserverInvalid NameserverStatus = "invalid"
+	// NameserverError means the check itself failed.
+	NameserverError NameserverStatus = "error"
+)
+
+var (
+	// ErrNameserverTimeout is matched by a NameserverVerificationError
+	// returned because the wait timed out.
+	ErrNameserverTimeout = errors.New("enzonix: nameserver verification timed out")
+	// ErrNameserverInvalid is matched by a NameserverVerificationError
+	// returned because the check reported invalid nameservers and
+	// StopOnInvalid was set.
+	ErrNameserverInvalid = errors.New("enzonix: nameservers invalid")
+)
+
+// IsTerminal reports whether the status is a conclusive check result,
+// valid or invalid.
+func (s NameserverStatus) IsTerminal() bool {
+	return s == NameserverValid || s == NameserverInvalid
+}
+
+// IsVerified reports whether the status is NameserverValid.
+func (s NameserverStatus) IsVerified() bool {
+	return s == NameserverValid
+}
+
+// IsKnown reports whether the status is one of the documented constants.
+func (s NameserverStatus) IsKnown() bool {
+	switch s {
+	case NameserverPending, NameserverValid, NameserverInvalid, NameserverError:
+		return true
+	}
+	return false
+}
+
+// UnmarshalJSON normalizes the case of known statuses and keeps unknown
+// values verbatim, so statuses added to the API later survive a round
+// trip.
+func (s *NameserverStatus) UnmarshalJSON(data []byte) error {
+	var raw *string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("enzonix: decode nameserver status: %w", err)
+	}
+	if raw == nil {
+		*s = ""
+		return nil
+	}
+	if known := NameserverStatus(strings.ToLower(strings.TrimSpace(*raw))); known.IsKnown() {
+		*s = known
+		return nil
+	}
+	*s = NameserverStatus(*raw)
+	return nil
+}
+
+// IsVerified reports whether the domain's nameservers have been verified.
+func (d Domain) IsVerified() bool {
+	return d.NameserverVerifiedAt != nil && !d.NameserverVerifiedAt.IsZero()
+}
+
+// VerificationAge returns the time since the nameservers were verified, or
+// zero for unverified domains.
+func (d Domain) VerificationAge() time.Duration {
+	if !d.IsVerified() {
+		return 0
+	}
+	return time.Since(*d.NameserverVerifiedAt)
+}
+
+// Status interprets the check response. The check's own status takes
+// precedence over the domain's stored status; a valid check is always
+// NameserverValid.
+func (r *NameserverCheckResponse) Status() NameserverStatus {
+	if r.Check.Valid {
+		return NameserverValid
+	}
+	status := r.Check.Status
+	if status == "" {
+		status = r.Domain.NameserverCheckStatus
+	}
+	if status == "" {
+		return NameserverPending
+	}
+	return status
+}
+
+// NameserverProgress is passed to WaitOptions.OnProgress after every check.
+type NameserverProgress struct {
+	Attempt int
+	Status  NameserverStatus
+	// Response is the check result, nil when the request failed.
+	Response *NameserverCheckResponse
+	// Err is the request error of a failed check; Status is then
+	// NameserverError.
+	Err     error
+	Elapsed time.Duration
+	// NextCheck is the wait before the next check, zero after the last.
+	NextCheck time.Duration
+}
+
+// WaitOptions configures WaitForNameserverVerification. Zero values select
+// the defaults.
+type WaitOptions struct {
+	// Timeout bounds the whole wait; defaults to 10 minutes. A deadline on
+	// the context applies as well.
+	Timeout time.Duration
+	// InitialInterval is the wait after the first check; defaults to 5s.
+	InitialInterval time.Duration
+	// MaxInterval caps the backoff; defaults to 1 minute.
+	MaxInterval time.Duration
+	// Multiplier grows the interval after each check; defaults to 2.
+	Multiplier float64
+	// StopOnInvalid returns as soon as a check reports invalid
+	// nameservers instead of waiting for the delegation to change.
+	StopOnInvalid bool
+	// OnProgress is called with the result of every check.
+	OnProgress func(NameserverProgress)
+}
+
+// NameserverVerificationError explains why WaitForNameserverVerification
+// gave up. It matches ErrNameserverTimeout or ErrNameserverInvalid.
+type NameserverVerificationError struct {
+	DomainID string
+	// DomainName is empty when no check succeeded.
+	DomainName string
+	TimedOut   bool
+	Attempts   int
+	Elapsed    time.Duration
+	// LastStatus and LastResponse describe the last successful check.
+	LastStatus   NameserverStatus
+	LastResponse *NameserverCheckResponse
+	// LastErr is the error of the last check when that check failed.
+	LastErr error
+	// cause is the context error that ended the wait.
+	cause error
+}
+
+// Error satisfies the error interface.
+func (e *NameserverVerificationError) Error() string {
+	target := e.DomainID
+	if e.DomainName != "" {
+		target = e.DomainName
+	}
+	var b strings.Builder
+	if e.TimedOut {
+		fmt.Fprintf(&b, "enzonix: nameserver verification of %s timed out after %s (%d checks)", target, e.Elapsed.Round(time.Millisecond), e.Attempts)
+	} else {
+		fmt.Fprintf(&b, "enzonix: nameservers of %s are invalid after %d checks", target, e.Attempts)
+	}
+	if e.LastStatus != "" {
+		fmt.Fprintf(&b, ": last status %s", e.LastStatus)
+		if r := e.LastResponse; r != nil && r.Domain.NameserverLastChecked != nil {
+			fmt.Fprintf(&b, " (checked %s)", r.Domain.NameserverLastChecked.UTC().Format(time.RFC3339))
+		}
+	} else {
+		b.WriteString(": no check succeeded")
+	}
+	if e.LastErr != nil {
+		fmt.Fprintf(&b, ", last error: %v", e.LastErr)
+	}
+	return b.String()
+}
+
+// Is matches ErrNameserverTimeout or ErrNameserverInvalid.
+func (e *NameserverVerificationError) Is(target error) bool {
+	return (target == ErrNameserverTimeout && e.TimedOut) ||
+		(target == ErrNameserverInvalid && !e.TimedOut)
+}
+
+// Unwrap exposes the ending context error and the last check error.
+func (e *NameserverVerificationError) Unwrap() []error {
+	var errs []error
+	if e.cause != nil {
+		errs = append(errs, e.cause)
+	}
+	if e.LastErr != nil {
+		errs = append(errs, e.LastErr)
+	}
+	return errs
+}
+
+// WaitForNameserverVerification calls CheckNameserver with exponential
+// backoff until the domain's nameservers are verified. Failed checks that
+// may succeed on retry, such as server errors or rate limiting, count as
+// NameserverError and are retried; other API errors are returned at once.
+// On timeout it returns a *NameserverVerificationError describing the last
+// observed state.
+func (c *Client) WaitForNameserverVerification(ctx context.Context, domainID string, opts WaitOptions) (*NameserverCheckResponse, error) {
+	if err := requireID(domainID, "domain id"); err != nil {
+		return nil, err
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Minute
+	}
+	if opts.InitialInterval <= 0 {
+		opts.InitialInterval = 5 * time.Second
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = time.Minute
+	}
+	if opts.Multiplier < 1 {
+		opts.Multiplier = 2
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	var (
+		start    = time.Now()
+		interval = opts.InitialInterval
+		verr     = &NameserverVerificationError{DomainID: domainID}
+	)
+	for attempt := 1; ; attempt++ {
+		resp, err := c.CheckNameserver(ctx, domainID)
+		verr.Attempts = attempt
+		verr.Elapsed = time.Since(start)
+
+		progress := NameserverProgress{Attempt: attempt, Response: resp, Err: err, Elapsed: verr.Elapsed}
+		switch {
+		case err == nil:
+			progress.Status = resp.Status()
+			verr.LastStatus, verr.LastResponse, verr.LastErr = progress.Status, resp, nil
+			verr.DomainName = resp.Domain.Name
+		case ctx.Err() != nil:
+			return nil, verr.end(ctx, start)
+		case IsRateLimited(err) || IsServerError(err) || isNetworkError(err):
+			progress.Status = NameserverError
+			verr.LastErr = err
+		default:
+			return nil, err
+		}
+
+		done := progress.Status == NameserverValid ||
+			(opts.StopOnInvalid && progress.Status == NameserverInvalid)
+		if !done {
+			progress.NextCheck = interval
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+		if progress.Status == NameserverValid {
+			return resp, nil
+		}
+		if done {
+			return nil, verr
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, verr.end(ctx, start)
+		case <-timer.C:
+		}
+		interval = time.Duration(float64(interval) * opts.Multiplier)
+		if interval > opts.MaxInterval {
+			interval = opts.MaxInterval
+		}
+	}
+}
+
+// end finishes the error once the context is done. Cancellation by the
+// caller is reported as is.
+func (e *NameserverVerificationError) end(ctx context.Context, start time.Time) error {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return ctx.Err()
+	}
+	e.TimedOut = true
+	e.Elapsed = time.Since(start)
+	e.cause = ctx.Err()
+	return e
+}
+
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
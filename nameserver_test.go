@@ -0,0 +1,130 @@
+package enzonix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForNameserverVerification(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeClient(t)
+	domain := fake.AddDomain("example.com")
+	fake.FailNext(http.StatusServiceUnavailable, "maintenance")
+
+	var progress []NameserverProgress
+	resp, err := client.WaitForNameserverVerification(context.Background(), domain.ID, WaitOptions{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     4 * time.Millisecond,
+		Timeout:         5 * time.Second,
+		OnProgress: func(p NameserverProgress) {
+			progress = append(progress, p)
+			if p.Attempt == 3 {
+				fake.SetNameserverStatus(domain.ID, "valid")
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Status() != NameserverValid || resp.Domain.NameserverVerifiedAt == nil {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	var statuses []string
+	for _, p := range progress {
+		statuses = append(statuses, string(p.Status))
+	}
+	if got := strings.Join(statuses, ","); got != "error,pending,pending,valid" {
+		t.Fatalf("unexpected progress %s", got)
+	}
+	if progress[0].Err == nil || !IsServerError(progress[0].Err) {
+		t.Fatalf("expected server error in first progress, got %v", progress[0].Err)
+	}
+	if progress[1].NextCheck != 2*time.Millisecond || progress[3].NextCheck != 0 {
+		t.Fatalf("unexpected backoff %v, %v", progress[1].NextCheck, progress[3].NextCheck)
+	}
+}
+
+func TestWaitForNameserverVerificationTimeout(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeClient(t)
+	domain := fake.AddDomain("example.com")
+	fake.SetNameserverStatus(domain.ID, "invalid")
+
+	_, err := client.WaitForNameserverVerification(context.Background(), domain.ID, WaitOptions{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     5 * time.Millisecond,
+		Timeout:         50 * time.Millisecond,
+	})
+	var verr *NameserverVerificationError
+	if !errors.As(err, &verr) || !errors.Is(err, ErrNameserverTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if verr.LastStatus != NameserverInvalid || verr.Attempts < 2 || verr.DomainName != "example.com." {
+		t.Fatalf("unexpected error details %+v", verr)
+	}
+	if !strings.Contains(err.Error(), "last status invalid") {
+		t.Fatalf("error does not explain last state: %v", err)
+	}
+
+	_, err = client.WaitForNameserverVerification(context.Background(), domain.ID, WaitOptions{StopOnInvalid: true})
+	if !errors.Is(err, ErrNameserverInvalid) || errors.Is(err, ErrNameserverTimeout) {
+		t.Fatalf("expected invalid error, got %v", err)
+	}
+
+	// Permanent API errors end the wait immediately.
+	_, err = client.WaitForNameserverVerification(context.Background(), "missing", WaitOptions{})
+	if !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestNameserverStatusJSON(t *testing.T) {
+	t.Parallel()
+
+	var domain Domain
+	data := `{"id":"d1","nameserver_check_status":"VALID","nameserver_verified_at":"2024-01-01T00:00:00Z"}`
+	if err := json.Unmarshal([]byte(data), &domain); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if domain.NameserverCheckStatus != NameserverValid || !domain.IsVerified() || domain.VerificationAge() <= 0 {
+		t.Fatalf("unexpected domain %+v", domain)
+	}
+
+	var check NameserverCheck
+	if err := json.Unmarshal([]byte(`{"valid":false,"status":"propagating"}`), &check); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if check.Status != "propagating" || check.Status.IsKnown() || check.Status.IsTerminal() {
+		t.Fatalf("unknown status not preserved: %+v", check)
+	}
+	out, err := json.Marshal(check)
+	if err != nil || string(out) != `{"valid":false,"status":"propagating"}` {
+		t.Fatalf("unexpected round trip %s, %v", out, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"status":null}`), &check); err != nil || check.Status != "" {
+		t.Fatalf("expected null to decode as empty status, got %q, %v", check.Status, err)
+	}
+	if err := json.Unmarshal([]byte(`{"status":7}`), &check); err == nil {
+		t.Fatal("expected error for non-string status")
+	}
+
+	for status, terminal := range map[NameserverStatus]bool{
+		NameserverPending: false, NameserverValid: true, NameserverInvalid: true, NameserverError: false,
+	} {
+		if status.IsTerminal() != terminal || status.IsVerified() != (status == NameserverValid) {
+			t.Fatalf("unexpected helpers for %s", status)
+		}
+	}
+	if (Domain{}).IsVerified() || (Domain{}).VerificationAge() != 0 {
+		t.Fatal("unverified domain reported as verified")
+	}
+}
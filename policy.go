@@ -0,0 +1,378 @@
+package enzonix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/yamlite"
+	"github.com/Enzonix-LLC/dns-sdk-go/zonefile"
+)
+
+// PolicyEffect is the outcome of a matching policy rule.
+type PolicyEffect string
+
+const (
+	// PolicyDeny rejects the operation.
+	PolicyDeny PolicyEffect = "deny"
+	// PolicyRequireConfirmation rejects the operation unless the context
+	// carries a confirmation token, see WithConfirmation.
+	PolicyRequireConfirmation PolicyEffect = "require_confirmation"
+)
+
+var (
+	// ErrPolicyViolation is matched by errors.Is for every
+	// PolicyViolationError.
+	ErrPolicyViolation = errors.New("enzonix: policy violation")
+	// ErrConfirmationRequired is additionally matched when the violated rule
+	// would allow the operation with a valid confirmation token.
+	ErrConfirmationRequired = errors.New("enzonix: confirmation required")
+)
+
+// PolicyRule is a declarative rule evaluated before mutations. All non-empty
+// conditions must hold for the rule to match.
+type PolicyRule struct {
+	Name   string       `json:"name"`
+	Effect PolicyEffect `json:"effect"`
+	// Operations limits the rule to the named client methods, e.g.
+	// "DeleteRecord". Empty matches every guarded operation.
+	Operations []string `json:"operations,omitempty"`
+	// Domains holds path.Match patterns for domain names, e.g. "*.prod".
+	Domains []string `json:"domains,omitempty"`
+	// Names holds path.Match patterns for record names; "@" is the apex.
+	Names []string `json:"names,omitempty"`
+	// Types lists record types, e.g. "NS".
+	Types []string `json:"types,omitempty"`
+	// MinTTL matches operations that would leave a record with a TTL
+	// below this value.
+	MinTTL int `json:"min_ttl,omitempty"`
+	// ConfirmationToken is the token required by PolicyRequireConfirmation
+	// rules. When empty, any non-empty token confirms.
+	ConfirmationToken string `json:"confirmation_token,omitempty"`
+	// Message is included in the error returned for violations.
+	Message string `json:"message,omitempty"`
+}
+
+// Policy is a set of rules evaluated before UpdateRecord, DeleteRecord,
+// DeleteDomain, ImportBindZone and CreateRecord. The first matching rule
+// decides; operations matching no rule are allowed.
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+	// Logger receives a record of every decision. Allowed operations are
+	// logged at debug level, rejections at warn level. Defaults to
+	// slog.Default().
+	Logger *slog.Logger `json:"-"`
+}
+
+// PolicyViolationError reports an operation rejected by a policy rule.
+type PolicyViolationError struct {
+	Operation string
+	Rule      PolicyRule
+	// Target describes the rejected change, e.g. "NS @ in example.com".
+	Target string
+}
+
+// Error satisfies the error interface.
+func (e *PolicyViolationError) Error() string {
+	msg := fmt.Sprintf("enzonix: policy rule %q denies %s of %s", e.Rule.Name, e.Operation, e.Target)
+	if e.Rule.Effect == PolicyRequireConfirmation {
+		msg = fmt.Sprintf("enzonix: policy rule %q requires confirmation for %s of %s", e.Rule.Name, e.Operation, e.Target)
+	}
+	if e.Rule.Message != "" {
+		msg += ": " + e.Rule.Message
+	}
+	return msg
+}
+
+// Is matches ErrPolicyViolation and, for confirmation rules,
+// ErrConfirmationRequired.
+func (e *PolicyViolationError) Is(target error) bool {
+	return target == ErrPolicyViolation ||
+		(target == ErrConfirmationRequired && e.Rule.Effect == PolicyRequireConfirmation)
+}
+
+// ParsePolicy parses a policy from JSON or YAML.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var p Policy
+	trimmed := bytes.TrimSpace(data)
+	var err error
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		err = json.Unmarshal(trimmed, &p)
+	} else {
+		err = yamlite.Unmarshal(data, &p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("enzonix: parse policy: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// LoadPolicyFile reads a JSON or YAML policy file.
+func LoadPolicyFile(filename string) (*Policy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("enzonix: read policy: %w", err)
+	}
+	return ParsePolicy(data)
+}
+
+// Validate checks the rules for unknown effects and malformed patterns.
+func (p *Policy) Validate() error {
+	for i, rule := range p.Rules {
+		label := rule.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i+1)
+		}
+		switch rule.Effect {
+		case PolicyDeny, PolicyRequireConfirmation:
+		default:
+			return fmt.Errorf("enzonix: policy rule %s: unknown effect %q", label, rule.Effect)
+		}
+		for _, pattern := range append(append([]string(nil), rule.Domains...), rule.Names...) {
+			if _, err := path.Match(strings.ToLower(pattern), ""); err != nil {
+				return fmt.Errorf("enzonix: policy rule %s: invalid pattern %q", label, pattern)
+			}
+		}
+		for _, op := range rule.Operations {
+			if !policyOperations[op] {
+				return fmt.Errorf("enzonix: policy rule %s: unknown operation %q", label, op)
+			}
+		}
+	}
+	return nil
+}
+
+var policyOperations = map[string]bool{
+	"CreateRecord":   true,
+	"UpdateRecord":   true,
+	"DeleteRecord":   true,
+	"DeleteDomain":   true,
+	"ImportBindZone": true,
+}
+
+// WithPolicy installs a policy evaluated before guarded mutations.
+func WithPolicy(p *Policy) Option {
+	return func(c *Client) error {
+		if p == nil {
+			return errors.New("enzonix: policy must not be nil")
+		}
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		c.policy = p
+		return nil
+	}
+}
+
+type confirmationKey struct{}
+
+// WithConfirmation returns a context carrying a confirmation token for
+// PolicyRequireConfirmation rules.
+func WithConfirmation(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, confirmationKey{}, token)
+}
+
+// policySubject describes a mutation for rule matching.
+type policySubject struct {
+	domain string
+	name   string
+	typ    string
+	// ttl is the TTL the record would have afterwards, or -1 if unchanged
+	// or unknown.
+	ttl int
+	// record reports whether the subject is a record rather than a domain.
+	record bool
+}
+
+func (s policySubject) String() string {
+	if !s.record {
+		return "domain " + s.domain
+	}
+	return fmt.Sprintf("%s %s in %s", strings.ToUpper(s.typ), s.name, s.domain)
+}
+
+func (r PolicyRule) matches(op string, s policySubject) bool {
+	if len(r.Operations) > 0 && !containsString(r.Operations, op) {
+		return false
+	}
+	if len(r.Domains) > 0 && !matchAny(r.Domains, normalizeDomainName(s.domain)) {
+		return false
+	}
+	if len(r.Names) > 0 || len(r.Types) > 0 || r.MinTTL > 0 {
+		if !s.record {
+			return false
+		}
+	}
+	if len(r.Names) > 0 && !matchAny(r.Names, apexName(s.name, s.domain)) {
+		return false
+	}
+	if len(r.Types) > 0 && !containsFold(r.Types, s.typ) {
+		return false
+	}
+	if r.MinTTL > 0 && (s.ttl < 0 || s.ttl >= r.MinTTL) {
+		return false
+	}
+	return true
+}
+
+// evaluate returns the violation for the first matching rule, or nil.
+func (p *Policy) evaluate(ctx context.Context, op string, subjects []policySubject) error {
+	logger := p.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	token, _ := ctx.Value(confirmationKey{}).(string)
+	for _, s := range subjects {
+		for _, rule := range p.Rules {
+			if !rule.matches(op, s) {
+				continue
+			}
+			if rule.Effect == PolicyRequireConfirmation && token != "" &&
+				(rule.ConfirmationToken == "" || rule.ConfirmationToken == token) {
+				logger.LogAttrs(ctx, slog.LevelInfo, "enzonix policy decision",
+					slog.String("operation", op), slog.String("target", s.String()),
+					slog.String("rule", rule.Name), slog.String("decision", "confirmed"))
+				break
+			}
+			logger.LogAttrs(ctx, slog.LevelWarn, "enzonix policy decision",
+				slog.String("operation", op), slog.String("target", s.String()),
+				slog.String("rule", rule.Name), slog.String("decision", string(rule.Effect)))
+			return &PolicyViolationError{Operation: op, Rule: rule, Target: s.String()}
+		}
+	}
+
+	logger.LogAttrs(ctx, slog.LevelDebug, "enzonix policy decision",
+		slog.String("operation", op), slog.Int("targets", len(subjects)), slog.String("decision", "allow"))
+	return nil
+}
+
+func (c *Client) policyCreateRecord(ctx context.Context, payload CreateRecordRequest) error {
+	if c.policy == nil {
+		return nil
+	}
+	domain, err := c.lookupDomain(ctx, payload.DomainID)
+	if err != nil {
+		return err
+	}
+	ttl := -1
+	if payload.TTL != nil {
+		ttl = *payload.TTL
+	}
+	return c.policy.evaluate(ctx, "CreateRecord", []policySubject{{
+		domain: domain.Name, name: payload.Name, typ: payload.Type, ttl: ttl, record: true,
+	}})
+}
+
+func (c *Client) policyRecord(ctx context.Context, op string, existing *recordRef, update *UpdateRecordRequest) error {
+	if c.policy == nil {
+		return nil
+	}
+	record, err := existing.get(ctx, c)
+	if err != nil {
+		return err
+	}
+	domain, err := c.lookupDomain(ctx, record.DomainID)
+	if err != nil {
+		return err
+	}
+
+	before := policySubject{domain: domain.Name, name: record.Name, typ: record.Type, ttl: -1, record: true}
+	subjects := []policySubject{before}
+	if update != nil {
+		after := before
+		if update.Name != nil {
+			after.name = *update.Name
+		}
+		if update.Type != nil {
+			after.typ = *update.Type
+		}
+		if update.TTL != nil {
+			after.ttl = *update.TTL
+		}
+		subjects = append(subjects, after)
+	}
+	return c.policy.evaluate(ctx, op, subjects)
+}
+
+func (c *Client) policyDeleteDomain(ctx context.Context, domainID string) error {
+	if c.policy == nil {
+		return nil
+	}
+	domain, err := c.lookupDomain(ctx, domainID)
+	if err != nil {
+		return err
+	}
+	return c.policy.evaluate(ctx, "DeleteDomain", []policySubject{{domain: domain.Name}})
+}
+
+func (c *Client) policyImport(ctx context.Context, zoneData []byte) error {
+	if c.policy == nil {
+		return nil
+	}
+	zone, err := zonefile.Parse(bytes.NewReader(zoneData), "")
+	if err != nil {
+		return fmt.Errorf("enzonix: policy check: %w", err)
+	}
+	// Every origin of the file is a domain being written to.
+	var subjects []policySubject
+	for _, origin := range zone.Origins {
+		subjects = append(subjects, policySubject{domain: origin})
+	}
+	if len(subjects) == 0 {
+		subjects = append(subjects, policySubject{domain: zone.Origin})
+	}
+	for _, r := range zone.Records {
+		subjects = append(subjects, policySubject{domain: r.Origin, name: r.Name, typ: r.Type, ttl: r.TTL, record: true})
+	}
+	return c.policy.evaluate(ctx, "ImportBindZone", subjects)
+}
+
+// apexName maps the apex to "@" so rules can name it independently of how
+// the API spells it.
+func apexName(name, domain string) string {
+	n := normalizeRecordName(name)
+	if n == "" || n == "@" || n == normalizeDomainName(domain) {
+		return "@"
+	}
+	if d := normalizeDomainName(domain); d != "" && strings.HasSuffix(n, "."+d) {
+		return strings.TrimSuffix(n, "."+d)
+	}
+	return n
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(strings.TrimSuffix(pattern, ".")), value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, want string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package enzonix
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+const testPolicyYAML = `
+rules:
+  - name: protect-apex-ns
+    effect: deny
+    operations: [DeleteRecord]
+    names: ["@"]
+    types: [NS]
+  - name: min-ttl
+    effect: deny
+    min_ttl: 60
+    message: TTLs below 60s overload resolvers
+  - name: confirm-mx
+    effect: require_confirmation
+    types: [MX]
+    confirmation_token: change-mx
+  - name: frozen-prod
+    effect: deny
+    domains: ["*.prod"]
+`
+
+func TestPolicyEnforcement(t *testing.T) {
+	t.Parallel()
+
+	policy, err := ParsePolicy([]byte(testPolicyYAML))
+	if err != nil {
+		t.Fatalf("parse policy: %v", err)
+	}
+	var logs bytes.Buffer
+	policy.Logger = slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	fake, client := newFakeClient(t, WithPolicy(policy))
+	domain := fake.AddDomain("example.com")
+	prod := fake.AddDomain("api.prod")
+	ns := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "@", Type: "NS", Value: "ns1.enzonix.com."})
+	mx := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "@", Type: "MX", Priority: 10, Value: "mx.example.com."})
+	www := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", Value: "192.0.2.1"})
+	ctx := context.Background()
+
+	expectRule := func(err error, rule string) {
+		t.Helper()
+		var violation *PolicyViolationError
+		if !errors.As(err, &violation) || !errors.Is(err, ErrPolicyViolation) {
+			t.Fatalf("expected policy violation of %s, got %v", rule, err)
+		}
+		if violation.Rule.Name != rule {
+			t.Fatalf("expected rule %s, got %s", rule, violation.Rule.Name)
+		}
+	}
+
+	expectRule(client.DeleteRecord(ctx, ns.ID), "protect-apex-ns")
+
+	low := 30
+	_, err = client.UpdateRecord(ctx, www.ID, UpdateRecordRequest{TTL: &low})
+	expectRule(err, "min-ttl")
+	if !strings.Contains(err.Error(), "overload resolvers") {
+		t.Fatalf("expected rule message in error, got %v", err)
+	}
+
+	target := "mx2.example.com."
+	_, err = client.UpdateRecord(ctx, mx.ID, UpdateRecordRequest{Value: &target})
+	expectRule(err, "confirm-mx")
+	if !errors.Is(err, ErrConfirmationRequired) {
+		t.Fatalf("expected ErrConfirmationRequired, got %v", err)
+	}
+	_, err = client.UpdateRecord(WithConfirmation(ctx, "wrong"), mx.ID, UpdateRecordRequest{Value: &target})
+	expectRule(err, "confirm-mx")
+	if _, err := client.UpdateRecord(WithConfirmation(ctx, "change-mx"), mx.ID, UpdateRecordRequest{Value: &target}); err != nil {
+		t.Fatalf("expected confirmed change to succeed, got %v", err)
+	}
+
+	expectRule(client.DeleteDomain(ctx, prod.ID), "frozen-prod")
+	_, err = client.ImportBindZone(ctx, []byte("$ORIGIN api.prod.\nwww 300 IN A 192.0.2.1\n"), "")
+	expectRule(err, "frozen-prod")
+	// Switching origins does not hide the earlier ones.
+	_, err = client.ImportBindZone(ctx, []byte("$ORIGIN api.prod.\nwww 300 IN A 192.0.2.1\n$ORIGIN other.com.\nwww 300 IN A 192.0.2.2\n"), "")
+	expectRule(err, "frozen-prod")
+	_, err = client.ImportBindZone(ctx, []byte("$ORIGIN new.example.\nwww 10 IN A 192.0.2.1\n"), "")
+	expectRule(err, "min-ttl")
+
+	// Unmatched operations pass through.
+	if err := client.DeleteRecord(ctx, www.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := logs.String()
+	for _, want := range []string{"decision=deny", "decision=require_confirmation", "decision=confirmed", "decision=allow", "rule=protect-apex-ns"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in policy log:\n%s", want, out)
+		}
+	}
+}
+
+func TestLoadPolicyFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "policy.json")
+	if err := os.WriteFile(jsonPath, []byte(`{"rules":[{"name":"no-ns","effect":"deny","types":["NS"]}]}`), 0o600); err != nil {
+		t.Fatalf("write policy: %v", err)
+	}
+	policy, err := LoadPolicyFile(jsonPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(policy.Rules) != 1 || policy.Rules[0].Types[0] != "NS" {
+		t.Fatalf("unexpected policy %#v", policy)
+	}
+
+	for _, bad := range []string{
+		"rules:\n  - name: x\n    effect: maybe\n",
+		"rules:\n  - name: x\n    effect: deny\n    operations: [Explode]\n",
+		"rules:\n  - name: x\n    effect: deny\n    names: [\"[\"]\n",
+	} {
+		if _, err := ParsePolicy([]byte(bad)); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package enzonix
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// RecorderMode controls how a cassette recorder handles HTTP traffic.
+type RecorderMode int
+
+const (
+	// RecorderPassthrough sends requests to the API without recording them.
+	RecorderPassthrough RecorderMode = iota
+	// RecorderRecord sends requests to the API and writes every
+	// request/response pair to the cassette file. An existing cassette is
+	// replaced unless WithCassetteAppend is given.
+	RecorderRecord
+	// RecorderReplay serves responses from the cassette file without any
+	// network access.
+	RecorderReplay
+)
+
+// String returns the mode name.
+func (m RecorderMode) String() string {
+	switch m {
+	case RecorderPassthrough:
+		return "passthrough"
+	case RecorderRecord:
+		return "record"
+	case RecorderReplay:
+		return "replay"
+	default:
+		return fmt.Sprintf("RecorderMode(%d)", int(m))
+	}
+}
+
+const redactedHeaderValue = "REDACTED"
+
+// defaultRedactedFields are response body fields that hold credentials.
+var defaultRedactedFields = []string{"api_token"}
+
+// ErrNoCassetteMatch is returned in replay mode when a request has no
+// matching interaction in the cassette.
+var ErrNoCassetteMatch = errors.New("enzonix: no matching cassette interaction")
+
+// Cassette is the on-disk representation of recorded HTTP interactions.
+type Cassette struct {
+	Interactions []CassetteInteraction `json:"interactions"`
+}
+
+// CassetteInteraction is a single recorded request/response pair.
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteRequest captures the recorded parts of an outgoing request.
+type CassetteRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// CassetteResponse captures the recorded parts of an API response.
+type CassetteResponse struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// RecorderOption customises a recorder configured by WithRecorder.
+type RecorderOption func(*recorder)
+
+// WithLenientMatching relaxes replay matching to method and path only,
+// ignoring query strings, bodies and the order in which interactions were
+// recorded. By default replay is strict: interactions are consumed in order
+// and must match method, URL and body exactly.
+func WithLenientMatching() RecorderOption {
+	return func(r *recorder) {
+		r.lenient = true
+	}
+}
+
+// WithCassetteAppend keeps the interactions of an existing cassette in
+// record mode and appends new ones after them.
+func WithCassetteAppend() RecorderOption {
+	return func(r *recorder) {
+		r.appendMode = true
+	}
+}
+
+// WithRedactedFields redacts the values of the named JSON fields, at any
+// depth, in recorded response bodies. "api_token" is always redacted.
+func WithRedactedFields(names ...string) RecorderOption {
+	return func(r *recorder) {
+		for _, name := range names {
+			r.redact[name] = true
+		}
+	}
+}
+
+// WithRecorder records or replays the HTTP traffic of the client using a
+// JSON cassette at path. The Authorization header and the api_token field
+// of response bodies are never written to disk.
+func WithRecorder(path string, mode RecorderMode, opts ...RecorderOption) Option {
+	return func(c *Client) error {
+		if strings.TrimSpace(path) == "" {
+			return errors.New("enzonix: cassette path must not be empty")
+		}
+
+		r := &recorder{path: path, mode: mode, redact: map[string]bool{}}
+		for _, name := range defaultRedactedFields {
+			r.redact[name] = true
+		}
+		for _, opt := range opts {
+			if opt != nil {
+				opt(r)
+			}
+		}
+
+		switch mode {
+		case RecorderPassthrough:
+		case RecorderRecord:
+			if r.appendMode {
+				if err := r.load(true); err != nil {
+					return err
+				}
+			}
+		case RecorderReplay:
+			if err := r.load(false); err != nil {
+				return err
+			}
+			r.used = make([]bool, len(r.cassette.Interactions))
+		default:
+			return fmt.Errorf("enzonix: unknown recorder mode %d", int(mode))
+		}
+
+		c.recorder = r
+		return nil
+	}
+}
+
+type recorder struct {
+	mu         sync.Mutex
+	path       string
+	mode       RecorderMode
+	lenient    bool
+	appendMode bool
+	redact     map[string]bool
+	cassette   Cassette
+	used       []bool
+	next       int
+}
+
+func (r *recorder) load(allowMissing bool) error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if allowMissing && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("enzonix: read cassette: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return fmt.Errorf("enzonix: decode cassette: %w", err)
+	}
+	return nil
+}
+
+func (r *recorder) roundTrip(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	switch r.mode {
+	case RecorderRecord:
+		return r.record(httpClient, req)
+	case RecorderReplay:
+		return r.replay(req)
+	default:
+		return httpClient.Do(req)
+	}
+}
+
+func (r *recorder) record(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	interaction := CassetteInteraction{
+		Request: CassetteRequest{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: scrubHeaders(req.Header),
+			Body:    string(reqBody),
+		},
+		Response: CassetteResponse{
+			StatusCode: res.StatusCode,
+			Headers:    res.Header.Clone(),
+			Body:       r.scrubBody(resBody),
+		},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	if err := r.save(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (r *recorder) save() error {
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("enzonix: encode cassette: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("enzonix: write cassette: %w", err)
+	}
+	return nil
+}
+
+func (r *recorder) replay(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx := -1
+	if r.lenient {
+		for i, interaction := range r.cassette.Interactions {
+			if !r.used[i] && matchLenient(interaction.Request, req) {
+				idx = i
+				break
+			}
+		}
+	} else if r.next < len(r.cassette.Interactions) {
+		if matchStrict(r.cassette.Interactions[r.next].Request, req, reqBody) {
+			idx = r.next
+		}
+	}
+	if idx < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoCassetteMatch, req.Method, req.URL.String())
+	}
+
+	r.used[idx] = true
+	if !r.lenient {
+		r.next++
+	}
+
+	recorded := r.cassette.Interactions[idx].Response
+	header := recorded.Headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+func matchStrict(recorded CassetteRequest, req *http.Request, body []byte) bool {
+	return recorded.Method == req.Method &&
+		recorded.URL == req.URL.String() &&
+		recorded.Body == string(body)
+}
+
+func matchLenient(recorded CassetteRequest, req *http.Request) bool {
+	if recorded.Method != req.Method {
+		return false
+	}
+	u, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	return u.Path == req.URL.Path
+}
+
+// readRequestBody returns the request body and rewinds it so the request can
+// still be sent.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func scrubHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	if out.Get("Authorization") != "" {
+		out.Set("Authorization", redactedHeaderValue)
+	}
+	return out
+}
+
+// scrubBody redacts the configured fields of a JSON body. Bodies without
+// such fields, or that are not JSON, are kept verbatim.
+func (r *recorder) scrubBody(body []byte) string {
+	var doc any
+	if len(r.redact) == 0 || json.Unmarshal(body, &doc) != nil {
+		return string(body)
+	}
+	if !redactFields(doc, r.redact) {
+		return string(body)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return string(body)
+	}
+	return string(data)
+}
+
+func redactFields(v any, names map[string]bool) bool {
+	found := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if names[k] {
+				v[k] = redactedHeaderValue
+				found = true
+				continue
+			}
+			found = redactFields(child, names) || found
+		}
+	case []any:
+		for _, child := range v {
+			found = redactFields(child, names) || found
+		}
+	}
+	return found
+}
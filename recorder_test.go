@@ -0,0 +1,197 @@
+package enzonix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorderRecordAndReplay(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/client/domains":
+			json.NewEncoder(w).Encode([]Domain{{ID: "domain-1", Name: "example.com."}})
+		case "/api/client/domains/domain-1/export/bind":
+			io.WriteString(w, "$ORIGIN example.com.\n")
+		default:
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	recording, err := NewClient("secret-key", WithBaseURL(server.URL), WithRecorder(cassette, RecorderRecord))
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+	if _, err := recording.ListDomains(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := recording.ExportBindZone(context.Background(), "domain-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatalf("read cassette: %v", err)
+	}
+	if strings.Contains(string(data), "secret-key") {
+		t.Fatalf("cassette leaked the api key")
+	}
+
+	// Close the server so replay cannot reach the network.
+	server.Close()
+
+	replaying, err := NewClient("other-key", WithBaseURL(server.URL), WithRecorder(cassette, RecorderReplay))
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+	domains, err := replaying.ListDomains(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(domains) != 1 || domains[0].ID != "domain-1" {
+		t.Fatalf("unexpected domains: %#v", domains)
+	}
+	zone, err := replaying.ExportBindZone(context.Background(), "domain-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(zone) != "$ORIGIN example.com.\n" {
+		t.Fatalf("unexpected zone %q", zone)
+	}
+
+	if _, err := replaying.ListDomains(context.Background()); !errors.Is(err, ErrNoCassetteMatch) {
+		t.Fatalf("expected cassette exhaustion, got %v", err)
+	}
+}
+
+func TestRecorderReplayMatching(t *testing.T) {
+	t.Parallel()
+
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+	fixture := Cassette{Interactions: []CassetteInteraction{
+		{
+			Request:  CassetteRequest{Method: http.MethodGet, URL: "https://example.test/api/client/domains"},
+			Response: CassetteResponse{StatusCode: http.StatusOK, Body: `[{"id":"domain-1"}]`},
+		},
+		{
+			Request:  CassetteRequest{Method: http.MethodDelete, URL: "https://example.test/api/client/records/abc"},
+			Response: CassetteResponse{StatusCode: http.StatusNotFound, Body: `{"message":"missing"}`},
+		},
+	}}
+	data, _ := json.Marshal(fixture)
+	if err := os.WriteFile(cassette, data, 0o600); err != nil {
+		t.Fatalf("write cassette: %v", err)
+	}
+
+	strict, err := NewClient("key", WithBaseURL("https://example.test"), WithRecorder(cassette, RecorderReplay))
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+	if err := strict.DeleteRecord(context.Background(), "abc"); !errors.Is(err, ErrNoCassetteMatch) {
+		t.Fatalf("expected strict ordering to reject out-of-order request, got %v", err)
+	}
+
+	lenient, err := NewClient("key",
+		WithBaseURL("https://example.test"),
+		WithRecorder(cassette, RecorderReplay, WithLenientMatching()),
+	)
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+	err = lenient.DeleteRecord(context.Background(), "abc")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected recorded 404, got %v", err)
+	}
+	if _, err := lenient.ListDomains(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRecorderValidation(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewClient("key", WithRecorder("", RecorderRecord)); err == nil {
+		t.Fatalf("expected error for empty cassette path")
+	}
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	if _, err := NewClient("key", WithRecorder(missing, RecorderReplay)); err == nil {
+		t.Fatalf("expected error for missing replay cassette")
+	}
+}
+
+func TestRecorderRerecordAndRedaction(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/client/domains":
+			json.NewEncoder(w).Encode([]Domain{{ID: "domain-1", Name: "example.com."}})
+		case "/api/client/rotate-api-key":
+			io.WriteString(w, `{"id": "client-1", "api_token": "new-secret-token", "profile": {"webhook_secret": "hook-secret"}}`)
+		default:
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+	record := func(opts ...RecorderOption) Cassette {
+		t.Helper()
+		client, err := NewClient("key", WithBaseURL(server.URL), WithRecorder(cassette, RecorderRecord, opts...))
+		if err != nil {
+			t.Fatalf("setup error: %v", err)
+		}
+		if _, err := client.ListDomains(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, err := os.ReadFile(cassette)
+		if err != nil {
+			t.Fatalf("read cassette: %v", err)
+		}
+		var c Cassette
+		if err := json.Unmarshal(data, &c); err != nil {
+			t.Fatalf("decode cassette: %v", err)
+		}
+		return c
+	}
+
+	record()
+	if c := record(); len(c.Interactions) != 1 {
+		t.Fatalf("re-recording kept stale interactions: %d", len(c.Interactions))
+	}
+	if c := record(WithCassetteAppend()); len(c.Interactions) != 2 {
+		t.Fatalf("append mode dropped interactions: %d", len(c.Interactions))
+	}
+
+	client, err := NewClient("key", WithBaseURL(server.URL), WithRecorder(cassette, RecorderRecord, WithRedactedFields("webhook_secret")))
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+	profile, err := client.RotateAPIKey(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.APIToken != "new-secret-token" {
+		t.Fatalf("live response was redacted: %#v", profile)
+	}
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatalf("read cassette: %v", err)
+	}
+	if strings.Contains(string(data), "new-secret-token") || strings.Contains(string(data), "hook-secret") {
+		t.Fatalf("cassette leaked a secret:\n%s", data)
+	}
+}
@@ -16,24 +16,27 @@ const clientAPIPrefix = "/api/client"
 
 // Domain represents a domain resource managed via the client API.
 type Domain struct {
-	ID                    string     `json:"id"`
-	ClientID              string     `json:"client_id"`
-	Name                  string     `json:"name"`
-	Active                bool       `json:"active"`
-	CreatedAt             *time.Time `json:"created_at"`
-	UpdatedAt             *time.Time `json:"updated_at"`
-	NameserverLastChecked *time.Time `json:"nameserver_last_checked_at"`
-	NameserverVerifiedAt  *time.Time `json:"nameserver_verified_at"`
-	NameserverCheckStatus string     `json:"nameserver_check_status"`
+	ID                    string           `json:"id"`
+	ClientID              string           `json:"client_id"`
+	Name                  string           `json:"name"`
+	Active                bool             `json:"active"`
+	CreatedAt             *time.Time       `json:"created_at"`
+	UpdatedAt             *time.Time       `json:"updated_at"`
+	NameserverLastChecked *time.Time       `json:"nameserver_last_checked_at"`
+	NameserverVerifiedAt  *time.Time       `json:"nameserver_verified_at"`
+	NameserverCheckStatus NameserverStatus `json:"nameserver_check_status"`
+}
+
+// NameserverCheck is the result of a single nameserver check.
+type NameserverCheck struct {
+	Valid  bool             `json:"valid"`
+	Status NameserverStatus `json:"status"`
 }
 
 // NameserverCheckResponse represents the check-nameserver payload.
 type NameserverCheckResponse struct {
-	Domain Domain `json:"domain"`
-	Check  struct {
-		Valid  bool   `json:"valid"`
-		Status string `json:"status"`
-	} `json:"check"`
+	Domain Domain          `json:"domain"`
+	Check  NameserverCheck `json:"check"`
 }
 
 // Record describes a DNS record managed by Enzonix.
@@ -73,6 +76,14 @@ type UpdateRecordRequest struct {
 
 // ListDomains retrieves all domains owned by the authenticated client.
 func (c *Client) ListDomains(ctx context.Context) ([]Domain, error) {
+	domains, err := c.listDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.filterDomains(domains), nil
+}
+
+func (c *Client) listDomains(ctx context.Context) ([]Domain, error) {
 	req, err := c.newRequest(ctx, http.MethodGet, clientAPIPrefix+"/domains", nil, nil)
 	if err != nil {
 		return nil, err
@@ -92,7 +103,14 @@ func (c *Client) CreateDomain(ctx context.Context, name string) (*Domain, error)
 	if name == "" {
 		return nil, fmt.Errorf("enzonix: domain name must not be empty")
 	}
+	if err := c.scopeWrite("CreateDomain"); err != nil {
+		return nil, err
+	}
+	if err := c.scopeDomainName("CreateDomain", name); err != nil {
+		return nil, err
+	}
 
+	ctx, audit := c.beginAudit(ctx, "CreateDomain")
 	payload := map[string]string{"name": name}
 	req, err := c.newRequest(ctx, http.MethodPost, clientAPIPrefix+"/domains", nil, payload)
 	if err != nil {
@@ -101,10 +119,14 @@ func (c *Client) CreateDomain(ctx context.Context, name string) (*Domain, error)
 
 	var domain Domain
 	if err := c.do(req, &domain); err != nil {
-		return nil, err
+		return nil, audit.finish(ctx, err)
 	}
 
-	return &domain, nil
+	if audit != nil {
+		audit.entry.DomainID = domain.ID
+		audit.entry.Domain = &domain
+	}
+	return &domain, audit.finish(ctx, nil)
 }
 
 // DeleteDomain deletes a domain by ID.
@@ -112,6 +134,33 @@ func (c *Client) DeleteDomain(ctx context.Context, domainID string) error {
 	if err := requireID(domainID, "domain id"); err != nil {
 		return err
 	}
+	if err := c.scopeWrite("DeleteDomain"); err != nil {
+		return err
+	}
+	if err := c.scopeDomain(ctx, "DeleteDomain", domainID); err != nil {
+		return err
+	}
+	if err := c.policyDeleteDomain(ctx, domainID); err != nil {
+		return err
+	}
+	if len(c.preDeleteHooks) > 0 {
+		domain, err := c.lookupDomain(ctx, domainID)
+		if err != nil {
+			return err
+		}
+		for _, hook := range c.preDeleteHooks {
+			if err := hook(ctx, c, *domain); err != nil {
+				return fmt.Errorf("enzonix: pre-delete hook: %w", err)
+			}
+		}
+	}
+
+	ctx, audit := c.beginAudit(ctx, "DeleteDomain")
+	if audit != nil {
+		audit.entry.DomainID = domainID
+		audit.entry.Domain, _ = c.lookupDomain(ctx, domainID)
+		audit.entry.Records, _ = c.listDomainRecords(ctx, domainID)
+	}
 
 	path := fmt.Sprintf("%s/domains/%s", clientAPIPrefix, url.PathEscape(domainID))
 	req, err := c.newRequest(ctx, http.MethodDelete, path, nil, nil)
@@ -119,7 +168,7 @@ func (c *Client) DeleteDomain(ctx context.Context, domainID string) error {
 		return err
 	}
 
-	return c.do(req, nil)
+	return audit.finish(ctx, c.do(req, nil))
 }
 
 // CheckNameserver triggers a nameserver validation for a domain.
@@ -127,6 +176,9 @@ func (c *Client) CheckNameserver(ctx context.Context, domainID string) (*Nameser
 	if err := requireID(domainID, "domain id"); err != nil {
 		return nil, err
 	}
+	if err := c.scopeDomain(ctx, "CheckNameserver", domainID); err != nil {
+		return nil, err
+	}
 
 	path := fmt.Sprintf("%s/domains/%s/check-nameserver", clientAPIPrefix, url.PathEscape(domainID))
 	req, err := c.newRequest(ctx, http.MethodPost, path, nil, nil)
@@ -147,7 +199,18 @@ func (c *Client) ListDomainRecords(ctx context.Context, domainID string) ([]Reco
 	if err := requireID(domainID, "domain id"); err != nil {
 		return nil, err
 	}
+	if err := c.scopeDomain(ctx, "ListDomainRecords", domainID); err != nil {
+		return nil, err
+	}
 
+	records, err := c.listDomainRecords(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+	return c.filterRecords(records), nil
+}
+
+func (c *Client) listDomainRecords(ctx context.Context, domainID string) ([]Record, error) {
 	path := fmt.Sprintf("%s/domains/%s/records", clientAPIPrefix, url.PathEscape(domainID))
 	req, err := c.newRequest(ctx, http.MethodGet, path, nil, nil)
 	if err != nil {
@@ -176,18 +239,38 @@ func (c *Client) CreateRecord(ctx context.Context, payload CreateRecordRequest)
 	if strings.TrimSpace(payload.Value) == "" {
 		return nil, fmt.Errorf("enzonix: record value must not be empty")
 	}
+	if err := c.scopeWrite("CreateRecord"); err != nil {
+		return nil, err
+	}
+	if err := c.scopeDomain(ctx, "CreateRecord", payload.DomainID); err != nil {
+		return nil, err
+	}
+	if err := c.scopeRecord("CreateRecord", payload.Name, payload.Type); err != nil {
+		return nil, err
+	}
+	if err := c.policyCreateRecord(ctx, payload); err != nil {
+		return nil, err
+	}
 
+	ctx, audit := c.beginAudit(ctx, "CreateRecord")
 	req, err := c.newRequest(ctx, http.MethodPost, clientAPIPrefix+"/records", nil, payload)
 	if err != nil {
 		return nil, err
 	}
 
 	var record Record
+	if audit != nil {
+		audit.entry.DomainID = payload.DomainID
+	}
 	if err := c.do(req, &record); err != nil {
-		return nil, err
+		return nil, audit.finish(ctx, err)
 	}
 
-	return &record, nil
+	if audit != nil {
+		audit.entry.RecordID = record.ID
+		audit.entry.After = &record
+	}
+	return &record, audit.finish(ctx, nil)
 }
 
 // UpdateRecord updates a record by ID and returns the updated resource.
@@ -195,6 +278,19 @@ func (c *Client) UpdateRecord(ctx context.Context, recordID string, payload Upda
 	if err := requireID(recordID, "record id"); err != nil {
 		return nil, err
 	}
+	if err := c.scopeWrite("UpdateRecord"); err != nil {
+		return nil, err
+	}
+	existing := &recordRef{id: recordID}
+	if err := c.scopeExistingRecord(ctx, "UpdateRecord", existing, &payload); err != nil {
+		return nil, err
+	}
+	if err := c.policyRecord(ctx, "UpdateRecord", existing, &payload); err != nil {
+		return nil, err
+	}
+
+	ctx, audit := c.beginAudit(ctx, "UpdateRecord")
+	c.auditBefore(ctx, audit, existing)
 
 	path := fmt.Sprintf("%s/records/%s", clientAPIPrefix, url.PathEscape(recordID))
 	req, err := c.newRequest(ctx, http.MethodPut, path, nil, payload)
@@ -204,10 +300,13 @@ func (c *Client) UpdateRecord(ctx context.Context, recordID string, payload Upda
 
 	var record Record
 	if err := c.do(req, &record); err != nil {
-		return nil, err
+		return nil, audit.finish(ctx, err)
 	}
 
-	return &record, nil
+	if audit != nil {
+		audit.entry.After = &record
+	}
+	return &record, audit.finish(ctx, nil)
 }
 
 // DeleteRecord deletes a record by ID.
@@ -215,6 +314,19 @@ func (c *Client) DeleteRecord(ctx context.Context, recordID string) error {
 	if err := requireID(recordID, "record id"); err != nil {
 		return err
 	}
+	if err := c.scopeWrite("DeleteRecord"); err != nil {
+		return err
+	}
+	existing := &recordRef{id: recordID}
+	if err := c.scopeExistingRecord(ctx, "DeleteRecord", existing, nil); err != nil {
+		return err
+	}
+	if err := c.policyRecord(ctx, "DeleteRecord", existing, nil); err != nil {
+		return err
+	}
+
+	ctx, audit := c.beginAudit(ctx, "DeleteRecord")
+	c.auditBefore(ctx, audit, existing)
 
 	path := fmt.Sprintf("%s/records/%s", clientAPIPrefix, url.PathEscape(recordID))
 	req, err := c.newRequest(ctx, http.MethodDelete, path, nil, nil)
@@ -222,7 +334,35 @@ func (c *Client) DeleteRecord(ctx context.Context, recordID string) error {
 		return err
 	}
 
-	return c.do(req, nil)
+	return audit.finish(ctx, c.do(req, nil))
+}
+
+// UpsertRecord updates the first record of the domain that shares the
+// payload's name and type, or creates a new record when none exists.
+func (c *Client) UpsertRecord(ctx context.Context, payload CreateRecordRequest) (*Record, error) {
+	if err := requireID(payload.DomainID, "domain id"); err != nil {
+		return nil, err
+	}
+
+	records, err := c.ListDomainRecords(ctx, payload.DomainID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if !sameRecordName(record.Name, payload.Name) || !strings.EqualFold(record.Type, payload.Type) {
+			continue
+		}
+		update := UpdateRecordRequest{
+			Value:        &payload.Value,
+			TTL:          payload.TTL,
+			Priority:     payload.Priority,
+			CountryCodes: payload.CountryCodes,
+		}
+		return c.UpdateRecord(ctx, record.ID, update)
+	}
+
+	return c.CreateRecord(ctx, payload)
 }
 
 // ExportBindZone downloads a domain's records as a BIND zone file.
@@ -230,6 +370,9 @@ func (c *Client) ExportBindZone(ctx context.Context, domainID string) ([]byte, e
 	if err := requireID(domainID, "domain id"); err != nil {
 		return nil, err
 	}
+	if err := c.scopeDomain(ctx, "ExportBindZone", domainID); err != nil {
+		return nil, err
+	}
 
 	path := fmt.Sprintf("%s/domains/%s/export/bind", clientAPIPrefix, url.PathEscape(domainID))
 	req, err := c.newRequest(ctx, http.MethodGet, path, nil, nil)
@@ -238,7 +381,7 @@ func (c *Client) ExportBindZone(ctx context.Context, domainID string) ([]byte, e
 	}
 	req.Header.Set("Accept", "text/plain")
 
-	res, err := c.httpClient.Do(req)
+	res, err := c.send(req)
 	if err != nil {
 		return nil, fmt.Errorf("enzonix: request failed: %w", err)
 	}
@@ -268,7 +411,14 @@ func (c *Client) ImportBindZone(ctx context.Context, zoneData []byte, contentTyp
 	if contentType == "" {
 		contentType = "text/plain"
 	}
+	if err := c.scopeImport(ctx, "ImportBindZone", zoneData); err != nil {
+		return nil, err
+	}
+	if err := c.policyImport(ctx, zoneData); err != nil {
+		return nil, err
+	}
 
+	ctx, audit := c.beginAudit(ctx, "ImportBindZone")
 	req, err := c.newRequest(ctx, http.MethodPost, clientAPIPrefix+"/import/bind", nil, nil)
 	if err != nil {
 		return nil, err
@@ -282,10 +432,15 @@ func (c *Client) ImportBindZone(ctx context.Context, zoneData []byte, contentTyp
 
 	var resp BindImportResponse
 	if err := c.do(req, &resp); err != nil {
-		return nil, err
+		return nil, audit.finish(ctx, err)
 	}
 
-	return &resp, nil
+	if audit != nil {
+		audit.entry.DomainID = resp.Domain.ID
+		audit.entry.Domain = &resp.Domain
+		audit.entry.Records = resp.Records
+	}
+	return &resp, audit.finish(ctx, nil)
 }
 
 // RotateAPIKey rotates the client's API key and returns the updated profile.
@@ -300,6 +455,11 @@ type ClientProfile struct {
 }
 
 func (c *Client) RotateAPIKey(ctx context.Context) (*ClientProfile, error) {
+	if err := c.scopeKeyRotation("RotateAPIKey"); err != nil {
+		return nil, err
+	}
+
+	ctx, audit := c.beginAudit(ctx, "RotateAPIKey")
 	req, err := c.newRequest(ctx, http.MethodPost, clientAPIPrefix+"/rotate-api-key", nil, nil)
 	if err != nil {
 		return nil, err
@@ -307,10 +467,78 @@ func (c *Client) RotateAPIKey(ctx context.Context) (*ClientProfile, error) {
 
 	var profile ClientProfile
 	if err := c.do(req, &profile); err != nil {
+		return nil, audit.finish(ctx, err)
+	}
+
+	// The entry deliberately carries nothing from the profile: it holds
+	// the new API token.
+	return &profile, audit.finish(ctx, nil)
+}
+
+// auditBefore records the current state of a record about to change. A
+// record that cannot be found is left out; the mutation itself will report
+// the problem.
+func (c *Client) auditBefore(ctx context.Context, audit *auditRun, existing *recordRef) {
+	if audit == nil {
+		return
+	}
+	audit.entry.RecordID = existing.id
+	if before, err := existing.get(ctx, c); err == nil {
+		audit.entry.Before = before
+		audit.entry.DomainID = before.DomainID
+	}
+}
+
+// lookupDomain returns a domain by ID, ignoring any client scope.
+func (c *Client) lookupDomain(ctx context.Context, domainID string) (*Domain, error) {
+	domains, err := c.listDomains(ctx)
+	if err != nil {
 		return nil, err
 	}
+	for _, d := range domains {
+		if d.ID == domainID {
+			return &d, nil
+		}
+	}
+	return nil, &APIError{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("domain %s not found", domainID)}
+}
 
-	return &profile, nil
+// findRecord locates a record by ID. The API has no endpoint for single
+// records, so the records of every domain are scanned until it is found.
+func (c *Client) findRecord(ctx context.Context, recordID string) (*Record, error) {
+	domains, err := c.listDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range domains {
+		records, err := c.listDomainRecords(ctx, d.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			if r.ID == recordID {
+				return &r, nil
+			}
+		}
+	}
+	return nil, &APIError{StatusCode: http.StatusNotFound, Message: fmt.Sprintf("record %s not found", recordID)}
+}
+
+// recordRef is a record a mutation refers to by ID. Its scope, policy and
+// audit hooks share one lookup, made only when one of them needs it.
+type recordRef struct {
+	id     string
+	done   bool
+	record *Record
+	err    error
+}
+
+func (r *recordRef) get(ctx context.Context, c *Client) (*Record, error) {
+	if !r.done {
+		r.record, r.err = c.findRecord(ctx, r.id)
+		r.done = true
+	}
+	return r.record, r.err
 }
 
 func requireID(value, label string) error {
@@ -320,6 +548,12 @@ func requireID(value, label string) error {
 	return nil
 }
 
+func sameRecordName(a, b string) bool {
+	a = strings.TrimSuffix(strings.TrimSpace(a), ".")
+	b = strings.TrimSuffix(strings.TrimSpace(b), ".")
+	return strings.EqualFold(a, b)
+}
+
 func parseAPIError(res *http.Response) error {
 	body, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
 	apiErr := &APIError{StatusCode: res.StatusCode}
@@ -144,3 +144,51 @@ func TestDeleteRecord(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestUpsertRecord(t *testing.T) {
+	t.Parallel()
+
+	var updated, created bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/client/domains/domain-123/records":
+			json.NewEncoder(w).Encode([]Record{
+				{ID: "1", DomainID: "domain-123", Name: "www", Type: "A", Value: "1.1.1.1"},
+			})
+		case r.Method == http.MethodPut && r.URL.Path == "/api/client/records/1":
+			updated = true
+			json.NewEncoder(w).Encode(Record{ID: "1", DomainID: "domain-123", Name: "www", Type: "A", Value: "2.2.2.2"})
+		case r.Method == http.MethodPost && r.URL.Path == "/api/client/records":
+			created = true
+			json.NewEncoder(w).Encode(Record{ID: "2", DomainID: "domain-123", Name: "api", Type: "A", Value: "3.3.3.3"})
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient("key", WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+
+	record, err := client.UpsertRecord(context.Background(), CreateRecordRequest{
+		DomainID: "domain-123", Name: "WWW.", Type: "a", Value: "2.2.2.2",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !updated || created || record.Value != "2.2.2.2" {
+		t.Fatalf("expected existing record to be updated, got %#v", record)
+	}
+
+	record, err = client.UpsertRecord(context.Background(), CreateRecordRequest{
+		DomainID: "domain-123", Name: "api", Type: "A", Value: "3.3.3.3",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !created || record.ID != "2" {
+		t.Fatalf("expected record to be created, got %#v", record)
+	}
+}
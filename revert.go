@@ -0,0 +1,413 @@
+package enzonix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrAuditNotReadable is returned by Revert when the client's audit
+	// sink cannot be read back, see AuditReader.
+	ErrAuditNotReadable = errors.New("enzonix: audit sink is not readable")
+	// ErrRevertConflict is returned when records changed again after the
+	// entries being reverted.
+	ErrRevertConflict = errors.New("enzonix: revert conflict")
+)
+
+// AuditReader is implemented by audit sinks that can return the entries
+// they recorded, oldest first. FileAuditSink implements it.
+type AuditReader interface {
+	AuditEntries(ctx context.Context) ([]AuditEntry, error)
+}
+
+// AuditEntries reads the journal, including rotated backups.
+func (s *FileAuditSink) AuditEntries(context.Context) ([]AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ReadAuditFile(s.path)
+}
+
+// RevertAction is the kind of operation a revert step performs.
+type RevertAction string
+
+const (
+	// RevertCreate re-creates a deleted record.
+	RevertCreate RevertAction = "create"
+	// RevertUpdate restores a record's previous state.
+	RevertUpdate RevertAction = "update"
+	// RevertDelete deletes a created record.
+	RevertDelete RevertAction = "delete"
+)
+
+// RevertStep is one inverse operation of a RevertPlan.
+type RevertStep struct {
+	// EntryIDs lists the audit entries the step undoes. Several entries
+	// touching the same record collapse into one step.
+	EntryIDs []string     `json:"entry_ids"`
+	Action   RevertAction `json:"action"`
+	// RecordID is the record to update or delete. It is empty for creates.
+	RecordID string `json:"record_id,omitempty"`
+	// Target is the state the record is restored to, or the record to be
+	// deleted.
+	Target Record `json:"target"`
+	// Current is the live record, nil when it does not exist.
+	Current *Record `json:"current,omitempty"`
+	// Conflict explains why the record no longer matches the journal, for
+	// example because it was changed again afterwards. Empty when the step
+	// is safe to apply.
+	Conflict string `json:"conflict,omitempty"`
+}
+
+// String describes the step for display.
+func (s RevertStep) String() string {
+	desc := fmt.Sprintf("%s %s %s %s (ttl %d)", s.Action, s.Target.Name, s.Target.Type, describeValue(s.Target), s.Target.TTL)
+	if s.RecordID != "" {
+		desc += " [" + s.RecordID + "]"
+	}
+	if s.Conflict != "" {
+		desc += ": CONFLICT: " + s.Conflict
+	}
+	return desc
+}
+
+// RevertPlan lists the operations that undo a set of audit entries, in the
+// order they are applied.
+type RevertPlan struct {
+	Steps []RevertStep `json:"steps"`
+}
+
+// Conflicts returns the steps whose records changed since the journal was
+// written.
+func (p *RevertPlan) Conflicts() []RevertStep {
+	var out []RevertStep
+	for _, s := range p.Steps {
+		if s.Conflict != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// RevertOptions controls how ApplyRevert treats conflicting steps. By
+// default a plan with conflicts is not applied at all.
+type RevertOptions struct {
+	// SkipConflicts applies the steps without conflicts and leaves the
+	// others alone.
+	SkipConflicts bool
+	// Force applies conflicting steps as well, overwriting later changes.
+	// Steps whose record no longer exists are still skipped, and so are
+	// creates of a record that already exists identically.
+	Force bool
+}
+
+// Revert undoes the given audit entries: deleted records are re-created,
+// updated records get their previous state back and created records are
+// deleted. Entries are read from the client's audit sink, which must
+// implement AuditReader. The plan is returned even when it is not applied
+// because of conflicts.
+func (c *Client) Revert(ctx context.Context, entryIDs []string) (*RevertPlan, error) {
+	plan, err := c.PlanRevert(ctx, entryIDs)
+	if err != nil {
+		return nil, err
+	}
+	return plan, c.ApplyRevert(ctx, plan, RevertOptions{})
+}
+
+// PlanRevert computes the inverse operations for the given audit entries
+// without changing anything. Only successful record operations and imports
+// can be reverted.
+func (c *Client) PlanRevert(ctx context.Context, entryIDs []string) (*RevertPlan, error) {
+	reader, ok := c.auditSink.(AuditReader)
+	if !ok {
+		return nil, ErrAuditNotReadable
+	}
+	journal, err := reader.AuditEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := selectAuditEntries(journal, entryIDs)
+	if err != nil {
+		return nil, err
+	}
+	return c.PlanRevertEntries(ctx, entries)
+}
+
+// PlanRevertEntries is like PlanRevert but takes the entries directly,
+// for journals read by other means.
+func (c *Client) PlanRevertEntries(ctx context.Context, entries []AuditEntry) (*RevertPlan, error) {
+	entries = append([]AuditEntry(nil), entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+
+	// Load the live records of every domain involved.
+	live := map[string]*Record{}
+	loaded := map[string]bool{}
+	for _, e := range entries {
+		if err := revertable(e); err != nil {
+			return nil, err
+		}
+		domainID := e.DomainID
+		if domainID == "" || loaded[domainID] {
+			continue
+		}
+		loaded[domainID] = true
+		records, err := c.ListDomainRecords(ctx, domainID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range records {
+			live[records[i].ID] = &records[i]
+		}
+	}
+
+	p := &revertPlanner{live: live, steps: map[string]*RevertStep{}}
+	// Undo the newest change first so that each entry is compared with
+	// the state its successors will be reverted to.
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		switch e.Operation {
+		case "CreateRecord":
+			p.undoCreate(e.ID, *e.After)
+		case "ImportBindZone":
+			for _, r := range e.Records {
+				p.undoCreate(e.ID, r)
+			}
+		case "UpdateRecord":
+			p.undoUpdate(e.ID, *e.Before, *e.After)
+		case "DeleteRecord":
+			p.undoDelete(e.ID, *e.Before)
+		}
+	}
+
+	plan := &RevertPlan{}
+	for _, id := range p.order {
+		if step := p.steps[id]; step != nil {
+			plan.Steps = append(plan.Steps, *step)
+		}
+	}
+	return plan, nil
+}
+
+// ApplyRevert executes a plan. The operations go through the client's
+// scope, policy and audit sink like any other mutation.
+func (c *Client) ApplyRevert(ctx context.Context, plan *RevertPlan, opts RevertOptions) error {
+	conflicts := plan.Conflicts()
+	if len(conflicts) > 0 && !opts.SkipConflicts && !opts.Force {
+		return fmt.Errorf("%w: %d of %d steps conflict with later changes", ErrRevertConflict, len(conflicts), len(plan.Steps))
+	}
+
+	var audit batchAudit
+	for _, step := range plan.Steps {
+		if step.Conflict != "" && (!opts.Force || (step.Action != RevertCreate && step.Current == nil)) {
+			continue
+		}
+		if step.Action == RevertCreate && step.Current != nil && sameRecordState(*step.Current, step.Target) {
+			// Forcing must not duplicate the record.
+			continue
+		}
+		var err error
+		switch step.Action {
+		case RevertCreate:
+			_, err = c.CreateRecord(ctx, createRequestFor(step.Target))
+		case RevertUpdate:
+			_, err = c.UpdateRecord(ctx, step.RecordID, updateRequestFor(step.Target))
+		case RevertDelete:
+			err = c.DeleteRecord(ctx, step.RecordID)
+		}
+		if !audit.absorb(err) {
+			return audit.join(fmt.Errorf("enzonix: revert %s: %w", step, err))
+		}
+	}
+	return audit.join(nil)
+}
+
+// revertPlanner accumulates one step per record. Records re-created by the
+// plan are keyed by their old ID so that older entries can amend them.
+type revertPlanner struct {
+	live  map[string]*Record
+	steps map[string]*RevertStep
+	order []string
+}
+
+func (p *revertPlanner) step(recordID string) *RevertStep {
+	if s, ok := p.steps[recordID]; ok {
+		return s
+	}
+	p.order = append(p.order, recordID)
+	return nil
+}
+
+// expected returns the state the record will have once the newer steps
+// ran, and whether the record will exist.
+func (p *revertPlanner) expected(recordID string) (*Record, bool) {
+	if s := p.steps[recordID]; s != nil {
+		if s.Action == RevertDelete {
+			return nil, false
+		}
+		return &s.Target, true
+	}
+	r, ok := p.live[recordID]
+	return r, ok
+}
+
+func (p *revertPlanner) undoCreate(entryID string, created Record) {
+	existing := p.step(created.ID)
+	current, exists := p.expected(created.ID)
+	step := &RevertStep{EntryIDs: []string{entryID}, Action: RevertDelete, RecordID: created.ID, Target: created, Current: p.live[created.ID]}
+	if existing != nil {
+		step.EntryIDs = append(existing.EntryIDs, entryID)
+		step.Conflict = existing.Conflict
+		if existing.Action == RevertCreate {
+			// Deleted later and now being un-created: nothing to do.
+			p.steps[created.ID] = nil
+			return
+		}
+	}
+	switch {
+	case !exists:
+		step.Conflict = "record no longer exists"
+	case !sameRecordState(*current, created) && step.Conflict == "":
+		step.Conflict = "record changed since it was created"
+	}
+	p.steps[created.ID] = step
+}
+
+func (p *revertPlanner) undoUpdate(entryID string, before, after Record) {
+	existing := p.step(after.ID)
+	current, exists := p.expected(after.ID)
+	if existing != nil && existing.Action == RevertCreate {
+		// Re-create the record as it was before this update.
+		existing.EntryIDs = append(existing.EntryIDs, entryID)
+		existing.Target = before
+		return
+	}
+	step := &RevertStep{EntryIDs: []string{entryID}, Action: RevertUpdate, RecordID: after.ID, Target: before, Current: p.live[after.ID]}
+	if existing != nil {
+		step.EntryIDs = append(existing.EntryIDs, entryID)
+		step.Conflict = existing.Conflict
+	}
+	switch {
+	case !exists:
+		step.Conflict = "record no longer exists"
+	case !sameRecordState(*current, after) && step.Conflict == "":
+		step.Conflict = "record changed since it was updated"
+	}
+	p.steps[after.ID] = step
+}
+
+func (p *revertPlanner) undoDelete(entryID string, deleted Record) {
+	existing := p.step(deleted.ID)
+	step := &RevertStep{EntryIDs: []string{entryID}, Action: RevertCreate, Target: deleted}
+	if existing != nil {
+		step.EntryIDs = append(existing.EntryIDs, entryID)
+	}
+	for _, r := range p.live {
+		if r.DomainID == deleted.DomainID && sameRecordState(*r, deleted) {
+			step.Current = r
+			step.Conflict = "an identical record already exists"
+			break
+		}
+	}
+	p.steps[deleted.ID] = step
+}
+
+func revertable(e AuditEntry) error {
+	if e.Outcome != AuditSuccess {
+		return fmt.Errorf("enzonix: audit entry %s did not succeed, nothing to revert", e.ID)
+	}
+	var ok bool
+	switch e.Operation {
+	case "CreateRecord":
+		ok = e.After != nil
+	case "UpdateRecord":
+		ok = e.Before != nil && e.After != nil
+	case "DeleteRecord":
+		ok = e.Before != nil
+	case "ImportBindZone":
+		ok = true
+	default:
+		return fmt.Errorf("enzonix: audit entry %s: %s cannot be reverted", e.ID, e.Operation)
+	}
+	if !ok {
+		return fmt.Errorf("enzonix: audit entry %s lacks the record state needed to revert it", e.ID)
+	}
+	return nil
+}
+
+func selectAuditEntries(journal []AuditEntry, ids []string) ([]AuditEntry, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("enzonix: no audit entries to revert")
+	}
+	byID := make(map[string]AuditEntry, len(journal))
+	for _, e := range journal {
+		byID[e.ID] = e
+	}
+	out := make([]AuditEntry, 0, len(ids))
+	seen := map[string]bool{}
+	for _, id := range ids {
+		e, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("enzonix: audit entry %s not found", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// sameRecordState compares the user-controlled fields of two records.
+func sameRecordState(a, b Record) bool {
+	if !sameRecordName(a.Name, b.Name) || !strings.EqualFold(a.Type, b.Type) ||
+		a.Value != b.Value || a.TTL != b.TTL || a.Priority != b.Priority ||
+		len(a.CountryCodes) != len(b.CountryCodes) {
+		return false
+	}
+	ac := append([]string(nil), a.CountryCodes...)
+	bc := append([]string(nil), b.CountryCodes...)
+	sort.Strings(ac)
+	sort.Strings(bc)
+	for i := range ac {
+		if !strings.EqualFold(ac[i], bc[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func createRequestFor(r Record) CreateRecordRequest {
+	ttl, priority := r.TTL, r.Priority
+	return CreateRecordRequest{
+		DomainID:     r.DomainID,
+		Name:         r.Name,
+		Type:         r.Type,
+		Value:        r.Value,
+		TTL:          &ttl,
+		Priority:     &priority,
+		CountryCodes: r.CountryCodes,
+	}
+}
+
+func updateRequestFor(r Record) UpdateRecordRequest {
+	// Country codes are omitted when empty, so a record that had none
+	// keeps any codes added since; the API offers no way to clear them.
+	name, typ, value, ttl, priority := r.Name, r.Type, r.Value, r.TTL, r.Priority
+	return UpdateRecordRequest{
+		Name:         &name,
+		Type:         &typ,
+		Value:        &value,
+		TTL:          &ttl,
+		Priority:     &priority,
+		CountryCodes: r.CountryCodes,
+	}
+}
+
+func describeValue(r Record) string {
+	if r.Priority != 0 {
+		return fmt.Sprintf("%d %s", r.Priority, r.Value)
+	}
+	return r.Value
+}
@@ -0,0 +1,269 @@
+package enzonix
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+func newAuditedClient(t *testing.T) (*fakeapi.Server, *Client, *FileAuditSink) {
+	t.Helper()
+	sink, err := NewFileAuditSink(filepath.Join(t.TempDir(), "journal.jsonl"), FileAuditOptions{})
+	if err != nil {
+		t.Fatalf("open sink: %v", err)
+	}
+	t.Cleanup(func() { sink.Close() })
+	fake, client := newFakeClient(t, WithAuditSink(sink))
+	return fake, client, sink
+}
+
+func entryIDs(t *testing.T, sink *FileAuditSink) []string {
+	t.Helper()
+	entries, err := sink.AuditEntries(context.Background())
+	if err != nil {
+		t.Fatalf("read journal: %v", err)
+	}
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestRevertRestoresRecords(t *testing.T) {
+	t.Parallel()
+
+	fake, client, sink := newAuditedClient(t)
+	domain := fake.AddDomain("example.com")
+	www := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1", CountryCodes: []string{"DE"}})
+	mail := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "@", Type: "MX", TTL: 3600, Priority: 10, Value: "mx.example.com."})
+	ctx := context.Background()
+
+	// A bad script run.
+	if _, err := client.CreateRecord(ctx, CreateRecordRequest{DomainID: domain.ID, Name: "tmp", Type: "TXT", Value: "oops"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	value, ttl := "203.0.113.9", 60
+	if _, err := client.UpdateRecord(ctx, www.ID, UpdateRecordRequest{Value: &value, TTL: &ttl, CountryCodes: []string{"US"}}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := client.DeleteRecord(ctx, mail.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	plan, err := client.Revert(ctx, entryIDs(t, sink))
+	if err != nil {
+		t.Fatalf("revert: %v", err)
+	}
+	if len(plan.Steps) != 3 || len(plan.Conflicts()) != 0 {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+
+	records := fake.Records(domain.ID)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records after revert, got %+v", records)
+	}
+	for _, r := range records {
+		switch r.Type {
+		case "A":
+			if r.ID != www.ID || r.Value != "192.0.2.1" || r.TTL != 300 || len(r.CountryCodes) != 1 || r.CountryCodes[0] != "DE" {
+				t.Fatalf("www not restored: %+v", r)
+			}
+		case "MX":
+			if r.Name != "@" || r.Priority != 10 || r.Value != "mx.example.com." || r.TTL != 3600 {
+				t.Fatalf("mx not re-created: %+v", r)
+			}
+		default:
+			t.Fatalf("unexpected record %+v", r)
+		}
+	}
+}
+
+// fullJournal is a readable journal that can start refusing writes.
+type fullJournal struct {
+	*FileAuditSink
+	full bool
+}
+
+func (j *fullJournal) WriteAuditEntry(ctx context.Context, entry AuditEntry) error {
+	if j.full {
+		return errors.New("disk full")
+	}
+	return j.FileAuditSink.WriteAuditEntry(ctx, entry)
+}
+
+func TestRevertContinuesPastAuditErrors(t *testing.T) {
+	t.Parallel()
+
+	file, err := NewFileAuditSink(filepath.Join(t.TempDir(), "journal.jsonl"), FileAuditOptions{})
+	if err != nil {
+		t.Fatalf("open sink: %v", err)
+	}
+	t.Cleanup(func() { file.Close() })
+	journal := &fullJournal{FileAuditSink: file}
+	fake, client := newFakeClient(t, WithAuditSink(journal))
+	domain := fake.AddDomain("example.com")
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b"} {
+		if _, err := client.CreateRecord(ctx, CreateRecordRequest{DomainID: domain.ID, Name: name, Type: "A", Value: "192.0.2.1"}); err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+	}
+	ids := entryIDs(t, file)
+
+	journal.full = true
+	_, err = client.Revert(ctx, ids)
+	if !IsAuditOnly(err) {
+		t.Fatalf("expected audit-only error, got %v", err)
+	}
+	if records := fake.Records(domain.ID); len(records) != 0 {
+		t.Fatalf("revert stopped after the first unaudited step: %+v", records)
+	}
+}
+
+func TestRevertDetectsConflicts(t *testing.T) {
+	t.Parallel()
+
+	fake, client, sink := newAuditedClient(t)
+	domain := fake.AddDomain("example.com")
+	www := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	ctx := context.Background()
+
+	first := "192.0.2.2"
+	if _, err := client.UpdateRecord(ctx, www.ID, UpdateRecordRequest{Value: &first}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	ids := entryIDs(t, sink)
+
+	// Someone else changes the record again, outside the journal.
+	other, err := NewClient("key", WithBaseURL(client.baseURL.String()))
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	second := "192.0.2.3"
+	if _, err := other.UpdateRecord(ctx, www.ID, UpdateRecordRequest{Value: &second}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	plan, err := client.Revert(ctx, ids)
+	if !errors.Is(err, ErrRevertConflict) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+	conflicts := plan.Conflicts()
+	if len(conflicts) != 1 || conflicts[0].Current == nil || conflicts[0].Current.Value != second {
+		t.Fatalf("unexpected conflicts %+v", conflicts)
+	}
+	if got := fake.Records(domain.ID)[0].Value; got != second {
+		t.Fatalf("conflicting plan must not be applied, value is %s", got)
+	}
+
+	if err := client.ApplyRevert(ctx, plan, RevertOptions{SkipConflicts: true}); err != nil {
+		t.Fatalf("apply with skip: %v", err)
+	}
+	if got := fake.Records(domain.ID)[0].Value; got != second {
+		t.Fatalf("skipped step was applied, value is %s", got)
+	}
+	if err := client.ApplyRevert(ctx, plan, RevertOptions{Force: true}); err != nil {
+		t.Fatalf("apply with force: %v", err)
+	}
+	if got := fake.Records(domain.ID)[0].Value; got != "192.0.2.1" {
+		t.Fatalf("forced revert not applied, value is %s", got)
+	}
+}
+
+func TestForcedRevertDoesNotDuplicate(t *testing.T) {
+	t.Parallel()
+
+	fake, client, sink := newAuditedClient(t)
+	domain := fake.AddDomain("example.com")
+	www := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	ctx := context.Background()
+
+	if err := client.DeleteRecord(ctx, www.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	ids := entryIDs(t, sink)
+	// Someone re-creates the record outside the journal.
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+
+	plan, err := client.PlanRevert(ctx, ids)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if conflicts := plan.Conflicts(); len(conflicts) != 1 || conflicts[0].Action != RevertCreate {
+		t.Fatalf("unexpected conflicts %+v", conflicts)
+	}
+	if err := client.ApplyRevert(ctx, plan, RevertOptions{Force: true}); err != nil {
+		t.Fatalf("apply with force: %v", err)
+	}
+	if n := len(fake.Records(domain.ID)); n != 1 {
+		t.Fatalf("forced revert duplicated the record: %d records", n)
+	}
+}
+
+func TestPlanRevertCollapsesChains(t *testing.T) {
+	t.Parallel()
+
+	fake, client, sink := newAuditedClient(t)
+	domain := fake.AddDomain("example.com")
+	ctx := context.Background()
+
+	created, err := client.CreateRecord(ctx, CreateRecordRequest{DomainID: domain.ID, Name: "api", Type: "A", Value: "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	value := "192.0.2.2"
+	if _, err := client.UpdateRecord(ctx, created.ID, UpdateRecordRequest{Value: &value}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	ids := entryIDs(t, sink)
+
+	plan, err := client.PlanRevert(ctx, ids)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if len(plan.Steps) != 1 || plan.Steps[0].Action != RevertDelete || plan.Steps[0].Conflict != "" || len(plan.Steps[0].EntryIDs) != 2 {
+		t.Fatalf("unexpected plan %+v", plan.Steps)
+	}
+
+	// Reverting only the create while the update stands is a conflict.
+	plan, err = client.PlanRevert(ctx, ids[:1])
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	if len(plan.Conflicts()) != 1 {
+		t.Fatalf("expected a conflict, got %+v", plan.Steps)
+	}
+}
+
+func TestRevertRejectsUnsupportedEntries(t *testing.T) {
+	t.Parallel()
+
+	fake, client, sink := newAuditedClient(t)
+	domain := fake.AddDomain("example.com")
+	ctx := context.Background()
+
+	if err := client.DeleteRecord(ctx, "missing"); err == nil {
+		t.Fatal("expected delete of missing record to fail")
+	}
+	if err := client.DeleteDomain(ctx, domain.ID); err != nil {
+		t.Fatalf("delete domain: %v", err)
+	}
+	for _, id := range entryIDs(t, sink) {
+		if _, err := client.PlanRevert(ctx, []string{id}); err == nil {
+			t.Fatalf("expected entry %s to be rejected", id)
+		}
+	}
+	if _, err := client.PlanRevert(ctx, []string{"unknown"}); err == nil {
+		t.Fatal("expected unknown entry to be rejected")
+	}
+
+	_, plain := newFakeClient(t, WithAuditSink(AuditSinkFunc(func(context.Context, AuditEntry) error { return nil })))
+	if _, err := plain.Revert(ctx, []string{"x"}); !errors.Is(err, ErrAuditNotReadable) {
+		t.Fatalf("expected ErrAuditNotReadable, got %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package enzonix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrLastRRSetValue is returned by DrainRRSetValue for the last value of a
+// set; use ReplaceRRSet with no values to remove a set.
+var ErrLastRRSetValue = errors.New("enzonix: refusing to drain the last value of an RRset")
+
+// RRSet is the group of records sharing a name and type. Records with
+// country codes belong to geo routing (see GeoRecordSet) and are not
+// members.
+type RRSet struct {
+	DomainID string
+	Name     string
+	Type     string
+	// TTL is the TTL of the first member; see Consistent.
+	TTL     int
+	Records []Record
+}
+
+// Values returns the members' values. MX and SRV values are prefixed with
+// their priority, as accepted by ReplaceRRSet.
+func (s *RRSet) Values() []string {
+	out := make([]string, 0, len(s.Records))
+	for _, r := range s.Records {
+		out = append(out, rrsetValue(r))
+	}
+	return out
+}
+
+// Consistent reports whether all members share the set's TTL.
+func (s *RRSet) Consistent() bool {
+	for _, r := range s.Records {
+		if r.TTL != s.TTL {
+			return false
+		}
+	}
+	return true
+}
+
+// RRSetResult reports the changes made to an RRset.
+type RRSetResult struct {
+	Created []Record
+	Updated []Record
+	Deleted []Record
+}
+
+// Changed reports whether any record was changed.
+func (r *RRSetResult) Changed() bool {
+	return len(r.Created)+len(r.Updated)+len(r.Deleted) > 0
+}
+
+// GetRRSet returns the records of domainID with the given name and type.
+// A set without members is returned empty, not as an error.
+func (c *Client) GetRRSet(ctx context.Context, domainID, name, typ string) (*RRSet, error) {
+	if err := requireID(domainID, "domain id"); err != nil {
+		return nil, err
+	}
+	records, err := c.ListDomainRecords(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+	set := &RRSet{DomainID: domainID, Name: name, Type: strings.ToUpper(typ)}
+	for _, r := range records {
+		if len(r.CountryCodes) == 0 && sameRecordName(r.Name, name) && strings.EqualFold(r.Type, typ) {
+			set.Records = append(set.Records, r)
+		}
+	}
+	if len(set.Records) > 0 {
+		set.TTL = set.Records[0].TTL
+	}
+	return set, nil
+}
+
+// ReplaceRRSet makes values the members of the set, with the minimal
+// changes: members whose value is kept stay (their TTL is aligned), other
+// members are updated to new values, and only the remainder is created or
+// deleted. Updates and creations run before deletions so the name never
+// resolves to nothing. MX and SRV values carry their priority first, e.g.
+// "10 mx.example.com.". A ttl of zero keeps the set's current TTL.
+//
+// No values deletes the set.
+func (c *Client) ReplaceRRSet(ctx context.Context, domainID, name, typ string, values []string, ttl int) (*RRSetResult, error) {
+	if strings.TrimSpace(name) == "" || strings.TrimSpace(typ) == "" {
+		return nil, errors.New("enzonix: RRset name and type must not be empty")
+	}
+	set, err := c.GetRRSet(ctx, domainID, name, typ)
+	if err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		ttl = set.TTL
+	}
+
+	var want []Record
+	seen := map[string]bool{}
+	for _, v := range values {
+		r, err := parseRRSetValue(set.Type, v)
+		if err != nil {
+			return nil, err
+		}
+		key := rrsetKey(r)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		r.DomainID, r.Name, r.Type, r.TTL = domainID, name, set.Type, ttl
+		want = append(want, r)
+	}
+
+	updates, creates, deletes := planRRSet(set.Records, want)
+	return c.applyRRSet(ctx, updates, creates, deletes)
+}
+
+// DrainRRSetValue removes one value from the set, leaving the other
+// members untouched. Draining a value that is not a member changes
+// nothing.
+func (c *Client) DrainRRSetValue(ctx context.Context, domainID, name, typ, value string) (*RRSetResult, error) {
+	set, err := c.GetRRSet(ctx, domainID, name, typ)
+	if err != nil {
+		return nil, err
+	}
+	drained, err := parseRRSetValue(set.Type, value)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		keep    []Record
+		deletes []Record
+	)
+	for _, r := range set.Records {
+		if rrsetKey(r) == rrsetKey(drained) {
+			deletes = append(deletes, r)
+		} else {
+			keep = append(keep, r)
+		}
+	}
+	if len(deletes) > 0 && len(keep) == 0 {
+		return nil, ErrLastRRSetValue
+	}
+	return c.applyRRSet(ctx, nil, nil, deletes)
+}
+
+func (c *Client) applyRRSet(ctx context.Context, updates, creates, deletes []Record) (*RRSetResult, error) {
+	res := &RRSetResult{}
+	var audit batchAudit
+	for _, r := range updates {
+		updated, err := c.UpdateRecord(ctx, r.ID, UpdateRecordRequest{Value: &r.Value, TTL: &r.TTL, Priority: &r.Priority})
+		if !audit.absorb(err) {
+			return res, audit.join(fmt.Errorf("enzonix: RRset update %s %s: %w", r.Name, describeValue(r), err))
+		}
+		res.Updated = append(res.Updated, *updated)
+	}
+	for _, r := range creates {
+		req := createRequestFor(r)
+		if r.TTL <= 0 {
+			req.TTL = nil
+		}
+		created, err := c.CreateRecord(ctx, req)
+		if !audit.absorb(err) {
+			return res, audit.join(fmt.Errorf("enzonix: RRset create %s %s: %w", r.Name, describeValue(r), err))
+		}
+		res.Created = append(res.Created, *created)
+	}
+	for _, r := range deletes {
+		if err := c.DeleteRecord(ctx, r.ID); !audit.absorb(err) {
+			return res, audit.join(fmt.Errorf("enzonix: RRset delete %s %s: %w", r.Name, describeValue(r), err))
+		}
+		res.Deleted = append(res.Deleted, r)
+	}
+	return res, audit.join(nil)
+}
+
+// planRRSet pairs live members with wanted values. Members keeping their
+// value only change when their TTL differs; the other members are reused
+// for new values before anything is created or deleted.
+func planRRSet(live, want []Record) (updates, creates, deletes []Record) {
+	used := make([]bool, len(live))
+	var pending []Record
+	for _, w := range want {
+		matched := false
+		for i, l := range live {
+			if used[i] || rrsetKey(l) != rrsetKey(w) {
+				continue
+			}
+			used[i], matched = true, true
+			if w.TTL > 0 && l.TTL != w.TTL {
+				l.TTL = w.TTL
+				updates = append(updates, l)
+			}
+			break
+		}
+		if !matched {
+			pending = append(pending, w)
+		}
+	}
+	for _, w := range pending {
+		reused := false
+		for i, l := range live {
+			if !used[i] {
+				used[i], reused = true, true
+				w.ID = l.ID
+				if w.TTL <= 0 {
+					w.TTL = l.TTL
+				}
+				updates = append(updates, w)
+				break
+			}
+		}
+		if !reused {
+			creates = append(creates, w)
+		}
+	}
+	for i, l := range live {
+		if !used[i] {
+			deletes = append(deletes, l)
+		}
+	}
+	return updates, creates, deletes
+}
+
+// parseRRSetValue splits the priority off MX and SRV values. The record
+// carries typ, which rrsetKey depends on.
+func parseRRSetValue(typ, value string) (Record, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Record{}, errors.New("enzonix: RRset value must not be empty")
+	}
+	switch strings.ToUpper(typ) {
+	case "MX", "SRV":
+		prio, rest, ok := strings.Cut(value, " ")
+		n, err := strconv.Atoi(prio)
+		if !ok || err != nil || n < 0 {
+			return Record{}, fmt.Errorf("enzonix: %s value %q must start with a priority", typ, value)
+		}
+		return Record{Type: typ, Priority: n, Value: strings.TrimSpace(rest)}, nil
+	}
+	return Record{Type: typ, Value: value}, nil
+}
+
+func rrsetValue(r Record) string {
+	switch strings.ToUpper(r.Type) {
+	case "MX", "SRV":
+		return fmt.Sprintf("%d %s", r.Priority, r.Value)
+	}
+	return r.Value
+}
+
+// rrsetKey identifies a member's data. Host names compare
+// case-insensitively and without a trailing dot; TXT data is exact.
+func rrsetKey(r Record) string {
+	value := r.Value
+	if !strings.EqualFold(r.Type, "TXT") {
+		value = strings.ToLower(strings.TrimSuffix(value, "."))
+	}
+	return strconv.Itoa(r.Priority) + " " + value
+}
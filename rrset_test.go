@@ -0,0 +1,154 @@
+package enzonix
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+func TestReplaceRRSet(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeClient(t)
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 600, Value: "192.0.2.2"})
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.3"})
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "198.51.100.1", CountryCodes: []string{"DE"}})
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "AAAA", TTL: 300, Value: "2001:db8::1"})
+	ctx := context.Background()
+
+	set, err := client.GetRRSet(ctx, domain.ID, "WWW.", "a")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if len(set.Records) != 3 || set.Consistent() {
+		t.Fatalf("unexpected set %+v", set)
+	}
+
+	// 192.0.2.1 stays, 192.0.2.2 gets its TTL aligned and 192.0.2.3 is
+	// reused for the new value.
+	res, err := client.ReplaceRRSet(ctx, domain.ID, "www", "A", []string{"192.0.2.1", "192.0.2.2", "192.0.2.4", "192.0.2.1"}, 300)
+	if err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	if len(res.Created) != 0 || len(res.Updated) != 2 || len(res.Deleted) != 0 {
+		t.Fatalf("expected two updates, got %+v", res)
+	}
+	set, _ = client.GetRRSet(ctx, domain.ID, "www", "A")
+	values := set.Values()
+	sort.Strings(values)
+	if !set.Consistent() || set.TTL != 300 || len(values) != 3 || values[2] != "192.0.2.4" {
+		t.Fatalf("unexpected set after replace: %+v", set)
+	}
+
+	res, err = client.ReplaceRRSet(ctx, domain.ID, "www", "A", []string{"192.0.2.1", "192.0.2.2", "192.0.2.4"}, 0)
+	if err != nil || res.Changed() {
+		t.Fatalf("expected no changes, got %+v, %v", res, err)
+	}
+
+	res, err = client.ReplaceRRSet(ctx, domain.ID, "www", "A", []string{"192.0.2.9"}, 0)
+	if err != nil || len(res.Updated) != 1 || len(res.Deleted) != 2 {
+		t.Fatalf("expected one update and two deletions, got %+v, %v", res, err)
+	}
+
+	// The geo variant and the AAAA set are not members.
+	var geo, aaaa int
+	for _, r := range fake.Records(domain.ID) {
+		switch {
+		case len(r.CountryCodes) > 0:
+			geo++
+		case r.Type == "AAAA":
+			aaaa++
+		}
+	}
+	if geo != 1 || aaaa != 1 {
+		t.Fatalf("non-members changed: %+v", fake.Records(domain.ID))
+	}
+}
+
+func TestReplaceRRSetPriorities(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeClient(t)
+	domain := fake.AddDomain("example.com")
+	ctx := context.Background()
+
+	res, err := client.ReplaceRRSet(ctx, domain.ID, "@", "MX", []string{"10 mx1.example.com.", "20 mx2.example.com."}, 3600)
+	if err != nil || len(res.Created) != 2 {
+		t.Fatalf("expected two creations, got %+v, %v", res, err)
+	}
+	res, err = client.ReplaceRRSet(ctx, domain.ID, "@", "MX", []string{"10 MX1.example.com", "30 mx2.example.com."}, 0)
+	if err != nil || len(res.Updated) != 1 || res.Updated[0].Priority != 30 {
+		t.Fatalf("expected a priority update, got %+v, %v", res, err)
+	}
+	if _, err := client.ReplaceRRSet(ctx, domain.ID, "@", "MX", []string{"mx3.example.com."}, 0); err == nil {
+		t.Fatal("expected an error for an MX value without priority")
+	}
+}
+
+func TestDrainRRSetValue(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeClient(t)
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "api", Type: "A", Value: "192.0.2.1"})
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "api", Type: "A", Value: "192.0.2.2"})
+	ctx := context.Background()
+
+	res, err := client.DrainRRSetValue(ctx, domain.ID, "api", "A", "192.0.2.1")
+	if err != nil || len(res.Deleted) != 1 || res.Deleted[0].Value != "192.0.2.1" {
+		t.Fatalf("expected one deletion, got %+v, %v", res, err)
+	}
+	res, err = client.DrainRRSetValue(ctx, domain.ID, "api", "A", "192.0.2.7")
+	if err != nil || res.Changed() {
+		t.Fatalf("expected no changes for a non-member, got %+v, %v", res, err)
+	}
+	if _, err := client.DrainRRSetValue(ctx, domain.ID, "api", "A", "192.0.2.2"); !errors.Is(err, ErrLastRRSetValue) {
+		t.Fatalf("expected ErrLastRRSetValue, got %v", err)
+	}
+	if len(fake.Records(domain.ID)) != 1 {
+		t.Fatalf("unexpected records %+v", fake.Records(domain.ID))
+	}
+}
+
+func TestRRSetTXTCase(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeClient(t)
+	domain := fake.AddDomain("example.com")
+	ctx := context.Background()
+
+	// TXT data is case-sensitive, so both values are members.
+	res, err := client.ReplaceRRSet(ctx, domain.ID, "@", "TXT", []string{"Hello", "hello"}, 300)
+	if err != nil || len(res.Created) != 2 {
+		t.Fatalf("expected two creations, got %+v, %v", res, err)
+	}
+	res, err = client.DrainRRSetValue(ctx, domain.ID, "@", "TXT", "Hello")
+	if err != nil || len(res.Deleted) != 1 || res.Deleted[0].Value != "Hello" {
+		t.Fatalf("expected the exact value to be drained, got %+v, %v", res, err)
+	}
+	if records := fake.Records(domain.ID); len(records) != 1 || records[0].Value != "hello" {
+		t.Fatalf("unexpected records %+v", records)
+	}
+}
+
+func TestReplaceRRSetAuditErrors(t *testing.T) {
+	t.Parallel()
+
+	failing := AuditSinkFunc(func(context.Context, AuditEntry) error { return errors.New("disk full") })
+	fake, client := newFakeClient(t, WithAuditSink(failing))
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "api", Type: "A", TTL: 300, Value: "192.0.2.1"})
+
+	res, err := client.ReplaceRRSet(context.Background(), domain.ID, "api", "A", []string{"192.0.2.3", "192.0.2.4"}, 300)
+	if !IsAuditOnly(err) || len(res.Updated) != 1 || len(res.Created) != 1 {
+		t.Fatalf("expected an update and a create, got %+v, %v", res, err)
+	}
+	if records := fake.Records(domain.ID); len(records) != 2 {
+		t.Fatalf("expected 2 records, got %+v", records)
+	}
+}
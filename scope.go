@@ -0,0 +1,338 @@
+package enzonix
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/zonefile"
+)
+
+// ErrScopeViolation is matched by errors.Is for every ScopeViolationError.
+var ErrScopeViolation = errors.New("enzonix: scope violation")
+
+// ScopeViolationError reports an operation rejected by a scoped client
+// before any request was sent.
+type ScopeViolationError struct {
+	Operation string
+	Reason    string
+}
+
+// Error satisfies the error interface.
+func (e *ScopeViolationError) Error() string {
+	return fmt.Sprintf("enzonix: scope violation: %s: %s", e.Operation, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrScopeViolation) report true.
+func (e *ScopeViolationError) Is(target error) bool {
+	return target == ErrScopeViolation
+}
+
+// ScopeOptions restricts what a scoped client may access. Empty fields do
+// not restrict.
+type ScopeOptions struct {
+	// DomainIDs and DomainNames together form the domain allow-list. A
+	// domain is allowed when either its ID or its name is listed.
+	DomainIDs   []string
+	DomainNames []string
+	// RecordTypes limits records to the listed types, e.g. "TXT".
+	RecordTypes []string
+	// RecordNames limits records to names matching any of the path.Match
+	// patterns, e.g. "_acme-challenge*".
+	RecordNames []string
+	// ReadOnly rejects every mutating call.
+	ReadOnly bool
+}
+
+type scope struct {
+	domainIDs   map[string]bool
+	domainNames map[string]bool
+	recordTypes map[string]bool
+	recordNames []string
+	readOnly    bool
+}
+
+// Scoped returns a copy of the client restricted by opts. Reads are
+// filtered to the allowed domains and records; writes outside the scope,
+// and RotateAPIKey, fail with a ScopeViolationError. Scoping a scoped client
+// narrows it further. The copy shares credentials and transport with c.
+func (c *Client) Scoped(opts ScopeOptions) (*Client, error) {
+	s := &scope{readOnly: opts.ReadOnly}
+
+	if len(opts.DomainIDs)+len(opts.DomainNames) > 0 {
+		s.domainIDs = map[string]bool{}
+		s.domainNames = map[string]bool{}
+		for _, id := range opts.DomainIDs {
+			if strings.TrimSpace(id) == "" {
+				return nil, errors.New("enzonix: scope domain id must not be empty")
+			}
+			s.domainIDs[id] = true
+		}
+		for _, name := range opts.DomainNames {
+			if strings.TrimSpace(name) == "" {
+				return nil, errors.New("enzonix: scope domain name must not be empty")
+			}
+			s.domainNames[normalizeDomainName(name)] = true
+		}
+	}
+	if len(opts.RecordTypes) > 0 {
+		s.recordTypes = map[string]bool{}
+		for _, typ := range opts.RecordTypes {
+			s.recordTypes[strings.ToUpper(strings.TrimSpace(typ))] = true
+		}
+	}
+	for _, pattern := range opts.RecordNames {
+		pattern = normalizeRecordName(pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("enzonix: invalid record name pattern %q: %w", pattern, err)
+		}
+		s.recordNames = append(s.recordNames, pattern)
+	}
+
+	scoped := *c
+	scoped.scopes = append(append([]*scope(nil), c.scopes...), s)
+	return &scoped, nil
+}
+
+func (s *scope) restrictsDomains() bool { return s.domainIDs != nil }
+
+func (s *scope) restrictsRecords() bool { return s.recordTypes != nil || s.recordNames != nil }
+
+func (s *scope) allowsDomain(d Domain) bool {
+	if !s.restrictsDomains() {
+		return true
+	}
+	return s.domainIDs[d.ID] || s.domainNames[normalizeDomainName(d.Name)]
+}
+
+func (s *scope) allowsRecord(name, typ string) bool {
+	if s.recordTypes != nil && !s.recordTypes[strings.ToUpper(typ)] {
+		return false
+	}
+	if s.recordNames == nil {
+		return true
+	}
+	name = normalizeRecordName(name)
+	for _, pattern := range s.recordNames {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func scopeViolation(op, format string, args ...any) error {
+	return &ScopeViolationError{Operation: op, Reason: fmt.Sprintf(format, args...)}
+}
+
+// scopeWrite rejects mutating operations on read-only scoped clients.
+func (c *Client) scopeWrite(op string) error {
+	for _, s := range c.scopes {
+		if s.readOnly {
+			return scopeViolation(op, "client is read-only")
+		}
+	}
+	return nil
+}
+
+// scopeDomain rejects domains outside the allow-list. Domain names are
+// resolved through the API only when a scope lists names.
+func (c *Client) scopeDomain(ctx context.Context, op, domainID string) error {
+	var domain *Domain
+	for _, s := range c.scopes {
+		if !s.restrictsDomains() || s.domainIDs[domainID] {
+			continue
+		}
+		if len(s.domainNames) > 0 && domain == nil {
+			found, err := c.lookupDomain(ctx, domainID)
+			if err != nil {
+				return err
+			}
+			domain = found
+		}
+		if domain == nil || !s.allowsDomain(*domain) {
+			return scopeViolation(op, "domain %s is not allowed", domainID)
+		}
+	}
+	return nil
+}
+
+func (c *Client) scopeDomainName(op, name string) error {
+	for _, s := range c.scopes {
+		if s.restrictsDomains() && !s.domainNames[normalizeDomainName(name)] {
+			return scopeViolation(op, "domain %s is not allowed", name)
+		}
+	}
+	return nil
+}
+
+func (c *Client) scopeRecord(op, name, typ string) error {
+	for _, s := range c.scopes {
+		if !s.allowsRecord(name, typ) {
+			return scopeViolation(op, "record %s %s is not allowed", name, strings.ToUpper(typ))
+		}
+	}
+	return nil
+}
+
+// scopeExistingRecord checks a record referenced only by ID and, for
+// updates, the name and type it will have afterwards. The record is looked
+// up only when the scope restricts domains, types or names.
+func (c *Client) scopeExistingRecord(ctx context.Context, op string, existing *recordRef, update *UpdateRecordRequest) error {
+	needsLookup := false
+	for _, s := range c.scopes {
+		if s.restrictsDomains() || s.restrictsRecords() {
+			needsLookup = true
+		}
+	}
+	if !needsLookup {
+		return nil
+	}
+
+	record, err := existing.get(ctx, c)
+	if err != nil {
+		if IsNotFound(err) {
+			return scopeViolation(op, "record %s is not within an allowed domain", existing.id)
+		}
+		return err
+	}
+	if err := c.scopeDomain(ctx, op, record.DomainID); err != nil {
+		return err
+	}
+	if err := c.scopeRecord(op, record.Name, record.Type); err != nil {
+		return err
+	}
+	if update == nil {
+		return nil
+	}
+
+	name, typ := record.Name, record.Type
+	if update.Name != nil {
+		name = *update.Name
+	}
+	if update.Type != nil {
+		typ = *update.Type
+	}
+	return c.scopeRecord(op, name, typ)
+}
+
+func (c *Client) filterDomains(domains []Domain) []Domain {
+	if len(c.scopes) == 0 {
+		return domains
+	}
+	out := domains[:0]
+	for _, d := range domains {
+		allowed := true
+		for _, s := range c.scopes {
+			allowed = allowed && s.allowsDomain(d)
+		}
+		if allowed {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func (c *Client) filterRecords(records []Record) []Record {
+	if len(c.scopes) == 0 {
+		return records
+	}
+	out := records[:0]
+	for _, r := range records {
+		allowed := true
+		for _, s := range c.scopes {
+			allowed = allowed && s.allowsRecord(r.Name, r.Type)
+		}
+		if allowed {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// scopeImport checks a BIND import. Every domain the zone names must be
+// allowed: each $ORIGIN directive, and the origins of any absolute owner
+// names.
+func (c *Client) scopeImport(ctx context.Context, op string, zoneData []byte) error {
+	if err := c.scopeWrite(op); err != nil {
+		return err
+	}
+	var origins []string
+	for _, s := range c.scopes {
+		if s.restrictsRecords() {
+			return scopeViolation(op, "zone imports cannot be limited to record types or names")
+		}
+		if !s.restrictsDomains() {
+			continue
+		}
+		if origins == nil {
+			var err error
+			if origins, err = zoneOrigins(zoneData); err != nil {
+				return scopeViolation(op, "%v", err)
+			}
+		}
+		for _, origin := range origins {
+			if s.domainNames[normalizeDomainName(origin)] {
+				continue
+			}
+			domains, err := c.listDomains(ctx)
+			if err != nil {
+				return err
+			}
+			allowed := false
+			for _, d := range domains {
+				if normalizeDomainName(d.Name) == normalizeDomainName(origin) && s.allowsDomain(d) {
+					allowed = true
+				}
+			}
+			if !allowed {
+				return scopeViolation(op, "domain %s is not allowed", origin)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Client) scopeKeyRotation(op string) error {
+	if len(c.scopes) > 0 {
+		return scopeViolation(op, "scoped clients cannot rotate the api key")
+	}
+	return nil
+}
+
+// zoneOrigins returns every origin a zone file sets. Owner names written
+// absolutely must fall under one of them.
+func zoneOrigins(zoneData []byte) ([]string, error) {
+	zone, err := zonefile.Parse(bytes.NewReader(zoneData), "")
+	if err != nil {
+		return nil, err
+	}
+	if len(zone.Origins) == 0 {
+		return nil, fmt.Errorf("zone has no $ORIGIN to check against the domain allow-list")
+	}
+	for _, r := range zone.Records {
+		if !strings.HasSuffix(r.Name, ".") {
+			continue
+		}
+		name := normalizeDomainName(r.Name)
+		if !slices.ContainsFunc(zone.Origins, func(o string) bool {
+			o = normalizeDomainName(o)
+			return name == o || strings.HasSuffix(name, "."+o)
+		}) {
+			return nil, fmt.Errorf("owner %s is outside the zone's origins", r.Name)
+		}
+	}
+	return zone.Origins, nil
+}
+
+func normalizeDomainName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+func normalizeRecordName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
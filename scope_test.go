@@ -0,0 +1,209 @@
+package enzonix
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+func newFakeClient(t *testing.T, opts ...Option) (*fakeapi.Server, *Client) {
+	t.Helper()
+	fake := fakeapi.New("key")
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	client, err := NewClient("key", append([]Option{WithBaseURL(server.URL)}, opts...)...)
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+	return fake, client
+}
+
+func TestScopedDomainAllowList(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeClient(t)
+	allowed := fake.AddDomain("allowed.example")
+	other := fake.AddDomain("other.example")
+	otherRecord := fake.AddRecord(fakeapi.Record{DomainID: other.ID, Name: "www", Type: "A", Value: "192.0.2.1"})
+
+	scoped, err := client.Scoped(ScopeOptions{DomainNames: []string{"allowed.example."}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	domains, err := scoped.ListDomains(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(domains) != 1 || domains[0].ID != allowed.ID {
+		t.Fatalf("expected only the allowed domain, got %#v", domains)
+	}
+
+	if _, err := scoped.CreateRecord(ctx, CreateRecordRequest{DomainID: allowed.ID, Name: "www", Type: "A", Value: "192.0.2.2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checks := map[string]error{
+		"CreateRecord": func() error {
+			_, err := scoped.CreateRecord(ctx, CreateRecordRequest{DomainID: other.ID, Name: "www", Type: "A", Value: "192.0.2.2"})
+			return err
+		}(),
+		"DeleteRecord":      scoped.DeleteRecord(ctx, otherRecord.ID),
+		"DeleteDomain":      scoped.DeleteDomain(ctx, other.ID),
+		"ListDomainRecords": func() error { _, err := scoped.ListDomainRecords(ctx, other.ID); return err }(),
+		"ImportBindZone": func() error {
+			_, err := scoped.ImportBindZone(ctx, []byte("$ORIGIN other.example.\nwww 300 IN A 192.0.2.9\n"), "")
+			return err
+		}(),
+		"ImportBindZone (second origin)": func() error {
+			_, err := scoped.ImportBindZone(ctx, []byte("$ORIGIN allowed.example.\nwww 300 IN A 192.0.2.8\n$ORIGIN other.example.\nwww 300 IN A 192.0.2.9\n"), "")
+			return err
+		}(),
+		"ImportBindZone (absolute owner)": func() error {
+			_, err := scoped.ImportBindZone(ctx, []byte("$ORIGIN allowed.example.\nwww.other.example. 300 IN A 192.0.2.9\n"), "")
+			return err
+		}(),
+		"RotateAPIKey": func() error { _, err := scoped.RotateAPIKey(ctx); return err }(),
+	}
+	for op, err := range checks {
+		var violation *ScopeViolationError
+		if !errors.As(err, &violation) || !errors.Is(err, ErrScopeViolation) {
+			t.Fatalf("%s: expected scope violation, got %v", op, err)
+		}
+		if want, _, _ := strings.Cut(op, " ("); violation.Operation != want {
+			t.Fatalf("%s: unexpected operation %q", op, violation.Operation)
+		}
+	}
+
+	if len(fake.Records(other.ID)) != 1 || len(fake.Domains()) != 2 {
+		t.Fatalf("expected the other domain to be untouched")
+	}
+
+	zone := "$ORIGIN allowed.example.\napi 300 IN A 192.0.2.3\napi.allowed.example. 300 IN AAAA 2001:db8::3\n"
+	if _, err := scoped.ImportBindZone(ctx, []byte(zone), ""); err != nil {
+		t.Fatalf("import into the allowed domain: %v", err)
+	}
+}
+
+func TestScopedRecordRestrictions(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeClient(t)
+	domain := fake.AddDomain("example.com")
+	challenge := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "_acme-challenge", Type: "TXT", Value: "old"})
+	www := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", Value: "192.0.2.1"})
+
+	scoped, err := client.Scoped(ScopeOptions{RecordTypes: []string{"txt"}, RecordNames: []string{"_acme-challenge*"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	records, err := scoped.ListDomainRecords(ctx, domain.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].ID != challenge.ID {
+		t.Fatalf("expected only the challenge record, got %#v", records)
+	}
+
+	value := "new"
+	if _, err := scoped.UpdateRecord(ctx, challenge.ID, UpdateRecordRequest{Value: &value}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rename := "www2"
+	if _, err := scoped.UpdateRecord(ctx, challenge.ID, UpdateRecordRequest{Name: &rename}); !errors.Is(err, ErrScopeViolation) {
+		t.Fatalf("expected renaming out of scope to fail, got %v", err)
+	}
+	if err := scoped.DeleteRecord(ctx, www.ID); !errors.Is(err, ErrScopeViolation) {
+		t.Fatalf("expected deleting an A record to fail, got %v", err)
+	}
+	if _, err := scoped.CreateRecord(ctx, CreateRecordRequest{DomainID: domain.ID, Name: "mail", Type: "TXT", Value: "x"}); !errors.Is(err, ErrScopeViolation) {
+		t.Fatalf("expected creating an out-of-scope name to fail, got %v", err)
+	}
+}
+
+func TestScopedReadOnly(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeClient(t)
+	domain := fake.AddDomain("example.com")
+	record := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", Value: "192.0.2.1"})
+
+	readOnly, err := client.Scoped(ScopeOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := readOnly.ListDomainRecords(ctx, domain.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	before := len(fake.Requests())
+	value := "192.0.2.2"
+	if _, err := readOnly.UpdateRecord(ctx, record.ID, UpdateRecordRequest{Value: &value}); !errors.Is(err, ErrScopeViolation) {
+		t.Fatalf("expected read-only violation, got %v", err)
+	}
+	if _, err := readOnly.CreateDomain(ctx, "new.example"); !errors.Is(err, ErrScopeViolation) {
+		t.Fatalf("expected read-only violation, got %v", err)
+	}
+	if after := len(fake.Requests()); after != before {
+		t.Fatalf("expected no requests to be sent, got %d", after-before)
+	}
+
+	// The parent client is unaffected.
+	if _, err := client.UpdateRecord(ctx, record.ID, UpdateRecordRequest{Value: &value}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRecordHooksShareLookup(t *testing.T) {
+	t.Parallel()
+
+	policy, err := ParsePolicy([]byte(testPolicyYAML))
+	if err != nil {
+		t.Fatalf("parse policy: %v", err)
+	}
+	sink := AuditSinkFunc(func(context.Context, AuditEntry) error { return nil })
+	fake, parent := newFakeClient(t, WithPolicy(policy), WithAuditSink(sink))
+	domain := fake.AddDomain("example.com")
+	www := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", TTL: 300, Value: "192.0.2.1"})
+	api := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "api", Type: "A", TTL: 300, Value: "192.0.2.2"})
+	client, err := parent.Scoped(ScopeOptions{RecordTypes: []string{"A"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	lookups := func(run func() error) int {
+		t.Helper()
+		before := len(fake.Requests())
+		if err := run(); err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, r := range fake.Requests()[before:] {
+			if strings.HasPrefix(r, "GET ") && strings.HasSuffix(r, "/records") {
+				n++
+			}
+		}
+		return n
+	}
+	value := "192.0.2.3"
+	if n := lookups(func() error {
+		_, err := client.UpdateRecord(ctx, www.ID, UpdateRecordRequest{Value: &value})
+		return err
+	}); n != 1 {
+		t.Fatalf("update looked the record up %d times", n)
+	}
+	if n := lookups(func() error { return client.DeleteRecord(ctx, api.ID) }); n != 1 {
+		t.Fatalf("delete looked the record up %d times", n)
+	}
+}
@@ -0,0 +1,282 @@
+// Package verify checks that record changes have reached authoritative
+// nameservers. It speaks the DNS wire protocol directly, over UDP with a
+// TCP fallback for truncated responses, and needs no resolver
+// configuration.
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/dnswire"
+)
+
+var (
+	// ErrNoZone is returned when a record's name is relative and no zone
+	// was given with WithZone.
+	ErrNoZone = errors.New("verify: record name is relative and no zone was given")
+	// ErrNoServers is returned when no servers were given.
+	ErrNoServers = errors.New("verify: no servers to query")
+	// ErrNotPropagated is matched by a *PropagationError.
+	ErrNotPropagated = errors.New("verify: record not propagated")
+)
+
+// Answer is a record returned by a server, in the same shape as
+// enzonix.Record values: MX and SRV priorities are split from the value
+// and names are fully qualified.
+type Answer struct {
+	Name     string
+	Type     string
+	TTL      uint32
+	Priority int
+	Value    string
+}
+
+// RcodeError reports a response with an error code other than NXDOMAIN.
+type RcodeError struct {
+	Server string
+	Rcode  int
+}
+
+// Error satisfies the error interface.
+func (e *RcodeError) Error() string {
+	return fmt.Sprintf("verify: %s answered with rcode %d", e.Server, e.Rcode)
+}
+
+// Query asks server ("host" or "host:port") for the records of name and
+// type. A name that does not exist yields no answers and no error.
+func Query(ctx context.Context, server, name, typ string) ([]Answer, error) {
+	qtype, ok := dnswire.ParseType(typ)
+	if !ok {
+		return nil, fmt.Errorf("verify: unsupported record type %q", typ)
+	}
+	server = serverAddr(server)
+	resp, err := dnswire.Exchange(ctx, server, dnswire.NewQuery(name, qtype))
+	if err != nil {
+		return nil, err
+	}
+	switch resp.Rcode {
+	case dnswire.RcodeSuccess, dnswire.RcodeNXDomain:
+	default:
+		return nil, &RcodeError{Server: server, Rcode: int(resp.Rcode)}
+	}
+
+	var answers []Answer
+	for _, rr := range resp.Answers {
+		if rr.Type != qtype || !dnswire.EqualNames(rr.Name, name) {
+			continue
+		}
+		value, priority, err := dnswire.UnpackRData(rr.Type, rr.Data)
+		if err != nil {
+			return nil, fmt.Errorf("verify: decode answer from %s: %w", server, err)
+		}
+		answers = append(answers, Answer{Name: rr.Name, Type: typ, TTL: rr.TTL, Priority: priority, Value: value})
+	}
+	return answers, nil
+}
+
+// ServerResult is the outcome of checking one server.
+type ServerResult struct {
+	Server     string
+	Propagated bool
+	Answers    []Answer
+	// Err is the query error, if the query failed.
+	Err error
+}
+
+// Option customises Check and WaitForPropagation.
+type Option func(*config)
+
+type config struct {
+	zone         string
+	interval     time.Duration
+	queryTimeout time.Duration
+	onProgress   func([]ServerResult)
+}
+
+// WithZone sets the zone relative record names belong to, usually the
+// domain's name. It is required unless the record name is absolute.
+func WithZone(zone string) Option {
+	return func(c *config) { c.zone = zone }
+}
+
+// WithInterval sets the wait between rounds of queries, 2s by default.
+func WithInterval(d time.Duration) Option {
+	return func(c *config) { c.interval = d }
+}
+
+// WithQueryTimeout bounds each query, 3s by default.
+func WithQueryTimeout(d time.Duration) Option {
+	return func(c *config) { c.queryTimeout = d }
+}
+
+// WithProgress is called with the results of every round.
+func WithProgress(fn func([]ServerResult)) Option {
+	return func(c *config) { c.onProgress = fn }
+}
+
+// PropagationError lists the servers that did not serve the record before
+// the context ended. It matches ErrNotPropagated and unwraps to the
+// context error.
+type PropagationError struct {
+	Record  enzonix.Record
+	Pending []ServerResult
+	cause   error
+}
+
+// Error satisfies the error interface.
+func (e *PropagationError) Error() string {
+	servers := make([]string, 0, len(e.Pending))
+	for _, r := range e.Pending {
+		if r.Err != nil {
+			servers = append(servers, fmt.Sprintf("%s (%v)", r.Server, r.Err))
+		} else {
+			servers = append(servers, r.Server)
+		}
+	}
+	return fmt.Sprintf("verify: %s %s %q not propagated to %s", e.Record.Name, e.Record.Type, e.Record.Value, strings.Join(servers, ", "))
+}
+
+// Is matches ErrNotPropagated.
+func (e *PropagationError) Is(target error) bool { return target == ErrNotPropagated }
+
+// Unwrap returns the context error that ended the wait.
+func (e *PropagationError) Unwrap() error { return e.cause }
+
+// Check queries every server once and reports whether each serves the
+// record: an answer with the record's value, and priority for MX and SRV.
+func Check(ctx context.Context, record enzonix.Record, servers []string, opts ...Option) ([]ServerResult, error) {
+	cfg := newConfig(opts)
+	want, err := expected(record, cfg.zone)
+	if err != nil {
+		return nil, err
+	}
+	if len(servers) == 0 {
+		return nil, ErrNoServers
+	}
+	return check(ctx, want, servers, cfg), nil
+}
+
+// WaitForPropagation queries servers until all of them serve the record,
+// repeating every interval. Bound the wait with the context; when it ends
+// first the error is a *PropagationError.
+func WaitForPropagation(ctx context.Context, record enzonix.Record, servers []string, opts ...Option) error {
+	cfg := newConfig(opts)
+	want, err := expected(record, cfg.zone)
+	if err != nil {
+		return err
+	}
+	if len(servers) == 0 {
+		return ErrNoServers
+	}
+
+	pending := servers
+	for {
+		results := check(ctx, want, pending, cfg)
+		if cfg.onProgress != nil {
+			cfg.onProgress(results)
+		}
+		var lagging []ServerResult
+		pending = pending[:0:0]
+		for _, r := range results {
+			if !r.Propagated {
+				lagging = append(lagging, r)
+				pending = append(pending, r.Server)
+			}
+		}
+		if len(lagging) == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(cfg.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &PropagationError{Record: record, Pending: lagging, cause: ctx.Err()}
+		case <-timer.C:
+		}
+	}
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{interval: 2 * time.Second, queryTimeout: 3 * time.Second}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// expected converts the record to the answer servers should return.
+func expected(record enzonix.Record, zone string) (Answer, error) {
+	if zone == "" && !strings.HasSuffix(record.Name, ".") {
+		return Answer{}, ErrNoZone
+	}
+	typ, ok := dnswire.ParseType(record.Type)
+	if !ok {
+		return Answer{}, fmt.Errorf("verify: unsupported record type %q", record.Type)
+	}
+	// Round-trip the value through the wire format so that it compares
+	// like an answer: names qualified, TXT unquoted, CAA requoted.
+	data, err := dnswire.PackRData(typ, record.Value, record.Priority, zone)
+	if err != nil {
+		return Answer{}, fmt.Errorf("verify: %w", err)
+	}
+	value, priority, err := dnswire.UnpackRData(typ, data)
+	if err != nil {
+		return Answer{}, fmt.Errorf("verify: %w", err)
+	}
+	return Answer{
+		Name:     dnswire.Absolute(record.Name, zone),
+		Type:     dnswire.TypeString(typ),
+		Priority: priority,
+		Value:    value,
+	}, nil
+}
+
+func check(ctx context.Context, want Answer, servers []string, cfg config) []ServerResult {
+	results := make([]ServerResult, len(servers))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func(i int, server string) {
+			defer wg.Done()
+			qctx, cancel := context.WithTimeout(ctx, cfg.queryTimeout)
+			defer cancel()
+			answers, err := Query(qctx, server, want.Name, want.Type)
+			results[i] = ServerResult{Server: server, Answers: answers, Err: err}
+			for _, a := range answers {
+				if matches(want, a) {
+					results[i].Propagated = true
+					break
+				}
+			}
+		}(i, server)
+	}
+	wg.Wait()
+	return results
+}
+
+func matches(want, got Answer) bool {
+	if want.Priority != got.Priority {
+		return false
+	}
+	switch want.Type {
+	case "TXT", "CAA":
+		return want.Value == got.Value
+	}
+	// Names and IPv6 addresses compare case-insensitively.
+	return strings.EqualFold(want.Value, got.Value)
+}
+
+func serverAddr(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), "53")
+}
@@ -0,0 +1,233 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	enzonix "github.com/Enzonix-LLC/dns-sdk-go"
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/dnswire"
+)
+
+// stub is an in-process authoritative server answering from a mutable
+// record set, over UDP and, on the same port, TCP.
+type stub struct {
+	addr string
+
+	mu       sync.Mutex
+	records  []dnswire.RR
+	truncate bool
+	tcp      int
+}
+
+func newStub(t *testing.T) *stub {
+	t.Helper()
+	var (
+		udp *net.UDPConn
+		tcp net.Listener
+		err error
+	)
+	// Retry until the UDP port is also free for TCP.
+	for i := 0; i < 10; i++ {
+		udp, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		if err != nil {
+			t.Fatalf("listen udp: %v", err)
+		}
+		if tcp, err = net.Listen("tcp", udp.LocalAddr().String()); err == nil {
+			break
+		}
+		udp.Close()
+	}
+	if err != nil {
+		t.Fatalf("listen tcp: %v", err)
+	}
+	s := &stub{addr: udp.LocalAddr().String()}
+	t.Cleanup(func() {
+		udp.Close()
+		tcp.Close()
+	})
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			resp, err := s.answer(buf[:n])
+			if err != nil {
+				continue
+			}
+			s.mu.Lock()
+			truncate := s.truncate
+			s.mu.Unlock()
+			if truncate {
+				resp.Answers = nil
+				resp.Truncated = true
+			}
+			out, _ := resp.Pack()
+			_, _ = udp.WriteTo(out, from)
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.tcp++
+			s.mu.Unlock()
+			go func() {
+				defer conn.Close()
+				query, err := dnswire.ReadTCP(conn)
+				if err != nil {
+					return
+				}
+				resp, err := s.answer(query)
+				if err != nil {
+					return
+				}
+				out, _ := resp.Pack()
+				_ = dnswire.WriteTCP(conn, out)
+			}()
+		}
+	}()
+	return s
+}
+
+func (s *stub) answer(query []byte) (*dnswire.Message, error) {
+	q, err := dnswire.Unpack(query)
+	if err != nil || len(q.Questions) != 1 {
+		return nil, errors.New("bad query")
+	}
+	resp := &dnswire.Message{Header: q.Header, Questions: q.Questions}
+	resp.Response, resp.Authoritative = true, true
+	question := q.Questions[0]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exists := false
+	for _, rr := range s.records {
+		if !dnswire.EqualNames(rr.Name, question.Name) {
+			continue
+		}
+		exists = true
+		if rr.Type == question.Type {
+			resp.Answers = append(resp.Answers, rr)
+		}
+	}
+	if !exists {
+		resp.Rcode = dnswire.RcodeNXDomain
+	}
+	return resp, nil
+}
+
+func (s *stub) set(t *testing.T, name, typ, value string, priority int) {
+	t.Helper()
+	qtype, _ := dnswire.ParseType(typ)
+	data, err := dnswire.PackRData(qtype, value, priority, "example.com")
+	if err != nil {
+		t.Fatalf("pack: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, dnswire.RR{Name: name, Type: qtype, Class: dnswire.ClassINET, TTL: 300, Data: data})
+}
+
+func TestWaitForPropagation(t *testing.T) {
+	t.Parallel()
+
+	ready, lagging := newStub(t), newStub(t)
+	ready.set(t, "www.example.com.", "A", "192.0.2.1", 0)
+	record := enzonix.Record{Name: "www", Type: "A", Value: "192.0.2.1"}
+	ctx := context.Background()
+
+	var rounds int
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		lagging.set(t, "www.example.com.", "A", "192.0.2.1", 0)
+	}()
+	err := WaitForPropagation(ctx, record, []string{ready.addr, lagging.addr},
+		WithZone("example.com"),
+		WithInterval(10*time.Millisecond),
+		WithProgress(func([]ServerResult) { rounds++ }))
+	if err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if rounds < 2 {
+		t.Fatalf("expected the lagging server to be polled again, got %d rounds", rounds)
+	}
+
+	// A stale value never matches.
+	ready.set(t, "mail.example.com.", "MX", "old-mx.example.com.", 10)
+	short, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	mx := enzonix.Record{Name: "mail", Type: "MX", Priority: 10, Value: "mx.example.com"}
+	err = WaitForPropagation(short, mx, []string{ready.addr}, WithZone("example.com"), WithInterval(10*time.Millisecond))
+	var perr *PropagationError
+	if !errors.As(err, &perr) || !errors.Is(err, ErrNotPropagated) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a propagation error, got %v", err)
+	}
+	if len(perr.Pending) != 1 || len(perr.Pending[0].Answers) != 1 || perr.Pending[0].Answers[0].Value != "old-mx.example.com." {
+		t.Fatalf("unexpected pending servers %+v", perr.Pending)
+	}
+
+	if err := WaitForPropagation(ctx, record, []string{ready.addr}); !errors.Is(err, ErrNoZone) {
+		t.Fatalf("expected ErrNoZone, got %v", err)
+	}
+}
+
+func TestCheckValues(t *testing.T) {
+	t.Parallel()
+
+	s := newStub(t)
+	s.set(t, "example.com.", "TXT", "v=spf1 include:_spf.example.net ~all", 0)
+	s.set(t, "_sip._tcp.example.com.", "SRV", "5 5060 SIP.example.com.", 20)
+	s.set(t, "example.com.", "CAA", `0 issue "letsencrypt.org"`, 0)
+	ctx := context.Background()
+
+	for _, record := range []enzonix.Record{
+		{Name: "@", Type: "TXT", Value: "v=spf1 include:_spf.example.net ~all"},
+		{Name: "_sip._tcp", Type: "SRV", Priority: 20, Value: "5 5060 sip.example.com"},
+		{Name: "example.com.", Type: "CAA", Value: `0 issue "letsencrypt.org"`},
+	} {
+		results, err := Check(ctx, record, []string{s.addr}, WithZone("example.com"))
+		if err != nil || len(results) != 1 || !results[0].Propagated {
+			t.Fatalf("%s %s: unexpected results %+v, %v", record.Name, record.Type, results, err)
+		}
+	}
+
+	results, err := Check(ctx, enzonix.Record{Name: "_sip._tcp", Type: "SRV", Priority: 10, Value: "5 5060 sip.example.com"}, []string{s.addr}, WithZone("example.com"))
+	if err != nil || results[0].Propagated {
+		t.Fatalf("expected a different priority not to match, got %+v, %v", results, err)
+	}
+	answers, err := Query(ctx, s.addr, "missing.example.com", "A")
+	if err != nil || len(answers) != 0 {
+		t.Fatalf("expected no answers for NXDOMAIN, got %+v, %v", answers, err)
+	}
+}
+
+func TestQueryFallsBackToTCP(t *testing.T) {
+	t.Parallel()
+
+	s := newStub(t)
+	s.set(t, "big.example.com.", "TXT", strings.Repeat("a", 600), 0)
+	s.mu.Lock()
+	s.truncate = true
+	s.mu.Unlock()
+
+	answers, err := Query(context.Background(), s.addr, "big.example.com", "TXT")
+	if err != nil || len(answers) != 1 || len(answers[0].Value) != 600 {
+		t.Fatalf("unexpected answers %+v, %v", answers, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tcp != 1 {
+		t.Fatalf("expected one TCP query, got %d", s.tcp)
+	}
+}
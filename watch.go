@@ -0,0 +1,321 @@
+package enzonix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// ChangeType classifies a ChangeEvent.
+type ChangeType string
+
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// ChangeEvent reports a record change observed by Watch.
+type ChangeEvent struct {
+	Type     ChangeType `json:"type"`
+	DomainID string     `json:"domain_id"`
+	// Record is the current state, or the last known state for deletions.
+	Record Record `json:"record"`
+	// Previous is the last known state of an updated record.
+	Previous *Record   `json:"previous,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// WatchState persists the last seen records of each domain so a restarted
+// watcher only reports changes made while it was down.
+type WatchState interface {
+	// Load returns the saved records, and false when none were saved.
+	Load(ctx context.Context, domainID string) ([]Record, bool, error)
+	Save(ctx context.Context, domainID string, records []Record) error
+}
+
+// WatchOption customises Watch.
+type WatchOption func(*watchConfig)
+
+type watchConfig struct {
+	state      WatchState
+	handler    func(ChangeEvent)
+	onError    func(domainID string, err error)
+	jitter     float64
+	maxBackoff time.Duration
+}
+
+// WithWatchState persists the last seen state, see FileWatchState. Without
+// it state is kept in memory and the first poll after a start only records
+// a baseline.
+func WithWatchState(state WatchState) WatchOption {
+	return func(c *watchConfig) { c.state = state }
+}
+
+// WithWatchHandler delivers events to fn instead of the channel. Calls are
+// serialized. The channel is still closed once watching stops.
+func WithWatchHandler(fn func(ChangeEvent)) WatchOption {
+	return func(c *watchConfig) { c.handler = fn }
+}
+
+// WithWatchErrorHandler receives polling errors. Failing domains back off
+// exponentially and are retried.
+func WithWatchErrorHandler(fn func(domainID string, err error)) WatchOption {
+	return func(c *watchConfig) { c.onError = fn }
+}
+
+// WithWatchJitter randomizes each wait by up to the given fraction of the
+// interval, 0.1 by default, so that many domains do not poll in lockstep.
+func WithWatchJitter(fraction float64) WatchOption {
+	return func(c *watchConfig) { c.jitter = fraction }
+}
+
+// WithWatchMaxBackoff caps the wait after repeated failures. It defaults
+// to ten intervals.
+func WithWatchMaxBackoff(d time.Duration) WatchOption {
+	return func(c *watchConfig) { c.maxBackoff = d }
+}
+
+// Watch polls the records of the given domains every interval and reports
+// records created, updated or deleted since the last poll. Records are
+// matched by ID; a record counts as updated when its UpdatedAt or content
+// changed. Each domain is polled independently, starting at a random
+// offset within the first interval.
+//
+// The returned channel is closed after ctx is done. Events not received
+// block the domain's poller.
+func (c *Client) Watch(ctx context.Context, domainIDs []string, interval time.Duration, opts ...WatchOption) (<-chan ChangeEvent, error) {
+	if interval <= 0 {
+		return nil, errors.New("enzonix: watch interval must be positive")
+	}
+	if len(domainIDs) == 0 {
+		return nil, errors.New("enzonix: no domains to watch")
+	}
+	for _, id := range domainIDs {
+		if err := requireID(id, "domain id"); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := watchConfig{jitter: 0.1, maxBackoff: 10 * interval}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.state == nil {
+		cfg.state = NewMemoryWatchState()
+	}
+
+	events := make(chan ChangeEvent)
+	var (
+		wg        sync.WaitGroup
+		handlerMu sync.Mutex
+	)
+	emit := func(e ChangeEvent) bool {
+		if cfg.handler != nil {
+			handlerMu.Lock()
+			defer handlerMu.Unlock()
+			cfg.handler(e)
+			return true
+		}
+		select {
+		case events <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for _, id := range domainIDs {
+		wg.Add(1)
+		go func(domainID string) {
+			defer wg.Done()
+			c.watchDomain(ctx, domainID, interval, cfg, emit)
+		}(id)
+	}
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+	return events, nil
+}
+
+func (c *Client) watchDomain(ctx context.Context, domainID string, interval time.Duration, cfg watchConfig, emit func(ChangeEvent) bool) {
+	wait := time.Duration(rand.Int63n(int64(interval)))
+	failures := 0
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := c.pollDomain(ctx, domainID, cfg.state, emit); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if cfg.onError != nil {
+				cfg.onError(domainID, err)
+			}
+			failures++
+		} else {
+			failures = 0
+		}
+		wait = watchDelay(interval, failures, cfg)
+	}
+}
+
+// watchDelay returns the jittered wait before the next poll, doubling the
+// interval for every consecutive failure.
+func watchDelay(interval time.Duration, failures int, cfg watchConfig) time.Duration {
+	d := interval
+	for i := 0; i < failures && d < cfg.maxBackoff; i++ {
+		d *= 2
+	}
+	if cfg.maxBackoff > 0 && d > cfg.maxBackoff {
+		d = cfg.maxBackoff
+	}
+	if cfg.jitter > 0 {
+		spread := float64(d) * cfg.jitter
+		d += time.Duration((rand.Float64()*2 - 1) * spread)
+	}
+	if d <= 0 {
+		d = time.Millisecond
+	}
+	return d
+}
+
+func (c *Client) pollDomain(ctx context.Context, domainID string, state WatchState, emit func(ChangeEvent) bool) error {
+	current, err := c.ListDomainRecords(ctx, domainID)
+	if err != nil {
+		return err
+	}
+	previous, ok, err := state.Load(ctx, domainID)
+	if err != nil {
+		return fmt.Errorf("enzonix: load watch state: %w", err)
+	}
+	if ok {
+		for _, e := range diffRecords(domainID, previous, current, time.Now()) {
+			if !emit(e) {
+				// Stopped before the events were delivered; keep the
+				// old state so they are reported again next time.
+				return ctx.Err()
+			}
+		}
+	}
+	if err := state.Save(ctx, domainID, current); err != nil {
+		return fmt.Errorf("enzonix: save watch state: %w", err)
+	}
+	return nil
+}
+
+func diffRecords(domainID string, previous, current []Record, now time.Time) []ChangeEvent {
+	known := make(map[string]Record, len(previous))
+	for _, r := range previous {
+		known[r.ID] = r
+	}
+	var events []ChangeEvent
+	seen := make(map[string]bool, len(current))
+	for _, r := range current {
+		seen[r.ID] = true
+		old, ok := known[r.ID]
+		switch {
+		case !ok:
+			events = append(events, ChangeEvent{Type: ChangeCreated, DomainID: domainID, Record: r, Time: now})
+		case !sameTime(old.UpdatedAt, r.UpdatedAt) || !sameRecordState(old, r):
+			prev := old
+			events = append(events, ChangeEvent{Type: ChangeUpdated, DomainID: domainID, Record: r, Previous: &prev, Time: now})
+		}
+	}
+	for _, r := range previous {
+		if !seen[r.ID] {
+			events = append(events, ChangeEvent{Type: ChangeDeleted, DomainID: domainID, Record: r, Time: now})
+		}
+	}
+	return events
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// MemoryWatchState keeps watch state in memory.
+type MemoryWatchState struct {
+	mu      sync.Mutex
+	domains map[string][]Record
+}
+
+// NewMemoryWatchState returns an empty in-memory state.
+func NewMemoryWatchState() *MemoryWatchState {
+	return &MemoryWatchState{domains: map[string][]Record{}}
+}
+
+// Load implements WatchState.
+func (m *MemoryWatchState) Load(_ context.Context, domainID string) ([]Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	records, ok := m.domains[domainID]
+	return records, ok, nil
+}
+
+// Save implements WatchState.
+func (m *MemoryWatchState) Save(_ context.Context, domainID string, records []Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.domains[domainID] = append([]Record(nil), records...)
+	return nil
+}
+
+// FileWatchState keeps watch state in a JSON file, rewritten atomically
+// after every poll.
+type FileWatchState struct {
+	path string
+
+	mu      sync.Mutex
+	domains map[string][]Record
+}
+
+// NewFileWatchState loads the state file at path, which need not exist.
+func NewFileWatchState(path string) (*FileWatchState, error) {
+	s := &FileWatchState{path: path, domains: map[string][]Record{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("enzonix: read watch state: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.domains); err != nil {
+		return nil, fmt.Errorf("enzonix: decode watch state: %w", err)
+	}
+	return s, nil
+}
+
+// Load implements WatchState.
+func (f *FileWatchState) Load(_ context.Context, domainID string) ([]Record, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	records, ok := f.domains[domainID]
+	return records, ok, nil
+}
+
+// Save implements WatchState.
+func (f *FileWatchState) Save(_ context.Context, domainID string, records []Record) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.domains[domainID] = append([]Record(nil), records...)
+	data, err := json.Marshal(f.domains)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.path, data)
+}
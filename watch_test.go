@@ -0,0 +1,142 @@
+package enzonix
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Enzonix-LLC/dns-sdk-go/internal/fakeapi"
+)
+
+func nextEvent(t *testing.T, events <-chan ChangeEvent) ChangeEvent {
+	t.Helper()
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("event channel closed")
+		}
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return ChangeEvent{}
+}
+
+func TestWatchReportsChanges(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeClient(t)
+	domain := fake.AddDomain("example.com")
+	www := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", Value: "192.0.2.1"})
+	old := fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "old", Type: "A", Value: "192.0.2.9"})
+
+	state := NewMemoryWatchState()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.Watch(ctx, []string{domain.ID}, 10*time.Millisecond, WithWatchState(state))
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+
+	// Wait for the baseline before changing anything.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok, _ := state.Load(ctx, domain.ID); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no baseline recorded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	other, err := NewClient("key", WithBaseURL(client.baseURL.String()))
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	value := "192.0.2.2"
+	if _, err := other.UpdateRecord(ctx, www.ID, UpdateRecordRequest{Value: &value}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	e := nextEvent(t, events)
+	if e.Type != ChangeUpdated || e.Record.Value != value || e.Previous == nil || e.Previous.Value != "192.0.2.1" {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	if err := other.DeleteRecord(ctx, old.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if e := nextEvent(t, events); e.Type != ChangeDeleted || e.Record.ID != old.ID {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	created, err := other.CreateRecord(ctx, CreateRecordRequest{DomainID: domain.ID, Name: "new", Type: "TXT", Value: "hello"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if e := nextEvent(t, events); e.Type != ChangeCreated || e.Record.ID != created.ID || e.DomainID != domain.ID {
+		t.Fatalf("unexpected event %+v", e)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchPersistsState(t *testing.T) {
+	t.Parallel()
+
+	fake, client := newFakeClient(t)
+	domain := fake.AddDomain("example.com")
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "www", Type: "A", Value: "192.0.2.1"})
+	path := filepath.Join(t.TempDir(), "watch.json")
+
+	state, err := NewFileWatchState(path)
+	if err != nil {
+		t.Fatalf("state: %v", err)
+	}
+	ctx := context.Background()
+	if err := client.pollDomain(ctx, domain.ID, state, func(e ChangeEvent) bool {
+		t.Fatalf("unexpected event on first poll: %+v", e)
+		return true
+	}); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+
+	// A change while the watcher is down.
+	fake.AddRecord(fakeapi.Record{DomainID: domain.ID, Name: "api", Type: "A", Value: "192.0.2.5"})
+
+	restarted, err := NewFileWatchState(path)
+	if err != nil {
+		t.Fatalf("reload state: %v", err)
+	}
+	var got []ChangeEvent
+	if err := client.pollDomain(ctx, domain.ID, restarted, func(e ChangeEvent) bool {
+		got = append(got, e)
+		return true
+	}); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(got) != 1 || got[0].Type != ChangeCreated || got[0].Record.Name != "api" {
+		t.Fatalf("expected only the missed change, got %+v", got)
+	}
+}
+
+func TestWatchDelayBacksOff(t *testing.T) {
+	t.Parallel()
+
+	cfg := watchConfig{maxBackoff: time.Second}
+	for failures, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		if got := watchDelay(100*time.Millisecond, failures, cfg); got != want {
+			t.Fatalf("failures=%d: got %v, want %v", failures, got, want)
+		}
+	}
+
+	cfg.jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := watchDelay(100*time.Millisecond, 0, cfg); got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("jittered delay %v out of range", got)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package zonefile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxString is the longest character-string a TXT record can hold.
+const maxString = 255
+
+// Write renders zone as a zone file that Parse reads back: a $ORIGIN and
+// $TTL header followed by one line per record. Names and values are
+// written as they are, so targets outside the origin need a trailing dot.
+// TXT and SPF values are quoted and split into 255-byte strings.
+func Write(w io.Writer, zone *Zone) error {
+	bw := bufio.NewWriter(w)
+	if zone.Origin != "" {
+		fmt.Fprintf(bw, "$ORIGIN %s\n", canonical(zone.Origin))
+	}
+	if zone.DefaultTTL > 0 {
+		fmt.Fprintf(bw, "$TTL %d\n", zone.DefaultTTL)
+	}
+	for _, r := range zone.Records {
+		name, class, typ := r.Name, r.Class, strings.ToUpper(r.Type)
+		if name == "" {
+			name = "@"
+		}
+		if class == "" {
+			class = "IN"
+		}
+		value := r.Value
+		switch typ {
+		case "MX", "SRV":
+			value = fmt.Sprintf("%d %s", r.Priority, value)
+		case "TXT", "SPF":
+			value = quoteText(value)
+		}
+		fmt.Fprintf(bw, "%s\t%d\t%s\t%s\t%s\n", name, r.TTL, class, typ, value)
+	}
+	return bw.Flush()
+}
+
+// quoteText quotes s as one or more character-strings.
+func quoteText(s string) string {
+	var parts []string
+	for {
+		chunk := s
+		if len(chunk) > maxString {
+			chunk = chunk[:maxString]
+		}
+		s = s[len(chunk):]
+		var b strings.Builder
+		b.WriteByte('"')
+		for i := 0; i < len(chunk); i++ {
+			if chunk[i] == '"' || chunk[i] == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(chunk[i])
+		}
+		b.WriteByte('"')
+		parts = append(parts, b.String())
+		if s == "" {
+			return strings.Join(parts, " ")
+		}
+	}
+}
@@ -0,0 +1,335 @@
+// Package zonefile parses BIND-style zone files into a flat list of
+// records shaped like Enzonix records: names relative to the origin ("@"
+// for the apex), MX and SRV priorities split from the value, and TXT
+// strings unquoted. Write renders such records back into a zone file.
+package zonefile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Record is a single resource record from a zone file.
+type Record struct {
+	Name     string
+	TTL      int
+	Class    string
+	Type     string
+	Priority int
+	Value    string
+	// Origin is the origin in effect for the record, which Name is
+	// relative to.
+	Origin string
+	// Line is the line number the record starts on.
+	Line int
+}
+
+// Zone is the parsed content of a zone file.
+type Zone struct {
+	// Origin is the origin in effect at the end of the file.
+	Origin string
+	// Origins lists every origin set by a $ORIGIN directive, in order.
+	Origins    []string
+	DefaultTTL int
+	Records    []Record
+}
+
+// ParseError reports a malformed line.
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+// Error satisfies the error interface.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("zonefile: line %d: %s", e.Line, e.Msg)
+}
+
+var classes = map[string]bool{"IN": true, "CH": true, "HS": true, "CS": true}
+
+// Parse reads a zone file. origin is used until a $ORIGIN directive
+// appears and may be empty.
+func Parse(r io.Reader, origin string) (*Zone, error) {
+	zone := &Zone{Origin: canonical(origin), DefaultTTL: 3600}
+
+	var (
+		owner      = "@"
+		lastTTL    = 0
+		ttlDefined = false
+	)
+
+	entries, err := logicalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		tokens := entry.tokens
+		if len(tokens) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(tokens[0].text) {
+		case "$ORIGIN":
+			if len(tokens) < 2 {
+				return nil, &ParseError{entry.line, "$ORIGIN requires a name"}
+			}
+			zone.Origin = canonical(absolute(tokens[1].text, zone.Origin))
+			zone.Origins = append(zone.Origins, zone.Origin)
+			continue
+		case "$TTL":
+			if len(tokens) < 2 {
+				return nil, &ParseError{entry.line, "$TTL requires a value"}
+			}
+			ttl, err := ParseTTL(tokens[1].text)
+			if err != nil {
+				return nil, &ParseError{entry.line, err.Error()}
+			}
+			zone.DefaultTTL = ttl
+			ttlDefined = true
+			continue
+		case "$INCLUDE", "$GENERATE":
+			return nil, &ParseError{entry.line, tokens[0].text + " is not supported"}
+		}
+
+		if !entry.indented {
+			owner = tokens[0].text
+			tokens = tokens[1:]
+		}
+
+		rec := Record{Name: relative(owner, zone.Origin), TTL: -1, Class: "IN", Origin: zone.Origin, Line: entry.line}
+
+		// TTL and class may appear in either order before the type.
+		for i := 0; i < 2 && len(tokens) > 0 && !tokens[0].quoted; i++ {
+			if classes[strings.ToUpper(tokens[0].text)] {
+				rec.Class = strings.ToUpper(tokens[0].text)
+				tokens = tokens[1:]
+				continue
+			}
+			if ttl, err := ParseTTL(tokens[0].text); err == nil {
+				rec.TTL = ttl
+				tokens = tokens[1:]
+			}
+		}
+		if len(tokens) < 2 {
+			return nil, &ParseError{entry.line, "record needs a type and data"}
+		}
+		rec.Type = strings.ToUpper(tokens[0].text)
+		rdata := tokens[1:]
+
+		// Without an explicit TTL, $TTL applies; before any $TTL the
+		// previous record's TTL is inherited (RFC 2308 section 4).
+		if rec.TTL < 0 {
+			if !ttlDefined && lastTTL > 0 {
+				rec.TTL = lastTTL
+			} else {
+				rec.TTL = zone.DefaultTTL
+			}
+		}
+		lastTTL = rec.TTL
+
+		switch rec.Type {
+		case "MX", "SRV":
+			prio, err := strconv.Atoi(rdata[0].text)
+			if err != nil || len(rdata) < 2 {
+				return nil, &ParseError{entry.line, rec.Type + " record needs a priority and target"}
+			}
+			rec.Priority = prio
+			rec.Value = joinTokens(rdata[1:])
+		case "TXT", "SPF":
+			parts := make([]string, 0, len(rdata))
+			for _, t := range rdata {
+				parts = append(parts, t.text)
+			}
+			rec.Value = strings.Join(parts, "")
+		default:
+			rec.Value = joinTokens(rdata)
+		}
+
+		zone.Records = append(zone.Records, rec)
+	}
+
+	return zone, nil
+}
+
+// ParseTTL parses a TTL in seconds or BIND unit notation such as "1h30m".
+func ParseTTL(s string) (int, error) {
+	if s == "" {
+		return 0, fmt.Errorf("invalid TTL %q", s)
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("invalid TTL %q", s)
+		}
+		return n, nil
+	}
+	total, current := 0, 0
+	digits := false
+	for _, r := range strings.ToLower(s) {
+		if r >= '0' && r <= '9' {
+			current = current*10 + int(r-'0')
+			digits = true
+			continue
+		}
+		if !digits {
+			return 0, fmt.Errorf("invalid TTL %q", s)
+		}
+		switch r {
+		case 's':
+			total += current
+		case 'm':
+			total += current * 60
+		case 'h':
+			total += current * 3600
+		case 'd':
+			total += current * 86400
+		case 'w':
+			total += current * 604800
+		default:
+			return 0, fmt.Errorf("invalid TTL %q", s)
+		}
+		current, digits = 0, false
+	}
+	if digits {
+		return 0, fmt.Errorf("invalid TTL %q", s)
+	}
+	return total, nil
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+type logicalLine struct {
+	line     int
+	indented bool
+	tokens   []token
+}
+
+// logicalLines tokenizes the input, joining parenthesised continuations and
+// dropping comments.
+func logicalLines(r io.Reader) ([]logicalLine, error) {
+	var (
+		out     []logicalLine
+		current *logicalLine
+		depth   int
+	)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		text := scanner.Text()
+		if current == nil {
+			current = &logicalLine{line: n, indented: len(text) > 0 && (text[0] == ' ' || text[0] == '\t')}
+		}
+
+		for i := 0; i < len(text); {
+			c := text[i]
+			switch {
+			case c == ';':
+				i = len(text)
+			case c == ' ' || c == '\t' || c == '\r':
+				i++
+			case c == '(':
+				depth++
+				i++
+			case c == ')':
+				if depth == 0 {
+					return nil, &ParseError{n, "unbalanced parenthesis"}
+				}
+				depth--
+				i++
+			case c == '"':
+				var b strings.Builder
+				j := i + 1
+				for ; j < len(text) && text[j] != '"'; j++ {
+					if text[j] == '\\' && j+1 < len(text) {
+						j++
+					}
+					b.WriteByte(text[j])
+				}
+				if j >= len(text) {
+					return nil, &ParseError{n, "unterminated quoted string"}
+				}
+				current.tokens = append(current.tokens, token{text: b.String(), quoted: true})
+				i = j + 1
+			default:
+				j := i
+				for j < len(text) && !strings.ContainsRune(" \t\r;()\"", rune(text[j])) {
+					j++
+				}
+				current.tokens = append(current.tokens, token{text: text[i:j]})
+				i = j
+			}
+		}
+
+		if depth == 0 {
+			if len(current.tokens) > 0 {
+				out = append(out, *current)
+			}
+			current = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if depth != 0 {
+		return nil, &ParseError{current.line, "unterminated parenthesis"}
+	}
+	return out, nil
+}
+
+func joinTokens(tokens []token) string {
+	parts := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if t.quoted {
+			parts = append(parts, strconv.Quote(t.text))
+		} else {
+			parts = append(parts, t.text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func canonical(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name != "" && !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+func absolute(name, origin string) string {
+	switch {
+	case name == "@":
+		return origin
+	case strings.HasSuffix(name, "."):
+		return name
+	case origin == "":
+		return name
+	default:
+		return name + "." + origin
+	}
+}
+
+// relative converts an owner name to the form used by the Enzonix API.
+func relative(name, origin string) string {
+	if name == "@" {
+		return "@"
+	}
+	if !strings.HasSuffix(name, ".") || origin == "" {
+		return name
+	}
+	lower := strings.ToLower(name)
+	if lower == origin {
+		return "@"
+	}
+	if strings.HasSuffix(lower, "."+origin) {
+		return name[:len(name)-len(origin)-1]
+	}
+	return name
+}
@@ -0,0 +1,114 @@
+package zonefile
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	input := `$ORIGIN example.com.
+$TTL 1h
+@	IN	SOA	ns1.example.com. hostmaster.example.com. (
+		2024010101 ; serial
+		7200 3600 1209600 300 )
+@		300	IN	NS	ns1.enzonix.com.
+		300	IN	NS	ns2.enzonix.com.
+www.example.com.	IN	A	192.0.2.1
+mail	600	MX	10 mx.example.com.
+_sip._tcp	IN	600	SRV	10 60 5060 sip.example.com.
+txt		TXT	"v=spf1 include:_spf.example.com; -all" "second"
+`
+	zone, err := Parse(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if zone.Origin != "example.com." || zone.DefaultTTL != 3600 {
+		t.Fatalf("unexpected zone header %#v", zone)
+	}
+
+	got := make([]Record, 0, len(zone.Records))
+	for _, r := range zone.Records {
+		if r.Origin != "example.com." {
+			t.Fatalf("unexpected origin of %#v", r)
+		}
+		r.Line, r.Origin = 0, ""
+		got = append(got, r)
+	}
+	want := []Record{
+		{Name: "@", TTL: 3600, Class: "IN", Type: "SOA", Value: "ns1.example.com. hostmaster.example.com. 2024010101 7200 3600 1209600 300"},
+		{Name: "@", TTL: 300, Class: "IN", Type: "NS", Value: "ns1.enzonix.com."},
+		{Name: "@", TTL: 300, Class: "IN", Type: "NS", Value: "ns2.enzonix.com."},
+		{Name: "www", TTL: 3600, Class: "IN", Type: "A", Value: "192.0.2.1"},
+		{Name: "mail", TTL: 600, Class: "IN", Type: "MX", Priority: 10, Value: "mx.example.com."},
+		{Name: "_sip._tcp", TTL: 600, Class: "IN", Type: "SRV", Priority: 10, Value: "60 5060 sip.example.com."},
+		{Name: "txt", TTL: 3600, Class: "IN", Type: "TXT", Value: "v=spf1 include:_spf.example.com; -allsecond"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected records:\n got %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{
+		"www IN A\n",
+		"@ IN SOA ( a b\n",
+		"txt IN TXT \"unterminated\n",
+		"mail IN MX mx.example.com.\n",
+		"$INCLUDE other.zone\n",
+	} {
+		_, err := Parse(strings.NewReader(input), "example.com")
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("expected parse error for %q, got %v", input, err)
+		}
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]int{"300": 300, "1h30m": 5400, "2d": 172800, "1w": 604800}
+	for in, want := range cases {
+		got, err := ParseTTL(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseTTL(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "h", "10x", "-5"} {
+		if _, err := ParseTTL(in); err == nil {
+			t.Fatalf("expected error for %q", in)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("k", 300)
+	in := &Zone{Origin: "example.com.", Origins: []string{"example.com."}, DefaultTTL: 3600, Records: []Record{
+		{Name: "@", TTL: 3600, Class: "IN", Type: "MX", Priority: 10, Value: "mx.example.com."},
+		{Name: "_sip._tcp", TTL: 600, Class: "IN", Type: "SRV", Priority: 5, Value: "10 5060 sip.example.com."},
+		{Name: "txt", TTL: 300, Class: "IN", Type: "TXT", Value: `say "hi"; \ ` + long},
+		{Name: "www", TTL: 300, Class: "IN", Type: "A", Value: "192.0.2.1"},
+	}}
+	var b strings.Builder
+	if err := Write(&b, in); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Parse(strings.NewReader(b.String()), "")
+	if err != nil {
+		t.Fatalf("parse: %v\n%s", err, b.String())
+	}
+	for i := range out.Records {
+		out.Records[i].Line, out.Records[i].Origin = 0, ""
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("round trip changed the zone:\n got %#v\nwant %#v", out, in)
+	}
+}